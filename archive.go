@@ -0,0 +1,361 @@
+package gositemapfetcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ArchiveRecord describes a fetched sitemap body passed to Options.Archive. It is
+// JSON-serializable so it can be stored next to the body.
+type ArchiveRecord struct {
+	// URL is the sitemap URL that was requested.
+	URL string `json:"url"`
+	// FinalURL is the URL that served the body after redirects.
+	FinalURL   string      `json:"final_url,omitempty"`
+	FetchedAt  time.Time   `json:"fetched_at"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	// Incomplete is set by ArchiveDir when the body could not be read to the
+	// end; Error says why. ReplayTransport refuses incomplete records.
+	Incomplete bool   `json:"incomplete,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ArchiveAborter may be implemented by the writers Options.Archive returns.
+// When a body cannot be read in full (a network error, a canceled walk, or a
+// body over SpecMaxSitemapBytes left unread by the parser), Abort is called in
+// place of Close, so the archive is not mistaken for what the server sent.
+// Writers without it are closed on the partial body.
+type ArchiveAborter interface {
+	Abort(err error) error
+}
+
+// ArchiveDir returns an Options.Archive function that stores each sitemap body in
+// dir as <key>.body, with its ArchiveRecord in <key>.json, where key is derived
+// from the requested URL. A later fetch of the same URL overwrites the earlier one.
+func ArchiveDir(dir string) func(ArchiveRecord) (io.WriteCloser, error) {
+	return func(record ArchiveRecord) (io.WriteCloser, error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		base := filepath.Join(dir, archiveKey(record.URL))
+		meta, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(base+".json", meta, 0o644); err != nil {
+			return nil, err
+		}
+		file, err := os.Create(base + ".body")
+		if err != nil {
+			return nil, err
+		}
+		return &archiveFile{File: file, base: base, record: record}, nil
+	}
+}
+
+// archiveFile is the ArchiveDir writer; Abort marks its record incomplete.
+type archiveFile struct {
+	*os.File
+	base   string
+	record ArchiveRecord
+}
+
+func (a *archiveFile) Abort(err error) error {
+	cerr := a.File.Close()
+	a.record.Incomplete = true
+	a.record.Error = err.Error()
+	meta, merr := json.MarshalIndent(a.record, "", "  ")
+	if merr != nil {
+		return merr
+	}
+	if werr := os.WriteFile(a.base+".json", meta, 0o644); werr != nil {
+		return werr
+	}
+	return cerr
+}
+
+// ReplayTransport is an http.RoundTripper that serves sitemap bodies stored by
+// ArchiveDir instead of using the network, so a walk can be re-run
+// deterministically over a historical snapshot:
+//
+//	client := &http.Client{Transport: &ReplayTransport{Dir: dir}}
+//	fetcher := New(Options{HTTPClient: client})
+//
+// Each response carries the archived status code and headers. URLs with no
+// archived body, including robots.txt, get 404 Not Found.
+type ReplayTransport struct {
+	Dir string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	resp, err := loadArchived(t.Dir, archiveKey(req.URL.String()), req)
+	if err != nil || resp != nil {
+		return resp, err
+	}
+	return replayResponse(req, http.StatusNotFound, http.Header{}, http.NoBody), nil
+}
+
+// RecordMode controls when a RecordTransport uses the network.
+type RecordMode int
+
+const (
+	// RecordMissing replays recorded interactions and records the ones that
+	// are missing. It is the default.
+	RecordMissing RecordMode = iota
+	// RecordAll always uses the network and overwrites earlier recordings.
+	RecordAll
+	// ReplayOnly never uses the network; requests without a recording fail.
+	ReplayOnly
+)
+
+// RecordTransport is a record/replay http.RoundTripper for deterministic
+// integration tests. The first run performs real requests through Transport and
+// stores every response, including robots.txt and error statuses, in Dir; later
+// runs replay them without touching the network:
+//
+//	client := &http.Client{Transport: &RecordTransport{Dir: "testdata/example.com"}}
+//	fetcher := New(Options{HTTPClient: client})
+//
+// Recordings use the same layout as ArchiveDir, so GET recordings can also be
+// served by ReplayTransport. Bodies are stored as delivered by Transport; the
+// default transport has already undone Content-Encoding: gzip.
+type RecordTransport struct {
+	Dir string
+	// Transport performs live requests. Nil means http.DefaultTransport.
+	Transport http.RoundTripper
+	// Mode defaults to RecordMissing.
+	Mode RecordMode
+
+	mu sync.Mutex
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := recordKey(req)
+	if t.Mode != RecordAll {
+		resp, err := loadArchived(t.Dir, key, req)
+		if err != nil || resp != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return resp, err
+		}
+	}
+	if t.Mode == ReplayOnly {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("replay %s %s: no recording in %s", req.Method, req.URL, t.Dir)
+	}
+
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	record := ArchiveRecord{
+		URL:        req.URL.String(),
+		FetchedAt:  time.Now().UTC(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+	if err := t.save(key, record, body); err != nil {
+		return nil, fmt.Errorf("record %s: %w", req.URL, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+func (t *RecordTransport) save(key string, record ArchiveRecord, body []byte) error {
+	meta, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(t.Dir, key)
+	if err := os.WriteFile(base+".body", body, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".json", meta, 0o644)
+}
+
+// recordKey keys GET requests like ArchiveDir and other methods by method and URL.
+func recordKey(req *http.Request) string {
+	if req.Method == "" || req.Method == http.MethodGet {
+		return archiveKey(req.URL.String())
+	}
+	return archiveKey(req.Method + " " + req.URL.String())
+}
+
+// loadArchived returns the response stored under key in dir, or nil if there
+// is none.
+func loadArchived(dir, key string, req *http.Request) (*http.Response, error) {
+	base := filepath.Join(dir, key)
+	meta, err := os.ReadFile(base + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record ArchiveRecord
+	if err := json.Unmarshal(meta, &record); err != nil {
+		return nil, fmt.Errorf("replay %s: %w", req.URL, err)
+	}
+	if record.Incomplete {
+		return nil, fmt.Errorf("replay %s: archived body is incomplete: %s", req.URL, record.Error)
+	}
+	body, err := os.Open(base + ".body")
+	if err != nil {
+		return nil, err
+	}
+	header := record.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Del("Content-Length")
+	status := record.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if req.Method == http.MethodHead {
+		body.Close()
+		return replayResponse(req, status, header, http.NoBody), nil
+	}
+	return replayResponse(req, status, header, body), nil
+}
+
+func replayResponse(req *http.Request, status int, header http.Header, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: -1,
+		Request:       req,
+	}
+}
+
+// archiveKey maps a sitemap URL to a file-name-safe key.
+func archiveKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// archiveBody tees resp.Body into a writer obtained from Options.Archive.
+func (f *SitemapFetcher) archiveBody(loc *url.URL, resp *http.Response) error {
+	record := ArchiveRecord{
+		URL:        loc.String(),
+		FetchedAt:  time.Now().UTC(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+	if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != record.URL {
+		record.FinalURL = resp.Request.URL.String()
+	}
+	writer, err := f.opts.Archive(record)
+	if err != nil {
+		return &ErrArchive{URL: loc, Err: err}
+	}
+	resp.Body = &archiveReader{body: resp.Body, writer: writer, loc: loc}
+	return nil
+}
+
+// archiveReader copies everything read from body into writer. Write failures
+// surface from Read as *ErrArchive so they end the walk instead of looking like
+// parse errors.
+type archiveReader struct {
+	body   io.ReadCloser
+	writer io.WriteCloser
+	loc    *url.URL
+	closed bool
+}
+
+func (r *archiveReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		if _, werr := r.writer.Write(p[:n]); werr != nil {
+			return n, &ErrArchive{URL: r.loc, Err: werr}
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		if cerr := r.closeWriter(); cerr != nil {
+			return n, &ErrArchive{URL: r.loc, Err: cerr}
+		}
+	}
+	return n, err
+}
+
+func (r *archiveReader) Close() error {
+	werr := r.finish()
+	if err := r.body.Close(); err != nil {
+		return err
+	}
+	return werr
+}
+
+// finish completes the archive when the parser stopped reading early: the
+// rest of the body is copied into it, so the archive holds what the server
+// sent. A body that cannot be read to the end within SpecMaxSitemapBytes is
+// reported to an ArchiveAborter instead.
+func (r *archiveReader) finish() error {
+	if r.closed {
+		return nil
+	}
+	_, err := io.Copy(io.Discard, io.LimitReader(r, SpecMaxSitemapBytes))
+	var archiveErr *ErrArchive
+	switch {
+	case r.closed:
+		return nil
+	case errors.As(err, &archiveErr):
+		r.closeWriter()
+		return archiveErr.Err
+	case err == nil:
+		err = fmt.Errorf("body exceeds %d bytes", SpecMaxSitemapBytes)
+	}
+	r.closed = true
+	if aborter, ok := r.writer.(ArchiveAborter); ok {
+		return aborter.Abort(err)
+	}
+	return r.writer.Close()
+}
+
+func (r *archiveReader) closeWriter() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.writer.Close()
+}
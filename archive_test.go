@@ -0,0 +1,249 @@
+package gositemapfetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSitemapFetcher_ArchiveDir(t *testing.T) {
+	body := `<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	dir := t.TempDir()
+	items, err := collectItems(New(Options{Archive: ArchiveDir(dir)}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	base := filepath.Join(dir, archiveKey(sitemapURL.String()))
+	archived, err := os.ReadFile(base + ".body")
+	if err != nil {
+		t.Fatalf("failed to read archived body: %v", err)
+	}
+	if string(archived) != body {
+		t.Fatalf("expected archived body %q, got %q", body, archived)
+	}
+	meta, err := os.ReadFile(base + ".json")
+	if err != nil {
+		t.Fatalf("failed to read archive record: %v", err)
+	}
+	var record ArchiveRecord
+	if err := json.Unmarshal(meta, &record); err != nil {
+		t.Fatalf("failed to decode archive record: %v", err)
+	}
+	if record.URL != sitemapURL.String() || record.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected archive record: %+v", record)
+	}
+	if got := record.Header.Get("Content-Type"); got != "application/xml" {
+		t.Fatalf("expected archived content type, got %q", got)
+	}
+	if record.FetchedAt.IsZero() {
+		t.Fatalf("expected fetch time to be recorded")
+	}
+}
+
+func TestSitemapFetcher_ArchiveEarlyStop(t *testing.T) {
+	// Large enough that the parser stops well before the end.
+	body := "<urlset>" + strings.Repeat(`<url><loc>/page</loc></url>`, 5000) + "</urlset>"
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(body))
+		case "/cut.xml":
+			w.Header().Set("Content-Length", "1000")
+			_, _ = w.Write([]byte(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	dir := t.TempDir()
+	items, err := collectItems(New(Options{Archive: ArchiveDir(dir), MaxURLs: 1, StopAtMaxURLs: true}), sitemapURL)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected the walk to stop after 1 item, got %d (%v)", len(items), err)
+	}
+	archived, err := os.ReadFile(filepath.Join(dir, archiveKey(sitemapURL.String())+".body"))
+	if err != nil {
+		t.Fatalf("failed to read archived body: %v", err)
+	}
+	if string(archived) != body {
+		t.Fatalf("expected the whole body archived after an early stop, got %q", archived)
+	}
+
+	// A body cut short by the server is marked incomplete and not replayed.
+	cutURL, err := url.Parse(server.URL + "/cut.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	if _, err := collectItems(New(Options{Archive: ArchiveDir(dir)}), cutURL); err == nil {
+		t.Fatal("expected the truncated body to fail the walk")
+	}
+	meta, err := os.ReadFile(filepath.Join(dir, archiveKey(cutURL.String())+".json"))
+	if err != nil {
+		t.Fatalf("failed to read archive record: %v", err)
+	}
+	var record ArchiveRecord
+	if err := json.Unmarshal(meta, &record); err != nil {
+		t.Fatalf("failed to decode archive record: %v", err)
+	}
+	if !record.Incomplete || record.Error == "" {
+		t.Fatalf("expected the record marked incomplete, got %+v", record)
+	}
+	req, _ := http.NewRequest(http.MethodGet, cutURL.String(), nil)
+	if _, err := (&ReplayTransport{Dir: dir}).RoundTrip(req); err == nil {
+		t.Fatal("expected replay to refuse an incomplete record")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (failingWriter) Close() error              { return nil }
+
+func TestSitemapFetcher_ArchiveFailureAbortsWalk(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		SkipFetchErrors: true,
+		Archive: func(ArchiveRecord) (io.WriteCloser, error) {
+			return failingWriter{}, nil
+		},
+	})
+	_, err = collectItems(fetcher, sitemapURL)
+	var archiveErr *ErrArchive
+	if !errors.As(err, &archiveErr) {
+		t.Fatalf("expected ErrArchive, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_ReplayTransport(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/child.xml</loc></sitemap></sitemapindex>`))
+		case "/child.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc><lastmod>2024-01-01</lastmod></url><url><loc>/b</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	siteURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse site URL: %v", err)
+	}
+
+	dir := t.TempDir()
+	live, err := collectItems(New(Options{Archive: ArchiveDir(dir)}), siteURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server.Close()
+
+	replayed, err := collectItems(New(Options{
+		HTTPClient: &http.Client{Transport: &ReplayTransport{Dir: dir}},
+	}), siteURL)
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if len(replayed) != len(live) || len(replayed) != 2 {
+		t.Fatalf("expected %d replayed items, got %d", len(live), len(replayed))
+	}
+	for i := range live {
+		if !replayed[i].Equal(live[i]) {
+			t.Fatalf("item %d: expected %+v, got %+v", i, live[i], replayed[i])
+		}
+	}
+}
+
+func TestSitemapFetcher_RecordTransport(t *testing.T) {
+	var requests int
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nSitemap: /custom.xml\n"))
+		case "/custom.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	siteURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse site URL: %v", err)
+	}
+
+	dir := t.TempDir()
+	recorded, err := collectItems(New(Options{
+		HTTPClient: &http.Client{Transport: &RecordTransport{Dir: dir}},
+	}), siteURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recorded) != 2 || requests == 0 {
+		t.Fatalf("expected 2 live items, got %d after %d requests", len(recorded), requests)
+	}
+	server.Close()
+
+	replayed, err := collectItems(New(Options{
+		HTTPClient: &http.Client{Transport: &RecordTransport{Dir: dir, Mode: ReplayOnly}},
+	}), siteURL)
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if len(replayed) != len(recorded) {
+		t.Fatalf("expected %d replayed items, got %d", len(recorded), len(replayed))
+	}
+	for i := range recorded {
+		if replayed[i].Loc.String() != recorded[i].Loc.String() {
+			t.Fatalf("item %d: expected %s, got %s", i, recorded[i].Loc, replayed[i].Loc)
+		}
+	}
+
+	client := &http.Client{Transport: &RecordTransport{Dir: dir, Mode: ReplayOnly}}
+	if _, err := client.Get("http://unrecorded.invalid/"); err == nil {
+		t.Fatal("expected error for unrecorded request in ReplayOnly mode")
+	}
+}
@@ -0,0 +1,161 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthProvider adds credentials to the fetcher's requests for sitemaps,
+// robots.txt, and Verify link checks (not Ping). Authorize runs before every
+// request, retries included, so a provider can hand out a fresh token each
+// time. It sees the request URL and must leave requests to hosts it does not
+// serve untouched.
+//
+// A provider that also implements Invalidate() is told when a sitemap request
+// is answered 401 Unauthorized; the request is then retried once, authorized
+// anew.
+type AuthProvider interface {
+	Authorize(ctx context.Context, req *http.Request) error
+}
+
+// AuthProviderFunc adapts a function to AuthProvider.
+type AuthProviderFunc func(ctx context.Context, req *http.Request) error
+
+// Authorize calls fn.
+func (fn AuthProviderFunc) Authorize(ctx context.Context, req *http.Request) error {
+	return fn(ctx, req)
+}
+
+// Token is an access token with an optional expiry, as issued by an OAuth2
+// token endpoint.
+type Token struct {
+	AccessToken string
+	// TokenType is the Authorization scheme. Empty => "Bearer".
+	TokenType string
+	// Expiry is when the token stops being valid. Zero => it does not expire.
+	Expiry time.Time
+}
+
+// TokenSource returns a valid token, fetching or refreshing it as needed.
+// An oauth2.TokenSource adapts with a TokenSourceFunc that copies the fields.
+type TokenSource interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// TokenSourceFunc adapts a function to TokenSource.
+type TokenSourceFunc func(ctx context.Context) (Token, error)
+
+// Token calls fn.
+func (fn TokenSourceFunc) Token(ctx context.Context) (Token, error) {
+	return fn(ctx)
+}
+
+// tokenExpiryLeeway renews tokens this long before they expire, so none
+// expires in flight.
+const tokenExpiryLeeway = 10 * time.Second
+
+// BearerAuth is an AuthProvider that sets an Authorization header from a
+// TokenSource. It caches the token until shortly before its expiry or until a
+// 401 response invalidates it, then asks the source for a new one. It is safe
+// for concurrent use.
+type BearerAuth struct {
+	source TokenSource
+	hosts  map[string]struct{}
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewBearerAuth returns a BearerAuth drawing tokens from source. Tokens are
+// only sent to the listed hosts (host or host:port, as in URL.Host). With no
+// hosts listed, they are only sent to the hosts of the URLs a walk starts
+// from, so a sitemap index cannot hand the token to another site.
+func NewBearerAuth(source TokenSource, hosts ...string) *BearerAuth {
+	auth := &BearerAuth{source: source}
+	if len(hosts) > 0 {
+		auth.hosts = make(map[string]struct{}, len(hosts))
+		for _, host := range hosts {
+			auth.hosts[strings.ToLower(host)] = struct{}{}
+		}
+	}
+	return auth
+}
+
+// Authorize implements AuthProvider.
+func (a *BearerAuth) Authorize(ctx context.Context, req *http.Request) error {
+	hosts := a.hosts
+	if hosts == nil {
+		hosts, _ = ctx.Value(rootHostsKey{}).(map[string]struct{})
+	}
+	if _, ok := hosts[strings.ToLower(req.URL.Host)]; !ok {
+		return nil
+	}
+	token, err := a.current(ctx)
+	if err != nil {
+		return err
+	}
+	scheme := token.TokenType
+	if scheme == "" {
+		scheme = "Bearer"
+	}
+	req.Header.Set("Authorization", scheme+" "+token.AccessToken)
+	return nil
+}
+
+// Invalidate drops the cached token, so the next request fetches a new one.
+func (a *BearerAuth) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = nil
+}
+
+func (a *BearerAuth) current(ctx context.Context) (Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != nil && (a.token.Expiry.IsZero() || time.Until(a.token.Expiry) > tokenExpiryLeeway) {
+		return *a.token, nil
+	}
+	token, err := a.source.Token(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+	a.token = &token
+	return token, nil
+}
+
+type rootHostsKey struct{}
+
+// withRootHosts records the hosts of a walk's inputs in ctx, for providers that
+// scope credentials to them.
+func withRootHosts(ctx context.Context, inputs []*url.URL) context.Context {
+	hosts := make(map[string]struct{}, len(inputs))
+	for _, input := range inputs {
+		hosts[strings.ToLower(input.Host)] = struct{}{}
+	}
+	return context.WithValue(ctx, rootHostsKey{}, hosts)
+}
+
+// authorize applies Options.Auth to req.
+func (f *SitemapFetcher) authorize(ctx context.Context, req *http.Request) error {
+	if f.opts.Auth == nil {
+		return nil
+	}
+	if err := f.opts.Auth.Authorize(ctx, req); err != nil {
+		return &ErrAuth{URL: cloneURL(req.URL), Err: err}
+	}
+	return nil
+}
+
+// invalidateAuth reports a 401 to Options.Auth and whether it can supply new
+// credentials for a retry.
+func (f *SitemapFetcher) invalidateAuth() bool {
+	invalidator, ok := f.opts.Auth.(interface{ Invalidate() })
+	if ok {
+		invalidator.Invalidate()
+	}
+	return ok
+}
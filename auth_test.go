@@ -0,0 +1,255 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_BearerAuthRefresh(t *testing.T) {
+	var mu sync.Mutex
+	valid := "token-1"
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ok := r.Header.Get("Authorization") == "Bearer "+valid
+		mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	var issued int
+	src := TokenSourceFunc(func(context.Context) (Token, error) {
+		issued++
+		return Token{AccessToken: fmt.Sprintf("token-%d", issued), Expiry: time.Now().Add(time.Hour)}, nil
+	})
+	fetcher := New(Options{IgnoreRobots: true, Auth: NewBearerAuth(src, sitemapURL.Host)})
+
+	for walk := 1; walk <= 2; walk++ {
+		items, err := collectItems(fetcher, sitemapURL)
+		if err != nil || len(items) != 1 {
+			t.Fatalf("walk %d: expected 1 item, got %d, %v", walk, len(items), err)
+		}
+	}
+	if issued != 1 {
+		t.Fatalf("expected the cached token to be reused, issued %d", issued)
+	}
+
+	// The gateway revokes the token; the 401 triggers one refresh and a retry.
+	mu.Lock()
+	valid = "token-2"
+	mu.Unlock()
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected the retry with a fresh token to succeed, got %d, %v", len(items), err)
+	}
+	if issued != 2 {
+		t.Fatalf("expected one refresh, issued %d", issued)
+	}
+
+	// A token that keeps failing is not retried forever.
+	mu.Lock()
+	valid = "never"
+	mu.Unlock()
+	_, err = collectItems(fetcher, sitemapURL)
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 after one retry, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_BearerAuthRefreshOnLastAttempt(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		switch {
+		case n <= maxRetryAttempts:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Header.Get("Authorization") != "Bearer token-2":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	var issued int
+	src := TokenSourceFunc(func(context.Context) (Token, error) {
+		issued++
+		return Token{AccessToken: fmt.Sprintf("token-%d", issued), Expiry: time.Now().Add(time.Hour)}, nil
+	})
+	fetcher := New(Options{
+		IgnoreRobots: true,
+		Auth:         NewBearerAuth(src, sitemapURL.Host),
+		StatusPolicy: func(code int) Action {
+			if code == http.StatusServiceUnavailable {
+				return ActionRetry
+			}
+			return ActionDefault
+		},
+	})
+	// The 401 arrives on the last attempt; the reauthorized retry still runs.
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected the reauthorized retry to succeed, got %d, %v", len(items), err)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	var issued int
+	auth := NewBearerAuth(TokenSourceFunc(func(context.Context) (Token, error) {
+		issued++
+		// Expires within the leeway, so every request renews it.
+		return Token{AccessToken: "t", TokenType: "MAC", Expiry: time.Now().Add(time.Second)}, nil
+	}), "API.example.com")
+
+	for _, raw := range []string{"https://api.example.com/sitemap.xml", "https://api.example.com/other.xml"} {
+		req, _ := http.NewRequest(http.MethodGet, raw, nil)
+		if err := auth.Authorize(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := req.Header.Get("Authorization"); got != "MAC t" {
+			t.Fatalf("expected the token's scheme, got %q", got)
+		}
+	}
+	if issued != 2 {
+		t.Fatalf("expected a near-expiry token to be renewed, issued %d", issued)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://cdn.example.net/sitemap.xml", nil)
+	if err := auth.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Fatalf("expected no token for an unlisted host, got %q", got)
+	}
+}
+
+func TestSitemapFetcher_BearerAuthDefaultsToRootHosts(t *testing.T) {
+	var mu sync.Mutex
+	headers := map[string]string{}
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		headers[r.URL.Path] = r.Header.Get("Authorization")
+	}
+	other := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		_, _ = w.Write([]byte(`<urlset><url><loc>/b</loc></url></urlset>`))
+	}))
+	defer other.Close()
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		if r.URL.Path == "/index.xml" {
+			_, _ = fmt.Fprintf(w, `<sitemapindex><sitemap><loc>/local.xml</loc></sitemap><sitemap><loc>%s/remote.xml</loc></sitemap></sitemapindex>`, other.URL)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+	src := TokenSourceFunc(func(context.Context) (Token, error) {
+		return Token{AccessToken: "secret"}, nil
+	})
+	fetcher := New(Options{IgnoreRobots: true, Auth: NewBearerAuth(src)})
+	items, err := collectItems(fetcher, indexURL)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d, %v", len(items), err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/index.xml", "/local.xml"} {
+		if got := headers[path]; got != "Bearer secret" {
+			t.Fatalf("expected the token on the walk's own host for %s, got %q", path, got)
+		}
+	}
+	if got, ok := headers["/remote.xml"]; !ok || got != "" {
+		t.Fatalf("expected no token for a sitemap on another host, got %q (fetched %v)", got, ok)
+	}
+}
+
+func TestSitemapFetcher_AuthError(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{IgnoreRobots: true, Auth: AuthProviderFunc(func(context.Context, *http.Request) error {
+		return errors.New("token endpoint down")
+	})})
+	_, err = collectItems(fetcher, sitemapURL)
+	var authErr *ErrAuth
+	if !errors.As(err, &authErr) || !strings.Contains(err.Error(), "token endpoint down") {
+		t.Fatalf("expected ErrAuth, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_AuthErrorReleasesHostSlot(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		case "/b.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/b</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{
+		IgnoreRobots:       true,
+		SkipFetchErrors:    true,
+		ConcurrencyPerHost: 1,
+		Auth: AuthProviderFunc(func(_ context.Context, req *http.Request) error {
+			if req.URL.Path == "/a.xml" {
+				return errors.New("token endpoint down")
+			}
+			return nil
+		}),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var items []Item
+	err = fetcher.Walk(ctx, sitemapURL, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected /b.xml fetched after the failed authorization, got %d items (%v)", len(items), err)
+	}
+}
@@ -0,0 +1,112 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = time.Minute
+)
+
+// CircuitBreakerOptions configures Options.CircuitBreaker.
+type CircuitBreakerOptions struct {
+	// Failures is the number of consecutive failed sitemap requests to one host
+	// that opens its circuit. Transport errors, timeouts, 5xx responses, and 429
+	// responses count as failures. 0 => 5.
+	Failures int
+	// Cooldown is how long an open circuit skips the host. After it, one request
+	// is let through: success closes the circuit, failure reopens it. 0 => 1m.
+	Cooldown time.Duration
+}
+
+// hostBreaker tracks consecutive sitemap fetch failures per host. It is shared
+// by every walk of a SitemapFetcher.
+type hostBreaker struct {
+	failures int
+	cooldown time.Duration
+	mu       sync.Mutex
+	hosts    map[string]*breakerState
+}
+
+type breakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newHostBreaker(opts *CircuitBreakerOptions) *hostBreaker {
+	if opts == nil {
+		return nil
+	}
+	b := &hostBreaker{failures: opts.Failures, cooldown: opts.Cooldown, hosts: map[string]*breakerState{}}
+	if b.failures <= 0 {
+		b.failures = defaultBreakerFailures
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = defaultBreakerCooldown
+	}
+	return b
+}
+
+// allow returns *ErrCircuitOpen while loc's host is cooling down.
+func (b *hostBreaker) allow(loc *url.URL) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.hosts[loc.Host]
+	if state == nil || !time.Now().Before(state.openUntil) {
+		return nil
+	}
+	return &ErrCircuitOpen{URL: cloneURL(loc), Host: loc.Host, Until: state.openUntil}
+}
+
+// record notes the outcome of a request to loc's host. It reports whether the
+// failure opened the circuit.
+func (b *hostBreaker) record(loc *url.URL, failed bool) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.hosts[loc.Host]
+	if !failed {
+		if state != nil {
+			delete(b.hosts, loc.Host)
+		}
+		return false
+	}
+	if state == nil {
+		state = &breakerState{}
+		b.hosts[loc.Host] = state
+	}
+	state.failures++
+	if state.failures < b.failures {
+		return false
+	}
+	state.openUntil = time.Now().Add(b.cooldown)
+	return true
+}
+
+// recordFetch feeds a sitemap response or transport error to the circuit
+// breaker. Failures caused by the caller's own context ending are ignored.
+func (f *SitemapFetcher) recordFetch(ctx context.Context, loc *url.URL, resp *http.Response, err error) {
+	if f.breaker == nil || ctx.Err() != nil {
+		return
+	}
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	if f.breaker.record(loc, failed) {
+		f.logger.WarnContext(
+			ctx,
+			"too many consecutive failures, skipping host",
+			"host", loc.Host,
+			"failures", f.breaker.failures,
+			"cooldown", f.breaker.cooldown.String(),
+		)
+	}
+}
@@ -0,0 +1,95 @@
+package gositemapfetcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_CircuitBreaker(t *testing.T) {
+	var deadRequests int32
+	dead := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deadRequests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer dead.Close()
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			var body strings.Builder
+			body.WriteString("<sitemapindex>")
+			for i := range 5 {
+				fmt.Fprintf(&body, "<sitemap><loc>%s/shard-%d.xml</loc></sitemap>", dead.URL, i)
+			}
+			body.WriteString("<sitemap><loc>/live.xml</loc></sitemap></sitemapindex>")
+			_, _ = w.Write([]byte(body.String()))
+		case "/live.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/page</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		IgnoreRobots:   true,
+		SkipNon200:     true,
+		CircuitBreaker: &CircuitBreakerOptions{Failures: 2, Cooldown: time.Hour},
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Loc.Path != "/page" {
+		t.Fatalf("expected the live sitemap to be walked, got %v", items)
+	}
+	if got := atomic.LoadInt32(&deadRequests); got != 2 {
+		t.Fatalf("expected 2 requests to the failing host, got %d", got)
+	}
+
+	skipped := fetcher.SkippedSitemaps()
+	if len(skipped) != 5 {
+		t.Fatalf("expected 5 skipped sitemaps, got %d", len(skipped))
+	}
+	var open int
+	for _, entry := range skipped {
+		var circuitErr *ErrCircuitOpen
+		if errors.As(entry.Err, &circuitErr) {
+			open++
+		}
+	}
+	if open != 3 {
+		t.Fatalf("expected 3 sitemaps skipped by the open circuit, got %d", open)
+	}
+}
+
+func TestHostBreaker_HalfOpen(t *testing.T) {
+	breaker := newHostBreaker(&CircuitBreakerOptions{Failures: 1, Cooldown: 20 * time.Millisecond})
+	loc := &url.URL{Scheme: "https", Host: "shard.example.com", Path: "/sitemap.xml"}
+
+	if !breaker.record(loc, true) {
+		t.Fatalf("expected the first failure to open the circuit")
+	}
+	if err := breaker.allow(loc); err == nil {
+		t.Fatalf("expected the circuit to be open")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := breaker.allow(loc); err != nil {
+		t.Fatalf("expected a trial request after the cooldown, got %v", err)
+	}
+	breaker.record(loc, false)
+	if err := breaker.allow(loc); err != nil {
+		t.Fatalf("expected success to close the circuit")
+	}
+}
@@ -0,0 +1,118 @@
+package gositemapfetcher
+
+import (
+	"errors"
+	"sync"
+)
+
+// callbackPool runs the item callback on CallbackConcurrency goroutines. Items
+// wait in a channel of CallbackBuffer slots, which bounds memory: dispatch
+// blocks, and with it the walk, while the channel is full.
+type callbackPool struct {
+	yield    func(Item) error
+	items    chan Item
+	workers  sync.WaitGroup
+	inflight sync.WaitGroup
+
+	mu sync.Mutex
+	// err is the first ErrStopWalk or ErrYield returned by a callback.
+	err error
+	// skip is the sitemap whose remainder a callback skipped with ErrSkipSitemap.
+	skip string
+}
+
+func newCallbackPool(workers, buffer int, yield func(Item) error) *callbackPool {
+	p := &callbackPool{yield: yield, items: make(chan Item, max(buffer, 0))}
+	p.workers.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *callbackPool) work() {
+	defer p.workers.Done()
+	for item := range p.items {
+		p.run(item)
+		p.inflight.Done()
+	}
+}
+
+func (p *callbackPool) run(item Item) {
+	p.mu.Lock()
+	stopped := p.err != nil || (p.skip != "" && p.skip == canonicalURLKey(item.Sitemap))
+	p.mu.Unlock()
+	if stopped {
+		return
+	}
+	err := p.yield(item)
+	if err == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case errors.Is(err, ErrSkipSitemap):
+		p.skip = canonicalURLKey(item.Sitemap)
+	case p.err != nil:
+	case errors.Is(err, ErrStopWalk):
+		p.err = err
+	default:
+		p.err = &ErrYield{Err: err}
+	}
+}
+
+// dispatch queues item for a worker, blocking while every worker is busy and
+// the buffer is full. It returns an error recorded by an earlier callback
+// instead of dispatching.
+func (p *callbackPool) dispatch(item Item) error {
+	p.mu.Lock()
+	err := p.err
+	if err == nil && p.skip != "" && p.skip == canonicalURLKey(item.Sitemap) {
+		p.skip = ""
+		err = ErrSkipSitemap
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	p.inflight.Add(1)
+	p.items <- item
+	return nil
+}
+
+// wait blocks until every dispatched callback has returned.
+func (p *callbackPool) wait() {
+	p.inflight.Wait()
+}
+
+// close waits for outstanding callbacks, stops the workers, and returns the
+// first error a callback recorded.
+func (p *callbackPool) close() error {
+	close(p.items)
+	p.workers.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// finish emits the SampleN reservoir, stops the callback pool, if any, and
+// folds their errors into err, the result of the walk. A walk that completed
+// then saves its sitemap states.
+func (w *walk) finish(err error) error {
+	if flushErr := w.finishSample(err); err == nil {
+		err = flushErr
+	}
+	if w.callbacks != nil {
+		poolErr := w.callbacks.close()
+		if errors.Is(poolErr, ErrStopWalk) {
+			w.partial = true
+		} else if err == nil && poolErr != nil {
+			err = poolErr
+		}
+	}
+	if err == nil && w.states != nil {
+		err = w.saveStates()
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCallbackTestServer(t *testing.T, urls int) *url.URL {
+	t.Helper()
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body strings.Builder
+		body.WriteString("<urlset>")
+		for i := range urls {
+			fmt.Fprintf(&body, "<url><loc>/%d</loc></url>", i)
+		}
+		body.WriteString("</urlset>")
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	t.Cleanup(server.Close)
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	return sitemapURL
+}
+
+func TestSitemapFetcher_CallbackConcurrency(t *testing.T) {
+	sitemapURL := newCallbackTestServer(t, 8)
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var inFlight, maxInFlight int32
+	start := time.Now()
+	err := New(Options{IgnoreRobots: true, CallbackConcurrency: 4}).Walk(context.Background(), sitemapURL, func(item Item) error {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if current <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, current) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		seen[item.Loc.Path] = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 8 {
+		t.Fatalf("expected every callback to finish before Walk returns, got %d", len(seen))
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got < 2 || got > 4 {
+		t.Fatalf("expected 2-4 concurrent callbacks, got %d", got)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("expected callbacks to overlap, took %s", elapsed)
+	}
+}
+
+func TestSitemapFetcher_CallbackConcurrencyError(t *testing.T) {
+	sitemapURL := newCallbackTestServer(t, 100)
+
+	var calls int32
+	boom := errors.New("boom")
+	err := New(Options{IgnoreRobots: true, CallbackConcurrency: 4}).Walk(context.Background(), sitemapURL, func(item Item) error {
+		atomic.AddInt32(&calls, 1)
+		if item.Loc.Path == "/3" {
+			return boom
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	var yieldErr *ErrYield
+	if !errors.As(err, &yieldErr) || !errors.Is(err, boom) {
+		t.Fatalf("expected ErrYield wrapping boom, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got == 100 {
+		t.Fatalf("expected the walk to stop early, got %d callbacks", got)
+	}
+}
+
+func TestSitemapFetcher_CallbackBuffer(t *testing.T) {
+	sitemapURL := newCallbackTestServer(t, 20)
+
+	release := make(chan struct{})
+	var dispatched, delivered atomic.Int32
+	fetcher := New(Options{
+		IgnoreRobots:        true,
+		CallbackConcurrency: 2,
+		CallbackBuffer:      3,
+		OnEvent: func(event Event) {
+			if event.Kind == EventItemEmitted {
+				dispatched.Add(1)
+			}
+		},
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- fetcher.Walk(context.Background(), sitemapURL, func(Item) error {
+			<-release
+			delivered.Add(1)
+			return nil
+		})
+	}()
+
+	// Two items are held by blocked callbacks and three wait in the buffer;
+	// the parser then blocks instead of reading further.
+	deadline := time.Now().Add(time.Second)
+	for dispatched.Load() < 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := dispatched.Load(); got != 5 {
+		t.Fatalf("expected the walk to stop at 5 queued items, got %d", got)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := delivered.Load(); got != 20 {
+		t.Fatalf("expected every item delivered, got %d", got)
+	}
+}
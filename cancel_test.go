@@ -0,0 +1,125 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// inflightTransport counts requests whose response body is still open.
+type inflightTransport struct {
+	inflight atomic.Int32
+}
+
+func (t *inflightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.inflight.Add(1)
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.inflight.Add(-1)
+		return nil, err
+	}
+	resp.Body = &inflightBody{ReadCloser: resp.Body, t: t}
+	return resp, nil
+}
+
+type inflightBody struct {
+	io.ReadCloser
+	t    *inflightTransport
+	once sync.Once
+}
+
+func (b *inflightBody) Close() error {
+	b.once.Do(func() { b.t.inflight.Add(-1) })
+	return b.ReadCloser.Close()
+}
+
+func TestSitemapFetcher_AbortCancelsInflightDownloads(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/first.xml</loc></sitemap>
+<sitemap><loc>/slow-1.xml</loc></sitemap>
+<sitemap><loc>/slow-2.xml</loc></sitemap>
+<sitemap><loc>/slow-3.xml</loc></sitemap>
+</sitemapindex>`))
+		case r.URL.Path == "/first.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`))
+		case strings.HasPrefix(r.URL.Path, "/slow"):
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	transport := &inflightTransport{}
+	fetcher := New(Options{
+		HTTPClient:       &http.Client{Transport: transport},
+		FetchConcurrency: 4,
+		MaxURLs:          1,
+	})
+
+	start := time.Now()
+	_, err = collectItems(fetcher, sitemapURL)
+	if _, ok := err.(*ErrMaxURLs); !ok {
+		t.Fatalf("expected ErrMaxURLs, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the walk to stop promptly, took %s", elapsed)
+	}
+	if n := transport.inflight.Load(); n != 0 {
+		t.Fatalf("expected no downloads in flight after the walk returned, got %d", n)
+	}
+}
+
+func TestSitemapFetcher_AbortCancelsLinkChecks(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset>
+<url><loc>/fast</loc></url>
+<url><loc>/slow-1</loc></url>
+<url><loc>/slow-2</loc></url>
+<url><loc>/slow-3</loc></url>
+</urlset>`))
+		case strings.HasPrefix(r.URL.Path, "/slow"):
+			<-r.Context().Done()
+		case r.URL.Path == "/fast":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	transport := &inflightTransport{}
+	fetcher := New(Options{
+		HTTPClient: &http.Client{Transport: transport},
+		Verify:     &VerifyOptions{Concurrency: 4},
+	})
+
+	err = fetcher.Walk(context.Background(), sitemapURL, func(Item) error {
+		return ErrStopWalk
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := transport.inflight.Load(); n != 0 {
+		t.Fatalf("expected link checks to be cancelled when the walk stopped, got %d in flight", n)
+	}
+}
@@ -0,0 +1,54 @@
+package gositemapfetcher
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Checkpoint is a JSON-serializable snapshot of walk progress. Pass it back as
+// Options.Resume to continue an interrupted walk of the same input URL.
+type Checkpoint struct {
+	// Input is the normalized URL a single-root walk started from.
+	Input string `json:"input,omitempty"`
+	// Inputs lists the normalized URLs a WalkAll of several roots started
+	// from, in order; Input is then empty.
+	Inputs []string `json:"inputs,omitempty"`
+	// Visited holds the canonical URLs of sitemaps already processed. It is
+	// empty when Options.VisitedStore is a custom store, which keeps them itself.
+	Visited []string `json:"visited,omitempty"`
+	// Current is the sitemap being parsed when the checkpoint was taken, if any.
+	Current *CheckpointTask `json:"current,omitempty"`
+	// Position is the number of entries of Current already processed.
+	Position int `json:"position,omitempty"`
+	// Queue lists sitemaps discovered but not yet fetched, in order.
+	Queue []CheckpointTask `json:"queue,omitempty"`
+	// URLs and Sitemaps are the counts used for MaxURLs and MaxSitemaps.
+	URLs     int `json:"urls"`
+	Sitemaps int `json:"sitemaps"`
+}
+
+// CheckpointTask is a sitemap waiting to be fetched.
+type CheckpointTask struct {
+	URL          string     `json:"url"`
+	Depth        int        `json:"depth"`
+	AllowMissing bool       `json:"allow_missing,omitempty"`
+	LastMod      *time.Time `json:"lastmod,omitempty"`
+}
+
+func newCheckpointTask(task sitemapTask) CheckpointTask {
+	return CheckpointTask{
+		URL:          task.loc.String(),
+		Depth:        task.depth,
+		AllowMissing: task.allowMissing,
+		LastMod:      task.lastMod,
+	}
+}
+
+func (t CheckpointTask) task() (sitemapTask, error) {
+	loc, err := url.Parse(t.URL)
+	if err != nil {
+		return sitemapTask{}, &ErrInvalidCheckpoint{Reason: fmt.Sprintf("invalid sitemap URL %q: %v", t.URL, err)}
+	}
+	return sitemapTask{loc: loc, depth: t.Depth, allowMissing: t.AllowMissing, lastMod: t.LastMod}, nil
+}
@@ -0,0 +1,107 @@
+package gositemapfetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestSitemapFetcher_CheckpointResume(t *testing.T) {
+	const index = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/a.xml</loc></sitemap>
+  <sitemap><loc>/b.xml</loc></sitemap>
+</sitemapindex>`
+	const a = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/a1</loc></url>
+  <url><loc>/a2</loc></url>
+</urlset>`
+	const b = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/b1</loc></url>
+  <url><loc>/b2</loc></url>
+</urlset>`
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(index))
+		case "/a.xml":
+			_, _ = w.Write([]byte(a))
+		case "/b.xml":
+			_, _ = w.Write([]byte(b))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	interrupted := errors.New("interrupted")
+	var saved []byte
+	fetcher := New(Options{
+		CheckpointEvery: 1,
+		OnCheckpoint: func(cp Checkpoint) error {
+			data, err := json.Marshal(cp)
+			if err != nil {
+				return err
+			}
+			saved = data
+			if cp.URLs == 3 {
+				return interrupted
+			}
+			return nil
+		},
+	})
+	items, err := collectItems(fetcher, indexURL)
+	var checkpointErr *ErrCheckpoint
+	if !errors.As(err, &checkpointErr) || !errors.Is(err, interrupted) {
+		t.Fatalf("expected ErrCheckpoint wrapping interruption, got %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items before interruption, got %d", len(items))
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(saved, &cp); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	mu.Lock()
+	requests = map[string]int{}
+	mu.Unlock()
+	items, err = collectItems(New(Options{Resume: &cp}), indexURL)
+	if err != nil {
+		t.Fatalf("resumed walk failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Loc.Path != "/b2" {
+		t.Fatalf("expected only /b2 after resume, got %v", items)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/index.xml"] != 0 || requests["/a.xml"] != 0 {
+		t.Fatalf("expected finished sitemaps not to be refetched, got %v", requests)
+	}
+
+	other, err := url.Parse(server.URL + "/other.xml")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	_, err = collectItems(New(Options{Resume: &cp}), other)
+	var invalid *ErrInvalidCheckpoint
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidCheckpoint, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+type domainResult struct {
+	domain  string
+	output  string
+	urls    int
+	skipped int
+	elapsed time.Duration
+	err     error
+}
+
+// batchBudget holds the --max-urls and --max-total-bytes budgets shared by
+// every walk of a --domains-file run. A nil budget is unlimited.
+type batchBudget struct {
+	maxURLs       int
+	stopAtMaxURLs bool
+	urls          atomic.Int64
+	maxBytes      int64
+	bytes         atomic.Int64
+}
+
+// takeURL claims one URL of the budget, or reports that it is spent: with
+// --stop-at-max-urls by stopping the walk, otherwise as ErrMaxURLs.
+func (b *batchBudget) takeURL() error {
+	if b == nil || b.maxURLs <= 0 {
+		return nil
+	}
+	if b.urls.Add(1) <= int64(b.maxURLs) {
+		return nil
+	}
+	if b.stopAtMaxURLs {
+		return gositemapfetcher.ErrStopWalk
+	}
+	return &gositemapfetcher.ErrMaxURLs{MaxURLs: b.maxURLs}
+}
+
+// spentBytes reports whether the byte budget has run out.
+func (b *batchBudget) spentBytes() error {
+	if b.maxBytes > 0 && b.bytes.Load() > b.maxBytes {
+		return &gositemapfetcher.ErrMaxTotalBytes{MaxTotalBytes: b.maxBytes, Downloaded: b.bytes.Load()}
+	}
+	return nil
+}
+
+// meteredTransport returns a clone of http.DefaultTransport whose connections
+// count the bytes they receive against the budget and fail once it is spent.
+// Metering at the connection keeps it an *http.Transport, which --resolve and
+// --protocol need.
+func (b *batchBudget) meteredTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dial := (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := b.spentBytes(); err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &meteredConn{Conn: conn, budget: b}, nil
+	}
+	return transport
+}
+
+type meteredConn struct {
+	net.Conn
+	budget *batchBudget
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	if err := c.budget.spentBytes(); err != nil {
+		return 0, err
+	}
+	n, err := c.Conn.Read(p)
+	c.budget.bytes.Add(int64(n))
+	return n, err
+}
+
+// runDomains walks every entry of domainsFile, writing each domain's URLs to its own file
+// in outputDir, and prints a summary table to stderr. The walks share one
+// fetcher, so --delay and connection reuse span domains, and --max-urls and
+// --max-total-bytes are budgets for the whole run; other limits apply per domain.
+func runDomains(ctx context.Context, domainsFile, outputDir, format string, parallel int, opts gositemapfetcher.Options) error {
+	domains, err := readDomains(domainsFile)
+	if err != nil {
+		return err
+	}
+	if len(domains) == 0 {
+		return fmt.Errorf("no domains in %s", domainsFile)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	budget := &batchBudget{maxURLs: opts.MaxURLs, stopAtMaxURLs: opts.StopAtMaxURLs, maxBytes: opts.MaxTotalBytes}
+	opts.MaxURLs, opts.MaxTotalBytes, opts.StopAtMaxURLs = 0, 0, false
+	if budget.maxBytes > 0 {
+		opts.HTTPClient = &http.Client{Transport: budget.meteredTransport()}
+	}
+	// Walks run concurrently on one fetcher, so skipped sitemaps are counted
+	// per walk ID rather than read from SkippedSitemapCount.
+	var skippedMu sync.Mutex
+	skipped := map[string]int{}
+	opts.OnEvent = func(event gositemapfetcher.Event) {
+		if event.Kind == gositemapfetcher.EventSitemapSkipped && event.Err != nil {
+			skippedMu.Lock()
+			skipped[event.WalkID]++
+			skippedMu.Unlock()
+		}
+	}
+	fetcher := gositemapfetcher.New(opts)
+
+	names := outputFileNames(domains, format)
+	results := make([]domainResult, len(domains))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				walkID := fmt.Sprintf("domain-%d", i+1)
+				walkCtx := gositemapfetcher.WithWalkID(ctx, walkID)
+				results[i] = walkDomain(walkCtx, fetcher, domains[i], filepath.Join(outputDir, names[i]), format, budget)
+				skippedMu.Lock()
+				results[i].skipped = skipped[walkID]
+				skippedMu.Unlock()
+			}
+		}()
+	}
+	for i := range domains {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed int
+	table := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "DOMAIN\tURLS\tSKIPPED\tELAPSED\tOUTPUT\tERROR")
+	for _, result := range results {
+		errText := "-"
+		if result.err != nil {
+			failed++
+			errText = result.err.Error()
+		}
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%s\t%s\n",
+			result.domain,
+			result.urls,
+			result.skipped,
+			result.elapsed.Truncate(time.Millisecond),
+			result.output,
+			errText,
+		)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d domains failed", failed, len(domains))
+	}
+	return nil
+}
+
+func walkDomain(ctx context.Context, fetcher *gositemapfetcher.SitemapFetcher, domain, output, format string, budget *batchBudget) (result domainResult) {
+	result.domain = domain
+	result.output = output
+	start := time.Now()
+	defer func() {
+		result.elapsed = time.Since(start)
+	}()
+
+	target, err := parseDomain(domain)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	writer := bufio.NewWriter(file)
+	result.urls, result.err = walkTo(ctx, fetcher, target, writer, format, budget)
+	var yieldErr *gositemapfetcher.ErrYield
+	if errors.As(result.err, &yieldErr) {
+		result.err = yieldErr.Err
+	}
+	if err := writer.Flush(); err != nil && result.err == nil {
+		result.err = err
+	}
+	if err := file.Close(); err != nil && result.err == nil {
+		result.err = err
+	}
+	return result
+}
+
+// readDomains returns the non-empty, non-comment lines of path, without
+// repeats.
+func readDomains(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domains []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		domains = append(domains, line)
+	}
+	return domains, scanner.Err()
+}
+
+// parseDomain accepts bare hosts ("example.com") as well as full URLs.
+func parseDomain(domain string) (*url.URL, error) {
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	parsed, err := url.Parse(domain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", domain, err)
+	}
+	return parsed, nil
+}
+
+// outputFileNames returns a distinct output file name for each domain entry:
+// the host, then the path of an entry that names a sitemap, with a numeric
+// suffix for names that would still collide.
+func outputFileNames(domains []string, format string) []string {
+	names := make([]string, len(domains))
+	used := map[string]bool{}
+	for i, domain := range domains {
+		base := domain
+		if target, err := parseDomain(domain); err == nil {
+			base = target.Host + strings.TrimSuffix(target.Path, "/")
+		}
+		base = strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(base)
+		name := base + outputExtensions[format]
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s-%d%s", base, n, outputExtensions[format])
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func batchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`))
+		case "/news.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/news</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeDomains(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write domains file: %v", err)
+	}
+	return path
+}
+
+// outputs returns the contents of every file in dir, by name.
+func outputs(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read output dir: %v", err)
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", entry.Name(), err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
+
+func TestOutputFileNames(t *testing.T) {
+	got := outputFileNames([]string{"example.com", "example.com/news-sitemap.xml", "https://example.com/", "EXAMPLE.com"}, "urls")
+	want := []string{"example.com.txt", "example.com_news-sitemap.xml.txt", "example.com-2.txt", "EXAMPLE.com-3.txt"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRunDomains(t *testing.T) {
+	server := batchServer(t)
+	domains := writeDomains(t,
+		"# sitemaps on one host",
+		server.URL+"/sitemap.xml",
+		server.URL+"/news.xml",
+		server.URL+"/sitemap.xml",
+	)
+	dir := t.TempDir()
+	if err := runDomains(t.Context(), domains, dir, "urls", 4, gositemapfetcher.Options{IgnoreRobots: true}); err != nil {
+		t.Fatalf("runDomains failed: %v", err)
+	}
+	files := outputs(t, dir)
+	host := strings.NewReplacer(":", "_").Replace(strings.TrimPrefix(server.URL, "http://"))
+	if len(files) != 2 {
+		t.Fatalf("expected one file per distinct entry, got %v", files)
+	}
+	if got := files[host+"_sitemap.xml.txt"]; got != server.URL+"/a\n"+server.URL+"/b\n" {
+		t.Fatalf("unexpected sitemap.xml output %q", got)
+	}
+	if got := files[host+"_news.xml.txt"]; got != server.URL+"/news\n" {
+		t.Fatalf("unexpected news.xml output %q", got)
+	}
+}
+
+func TestRunDomains_SharedMaxURLs(t *testing.T) {
+	server := batchServer(t)
+	domains := writeDomains(t, server.URL+"/sitemap.xml", server.URL+"/news.xml")
+
+	count := func(files map[string]string) int {
+		var urls int
+		for _, content := range files {
+			urls += strings.Count(content, "\n")
+		}
+		return urls
+	}
+
+	dir := t.TempDir()
+	opts := gositemapfetcher.Options{IgnoreRobots: true, MaxURLs: 2, StopAtMaxURLs: true}
+	if err := runDomains(t.Context(), domains, dir, "urls", 1, opts); err != nil {
+		t.Fatalf("runDomains failed: %v", err)
+	}
+	if urls := count(outputs(t, dir)); urls != 2 {
+		t.Fatalf("expected 2 URLs across all domains, got %d", urls)
+	}
+
+	dir = t.TempDir()
+	opts.StopAtMaxURLs = false
+	err := runDomains(t.Context(), domains, dir, "urls", 1, opts)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 domains failed") {
+		t.Fatalf("expected the domain past the budget to fail, got %v", err)
+	}
+	if urls := count(outputs(t, dir)); urls != 2 {
+		t.Fatalf("expected 2 URLs across all domains, got %d", urls)
+	}
+}
+
+func TestRunDomains_SharedMaxTotalBytes(t *testing.T) {
+	server := batchServer(t)
+	var lines []string
+	for i := range 5 {
+		lines = append(lines, fmt.Sprintf("%s/sitemap.xml?n=%d", server.URL, i))
+	}
+	domains := writeDomains(t, lines...)
+	dir := t.TempDir()
+	// One response fits in the budget; the run cannot download all five.
+	opts := gositemapfetcher.Options{IgnoreRobots: true, MaxTotalBytes: 300}
+	err := runDomains(t.Context(), domains, dir, "urls", 1, opts)
+	if err == nil || !strings.Contains(err.Error(), "of 5 domains failed") {
+		t.Fatalf("expected later domains to fail on the shared byte budget, got %v", err)
+	}
+	var written int
+	for _, content := range outputs(t, dir) {
+		if content != "" {
+			written++
+		}
+	}
+	if written == 0 || written == 5 {
+		t.Fatalf("expected some but not all domains written, got %d", written)
+	}
+}
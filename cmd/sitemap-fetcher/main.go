@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/sink"
 	"github.com/spf13/cobra"
 )
 
@@ -19,19 +22,38 @@ func main() {
 		maxDepth          int
 		maxSitemaps       int
 		maxURLs           int
+		maxTotalBytes     int64
 		skipNon200        bool
 		skipFetchErrors   bool
 		ignoreRobots      bool
 		userAgent         string
+		robotsUserAgent   string
 		perRequestTimeout time.Duration
+		delay             time.Duration
+		protocol          string
+		resolves          []string
+		walkTimeout       time.Duration
 		logLevel          string
+		domainsFile       string
+		outputDir         string
+		parallelDomains   int
+		includes          []string
+		excludes          []string
+		format            string
+		stopAtMaxURLs     bool
 	)
 
 	cmd := &cobra.Command{
 		Use:          "go-sitemap-fetcher [flags] <site or sitemap URL>",
-		Short:        "Fetch sitemaps and print URLs line by line",
+		Short:        "Fetch sitemaps and print URLs line by line, or as NDJSON/CSV",
 		SilenceUsage: true,
 		Args: func(cmd *cobra.Command, args []string) error {
+			if domainsFile != "" {
+				if len(args) == 0 {
+					return nil
+				}
+				return errors.New("URL argument cannot be combined with --domains-file")
+			}
 			if len(args) == 1 {
 				return nil
 			}
@@ -49,11 +71,6 @@ func main() {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			parsed, err := url.Parse(args[0])
-			if err != nil {
-				return fmt.Errorf("invalid URL %q: %w", args[0], err)
-			}
-
 			level, err := resolveLogLevel(logLevel)
 			if err != nil {
 				return err
@@ -63,22 +80,57 @@ func main() {
 			}
 			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
 
-			fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+			httpProtocol, err := resolveProtocol(protocol)
+			if err != nil {
+				return err
+			}
+			dialOverrides, err := parseResolves(resolves)
+			if err != nil {
+				return err
+			}
+			if _, ok := outputExtensions[format]; !ok {
+				return fmt.Errorf("invalid format %q (use urls, ndjson, csv)", format)
+			}
+			include, err := compilePatterns(includes)
+			if err != nil {
+				return err
+			}
+			exclude, err := compilePatterns(excludes)
+			if err != nil {
+				return err
+			}
+
+			opts := gositemapfetcher.Options{
 				MaxDepth:          maxDepth,
 				MaxSitemaps:       maxSitemaps,
 				MaxURLs:           maxURLs,
+				MaxTotalBytes:     maxTotalBytes,
+				StopAtMaxURLs:     stopAtMaxURLs,
 				SkipNon200:        skipNon200,
 				SkipFetchErrors:   skipFetchErrors,
 				IgnoreRobots:      ignoreRobots,
 				UserAgent:         userAgent,
+				RobotsUserAgent:   robotsUserAgent,
 				PerRequestTimeout: perRequestTimeout,
+				WalkTimeout:       walkTimeout,
+				Delay:             delay,
+				Protocol:          httpProtocol,
+				DialOverrides:     dialOverrides,
+				Include:           include,
+				Exclude:           exclude,
 				Logger:            logger,
-			})
+			}
 
-			return fetcher.Walk(context.Background(), parsed, func(item gositemapfetcher.Item) error {
-				_, err := fmt.Fprintln(os.Stdout, item.Loc.String())
-				return err
-			})
+			if domainsFile != "" {
+				return runDomains(context.Background(), domainsFile, outputDir, format, parallelDomains, opts)
+			}
+
+			parsed, err := url.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid URL %q: %w", args[0], err)
+			}
+			_, err = walkTo(context.Background(), gositemapfetcher.New(opts), parsed, os.Stdout, format, nil)
+			return err
 		},
 	}
 
@@ -86,12 +138,25 @@ func main() {
 	flags.IntVar(&maxDepth, "max-depth", 0, "Maximum sitemap index depth (0 = no limit)")
 	flags.IntVar(&maxSitemaps, "max-sitemaps", 0, "Maximum number of sitemaps to fetch (0 = no limit)")
 	flags.IntVar(&maxURLs, "max-urls", 0, "Maximum number of URLs to yield (0 = no limit)")
+	flags.Int64Var(&maxTotalBytes, "max-total-bytes", 0, "Maximum sitemap bytes to download per walk (0 = no limit)")
+	flags.BoolVar(&stopAtMaxURLs, "stop-at-max-urls", false, "Stop successfully once --max-urls URLs are printed instead of failing")
+	flags.StringArrayVar(&includes, "include", nil, "Only print URLs matching this regexp (repeatable)")
+	flags.StringArrayVar(&excludes, "exclude", nil, "Skip URLs matching this regexp (repeatable)")
+	flags.StringVar(&format, "format", "urls", "Output format: urls, ndjson, or csv")
 	flags.BoolVar(&skipNon200, "skip-non-200", false, "Skip non-200 sitemaps instead of failing")
 	flags.BoolVar(&skipFetchErrors, "skip-fetch-errors", false, "Skip sitemaps with any fetch/open errors instead of failing")
 	flags.BoolVar(&ignoreRobots, "ignore-robots", false, "Ignore robots.txt disallow rules")
 	flags.StringVar(&userAgent, "user-agent", "", "User-Agent for HTTP requests")
+	flags.StringVar(&robotsUserAgent, "robots-user-agent", "", "User-agent token matched against robots.txt rules (default: --user-agent)")
 	flags.DurationVar(&perRequestTimeout, "timeout", 0, "Per-request timeout (e.g. 5s, 500ms)")
+	flags.DurationVar(&walkTimeout, "walk-timeout", 0, "Time limit for the whole walk; URLs found so far are still printed (e.g. 2m)")
+	flags.DurationVar(&delay, "delay", 0, "Minimum pause between requests to the same host (e.g. 1s)")
+	flags.StringVar(&protocol, "protocol", "auto", "HTTP version: auto, http1, or http2")
+	flags.StringArrayVar(&resolves, "resolve", nil, "Connect to HOST at ADDR instead of resolving it, as HOST=ADDR (repeatable)")
 	flags.StringVar(&logLevel, "log-level", "", "Log level (debug, info, warn, error)")
+	flags.StringVar(&domainsFile, "domains-file", "", "File with one site or sitemap URL per line; walks each and writes per-domain files")
+	flags.StringVar(&outputDir, "output-dir", ".", "Directory for per-domain output files (with --domains-file)")
+	flags.IntVar(&parallelDomains, "parallel-domains", 4, "Number of domains walked concurrently (with --domains-file)")
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -99,6 +164,89 @@ func main() {
 	}
 }
 
+// outputExtensions maps each --format value to the file extension used in batch mode.
+var outputExtensions = map[string]string{
+	"urls":   ".txt",
+	"ndjson": ".ndjson",
+	"csv":    ".csv",
+}
+
+// walkTo writes every item discovered from target to w in the given format.
+// budget, if not nil, is the URL budget shared with other walks.
+func walkTo(ctx context.Context, fetcher *gositemapfetcher.SitemapFetcher, target *url.URL, w io.Writer, format string, budget *batchBudget) (int, error) {
+	var write func(gositemapfetcher.Item) error
+	flush := func() error { return nil }
+	switch format {
+	case "ndjson":
+		write = sink.NewNDJSON(w).Write
+	case "csv":
+		out := sink.NewCSV(w)
+		write, flush = out.Write, out.Flush
+	default:
+		write = func(item gositemapfetcher.Item) error {
+			_, err := fmt.Fprintln(w, item.Loc.String())
+			return err
+		}
+	}
+
+	var count int
+	err := fetcher.Walk(ctx, target, func(item gositemapfetcher.Item) error {
+		if err := budget.takeURL(); err != nil {
+			return err
+		}
+		if err := write(item); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+	return count, err
+}
+
+// compilePatterns compiles --include/--exclude values.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func parseResolves(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[string]string, len(values))
+	for _, value := range values {
+		host, addr, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(host) == "" || strings.TrimSpace(addr) == "" {
+			return nil, fmt.Errorf("invalid --resolve %q (use HOST=ADDR)", value)
+		}
+		overrides[strings.TrimSpace(host)] = strings.TrimSpace(addr)
+	}
+	return overrides, nil
+}
+
+func resolveProtocol(value string) (gositemapfetcher.HTTPProtocol, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return gositemapfetcher.ProtocolAuto, nil
+	case "http1", "http/1.1":
+		return gositemapfetcher.ProtocolHTTP1, nil
+	case "http2", "h2":
+		return gositemapfetcher.ProtocolHTTP2, nil
+	default:
+		return gositemapfetcher.ProtocolAuto, fmt.Errorf("invalid protocol %q (use auto, http1, http2)", value)
+	}
+}
+
 func resolveLogLevel(flagValue string) (slog.Level, error) {
 	value := strings.TrimSpace(flagValue)
 	if value == "" {
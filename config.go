@@ -0,0 +1,253 @@
+package gositemapfetcher
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Config is a serializable snapshot of the effective Options after defaults are
+// applied. Callbacks, clients, and loggers are reported only as present or absent,
+// and credentials are never included, so a Config is safe to log.
+type Config struct {
+	HTTPClient          string          `json:"http_client"`
+	HTTPClientTimeout   string          `json:"http_client_timeout,omitempty"`
+	MaxDepth            int             `json:"max_depth"`
+	MaxSitemaps         int             `json:"max_sitemaps"`
+	MaxURLs             int             `json:"max_urls"`
+	StopAtMaxURLs       bool            `json:"stop_at_max_urls"`
+	MaxURLsPerSitemap   int             `json:"max_urls_per_sitemap"`
+	MaxTotalBytes       int64           `json:"max_total_bytes"`
+	WarnURLsPerSitemap  int             `json:"warn_urls_per_sitemap,omitempty"`
+	WarnSitemapBytes    int64           `json:"warn_sitemap_bytes,omitempty"`
+	OnCheckpoint        bool            `json:"on_checkpoint"`
+	CheckpointEvery     int             `json:"checkpoint_every,omitempty"`
+	Resume              bool            `json:"resume"`
+	VisitedStore        bool            `json:"visited_store"`
+	DedupeURLs          bool            `json:"dedupe_urls"`
+	RetainURLs          bool            `json:"retain_urls"`
+	SitemapStates       bool            `json:"sitemap_states"`
+	Strict              bool            `json:"strict"`
+	StrictNamespaces    bool            `json:"strict_namespaces"`
+	Verify              *VerifyConfig   `json:"verify,omitempty"`
+	Archive             bool            `json:"archive"`
+	PerSitemapPolicy    string          `json:"max_urls_per_sitemap_policy"`
+	InvalidLoc          string          `json:"invalid_loc"`
+	FlagChangeFreq      bool            `json:"flag_changefreq"`
+	PriorityRange       string          `json:"priority_range"`
+	DefaultChangeFreq   string          `json:"default_changefreq,omitempty"`
+	DefaultPriority     *float64        `json:"default_priority,omitempty"`
+	LastModLocation     string          `json:"lastmod_location,omitempty"`
+	SkipNon200          bool            `json:"skip_non_200"`
+	SkipFetchErrors     bool            `json:"skip_fetch_errors"`
+	IgnoreRobots        bool            `json:"ignore_robots"`
+	RobotsCache         bool            `json:"robots_cache"`
+	RobotsCacheTTL      string          `json:"robots_cache_ttl,omitempty"`
+	UserAgent           string          `json:"user_agent"`
+	RobotsUserAgent     string          `json:"robots_user_agent"`
+	LogLevel            string          `json:"log_level,omitempty"`
+	SummaryLevel        string          `json:"summary_level,omitempty"`
+	ProgressLogInterval string          `json:"progress_log_interval,omitempty"`
+	TraceRequests       bool            `json:"trace_requests"`
+	NetworkTimings      bool            `json:"network_timings"`
+	SlowFetchWarning    string          `json:"slow_fetch_warning,omitempty"`
+	WalkIDHeader        string          `json:"walk_id_header,omitempty"`
+	Protocol            string          `json:"protocol"`
+	HTTP3Transport      bool            `json:"http3_transport,omitempty"`
+	RobotsDisallowedErr bool            `json:"error_on_robots_disallowed"`
+	RobotsFailure       string          `json:"robots_failure"`
+	PerRequestTimeout   string          `json:"per_request_timeout,omitempty"`
+	WalkTimeout         string          `json:"walk_timeout,omitempty"`
+	Delay               string          `json:"delay,omitempty"`
+	DelayJitter         string          `json:"delay_jitter,omitempty"`
+	Include             []string        `json:"include,omitempty"`
+	Exclude             []string        `json:"exclude,omitempty"`
+	StripQueryParams    []string        `json:"strip_query_params,omitempty"`
+	Transform           bool            `json:"transform"`
+	SampleRate          float64         `json:"sample_rate,omitempty"`
+	SampleN             int             `json:"sample_n,omitempty"`
+	SampleSeed          uint64          `json:"sample_seed,omitempty"`
+	OnError             bool            `json:"on_error"`
+	OnEvent             bool            `json:"on_event"`
+	Auth                bool            `json:"auth"`
+	AggregateErrors     bool            `json:"aggregate_errors"`
+	ReuseItems          bool            `json:"reuse_items"`
+	KeepExtensions      bool            `json:"keep_extensions"`
+	ExtensionDecoders   []string        `json:"extension_decoders,omitempty"`
+	ContentDecoders     []string        `json:"content_decoders,omitempty"`
+	AllowedSchemes      []string        `json:"allowed_schemes"`
+	AdvertiseEncodings  bool            `json:"advertise_encodings"`
+	ContentTypePolicy   string          `json:"content_type_policy"`
+	HTMLFallback        bool            `json:"html_fallback"`
+	AcceptEncoding      string          `json:"accept_encoding,omitempty"`
+	TransportDecoding   bool            `json:"transport_decoding"`
+	Formats             []SitemapFormat `json:"formats"`
+	Discovery           string          `json:"discovery"`
+	DetectNestedIndexes bool            `json:"detect_nested_indexes"`
+	CircuitBreaker      *BreakerConfig  `json:"circuit_breaker,omitempty"`
+	TraversalOrder      string          `json:"traversal_order"`
+	FetchConcurrency    int             `json:"fetch_concurrency,omitempty"`
+	ConcurrencyPerHost  int             `json:"concurrency_per_host,omitempty"`
+	CallbackConcurrency int             `json:"callback_concurrency,omitempty"`
+	CallbackBuffer      int             `json:"callback_buffer,omitempty"`
+	StatusPolicy        bool            `json:"status_policy"`
+	Pipeline            []PipelineStage `json:"pipeline"`
+
+	DialOverrides map[string]string `json:"dial_overrides,omitempty"`
+}
+
+// VerifyConfig is the serializable form of VerifyOptions.
+type VerifyConfig struct {
+	Concurrency int    `json:"concurrency"`
+	Interval    string `json:"interval,omitempty"`
+	UseGET      bool   `json:"use_get"`
+}
+
+// BreakerConfig is the serializable form of CircuitBreakerOptions.
+type BreakerConfig struct {
+	Failures int    `json:"failures"`
+	Cooldown string `json:"cooldown"`
+}
+
+// Config returns the effective configuration of f for logging and reproducibility.
+func (f *SitemapFetcher) Config() Config {
+	opts := f.opts
+	cfg := Config{
+		HTTPClient:          "custom",
+		MaxDepth:            opts.MaxDepth,
+		MaxSitemaps:         opts.MaxSitemaps,
+		MaxURLs:             opts.MaxURLs,
+		StopAtMaxURLs:       opts.StopAtMaxURLs,
+		MaxURLsPerSitemap:   opts.MaxURLsPerSitemap,
+		MaxTotalBytes:       opts.MaxTotalBytes,
+		WarnURLsPerSitemap:  opts.WarnURLsPerSitemap,
+		WarnSitemapBytes:    opts.WarnSitemapBytes,
+		PerSitemapPolicy:    opts.MaxURLsPerSitemapPolicy.String(),
+		InvalidLoc:          opts.InvalidLoc.String(),
+		FlagChangeFreq:      opts.FlagChangeFreq,
+		PriorityRange:       opts.PriorityRange.String(),
+		DefaultChangeFreq:   string(opts.DefaultChangeFreq),
+		DefaultPriority:     opts.DefaultPriority,
+		OnCheckpoint:        opts.OnCheckpoint != nil,
+		CheckpointEvery:     opts.CheckpointEvery,
+		Resume:              opts.Resume != nil,
+		VisitedStore:        opts.VisitedStore != nil,
+		DedupeURLs:          opts.DedupeURLs,
+		RetainURLs:          opts.RetainURLs,
+		SitemapStates:       opts.SitemapStates != nil,
+		Strict:              opts.Strict,
+		StrictNamespaces:    opts.StrictNamespaces,
+		Archive:             opts.Archive != nil,
+		SkipNon200:          opts.SkipNon200,
+		SkipFetchErrors:     opts.SkipFetchErrors,
+		IgnoreRobots:        opts.IgnoreRobots,
+		RobotsCache:         opts.RobotsCache != nil,
+		UserAgent:           opts.UserAgent,
+		RobotsUserAgent:     opts.RobotsUserAgent,
+		WalkIDHeader:        opts.WalkIDHeader,
+		TraceRequests:       opts.TraceRequests,
+		NetworkTimings:      opts.NetworkTimings,
+		Protocol:            opts.Protocol.String(),
+		DialOverrides:       opts.DialOverrides,
+		HTTP3Transport:      opts.HTTP3Transport != nil,
+		RobotsDisallowedErr: opts.ErrorOnRobotsDisallowed,
+		RobotsFailure:       opts.RobotsFailure.String(),
+		Include:             patternStrings(opts.Include),
+		Exclude:             patternStrings(opts.Exclude),
+		StripQueryParams:    append([]string(nil), opts.StripQueryParams...),
+		Transform:           opts.Transform != nil,
+		SampleRate:          opts.SampleRate,
+		SampleN:             opts.SampleN,
+		SampleSeed:          opts.SampleSeed,
+		OnError:             opts.OnError != nil,
+		OnEvent:             opts.OnEvent != nil,
+		Auth:                opts.Auth != nil,
+		AggregateErrors:     opts.AggregateErrors,
+		ReuseItems:          opts.ReuseItems,
+		KeepExtensions:      opts.KeepExtensions,
+		AdvertiseEncodings:  opts.AdvertiseEncodings,
+		ContentTypePolicy:   opts.ContentTypePolicy.String(),
+		HTMLFallback:        opts.HTMLFallback,
+		AcceptEncoding:      opts.AcceptEncoding,
+		TransportDecoding:   opts.TransportDecoding,
+		Formats:             append([]SitemapFormat{FormatXML}, opts.Formats...),
+		Discovery:           opts.Discovery.String(),
+		DetectNestedIndexes: opts.DetectNestedIndexes,
+		TraversalOrder:      opts.TraversalOrder.String(),
+		FetchConcurrency:    opts.FetchConcurrency,
+		ConcurrencyPerHost:  opts.ConcurrencyPerHost,
+		CallbackConcurrency: opts.CallbackConcurrency,
+		CallbackBuffer:      opts.CallbackBuffer,
+		StatusPolicy:        opts.StatusPolicy != nil,
+		Pipeline:            append([]PipelineStage(nil), opts.Pipeline...),
+	}
+	if opts.LastModLocation != nil {
+		cfg.LastModLocation = opts.LastModLocation.String()
+	}
+	for namespace := range opts.ExtensionDecoders {
+		cfg.ExtensionDecoders = append(cfg.ExtensionDecoders, namespace)
+	}
+	sort.Strings(cfg.ExtensionDecoders)
+	for encoding := range opts.ContentDecoders {
+		cfg.ContentDecoders = append(cfg.ContentDecoders, encoding)
+	}
+	sort.Strings(cfg.ContentDecoders)
+	for scheme := range f.schemes {
+		cfg.AllowedSchemes = append(cfg.AllowedSchemes, scheme)
+	}
+	sort.Strings(cfg.AllowedSchemes)
+	if opts.HTTPClient == http.DefaultClient {
+		cfg.HTTPClient = "default"
+	}
+	if opts.HTTPClient.Timeout > 0 {
+		cfg.HTTPClientTimeout = opts.HTTPClient.Timeout.String()
+	}
+	if opts.PerRequestTimeout > 0 {
+		cfg.PerRequestTimeout = opts.PerRequestTimeout.String()
+	}
+	if cfg.RobotsUserAgent == "" {
+		cfg.RobotsUserAgent = opts.UserAgent
+	}
+	if opts.RobotsCacheTTL > 0 {
+		cfg.RobotsCacheTTL = opts.RobotsCacheTTL.String()
+	}
+	if opts.LogLevel != nil {
+		cfg.LogLevel = opts.LogLevel.Level().String()
+	}
+	if opts.SummaryLevel != nil {
+		cfg.SummaryLevel = opts.SummaryLevel.Level().String()
+	}
+	if opts.SlowFetchWarning > 0 {
+		cfg.SlowFetchWarning = opts.SlowFetchWarning.String()
+	}
+	if opts.ProgressLogInterval > 0 {
+		cfg.ProgressLogInterval = opts.ProgressLogInterval.String()
+	}
+	if opts.WalkTimeout > 0 {
+		cfg.WalkTimeout = opts.WalkTimeout.String()
+	}
+	if opts.Delay > 0 {
+		cfg.Delay = opts.Delay.String()
+	}
+	if opts.DelayJitter > 0 {
+		cfg.DelayJitter = opts.DelayJitter.String()
+	}
+	if f.breaker != nil {
+		cfg.CircuitBreaker = &BreakerConfig{
+			Failures: f.breaker.failures,
+			Cooldown: f.breaker.cooldown.String(),
+		}
+	}
+	if opts.Verify != nil {
+		cfg.Verify = &VerifyConfig{
+			Concurrency: opts.Verify.Concurrency,
+			UseGET:      opts.Verify.UseGET,
+		}
+		if cfg.Verify.Concurrency <= 0 {
+			cfg.Verify.Concurrency = defaultVerifyConcurrency
+		}
+		if opts.Verify.Interval > 0 {
+			cfg.Verify.Interval = opts.Verify.Interval.String()
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,72 @@
+package gositemapfetcher
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_Config(t *testing.T) {
+	fetcher := New(Options{
+		MaxURLs:             10,
+		PerRequestTimeout:   5 * time.Second,
+		Include:             []*regexp.Regexp{regexp.MustCompile(`/blog/`)},
+		Formats:             []SitemapFormat{FormatText},
+		Discovery:           DiscoveryOff,
+		DetectNestedIndexes: true,
+	})
+
+	cfg := fetcher.Config()
+	if cfg.HTTPClient != "default" {
+		t.Fatalf("expected default client, got %q", cfg.HTTPClient)
+	}
+	if cfg.UserAgent != defaultUserAgent {
+		t.Fatalf("expected default user agent, got %q", cfg.UserAgent)
+	}
+	if cfg.PerRequestTimeout != "5s" {
+		t.Fatalf("expected 5s timeout, got %q", cfg.PerRequestTimeout)
+	}
+	if cfg.Discovery != "off" {
+		t.Fatalf("expected discovery off, got %q", cfg.Discovery)
+	}
+	if !cfg.DetectNestedIndexes {
+		t.Fatal("expected detect_nested_indexes to be reported")
+	}
+	if len(cfg.Pipeline) != len(DefaultPipeline) {
+		t.Fatalf("expected default pipeline, got %v", cfg.Pipeline)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"include":["/blog/"]`) {
+		t.Fatalf("expected include pattern in %s", data)
+	}
+}
+
+func TestConfig_CoversOptions(t *testing.T) {
+	// Options fields reported under another name, or not at all.
+	renamed := map[string]string{
+		"MaxURLsPerSitemapPolicy": "PerSitemapPolicy",
+		"ErrorOnRobotsDisallowed": "RobotsDisallowedErr",
+		"Logger":                  "",
+	}
+	config := reflect.TypeOf(Config{})
+	options := reflect.TypeOf(Options{})
+	for i := range options.NumField() {
+		name := options.Field(i).Name
+		if mapped, ok := renamed[name]; ok {
+			if mapped == "" {
+				continue
+			}
+			name = mapped
+		}
+		if _, ok := config.FieldByName(name); !ok {
+			t.Errorf("Options.%s has no Config field", name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ContentTypePolicy selects what happens when a sitemap response declares a
+// Content-Type that is not a sitemap format, such as text/html or
+// application/octet-stream. XML types (including any +xml type), gzip,
+// text/plain, and a missing header are always accepted.
+type ContentTypePolicy int
+
+const (
+	// ContentTypeSniff ignores the declared type and parses the body by its
+	// content; HTML pages still fail with ErrNotASitemap. Mislabeled
+	// responses are logged at debug level.
+	ContentTypeSniff ContentTypePolicy = iota
+	// ContentTypeSkip logs a warning and skips the sitemap, recording
+	// ErrUnexpectedContentType in SkippedSitemaps.
+	ContentTypeSkip
+	// ContentTypeError fails the sitemap with ErrUnexpectedContentType
+	// (OnError may continue past it).
+	ContentTypeError
+)
+
+func (p ContentTypePolicy) String() string {
+	switch p {
+	case ContentTypeSniff:
+		return "sniff"
+	case ContentTypeSkip:
+		return "skip"
+	case ContentTypeError:
+		return "error"
+	default:
+		return fmt.Sprintf("ContentTypePolicy(%d)", int(p))
+	}
+}
+
+type contentTypePolicyKey struct{}
+
+// WithContentTypePolicy returns a copy of ctx that makes a walk started with it
+// apply policy in place of Options.ContentTypePolicy, for example to be strict
+// about one known-good origin on a lenient fetcher.
+func WithContentTypePolicy(ctx context.Context, policy ContentTypePolicy) context.Context {
+	return context.WithValue(ctx, contentTypePolicyKey{}, policy)
+}
+
+// contentTypePolicy returns the policy for the walk running under ctx.
+func (f *SitemapFetcher) contentTypePolicy(ctx context.Context) ContentTypePolicy {
+	if policy, ok := ctx.Value(contentTypePolicyKey{}).(ContentTypePolicy); ok {
+		return policy
+	}
+	return f.opts.ContentTypePolicy
+}
+
+// checkContentType applies the Content-Type policy to a sitemap response. It
+// returns nil for an accepted type or under ContentTypeSniff.
+func (f *SitemapFetcher) checkContentType(ctx context.Context, loc *url.URL, header http.Header) *ErrUnexpectedContentType {
+	contentType := header.Get("Content-Type")
+	if sitemapContentType(contentType) {
+		return nil
+	}
+	if f.contentTypePolicy(ctx) == ContentTypeSniff {
+		f.logger.DebugContext(
+			ctx,
+			"unexpected sitemap content type; parsing by content",
+			"sitemap", loc.String(),
+			"content_type", contentType,
+		)
+		return nil
+	}
+	return &ErrUnexpectedContentType{URL: cloneURL(loc), ContentType: contentType}
+}
+
+// sitemapContentType reports whether a Content-Type header value may label a
+// sitemap in any supported format.
+func sitemapContentType(value string) bool {
+	if strings.TrimSpace(value) == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/xml", "text/xml", "application/gzip", "application/x-gzip", "text/plain":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,94 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// mislabeledSite serves an index listing /good.xml (application/xml) and
+// /bad.xml (application/octet-stream), both valid sitemaps.
+func mislabeledSite(t *testing.T) *url.URL {
+	t.Helper()
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/good.xml</loc></sitemap><sitemap><loc>/bad.xml</loc></sitemap></sitemapindex>`))
+		case "/good.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<urlset><url><loc>/good</loc></url></urlset>`))
+		case "/bad.xml":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(`<urlset><url><loc>/bad</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	return sitemapURL
+}
+
+func itemPaths(items []Item) string {
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		paths = append(paths, item.Loc.Path)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestSitemapFetcher_ContentTypePolicy(t *testing.T) {
+	sitemapURL := mislabeledSite(t)
+
+	items, err := collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+	if err != nil || itemPaths(items) != "/good,/bad" {
+		t.Fatalf("expected sniffing to parse both sitemaps, got %s (%v)", itemPaths(items), err)
+	}
+
+	fetcher := New(Options{IgnoreRobots: true, ContentTypePolicy: ContentTypeSkip})
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil || itemPaths(items) != "/good" {
+		t.Fatalf("expected the mislabeled sitemap to be skipped, got %s (%v)", itemPaths(items), err)
+	}
+	skipped := fetcher.SkippedSitemaps()
+	var typeErr *ErrUnexpectedContentType
+	if len(skipped) != 1 || !strings.HasSuffix(skipped[0].URL, "/bad.xml") || !errors.As(skipped[0].Err, &typeErr) {
+		t.Fatalf("expected /bad.xml skipped with ErrUnexpectedContentType, got %v", skipped)
+	}
+	if typeErr.ContentType != "application/octet-stream" {
+		t.Fatalf("unexpected content type %q", typeErr.ContentType)
+	}
+
+	_, err = collectItems(New(Options{IgnoreRobots: true, ContentTypePolicy: ContentTypeError}), sitemapURL)
+	if !errors.As(err, &typeErr) || typeErr.URL == nil || typeErr.URL.Path != "/bad.xml" {
+		t.Fatalf("expected ErrUnexpectedContentType, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_WithContentTypePolicy(t *testing.T) {
+	sitemapURL := mislabeledSite(t)
+	fetcher := New(Options{IgnoreRobots: true, ContentTypePolicy: ContentTypeError})
+
+	var items []Item
+	ctx := WithContentTypePolicy(context.Background(), ContentTypeSniff)
+	err := fetcher.Walk(ctx, sitemapURL, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil || itemPaths(items) != "/good,/bad" {
+		t.Fatalf("expected the override to sniff both sitemaps, got %s (%v)", itemPaths(items), err)
+	}
+
+	_, err = collectItems(fetcher, sitemapURL)
+	var typeErr *ErrUnexpectedContentType
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected the fetcher's policy on the next walk, got %v", err)
+	}
+}
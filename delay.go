@@ -0,0 +1,51 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostPacer spaces out requests to the same host by Options.Delay plus up to
+// Options.DelayJitter. It is shared by every walk of a SitemapFetcher so that
+// concurrent walks of one site stay polite too.
+type hostPacer struct {
+	delay  time.Duration
+	jitter time.Duration
+	mu     sync.Mutex
+	next   map[string]time.Time
+}
+
+func newHostPacer(opts Options) *hostPacer {
+	if opts.Delay <= 0 && opts.DelayJitter <= 0 {
+		return nil
+	}
+	return &hostPacer{delay: opts.Delay, jitter: opts.DelayJitter, next: map[string]time.Time{}}
+}
+
+// wait blocks until a request to u's host may be sent. The first request to a
+// host goes out immediately; each one reserves the next slot before sleeping,
+// so concurrent callers queue up instead of firing together.
+func (p *hostPacer) wait(ctx context.Context, u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	key := u.Scheme + "://" + u.Host
+	gap := p.delay
+	if p.jitter > 0 {
+		gap += rand.N(p.jitter + 1)
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next[key]
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next[key] = slot.Add(gap)
+	p.mu.Unlock()
+
+	return sleepWithContext(ctx, slot.Sub(now))
+}
@@ -0,0 +1,65 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// dialOverrideTransport returns a clone of next whose dialer connects to the
+// addresses in overrides instead of resolving the host. URLs, Host headers, and
+// TLS server names are unchanged. next must be an *http.Transport (nil means
+// http.DefaultTransport); any other RoundTripper is returned unchanged with an
+// *ErrUnsupportedTransport, since requests would go to the resolved hosts.
+func dialOverrideTransport(next http.RoundTripper, overrides map[string]string) (http.RoundTripper, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	base, ok := next.(*http.Transport)
+	if !ok {
+		return next, &ErrUnsupportedTransport{Option: "DialOverrides", Need: "an *http.Transport", Got: fmt.Sprintf("%T", next)}
+	}
+	table := make(map[string]string, len(overrides))
+	for host, target := range overrides {
+		table[strings.ToLower(host)] = target
+	}
+	dial := base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport := base.Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, network, overrideAddr(table, addr))
+	}
+	// DialTLSContext would bypass DialContext for https URLs.
+	if base.DialTLSContext != nil {
+		dialTLS := base.DialTLSContext
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialTLS(ctx, network, overrideAddr(table, addr))
+		}
+	}
+	return transport, nil
+}
+
+// overrideAddr maps a host:port dial address through the DialOverrides table.
+// An exact host:port key wins over a host key; a target without a port keeps
+// the original one.
+func overrideAddr(table map[string]string, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	host = strings.ToLower(host)
+	target, ok := table[net.JoinHostPort(host, port)]
+	if !ok {
+		if target, ok = table[host]; !ok {
+			return addr
+		}
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), port)
+}
@@ -0,0 +1,94 @@
+package gositemapfetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSitemapFetcher_DialOverrides(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	})
+
+	server := newTestServer(t, handler)
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	sitemapURL, err := url.Parse("http://sitemaps.invalid:" + port + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{DialOverrides: map[string]string{"Sitemaps.invalid": "127.0.0.1"}})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Loc.Host != "sitemaps.invalid:"+port {
+		t.Fatalf("expected items on the original host, got %+v", items)
+	}
+	for _, host := range hosts {
+		if host != "sitemaps.invalid:"+port {
+			t.Fatalf("expected the Host header to keep the URL host, got %q", host)
+		}
+	}
+
+	// httptest's certificate is valid for example.com, so a pinned TLS
+	// request only succeeds if the server name still comes from the URL.
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	tlsURL, err := url.Parse("https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher = New(Options{
+		HTTPClient:    tlsServer.Client(),
+		DialOverrides: map[string]string{"example.com:443": tlsServer.Listener.Addr().String()},
+	})
+	items, err = collectItems(fetcher, tlsURL)
+	if err != nil {
+		t.Fatalf("unexpected TLS error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item over TLS, got %d", len(items))
+	}
+}
+
+func TestSitemapFetcher_DialOverridesUnsupportedTransport(t *testing.T) {
+	var requests int
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{
+		HTTPClient:    &http.Client{Transport: &RecordTransport{Dir: t.TempDir()}},
+		DialOverrides: map[string]string{sitemapURL.Hostname(): "192.0.2.1"},
+	})
+	_, err = collectItems(fetcher, sitemapURL)
+	var unsupported *ErrUnsupportedTransport
+	if !errors.As(err, &unsupported) || unsupported.Option != "DialOverrides" {
+		t.Fatalf("expected ErrUnsupportedTransport for DialOverrides, got %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("expected no request to the unpinned host, got %d", requests)
+	}
+}
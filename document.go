@@ -0,0 +1,60 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/url"
+)
+
+// DocumentKind is the type of sitemap file returned by Fetch.
+type DocumentKind string
+
+const (
+	// DocumentURLSet is a sitemaps.org urlset.
+	DocumentURLSet DocumentKind = "urlset"
+	// DocumentIndex is a sitemaps.org sitemapindex.
+	DocumentIndex DocumentKind = "sitemapindex"
+	// DocumentText is a plain-text sitemap.
+	DocumentText DocumentKind = "text"
+	// DocumentFeed is an RSS or Atom feed used as a sitemap.
+	DocumentFeed DocumentKind = "feed"
+)
+
+// Document is a single parsed sitemap file.
+type Document struct {
+	URL  *url.URL
+	Kind DocumentKind
+	// Items holds the entries of a urlset, text sitemap, or feed.
+	Items []Item
+	// Sitemaps holds the sitemaps listed by a sitemapindex; they are not fetched.
+	Sitemaps []SitemapRef
+}
+
+// Fetch downloads and parses the single sitemap at u, without discovery and
+// without following index entries, and returns it as a Document. Filters, the
+// pipeline, limits, and robots.txt apply as for Walk. If the options skip the
+// sitemap (SkipNon200, SkipFetchErrors, or a robots.txt rule), Fetch returns an
+// empty Document whose Kind is "" and records the reason in SkippedSitemaps.
+func (f *SitemapFetcher) Fetch(ctx context.Context, u *url.URL) (*Document, error) {
+	doc := &Document{}
+	err := f.walk(ctx, []*url.URL{u}, func(item Item) error {
+		doc.Items = append(doc.Items, detachItem(item))
+		return nil
+	}, walkHooks{document: doc})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// collectChildren moves the sitemaps listed by the index just visited into the
+// Fetch document instead of queueing them.
+func (w *walk) collectChildren() {
+	for _, child := range w.children {
+		w.document.Sitemaps = append(w.document.Sitemaps, SitemapRef{
+			Loc:     child.loc,
+			LastMod: child.lastMod,
+			Depth:   child.depth,
+		})
+	}
+	w.children = nil
+}
@@ -0,0 +1,69 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestSitemapFetcher_Fetch(t *testing.T) {
+	var childRequests int
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/a.xml</loc><lastmod>2024-03-01</lastmod></sitemap>
+<sitemap><loc>/b.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/a.xml", "/b.xml":
+			childRequests++
+			_, _ = w.Write([]byte(`<urlset><url><loc>/one</loc><priority>0.5</priority></url><url><loc>/two</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := New(Options{CallbackConcurrency: 4})
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+	doc, err := fetcher.Fetch(context.Background(), indexURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Kind != DocumentIndex || len(doc.Items) != 0 || len(doc.Sitemaps) != 2 {
+		t.Fatalf("expected an index listing 2 sitemaps, got %+v", doc)
+	}
+	if doc.Sitemaps[0].Loc.Path != "/a.xml" || doc.Sitemaps[0].LastMod == nil || doc.Sitemaps[1].Loc.Path != "/b.xml" {
+		t.Fatalf("unexpected sitemaps: %+v", doc.Sitemaps)
+	}
+	if childRequests != 0 {
+		t.Fatalf("expected Fetch not to follow the index, got %d child requests", childRequests)
+	}
+
+	urlsetURL, err := url.Parse(server.URL + "/a.xml")
+	if err != nil {
+		t.Fatalf("failed to parse urlset URL: %v", err)
+	}
+	doc, err = fetcher.Fetch(context.Background(), urlsetURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Kind != DocumentURLSet || len(doc.Items) != 2 || len(doc.Sitemaps) != 0 {
+		t.Fatalf("expected a urlset with 2 items, got %+v", doc)
+	}
+	if doc.Items[0].Loc.Path != "/one" || doc.Items[0].Priority == nil || doc.Items[1].Loc.Path != "/two" {
+		t.Fatalf("unexpected items: %+v", doc.Items)
+	}
+
+	missingURL, err := url.Parse(server.URL + "/missing.xml")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), missingURL); err == nil {
+		t.Fatal("expected an error for a missing sitemap")
+	}
+}
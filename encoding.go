@@ -0,0 +1,139 @@
+package gositemapfetcher
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ContentDecoder wraps a response body compressed with one Content-Encoding.
+// Register decoders for encodings the standard library lacks in
+// Options.ContentDecoders; the encodings module provides brotli and zstd:
+//
+//	ContentDecoders: encodings.Decoders(),
+type ContentDecoder func(io.Reader) (io.ReadCloser, error)
+
+// builtinEncodings are decoded without an entry in Options.ContentDecoders.
+var builtinEncodings = []string{"gzip", "deflate"}
+
+// setAcceptEncoding sends Options.AcceptEncoding, or advertises the decodable
+// encodings when Options.AdvertiseEncodings is set. Setting the header also
+// stops the standard transport from decoding gzip itself, which decodeContent
+// then handles.
+func (f *SitemapFetcher) setAcceptEncoding(req *http.Request) {
+	switch {
+	case f.opts.AcceptEncoding != "":
+		req.Header.Set("Accept-Encoding", f.opts.AcceptEncoding)
+	case f.opts.AdvertiseEncodings && !f.opts.TransportDecoding:
+		req.Header.Set("Accept-Encoding", acceptEncoding(f.opts.ContentDecoders))
+	}
+}
+
+// decodeContent undoes the Content-Encoding of resp, or under
+// Options.TransportDecoding rejects a response the transport left encoded.
+func (f *SitemapFetcher) decodeContent(resp *http.Response) error {
+	if !f.opts.TransportDecoding {
+		return decodeContent(resp, f.opts.ContentDecoders)
+	}
+	if resp.Uncompressed {
+		return nil
+	}
+	for _, encoding := range contentEncodings(resp.Header) {
+		if encoding != "identity" {
+			return &ErrUnsupportedEncoding{Encoding: encoding}
+		}
+	}
+	return nil
+}
+
+// acceptEncoding lists the encodings advertised by Options.AdvertiseEncodings.
+func acceptEncoding(decoders map[string]ContentDecoder) string {
+	names := append([]string(nil), builtinEncodings...)
+	extra := make([]string, 0, len(decoders))
+	for name := range decoders {
+		if name != "gzip" && name != "deflate" {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return strings.Join(append(extra, names...), ", ")
+}
+
+// decodeContent replaces resp.Body with a stream that undoes its
+// Content-Encoding, unless the transport already did. Encodings are removed in
+// reverse order of application. A gzip label on a body without the gzip magic
+// is treated as identity, since servers often mislabel plain files. It returns
+// *ErrUnsupportedEncoding for an encoding with no decoder.
+func decodeContent(resp *http.Response, decoders map[string]ContentDecoder) error {
+	if resp.Uncompressed {
+		return nil
+	}
+	encodings := contentEncodings(resp.Header)
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := encodings[i]
+		decode := decoders[encoding]
+		if decode == nil {
+			switch encoding {
+			case "identity":
+				continue
+			case "gzip", "x-gzip":
+				decode = decodeGzip
+			case "deflate":
+				decode = decodeDeflate
+			default:
+				return &ErrUnsupportedEncoding{Encoding: encoding}
+			}
+		}
+		decoded, err := decode(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body = &multiCloser{reader: decoded, closers: []io.Closer{decoded, resp.Body}}
+	}
+	if len(encodings) > 0 {
+		resp.Header = resp.Header.Clone()
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	return nil
+}
+
+// contentEncodings returns the lowercased Content-Encoding tokens in order.
+func contentEncodings(header http.Header) []string {
+	var encodings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.ToLower(strings.TrimSpace(token)); token != "" {
+				encodings = append(encodings, token)
+			}
+		}
+	}
+	return encodings
+}
+
+func decodeGzip(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return io.NopCloser(buffered), nil
+	}
+	return gzip.NewReader(buffered)
+}
+
+// decodeDeflate accepts both zlib-wrapped data, as HTTP specifies, and the raw
+// deflate streams some servers send instead.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
@@ -0,0 +1,183 @@
+package gositemapfetcher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const encodedSitemap = `<urlset><url><loc>/page</loc></url></urlset>`
+
+func TestSitemapFetcher_ContentDecoders(t *testing.T) {
+	var acceptEncoding atomic.Value
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		acceptEncoding.Store(r.Header.Get("Accept-Encoding"))
+		// "br" stands in for a real brotli stream; base64 keeps the test stdlib-only.
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString([]byte(encodedSitemap))))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		IgnoreRobots:       true,
+		AdvertiseEncodings: true,
+		ContentDecoders: map[string]ContentDecoder{
+			"BR": func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+			},
+		},
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Loc.Path != "/page" {
+		t.Fatalf("expected decoded sitemap, got %v", items)
+	}
+	if got := acceptEncoding.Load(); got != "br, gzip, deflate" {
+		t.Fatalf("expected advertised encodings, got %q", got)
+	}
+
+	_, err = collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+	var encodingErr *ErrUnsupportedEncoding
+	if !errors.As(err, &encodingErr) || encodingErr.Encoding != "br" || encodingErr.URL == nil {
+		t.Fatalf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_DeflateEncoding(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(encodedSitemap))
+	zw.Close()
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Encoding", "deflate")
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	items, err := collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || !strings.HasSuffix(items[0].Loc.String(), "/page") {
+		t.Fatalf("expected decoded sitemap, got %v", items)
+	}
+}
+
+func TestSitemapFetcher_AcceptEncoding(t *testing.T) {
+	var acceptEncoding atomic.Value
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding.Store(r.Header.Get("Accept-Encoding"))
+		_, _ = w.Write([]byte(encodedSitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{IgnoreRobots: true, AcceptEncoding: "identity", AdvertiseEncodings: true})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected one item, got %v (%v)", items, err)
+	}
+	if got := acceptEncoding.Load(); got != "identity" {
+		t.Fatalf("expected Accept-Encoding: identity, got %q", got)
+	}
+}
+
+// base64Transport stands in for middleware that decodes a "br" body itself.
+type base64Transport struct {
+	strip bool
+}
+
+func (t base64Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || !t.strip || resp.Header.Get("Content-Encoding") != "br" {
+		return resp, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{base64.NewDecoder(base64.StdEncoding, resp.Body), resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func TestSitemapFetcher_TransportDecoding(t *testing.T) {
+	var acceptEncoding atomic.Value
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		acceptEncoding.Store(r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString([]byte(encodedSitemap))))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	decoders := map[string]ContentDecoder{
+		"br": func(io.Reader) (io.ReadCloser, error) {
+			return nil, errors.New("library decoder used")
+		},
+	}
+	fetcher := New(Options{
+		IgnoreRobots:       true,
+		HTTPClient:         &http.Client{Transport: base64Transport{strip: true}},
+		TransportDecoding:  true,
+		AdvertiseEncodings: true,
+		ContentDecoders:    decoders,
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil || len(items) != 1 || items[0].Loc.Path != "/page" {
+		t.Fatalf("expected the transport-decoded sitemap, got %v (%v)", items, err)
+	}
+	if got := acceptEncoding.Load(); got == "br, gzip, deflate" {
+		t.Fatalf("expected no advertised encodings, got %q", got)
+	}
+
+	fetcher = New(Options{
+		IgnoreRobots:      true,
+		HTTPClient:        &http.Client{Transport: base64Transport{}},
+		TransportDecoding: true,
+		ContentDecoders:   decoders,
+	})
+	_, err = collectItems(fetcher, sitemapURL)
+	var encodingErr *ErrUnsupportedEncoding
+	if !errors.As(err, &encodingErr) || encodingErr.Encoding != "br" || encodingErr.URL == nil {
+		t.Fatalf("expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
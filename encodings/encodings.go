@@ -0,0 +1,41 @@
+// Package encodings provides brotli and zstd decoders for
+// Options.ContentDecoders. It is a separate module so that the fetcher itself
+// stays dependency-free:
+//
+//	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+//		AdvertiseEncodings: true,
+//		ContentDecoders:    encodings.Decoders(),
+//	})
+package encodings
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Decoders returns a new map with the "br" and "zstd" decoders, to which more
+// can be added.
+func Decoders() map[string]gositemapfetcher.ContentDecoder {
+	return map[string]gositemapfetcher.ContentDecoder{
+		"br":   Brotli,
+		"zstd": Zstd,
+	}
+}
+
+// Brotli decodes a "br" Content-Encoding.
+func Brotli(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+// Zstd decodes a "zstd" Content-Encoding. The decoder streams on the calling
+// goroutine and is released when the body is closed.
+func Zstd(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
@@ -0,0 +1,75 @@
+package encodings
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecoders(t *testing.T) {
+	body := []byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`)
+	var br, zst bytes.Buffer
+	bw := brotli.NewWriter(&br)
+	_, _ = bw.Write(body)
+	if err := bw.Close(); err != nil {
+		t.Fatalf("brotli: %v", err)
+	}
+	zw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	_, _ = zw.Write(body)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+
+	var accepted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/br.xml":
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = w.Write(br.Bytes())
+		case "/zstd.xml":
+			w.Header().Set("Content-Encoding", "zstd")
+			_, _ = w.Write(zst.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+		IgnoreRobots:       true,
+		AdvertiseEncodings: true,
+		ContentDecoders:    Decoders(),
+	})
+	for _, path := range []string{"/br.xml", "/zstd.xml"} {
+		sitemapURL, err := url.Parse(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to parse sitemap URL: %v", err)
+		}
+		var locs []string
+		err = fetcher.Walk(context.Background(), sitemapURL, func(item gositemapfetcher.Item) error {
+			locs = append(locs, item.Loc.Path)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: walk failed: %v", path, err)
+		}
+		if got := strings.Join(locs, ","); got != "/a,/b" {
+			t.Fatalf("%s: expected /a,/b, got %s", path, got)
+		}
+		if !strings.Contains(accepted, "br") || !strings.Contains(accepted, "zstd") {
+			t.Fatalf("expected br and zstd advertised, got %q", accepted)
+		}
+	}
+}
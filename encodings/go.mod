@@ -0,0 +1,13 @@
+module github.com/enot-style/go-sitemap-fetcher/encodings
+
+go 1.25.5
+
+require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/enot-style/go-sitemap-fetcher v0.0.0
+	github.com/klauspost/compress v1.18.0
+)
+
+require github.com/temoto/robotstxt v1.1.2 // indirect
+
+replace github.com/enot-style/go-sitemap-fetcher => ..
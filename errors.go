@@ -1,10 +1,26 @@
 package gositemapfetcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+	"time"
 )
 
+// ErrSkipSitemap can be returned by the yield callback to skip the rest of the
+// current sitemap and continue with the next one. Walk does not return it.
+var ErrSkipSitemap = errors.New("skip this sitemap")
+
+// ErrStopWalk can be returned by the yield callback to end the walk early.
+// Walk returns nil in that case.
+var ErrStopWalk = errors.New("stop walk")
+
+// ErrNoPingEngines is returned by Ping when it is given no engines. The Google
+// and Bing endpoints are retired, so Ping has no default; use IndexNow instead.
+var ErrNoPingEngines = errors.New("ping: no engines given; the Google and Bing endpoints are retired, use IndexNow instead")
+
 // ErrNilYield indicates a nil yield callback was provided.
 type ErrNilYield struct{}
 
@@ -29,6 +45,93 @@ func (e *ErrInvalidURL) Unwrap() error {
 	return e.Err
 }
 
+// ErrInvalidPipeline indicates Options.Pipeline is not a usable stage list.
+type ErrInvalidPipeline struct {
+	Reason string
+}
+
+func (e *ErrInvalidPipeline) Error() string {
+	return fmt.Sprintf("invalid pipeline: %s", e.Reason)
+}
+
+// ErrInvalidCheckpoint indicates Options.Resume cannot be used for this walk.
+type ErrInvalidCheckpoint struct {
+	Reason string
+}
+
+func (e *ErrInvalidCheckpoint) Error() string {
+	return fmt.Sprintf("invalid checkpoint: %s", e.Reason)
+}
+
+// ErrCheckpoint wraps a failure returned by the OnCheckpoint callback.
+type ErrCheckpoint struct {
+	Err error
+}
+
+func (e *ErrCheckpoint) Error() string {
+	return fmt.Sprintf("checkpoint callback failed: %v", e.Err)
+}
+
+func (e *ErrCheckpoint) Unwrap() error {
+	return e.Err
+}
+
+// ErrVisitedStore wraps a failure of Options.VisitedStore. It ends the walk.
+type ErrVisitedStore struct {
+	Key string
+	Err error
+}
+
+func (e *ErrVisitedStore) Error() string {
+	return fmt.Sprintf("visited store failed for %q: %v", e.Key, e.Err)
+}
+
+func (e *ErrVisitedStore) Unwrap() error {
+	return e.Err
+}
+
+// ErrSitemapState wraps a failure of Options.SitemapStates. It ends the walk.
+type ErrSitemapState struct {
+	URL string
+	Err error
+}
+
+func (e *ErrSitemapState) Error() string {
+	return fmt.Sprintf("sitemap state store failed for %q: %v", e.URL, e.Err)
+}
+
+func (e *ErrSitemapState) Unwrap() error {
+	return e.Err
+}
+
+// ErrAuth wraps a failure of Options.Auth to authorize a request.
+type ErrAuth struct {
+	URL *url.URL
+	Err error
+}
+
+func (e *ErrAuth) Error() string {
+	return fmt.Sprintf("authorize request for %s: %v", e.URL, e.Err)
+}
+
+func (e *ErrAuth) Unwrap() error {
+	return e.Err
+}
+
+// ErrWalkTimeout indicates Options.WalkTimeout expired before the walk finished.
+// Items yielded before the deadline were delivered normally.
+type ErrWalkTimeout struct {
+	Timeout time.Duration
+}
+
+func (e *ErrWalkTimeout) Error() string {
+	return fmt.Sprintf("walk timed out after %s", e.Timeout)
+}
+
+func (e *ErrWalkTimeout) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
 // ErrNoSitemaps indicates that no sitemap URLs were discovered.
 type ErrNoSitemaps struct {
 	URL *url.URL
@@ -55,6 +158,173 @@ func (e *ErrHTTPStatus) Error() string {
 	return fmt.Sprintf("unexpected HTTP status %d for %s", e.StatusCode, e.URL)
 }
 
+// ErrNotASitemap indicates a sitemap URL returned an HTML page instead of XML.
+type ErrNotASitemap struct {
+	URL         *url.URL
+	ContentType string
+	// Hint suggests options that may locate the real sitemap.
+	Hint string
+}
+
+func (e *ErrNotASitemap) Error() string {
+	msg := "not a sitemap"
+	if e.URL != nil {
+		msg = fmt.Sprintf("not a sitemap: %s", e.URL)
+	}
+	if e.ContentType != "" {
+		msg += fmt.Sprintf(" (content type %q)", e.ContentType)
+	}
+	if e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+// ErrUnexpectedContentType indicates a sitemap response declared a
+// Content-Type that is not a sitemap format while Options.ContentTypePolicy
+// was ContentTypeSkip or ContentTypeError.
+type ErrUnexpectedContentType struct {
+	URL         *url.URL
+	ContentType string
+}
+
+func (e *ErrUnexpectedContentType) Error() string {
+	if e.URL == nil {
+		return fmt.Sprintf("unexpected sitemap content type %q", e.ContentType)
+	}
+	return fmt.Sprintf("unexpected sitemap content type %q for %s", e.ContentType, e.URL)
+}
+
+// ErrUnsupportedFormat indicates a sitemap is in a format not enabled by Options.Formats.
+type ErrUnsupportedFormat struct {
+	URL    *url.URL
+	Format SitemapFormat
+}
+
+func (e *ErrUnsupportedFormat) Error() string {
+	if e.URL == nil {
+		return fmt.Sprintf("unsupported sitemap format %q", e.Format)
+	}
+	return fmt.Sprintf("unsupported sitemap format %q for %s", e.Format, e.URL)
+}
+
+// ErrUnsupportedEncoding indicates a sitemap response used a Content-Encoding
+// with no decoder; register one in Options.ContentDecoders. Under
+// Options.TransportDecoding it reports a response the transport left encoded.
+type ErrUnsupportedEncoding struct {
+	URL      *url.URL
+	Encoding string
+}
+
+func (e *ErrUnsupportedEncoding) Error() string {
+	if e.URL == nil {
+		return fmt.Sprintf("unsupported content encoding %q", e.Encoding)
+	}
+	return fmt.Sprintf("unsupported content encoding %q for %s", e.Encoding, e.URL.String())
+}
+
+// ErrUnsupportedTransport indicates an option that adapts the HTTP client's
+// transport cannot apply to it. The fetcher's walks and pings fail with it
+// rather than silently ignoring the option.
+type ErrUnsupportedTransport struct {
+	// Option names the option, such as "Protocol http2".
+	Option string
+	// Need is what the option requires and Got what was configured instead.
+	Need string
+	Got  string
+}
+
+func (e *ErrUnsupportedTransport) Error() string {
+	return fmt.Sprintf("%s needs %s, got %s", e.Option, e.Need, e.Got)
+}
+
+// ErrSpecViolation indicates a sitemap broke the sitemaps.org protocol while Options.Strict was set.
+type ErrSpecViolation struct {
+	Finding Finding
+}
+
+func (e *ErrSpecViolation) Error() string {
+	return fmt.Sprintf("sitemap spec violation: %s", e.Finding)
+}
+
+// ErrArchive indicates that storing a sitemap body via Options.Archive failed.
+type ErrArchive struct {
+	URL *url.URL
+	Err error
+}
+
+func (e *ErrArchive) Error() string {
+	if e.URL == nil {
+		return fmt.Sprintf("archive sitemap: %v", e.Err)
+	}
+	return fmt.Sprintf("archive sitemap %s: %v", e.URL, e.Err)
+}
+
+func (e *ErrArchive) Unwrap() error {
+	return e.Err
+}
+
+// ErrCircuitOpen indicates a sitemap was skipped because its host failed
+// CircuitBreakerOptions.Failures times in a row and is cooling down.
+type ErrCircuitOpen struct {
+	URL   *url.URL
+	Host  string
+	Until time.Time
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit open for host %s until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// ErrRobotsDisallowed indicates robots.txt blocks a start sitemap and
+// Options.ErrorOnRobotsDisallowed is set.
+type ErrRobotsDisallowed struct {
+	URL *url.URL
+}
+
+func (e *ErrRobotsDisallowed) Error() string {
+	return fmt.Sprintf("robots.txt disallows %s", e.URL)
+}
+
+// ErrRobotsUnavailable indicates robots.txt returned a 5xx status or could not
+// be fetched, and Options.RobotsFailure is RobotsFailError.
+type ErrRobotsUnavailable struct {
+	URL        *url.URL
+	StatusCode int
+	Err        error
+}
+
+func (e *ErrRobotsUnavailable) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("robots.txt %s unavailable: %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("robots.txt %s unavailable: status %d", e.URL, e.StatusCode)
+}
+
+func (e *ErrRobotsUnavailable) Unwrap() error {
+	return e.Err
+}
+
+// ErrRedirectLoop indicates a sitemap fetch hit a redirect cycle or too many redirects.
+type ErrRedirectLoop struct {
+	URL *url.URL
+	// Chain lists the redirect hops; for a cycle it starts and ends with the repeated URL.
+	Chain []string
+	// TooLong is true when the chain exceeded the redirect limit without repeating.
+	TooLong bool
+}
+
+func (e *ErrRedirectLoop) Error() string {
+	kind := "redirect loop"
+	if e.TooLong {
+		kind = "too many redirects"
+	}
+	if e.URL == nil {
+		return fmt.Sprintf("%s: %s", kind, strings.Join(e.Chain, " -> "))
+	}
+	return fmt.Sprintf("%s for %s: %s", kind, e.URL, strings.Join(e.Chain, " -> "))
+}
+
 // ErrSitemapParse indicates a failure while parsing sitemap XML.
 type ErrSitemapParse struct {
 	URL *url.URL
@@ -103,6 +373,61 @@ func (e *ErrMaxURLs) Error() string {
 	return fmt.Sprintf("max URLs %d exceeded", e.MaxURLs)
 }
 
+// ErrInvalidLoc indicates a <loc> that cannot be parsed as a URL, under InvalidLocError.
+type ErrInvalidLoc struct {
+	Sitemap *url.URL
+	// Position is the 1-based index of the <url> entry within Sitemap.
+	Position int
+	// Loc is the entry's <loc> text.
+	Loc string
+	Err error
+}
+
+func (e *ErrInvalidLoc) Error() string {
+	return fmt.Sprintf("invalid loc %q at entry %d of %s: %v", e.Loc, e.Position, e.Sitemap, e.Err)
+}
+
+func (e *ErrInvalidLoc) Unwrap() error {
+	return e.Err
+}
+
+// ErrMaxTotalBytes indicates a walk downloaded more than MaxTotalBytes.
+type ErrMaxTotalBytes struct {
+	MaxTotalBytes int64
+	// Downloaded is the byte count when the budget ran out.
+	Downloaded int64
+	// URL is the sitemap being read at that point.
+	URL *url.URL
+}
+
+func (e *ErrMaxTotalBytes) Error() string {
+	return fmt.Sprintf("max total bytes %d exceeded (%d downloaded) at %s", e.MaxTotalBytes, e.Downloaded, e.URL)
+}
+
+// ErrSitemapTooLarge indicates a sitemap downloaded ahead of its turn under
+// FetchConcurrency decoded to more than Limit bytes, so it was not buffered.
+type ErrSitemapTooLarge struct {
+	URL   *url.URL
+	Limit int64
+}
+
+func (e *ErrSitemapTooLarge) Error() string {
+	return fmt.Sprintf("sitemap %s exceeds %d bytes", e.URL, e.Limit)
+}
+
+// ErrMaxURLsPerSitemap indicates a single sitemap exceeded MaxURLsPerSitemap.
+type ErrMaxURLsPerSitemap struct {
+	URL               *url.URL
+	MaxURLsPerSitemap int
+}
+
+func (e *ErrMaxURLsPerSitemap) Error() string {
+	if e.URL == nil {
+		return fmt.Sprintf("max URLs per sitemap %d exceeded", e.MaxURLsPerSitemap)
+	}
+	return fmt.Sprintf("max URLs per sitemap %d exceeded in %s", e.MaxURLsPerSitemap, e.URL)
+}
+
 // ErrYield wraps a failure returned by the yield callback.
 type ErrYield struct {
 	Err error
@@ -115,3 +440,28 @@ func (e *ErrYield) Error() string {
 func (e *ErrYield) Unwrap() error {
 	return e.Err
 }
+
+// WalkErrors lists every sitemap that failed while the walk continued past it.
+type WalkErrors struct {
+	Sitemaps []SkippedSitemap
+}
+
+func (e *WalkErrors) Error() string {
+	if len(e.Sitemaps) == 1 {
+		return fmt.Sprintf("1 sitemap failed: %s: %v", e.Sitemaps[0].URL, e.Sitemaps[0].Err)
+	}
+	parts := make([]string, 0, len(e.Sitemaps))
+	for _, skipped := range e.Sitemaps {
+		parts = append(parts, fmt.Sprintf("%s: %v", skipped.URL, skipped.Err))
+	}
+	return fmt.Sprintf("%d sitemaps failed: %s", len(e.Sitemaps), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes each sitemap's error to errors.Is and errors.As.
+func (e *WalkErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e.Sitemaps))
+	for _, skipped := range e.Sitemaps {
+		errs = append(errs, skipped.Err)
+	}
+	return errs
+}
@@ -0,0 +1,97 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// EventKind identifies a walk lifecycle event.
+type EventKind int
+
+const (
+	// EventSitemapStarted is sent before a sitemap is requested.
+	EventSitemapStarted EventKind = iota + 1
+	// EventSitemapFinished is sent once a sitemap has been read; Stat holds its
+	// timings and Err the error that ended it early, if any.
+	EventSitemapFinished
+	// EventSitemapSkipped is sent for a sitemap left unread: skipped on error
+	// (as listed by SkippedSitemaps), disallowed by robots.txt, or unchanged
+	// under Options.SitemapStates. Err holds the reason when there is one.
+	EventSitemapSkipped
+	// EventItemEmitted is sent after Item has been delivered to the callback.
+	EventItemEmitted
+	// EventWalkFinished is the last event of a walk; Err is the walk's result
+	// and URLs and Sitemaps its totals.
+	EventWalkFinished
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventSitemapStarted:
+		return "sitemap-started"
+	case EventSitemapFinished:
+		return "sitemap-finished"
+	case EventSitemapSkipped:
+		return "sitemap-skipped"
+	case EventItemEmitted:
+		return "item-emitted"
+	case EventWalkFinished:
+		return "walk-finished"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event is one step of a walk, sent to Options.OnEvent.
+type Event struct {
+	Kind   EventKind
+	WalkID string
+	Time   time.Time
+	// Sitemap is the sitemap concerned: the one read for sitemap events, and
+	// the item's sitemap for EventItemEmitted. nil for EventWalkFinished.
+	Sitemap *url.URL
+	Item    *Item
+	Stat    *SitemapStat
+	Err     error
+	// URLs and Sitemaps count the items delivered and sitemaps requested by
+	// the walk; set on EventWalkFinished.
+	URLs     int
+	Sitemaps int
+}
+
+// SendEvents returns an Options.OnEvent callback that sends every event to ch,
+// for consumers that prefer a channel. Sends block, so keep ch drained (or
+// buffered) while walking; the walk never closes it.
+func SendEvents(ch chan<- Event) func(Event) {
+	return func(event Event) {
+		ch <- event
+	}
+}
+
+// emit sends event to Options.OnEvent, if set, stamping its walk ID and time.
+func (f *SitemapFetcher) emit(ctx context.Context, event Event) {
+	if f.opts.OnEvent == nil {
+		return
+	}
+	event.WalkID, _ = WalkID(ctx)
+	event.Time = time.Now()
+	f.opts.OnEvent(event)
+}
+
+// emitSkipped sends EventSitemapSkipped for a sitemap left unread without error.
+func (w *walk) emitSkipped(task sitemapTask) {
+	if w.f.opts.OnEvent == nil {
+		return
+	}
+	w.f.emit(w.ctx, Event{Kind: EventSitemapSkipped, Sitemap: cloneURL(task.loc)})
+}
+
+// emitItem sends EventItemEmitted for a delivered item.
+func (w *walk) emitItem(item Item) {
+	if w.f.opts.OnEvent == nil {
+		return
+	}
+	w.f.emit(w.ctx, Event{Kind: EventItemEmitted, Sitemap: item.Sitemap, Item: &item})
+}
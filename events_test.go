@@ -0,0 +1,82 @@
+package gositemapfetcher
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSitemapFetcher_OnEvent(t *testing.T) {
+	sitemaps := map[string]string{
+		"/sitemap.xml": `<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/missing.xml</loc></sitemap></sitemapindex>`,
+		"/a.xml":       `<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`,
+	}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := sitemaps[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	events := make(chan Event, 64)
+	fetcher := New(Options{IgnoreRobots: true, SkipNon200: true, OnEvent: SendEvents(events)})
+	if _, err := collectItems(fetcher, sitemapURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	close(events)
+
+	var kinds []string
+	var walkID string
+	for event := range events {
+		label := event.Kind.String()
+		if event.Sitemap != nil {
+			label += " " + event.Sitemap.Path
+		}
+		if event.Item != nil {
+			label += " " + event.Item.Loc.Path
+		}
+		kinds = append(kinds, label)
+		if walkID == "" {
+			walkID = event.WalkID
+		}
+		if event.WalkID == "" || event.WalkID != walkID || event.Time.IsZero() {
+			t.Fatalf("expected every event stamped with one walk ID and a time, got %+v", event)
+		}
+		switch event.Kind {
+		case EventSitemapFinished:
+			if event.Stat == nil || event.Stat.URL != event.Sitemap.String() {
+				t.Fatalf("expected the sitemap's stat, got %+v", event.Stat)
+			}
+		case EventSitemapSkipped:
+			if event.Err == nil {
+				t.Fatalf("expected the skip reason, got %+v", event)
+			}
+		case EventWalkFinished:
+			if event.Err != nil || event.URLs != 2 || event.Sitemaps != 3 {
+				t.Fatalf("unexpected walk totals: %+v", event)
+			}
+		}
+	}
+	want := []string{
+		"sitemap-started /sitemap.xml",
+		"sitemap-finished /sitemap.xml",
+		"sitemap-started /a.xml",
+		"item-emitted /a.xml /one",
+		"item-emitted /a.xml /two",
+		"sitemap-finished /a.xml",
+		"sitemap-started /missing.xml",
+		"sitemap-skipped /missing.xml",
+		"walk-finished",
+	}
+	if strings.Join(kinds, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected events:\n%s\nwant:\n%s", strings.Join(kinds, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/enot-style/go-sitemap-fetcher/grpcserver
+
+go 1.25.5
+
+require (
+	github.com/enot-style/go-sitemap-fetcher v0.0.0
+	google.golang.org/grpc v1.79.3
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/temoto/robotstxt v1.1.2 // indirect
+	golang.org/x/net v0.48.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+	golang.org/x/text v0.32.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251202230838-ff82c1b0f217 // indirect
+)
+
+replace github.com/enot-style/go-sitemap-fetcher => ..
@@ -0,0 +1,199 @@
+// Package grpcserver implements the SitemapFetcher gRPC service defined in
+// proto/sitemapfetcher/v1 on top of WalkAll. It is a separate module so that
+// the fetcher itself does not depend on gRPC:
+//
+//	lis, _ := net.Listen("tcp", ":9090")
+//	srv := grpc.NewServer()
+//	sitemapfetcherv1.RegisterSitemapFetcherServer(srv, grpcserver.New(grpcserver.Options{
+//		Fetcher: gositemapfetcher.Options{UserAgent: "my-bot"},
+//	}))
+//	log.Fatal(srv.Serve(lis))
+//
+// Walk streams each item as it is yielded. A failed walk ends the stream with
+// a status carrying the walk's error; cancelling the call stops the walk.
+package grpcserver
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/grpcserver/sitemapfetcherv1"
+	"github.com/enot-style/go-sitemap-fetcher/server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Options configures the service.
+type Options struct {
+	// Fetcher is the base configuration of every walk. Request options are
+	// merged into it as by the HTTP server (server.Overrides): its limits are
+	// caps that requests can lower but not raise, Delay can only grow,
+	// Exclude patterns are added, and Include, UserAgent, RobotsUserAgent,
+	// and ignore_robots can be set only where the operator allows.
+	Fetcher gositemapfetcher.Options
+}
+
+// Server implements sitemapfetcherv1.SitemapFetcherServer. It is safe for
+// concurrent use; every call gets its own fetcher.
+type Server struct {
+	sitemapfetcherv1.UnimplementedSitemapFetcherServer
+	opts Options
+}
+
+// New returns a service walking with opts.
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// Walk implements sitemapfetcherv1.SitemapFetcherServer.
+func (s *Server) Walk(req *sitemapfetcherv1.WalkRequest, stream sitemapfetcherv1.SitemapFetcher_WalkServer) error {
+	roots, opts, err := s.walkOptions(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx := stream.Context()
+	// With CallbackConcurrency the callback runs on several goroutines, and a
+	// stream allows only one Send at a time.
+	var sendMu sync.Mutex
+	err = gositemapfetcher.New(opts).WalkAll(ctx, roots, func(item gositemapfetcher.Item) error {
+		msg, err := itemMessage(item)
+		if err != nil {
+			return err
+		}
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(msg)
+	})
+	if err != nil {
+		return walkStatus(ctx, err)
+	}
+	return nil
+}
+
+// walkOptions validates req and merges it into the base options.
+func (s *Server) walkOptions(req *sitemapfetcherv1.WalkRequest) ([]*url.URL, gositemapfetcher.Options, error) {
+	opts := s.opts.Fetcher
+	var roots []*url.URL
+	for _, raw := range req.GetUrls() {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, opts, fmt.Errorf("invalid URL %q: %w", raw, err)
+		}
+		roots = append(roots, u)
+	}
+	if len(roots) == 0 {
+		return nil, opts, errors.New("missing urls")
+	}
+	o := req.GetOptions()
+	opts, err := server.Overrides{
+		MaxDepth:           int(o.GetMaxDepth()),
+		MaxSitemaps:        int(o.GetMaxSitemaps()),
+		MaxURLs:            int(o.GetMaxUrls()),
+		MaxURLsPerSitemap:  int(o.GetMaxUrlsPerSitemap()),
+		MaxTotalBytes:      o.GetMaxTotalBytes(),
+		PerRequestTimeout:  o.GetPerRequestTimeout().AsDuration(),
+		WalkTimeout:        o.GetWalkTimeout().AsDuration(),
+		FetchConcurrency:   int(o.GetFetchConcurrency()),
+		ConcurrencyPerHost: int(o.GetConcurrencyPerHost()),
+		Delay:              o.GetDelay().AsDuration(),
+		StopAtMaxURLs:      o.GetStopAtMaxUrls(),
+		SkipNon200:         o.GetSkipNon_200(),
+		SkipFetchErrors:    o.GetSkipFetchErrors(),
+		DedupeURLs:         o.GetDedupeUrls(),
+		Strict:             o.GetStrict(),
+		KeepExtensions:     o.GetKeepExtensions(),
+		IgnoreRobots:       o.GetIgnoreRobots(),
+		UserAgent:          o.GetUserAgent(),
+		RobotsUserAgent:    o.GetRobotsUserAgent(),
+		TraversalOrder:     o.GetTraversalOrder(),
+		Include:            o.GetInclude(),
+		Exclude:            o.GetExclude(),
+	}.Apply(opts)
+	if err != nil {
+		return nil, opts, err
+	}
+	return roots, opts, nil
+}
+
+// walkStatus maps the error ending a walk to a gRPC status.
+func walkStatus(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	var (
+		timeout   *gositemapfetcher.ErrWalkTimeout
+		maxDepth  *gositemapfetcher.ErrMaxDepth
+		sitemaps  *gositemapfetcher.ErrMaxSitemaps
+		maxURLs   *gositemapfetcher.ErrMaxURLs
+		maxBytes  *gositemapfetcher.ErrMaxTotalBytes
+		invalid   *gositemapfetcher.ErrInvalidURL
+		noSitemap *gositemapfetcher.ErrNoSitemaps
+	)
+	code := codes.Unknown
+	switch {
+	case errors.As(err, &timeout):
+		code = codes.DeadlineExceeded
+	case errors.As(err, &maxDepth), errors.As(err, &sitemaps), errors.As(err, &maxURLs), errors.As(err, &maxBytes):
+		code = codes.ResourceExhausted
+	case errors.As(err, &invalid):
+		code = codes.InvalidArgument
+	case errors.As(err, &noSitemap):
+		code = codes.NotFound
+	}
+	return status.Error(code, err.Error())
+}
+
+// itemMessage converts item to its wire form.
+func itemMessage(item gositemapfetcher.Item) (*sitemapfetcherv1.Item, error) {
+	msg := &sitemapfetcherv1.Item{
+		LastmodZoned:     item.LastModZoned,
+		Changefreq:       string(item.ChangeFreq),
+		Priority:         item.Priority,
+		RawLoc:           item.RawLoc,
+		RawChangefreq:    item.RawChangeFreq,
+		Mobile:           item.Mobile,
+		Depth:            int32(item.Depth),
+		Position:         int32(item.Position),
+		ValidationErrors: item.ValidationErrors,
+	}
+	if item.Loc != nil {
+		msg.Loc = item.Loc.String()
+	}
+	if item.Sitemap != nil {
+		msg.Sitemap = item.Sitemap.String()
+	}
+	if item.LastMod != nil {
+		msg.Lastmod = timestamppb.New(*item.LastMod)
+	}
+	if item.SitemapLastMod != nil {
+		msg.SitemapLastmod = timestamppb.New(*item.SitemapLastMod)
+	}
+	for _, ext := range item.Extensions {
+		fragment, err := xml.Marshal(ext)
+		if err != nil {
+			return nil, err
+		}
+		msg.Extensions = append(msg.Extensions, string(fragment))
+	}
+	if check := item.LinkCheck; check != nil {
+		msg.LinkCheck = &sitemapfetcherv1.LinkCheck{
+			StatusCode: int32(check.StatusCode),
+			Redirects:  check.Redirects,
+			Duration:   durationpb.New(check.Duration),
+		}
+		if check.FinalURL != nil {
+			msg.LinkCheck.FinalUrl = check.FinalURL.String()
+		}
+		if check.Err != nil {
+			msg.LinkCheck.Error = check.Err.Error()
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,187 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/grpcserver/sitemapfetcherv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// dialService serves srv over an in-memory listener and returns a client.
+func dialService(t *testing.T, srv *Server) sitemapfetcherv1.SitemapFetcherClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	sitemapfetcherv1.RegisterSitemapFetcherServer(grpcServer, srv)
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return sitemapfetcherv1.NewSitemapFetcherClient(conn)
+}
+
+// receiveAll reads the stream to its end and returns the items and final error.
+func receiveAll(stream sitemapfetcherv1.SitemapFetcher_WalkClient) ([]*sitemapfetcherv1.Item, error) {
+	var items []*sitemapfetcherv1.Item
+	for {
+		item, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+}
+
+func TestServer_Walk(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+<url><loc>/a</loc><lastmod>2024-01-02</lastmod><priority>0.5</priority><image:image xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"><image:loc>/a.png</image:loc></image:image></url>
+<url><loc>/b</loc></url><url><loc>/c</loc></url></urlset>`))
+		case "/broken.xml":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer origin.Close()
+	client := dialService(t, New(Options{Fetcher: gositemapfetcher.Options{IgnoreRobots: true}}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Walk(ctx, &sitemapfetcherv1.WalkRequest{
+		Urls:    []string{origin.URL + "/sitemap.xml"},
+		Options: &sitemapfetcherv1.Options{Exclude: []string{"/b$"}, KeepExtensions: true},
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	items, err := receiveAll(stream)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(items) != 2 || !strings.HasSuffix(items[0].GetLoc(), "/a") || !strings.HasSuffix(items[1].GetLoc(), "/c") {
+		t.Fatalf("expected /a and /c, got %v", items)
+	}
+	first := items[0]
+	if first.GetLastmod().AsTime() != time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC) || first.Priority == nil || first.GetPriority() != 0.5 || first.GetPosition() != 1 {
+		t.Fatalf("unexpected first item: %v", first)
+	}
+	want := `<image xmlns="http://www.google.com/schemas/sitemap-image/1.1"><loc xmlns="http://www.google.com/schemas/sitemap-image/1.1">/a.png</loc></image>`
+	if len(first.GetExtensions()) != 1 || first.GetExtensions()[0] != want {
+		t.Fatalf("expected the image extension as a fragment, got %q", first.GetExtensions())
+	}
+	if items[1].Priority != nil {
+		t.Fatalf("expected no priority when the sitemap gave none, got %v", items[1].GetPriority())
+	}
+
+	// A failed walk ends the stream with its error.
+	stream, err = client.Walk(ctx, &sitemapfetcherv1.WalkRequest{Urls: []string{origin.URL + "/broken.xml"}})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if _, err := receiveAll(stream); status.Code(err) != codes.Unknown || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("expected the walk's error in the status, got %v", err)
+	}
+}
+
+func TestServer_InvalidRequests(t *testing.T) {
+	client := dialService(t, New(Options{}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, req := range []*sitemapfetcherv1.WalkRequest{
+		{},
+		{Urls: []string{"https://example.com"}, Options: &sitemapfetcherv1.Options{IgnoreRobots: true}},
+		{Urls: []string{"https://example.com"}, Options: &sitemapfetcherv1.Options{Include: []string{"("}}},
+		{Urls: []string{"https://example.com"}, Options: &sitemapfetcherv1.Options{TraversalOrder: "random"}},
+	} {
+		stream, err := client.Walk(ctx, req)
+		if err != nil {
+			t.Fatalf("Walk failed: %v", err)
+		}
+		if _, err := receiveAll(stream); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected InvalidArgument for %v, got %v", req, err)
+		}
+	}
+}
+
+func TestServer_OperatorLimits(t *testing.T) {
+	srv := New(Options{Fetcher: gositemapfetcher.Options{MaxURLs: 100, WalkTimeout: time.Minute, Delay: time.Second, UserAgent: "operator-bot"}})
+	_, opts, err := srv.walkOptions(&sitemapfetcherv1.WalkRequest{
+		Urls: []string{"https://example.com"},
+		Options: &sitemapfetcherv1.Options{
+			MaxUrls:        1000,
+			MaxDepth:       2,
+			WalkTimeout:    durationpb.New(time.Hour),
+			Delay:          durationpb.New(time.Millisecond),
+			UserAgent:      "client-bot",
+			TraversalOrder: "depth-first",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.MaxURLs != 100 || opts.MaxDepth != 2 || opts.WalkTimeout != time.Minute || opts.Delay != time.Second || opts.UserAgent != "operator-bot" {
+		t.Fatalf("expected the operator's settings kept as caps, got %+v", opts)
+	}
+	if opts.TraversalOrder != gositemapfetcher.TraversalDepthFirst {
+		t.Fatalf("expected depth-first traversal, got %v", opts.TraversalOrder)
+	}
+}
+
+func TestServer_CallbackConcurrency(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("<urlset>")
+	for i := range 200 {
+		fmt.Fprintf(&body, "<url><loc>/page-%d</loc></url>", i)
+	}
+	body.WriteString("</urlset>")
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	defer origin.Close()
+	client := dialService(t, New(Options{Fetcher: gositemapfetcher.Options{IgnoreRobots: true, CallbackConcurrency: 4}}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Walk(ctx, &sitemapfetcherv1.WalkRequest{Urls: []string{origin.URL + "/sitemap.xml"}})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	items, err := receiveAll(stream)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if len(items) != 200 {
+		t.Fatalf("expected 200 items, got %d", len(items))
+	}
+}
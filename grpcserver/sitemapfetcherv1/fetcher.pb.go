@@ -0,0 +1,673 @@
+// Typed contract for calling the fetcher across service boundaries. The
+// messages mirror gositemapfetcher.Options and gositemapfetcher.Item; field
+// semantics and defaults are those of the Go types.
+//
+// The Go stubs and a server implementation live in the grpcserver module, so
+// the main module does not depend on gRPC. Generate stubs for other languages
+// with protoc; regenerate the Go ones from the repository root with:
+//
+//   protoc -I proto --go_out=grpcserver --go_opt=module=github.com/enot-style/go-sitemap-fetcher/grpcserver \
+//     --go-grpc_out=grpcserver --go-grpc_opt=module=github.com/enot-style/go-sitemap-fetcher/grpcserver \
+//     sitemapfetcher/v1/fetcher.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: sitemapfetcher/v1/fetcher.proto
+
+package sitemapfetcherv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WalkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Websites or sitemap URLs. Several URLs are walked as one run (WalkAll).
+	Urls          []string `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	Options       *Options `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WalkRequest) Reset() {
+	*x = WalkRequest{}
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WalkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WalkRequest) ProtoMessage() {}
+
+func (x *WalkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WalkRequest.ProtoReflect.Descriptor instead.
+func (*WalkRequest) Descriptor() ([]byte, []int) {
+	return file_sitemapfetcher_v1_fetcher_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WalkRequest) GetUrls() []string {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *WalkRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+// Options is the subset of gositemapfetcher.Options that can be sent over the
+// wire. Unset fields keep the server's defaults.
+type Options struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MaxDepth          int32                  `protobuf:"varint,1,opt,name=max_depth,json=maxDepth,proto3" json:"max_depth,omitempty"`
+	MaxSitemaps       int32                  `protobuf:"varint,2,opt,name=max_sitemaps,json=maxSitemaps,proto3" json:"max_sitemaps,omitempty"`
+	MaxUrls           int32                  `protobuf:"varint,3,opt,name=max_urls,json=maxUrls,proto3" json:"max_urls,omitempty"`
+	StopAtMaxUrls     bool                   `protobuf:"varint,4,opt,name=stop_at_max_urls,json=stopAtMaxUrls,proto3" json:"stop_at_max_urls,omitempty"`
+	MaxUrlsPerSitemap int32                  `protobuf:"varint,5,opt,name=max_urls_per_sitemap,json=maxUrlsPerSitemap,proto3" json:"max_urls_per_sitemap,omitempty"`
+	MaxTotalBytes     int64                  `protobuf:"varint,6,opt,name=max_total_bytes,json=maxTotalBytes,proto3" json:"max_total_bytes,omitempty"`
+	PerRequestTimeout *durationpb.Duration   `protobuf:"bytes,7,opt,name=per_request_timeout,json=perRequestTimeout,proto3" json:"per_request_timeout,omitempty"`
+	WalkTimeout       *durationpb.Duration   `protobuf:"bytes,8,opt,name=walk_timeout,json=walkTimeout,proto3" json:"walk_timeout,omitempty"`
+	Delay             *durationpb.Duration   `protobuf:"bytes,9,opt,name=delay,proto3" json:"delay,omitempty"`
+	SkipNon_200       bool                   `protobuf:"varint,10,opt,name=skip_non_200,json=skipNon200,proto3" json:"skip_non_200,omitempty"`
+	SkipFetchErrors   bool                   `protobuf:"varint,11,opt,name=skip_fetch_errors,json=skipFetchErrors,proto3" json:"skip_fetch_errors,omitempty"`
+	IgnoreRobots      bool                   `protobuf:"varint,12,opt,name=ignore_robots,json=ignoreRobots,proto3" json:"ignore_robots,omitempty"`
+	UserAgent         string                 `protobuf:"bytes,13,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	RobotsUserAgent   string                 `protobuf:"bytes,14,opt,name=robots_user_agent,json=robotsUserAgent,proto3" json:"robots_user_agent,omitempty"`
+	// Regular expressions matched against URLs, as Options.Include/Exclude.
+	Include        []string `protobuf:"bytes,15,rep,name=include,proto3" json:"include,omitempty"`
+	Exclude        []string `protobuf:"bytes,16,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	DedupeUrls     bool     `protobuf:"varint,17,opt,name=dedupe_urls,json=dedupeUrls,proto3" json:"dedupe_urls,omitempty"`
+	Strict         bool     `protobuf:"varint,18,opt,name=strict,proto3" json:"strict,omitempty"`
+	KeepExtensions bool     `protobuf:"varint,19,opt,name=keep_extensions,json=keepExtensions,proto3" json:"keep_extensions,omitempty"`
+	// Traversal order: "breadth-first", "depth-first", or "freshest-first".
+	TraversalOrder     string `protobuf:"bytes,20,opt,name=traversal_order,json=traversalOrder,proto3" json:"traversal_order,omitempty"`
+	FetchConcurrency   int32  `protobuf:"varint,21,opt,name=fetch_concurrency,json=fetchConcurrency,proto3" json:"fetch_concurrency,omitempty"`
+	ConcurrencyPerHost int32  `protobuf:"varint,22,opt,name=concurrency_per_host,json=concurrencyPerHost,proto3" json:"concurrency_per_host,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Options) Reset() {
+	*x = Options{}
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Options) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Options) ProtoMessage() {}
+
+func (x *Options) ProtoReflect() protoreflect.Message {
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Options.ProtoReflect.Descriptor instead.
+func (*Options) Descriptor() ([]byte, []int) {
+	return file_sitemapfetcher_v1_fetcher_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Options) GetMaxDepth() int32 {
+	if x != nil {
+		return x.MaxDepth
+	}
+	return 0
+}
+
+func (x *Options) GetMaxSitemaps() int32 {
+	if x != nil {
+		return x.MaxSitemaps
+	}
+	return 0
+}
+
+func (x *Options) GetMaxUrls() int32 {
+	if x != nil {
+		return x.MaxUrls
+	}
+	return 0
+}
+
+func (x *Options) GetStopAtMaxUrls() bool {
+	if x != nil {
+		return x.StopAtMaxUrls
+	}
+	return false
+}
+
+func (x *Options) GetMaxUrlsPerSitemap() int32 {
+	if x != nil {
+		return x.MaxUrlsPerSitemap
+	}
+	return 0
+}
+
+func (x *Options) GetMaxTotalBytes() int64 {
+	if x != nil {
+		return x.MaxTotalBytes
+	}
+	return 0
+}
+
+func (x *Options) GetPerRequestTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.PerRequestTimeout
+	}
+	return nil
+}
+
+func (x *Options) GetWalkTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.WalkTimeout
+	}
+	return nil
+}
+
+func (x *Options) GetDelay() *durationpb.Duration {
+	if x != nil {
+		return x.Delay
+	}
+	return nil
+}
+
+func (x *Options) GetSkipNon_200() bool {
+	if x != nil {
+		return x.SkipNon_200
+	}
+	return false
+}
+
+func (x *Options) GetSkipFetchErrors() bool {
+	if x != nil {
+		return x.SkipFetchErrors
+	}
+	return false
+}
+
+func (x *Options) GetIgnoreRobots() bool {
+	if x != nil {
+		return x.IgnoreRobots
+	}
+	return false
+}
+
+func (x *Options) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *Options) GetRobotsUserAgent() string {
+	if x != nil {
+		return x.RobotsUserAgent
+	}
+	return ""
+}
+
+func (x *Options) GetInclude() []string {
+	if x != nil {
+		return x.Include
+	}
+	return nil
+}
+
+func (x *Options) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+func (x *Options) GetDedupeUrls() bool {
+	if x != nil {
+		return x.DedupeUrls
+	}
+	return false
+}
+
+func (x *Options) GetStrict() bool {
+	if x != nil {
+		return x.Strict
+	}
+	return false
+}
+
+func (x *Options) GetKeepExtensions() bool {
+	if x != nil {
+		return x.KeepExtensions
+	}
+	return false
+}
+
+func (x *Options) GetTraversalOrder() string {
+	if x != nil {
+		return x.TraversalOrder
+	}
+	return ""
+}
+
+func (x *Options) GetFetchConcurrency() int32 {
+	if x != nil {
+		return x.FetchConcurrency
+	}
+	return 0
+}
+
+func (x *Options) GetConcurrencyPerHost() int32 {
+	if x != nil {
+		return x.ConcurrencyPerHost
+	}
+	return 0
+}
+
+// Item mirrors gositemapfetcher.Item. URLs are strings; optional values are
+// unset when the sitemap did not give them.
+type Item struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Loc            string                 `protobuf:"bytes,1,opt,name=loc,proto3" json:"loc,omitempty"`
+	Lastmod        *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=lastmod,proto3" json:"lastmod,omitempty"`
+	LastmodZoned   bool                   `protobuf:"varint,3,opt,name=lastmod_zoned,json=lastmodZoned,proto3" json:"lastmod_zoned,omitempty"`
+	Changefreq     string                 `protobuf:"bytes,4,opt,name=changefreq,proto3" json:"changefreq,omitempty"`
+	Priority       *float64               `protobuf:"fixed64,5,opt,name=priority,proto3,oneof" json:"priority,omitempty"`
+	Sitemap        string                 `protobuf:"bytes,6,opt,name=sitemap,proto3" json:"sitemap,omitempty"`
+	RawLoc         string                 `protobuf:"bytes,7,opt,name=raw_loc,json=rawLoc,proto3" json:"raw_loc,omitempty"`
+	RawChangefreq  string                 `protobuf:"bytes,8,opt,name=raw_changefreq,json=rawChangefreq,proto3" json:"raw_changefreq,omitempty"`
+	Mobile         bool                   `protobuf:"varint,9,opt,name=mobile,proto3" json:"mobile,omitempty"`
+	Depth          int32                  `protobuf:"varint,10,opt,name=depth,proto3" json:"depth,omitempty"`
+	Position       int32                  `protobuf:"varint,11,opt,name=position,proto3" json:"position,omitempty"`
+	SitemapLastmod *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=sitemap_lastmod,json=sitemapLastmod,proto3" json:"sitemap_lastmod,omitempty"`
+	// Unrecognized <url> children as XML fragments (Options.KeepExtensions).
+	Extensions       []string   `protobuf:"bytes,13,rep,name=extensions,proto3" json:"extensions,omitempty"`
+	LinkCheck        *LinkCheck `protobuf:"bytes,14,opt,name=link_check,json=linkCheck,proto3" json:"link_check,omitempty"`
+	ValidationErrors []string   `protobuf:"bytes,15,rep,name=validation_errors,json=validationErrors,proto3" json:"validation_errors,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_sitemapfetcher_v1_fetcher_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Item) GetLoc() string {
+	if x != nil {
+		return x.Loc
+	}
+	return ""
+}
+
+func (x *Item) GetLastmod() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Lastmod
+	}
+	return nil
+}
+
+func (x *Item) GetLastmodZoned() bool {
+	if x != nil {
+		return x.LastmodZoned
+	}
+	return false
+}
+
+func (x *Item) GetChangefreq() string {
+	if x != nil {
+		return x.Changefreq
+	}
+	return ""
+}
+
+func (x *Item) GetPriority() float64 {
+	if x != nil && x.Priority != nil {
+		return *x.Priority
+	}
+	return 0
+}
+
+func (x *Item) GetSitemap() string {
+	if x != nil {
+		return x.Sitemap
+	}
+	return ""
+}
+
+func (x *Item) GetRawLoc() string {
+	if x != nil {
+		return x.RawLoc
+	}
+	return ""
+}
+
+func (x *Item) GetRawChangefreq() string {
+	if x != nil {
+		return x.RawChangefreq
+	}
+	return ""
+}
+
+func (x *Item) GetMobile() bool {
+	if x != nil {
+		return x.Mobile
+	}
+	return false
+}
+
+func (x *Item) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *Item) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *Item) GetSitemapLastmod() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SitemapLastmod
+	}
+	return nil
+}
+
+func (x *Item) GetExtensions() []string {
+	if x != nil {
+		return x.Extensions
+	}
+	return nil
+}
+
+func (x *Item) GetLinkCheck() *LinkCheck {
+	if x != nil {
+		return x.LinkCheck
+	}
+	return nil
+}
+
+func (x *Item) GetValidationErrors() []string {
+	if x != nil {
+		return x.ValidationErrors
+	}
+	return nil
+}
+
+// LinkCheck mirrors gositemapfetcher.LinkCheck; error is the message only.
+type LinkCheck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StatusCode    int32                  `protobuf:"varint,1,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	FinalUrl      string                 `protobuf:"bytes,2,opt,name=final_url,json=finalUrl,proto3" json:"final_url,omitempty"`
+	Redirects     []string               `protobuf:"bytes,3,rep,name=redirects,proto3" json:"redirects,omitempty"`
+	Duration      *durationpb.Duration   `protobuf:"bytes,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkCheck) Reset() {
+	*x = LinkCheck{}
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkCheck) ProtoMessage() {}
+
+func (x *LinkCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_sitemapfetcher_v1_fetcher_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkCheck.ProtoReflect.Descriptor instead.
+func (*LinkCheck) Descriptor() ([]byte, []int) {
+	return file_sitemapfetcher_v1_fetcher_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LinkCheck) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *LinkCheck) GetFinalUrl() string {
+	if x != nil {
+		return x.FinalUrl
+	}
+	return ""
+}
+
+func (x *LinkCheck) GetRedirects() []string {
+	if x != nil {
+		return x.Redirects
+	}
+	return nil
+}
+
+func (x *LinkCheck) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *LinkCheck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_sitemapfetcher_v1_fetcher_proto protoreflect.FileDescriptor
+
+const file_sitemapfetcher_v1_fetcher_proto_rawDesc = "" +
+	"\n" +
+	"\x1fsitemapfetcher/v1/fetcher.proto\x12\x11sitemapfetcher.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"W\n" +
+	"\vWalkRequest\x12\x12\n" +
+	"\x04urls\x18\x01 \x03(\tR\x04urls\x124\n" +
+	"\aoptions\x18\x02 \x01(\v2\x1a.sitemapfetcher.v1.OptionsR\aoptions\"\xfc\x06\n" +
+	"\aOptions\x12\x1b\n" +
+	"\tmax_depth\x18\x01 \x01(\x05R\bmaxDepth\x12!\n" +
+	"\fmax_sitemaps\x18\x02 \x01(\x05R\vmaxSitemaps\x12\x19\n" +
+	"\bmax_urls\x18\x03 \x01(\x05R\amaxUrls\x12'\n" +
+	"\x10stop_at_max_urls\x18\x04 \x01(\bR\rstopAtMaxUrls\x12/\n" +
+	"\x14max_urls_per_sitemap\x18\x05 \x01(\x05R\x11maxUrlsPerSitemap\x12&\n" +
+	"\x0fmax_total_bytes\x18\x06 \x01(\x03R\rmaxTotalBytes\x12I\n" +
+	"\x13per_request_timeout\x18\a \x01(\v2\x19.google.protobuf.DurationR\x11perRequestTimeout\x12<\n" +
+	"\fwalk_timeout\x18\b \x01(\v2\x19.google.protobuf.DurationR\vwalkTimeout\x12/\n" +
+	"\x05delay\x18\t \x01(\v2\x19.google.protobuf.DurationR\x05delay\x12 \n" +
+	"\fskip_non_200\x18\n" +
+	" \x01(\bR\n" +
+	"skipNon200\x12*\n" +
+	"\x11skip_fetch_errors\x18\v \x01(\bR\x0fskipFetchErrors\x12#\n" +
+	"\rignore_robots\x18\f \x01(\bR\fignoreRobots\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\r \x01(\tR\tuserAgent\x12*\n" +
+	"\x11robots_user_agent\x18\x0e \x01(\tR\x0frobotsUserAgent\x12\x18\n" +
+	"\ainclude\x18\x0f \x03(\tR\ainclude\x12\x18\n" +
+	"\aexclude\x18\x10 \x03(\tR\aexclude\x12\x1f\n" +
+	"\vdedupe_urls\x18\x11 \x01(\bR\n" +
+	"dedupeUrls\x12\x16\n" +
+	"\x06strict\x18\x12 \x01(\bR\x06strict\x12'\n" +
+	"\x0fkeep_extensions\x18\x13 \x01(\bR\x0ekeepExtensions\x12'\n" +
+	"\x0ftraversal_order\x18\x14 \x01(\tR\x0etraversalOrder\x12+\n" +
+	"\x11fetch_concurrency\x18\x15 \x01(\x05R\x10fetchConcurrency\x120\n" +
+	"\x14concurrency_per_host\x18\x16 \x01(\x05R\x12concurrencyPerHost\"\xb4\x04\n" +
+	"\x04Item\x12\x10\n" +
+	"\x03loc\x18\x01 \x01(\tR\x03loc\x124\n" +
+	"\alastmod\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\alastmod\x12#\n" +
+	"\rlastmod_zoned\x18\x03 \x01(\bR\flastmodZoned\x12\x1e\n" +
+	"\n" +
+	"changefreq\x18\x04 \x01(\tR\n" +
+	"changefreq\x12\x1f\n" +
+	"\bpriority\x18\x05 \x01(\x01H\x00R\bpriority\x88\x01\x01\x12\x18\n" +
+	"\asitemap\x18\x06 \x01(\tR\asitemap\x12\x17\n" +
+	"\araw_loc\x18\a \x01(\tR\x06rawLoc\x12%\n" +
+	"\x0eraw_changefreq\x18\b \x01(\tR\rrawChangefreq\x12\x16\n" +
+	"\x06mobile\x18\t \x01(\bR\x06mobile\x12\x14\n" +
+	"\x05depth\x18\n" +
+	" \x01(\x05R\x05depth\x12\x1a\n" +
+	"\bposition\x18\v \x01(\x05R\bposition\x12C\n" +
+	"\x0fsitemap_lastmod\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\x0esitemapLastmod\x12\x1e\n" +
+	"\n" +
+	"extensions\x18\r \x03(\tR\n" +
+	"extensions\x12;\n" +
+	"\n" +
+	"link_check\x18\x0e \x01(\v2\x1c.sitemapfetcher.v1.LinkCheckR\tlinkCheck\x12+\n" +
+	"\x11validation_errors\x18\x0f \x03(\tR\x10validationErrorsB\v\n" +
+	"\t_priority\"\xb4\x01\n" +
+	"\tLinkCheck\x12\x1f\n" +
+	"\vstatus_code\x18\x01 \x01(\x05R\n" +
+	"statusCode\x12\x1b\n" +
+	"\tfinal_url\x18\x02 \x01(\tR\bfinalUrl\x12\x1c\n" +
+	"\tredirects\x18\x03 \x03(\tR\tredirects\x125\n" +
+	"\bduration\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\bduration\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error2S\n" +
+	"\x0eSitemapFetcher\x12A\n" +
+	"\x04Walk\x12\x1e.sitemapfetcher.v1.WalkRequest\x1a\x17.sitemapfetcher.v1.Item0\x01BWZUgithub.com/enot-style/go-sitemap-fetcher/grpcserver/sitemapfetcherv1;sitemapfetcherv1b\x06proto3"
+
+var (
+	file_sitemapfetcher_v1_fetcher_proto_rawDescOnce sync.Once
+	file_sitemapfetcher_v1_fetcher_proto_rawDescData []byte
+)
+
+func file_sitemapfetcher_v1_fetcher_proto_rawDescGZIP() []byte {
+	file_sitemapfetcher_v1_fetcher_proto_rawDescOnce.Do(func() {
+		file_sitemapfetcher_v1_fetcher_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sitemapfetcher_v1_fetcher_proto_rawDesc), len(file_sitemapfetcher_v1_fetcher_proto_rawDesc)))
+	})
+	return file_sitemapfetcher_v1_fetcher_proto_rawDescData
+}
+
+var file_sitemapfetcher_v1_fetcher_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_sitemapfetcher_v1_fetcher_proto_goTypes = []any{
+	(*WalkRequest)(nil),           // 0: sitemapfetcher.v1.WalkRequest
+	(*Options)(nil),               // 1: sitemapfetcher.v1.Options
+	(*Item)(nil),                  // 2: sitemapfetcher.v1.Item
+	(*LinkCheck)(nil),             // 3: sitemapfetcher.v1.LinkCheck
+	(*durationpb.Duration)(nil),   // 4: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+}
+var file_sitemapfetcher_v1_fetcher_proto_depIdxs = []int32{
+	1, // 0: sitemapfetcher.v1.WalkRequest.options:type_name -> sitemapfetcher.v1.Options
+	4, // 1: sitemapfetcher.v1.Options.per_request_timeout:type_name -> google.protobuf.Duration
+	4, // 2: sitemapfetcher.v1.Options.walk_timeout:type_name -> google.protobuf.Duration
+	4, // 3: sitemapfetcher.v1.Options.delay:type_name -> google.protobuf.Duration
+	5, // 4: sitemapfetcher.v1.Item.lastmod:type_name -> google.protobuf.Timestamp
+	5, // 5: sitemapfetcher.v1.Item.sitemap_lastmod:type_name -> google.protobuf.Timestamp
+	3, // 6: sitemapfetcher.v1.Item.link_check:type_name -> sitemapfetcher.v1.LinkCheck
+	4, // 7: sitemapfetcher.v1.LinkCheck.duration:type_name -> google.protobuf.Duration
+	0, // 8: sitemapfetcher.v1.SitemapFetcher.Walk:input_type -> sitemapfetcher.v1.WalkRequest
+	2, // 9: sitemapfetcher.v1.SitemapFetcher.Walk:output_type -> sitemapfetcher.v1.Item
+	9, // [9:10] is the sub-list for method output_type
+	8, // [8:9] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_sitemapfetcher_v1_fetcher_proto_init() }
+func file_sitemapfetcher_v1_fetcher_proto_init() {
+	if File_sitemapfetcher_v1_fetcher_proto != nil {
+		return
+	}
+	file_sitemapfetcher_v1_fetcher_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sitemapfetcher_v1_fetcher_proto_rawDesc), len(file_sitemapfetcher_v1_fetcher_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sitemapfetcher_v1_fetcher_proto_goTypes,
+		DependencyIndexes: file_sitemapfetcher_v1_fetcher_proto_depIdxs,
+		MessageInfos:      file_sitemapfetcher_v1_fetcher_proto_msgTypes,
+	}.Build()
+	File_sitemapfetcher_v1_fetcher_proto = out.File
+	file_sitemapfetcher_v1_fetcher_proto_goTypes = nil
+	file_sitemapfetcher_v1_fetcher_proto_depIdxs = nil
+}
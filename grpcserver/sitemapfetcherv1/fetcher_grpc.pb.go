@@ -0,0 +1,146 @@
+// Typed contract for calling the fetcher across service boundaries. The
+// messages mirror gositemapfetcher.Options and gositemapfetcher.Item; field
+// semantics and defaults are those of the Go types.
+//
+// The Go stubs and a server implementation live in the grpcserver module, so
+// the main module does not depend on gRPC. Generate stubs for other languages
+// with protoc; regenerate the Go ones from the repository root with:
+//
+//   protoc -I proto --go_out=grpcserver --go_opt=module=github.com/enot-style/go-sitemap-fetcher/grpcserver \
+//     --go-grpc_out=grpcserver --go-grpc_opt=module=github.com/enot-style/go-sitemap-fetcher/grpcserver \
+//     sitemapfetcher/v1/fetcher.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: sitemapfetcher/v1/fetcher.proto
+
+package sitemapfetcherv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SitemapFetcher_Walk_FullMethodName = "/sitemapfetcher.v1.SitemapFetcher/Walk"
+)
+
+// SitemapFetcherClient is the client API for SitemapFetcher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SitemapFetcher walks sitemaps and streams their entries.
+type SitemapFetcherClient interface {
+	// Walk streams the items of one walk. The stream ends when the walk does;
+	// a failed walk ends it with a status carrying the walk's error, after the
+	// items found before the failure. Cancelling the call stops the walk.
+	Walk(ctx context.Context, in *WalkRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Item], error)
+}
+
+type sitemapFetcherClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSitemapFetcherClient(cc grpc.ClientConnInterface) SitemapFetcherClient {
+	return &sitemapFetcherClient{cc}
+}
+
+func (c *sitemapFetcherClient) Walk(ctx context.Context, in *WalkRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Item], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SitemapFetcher_ServiceDesc.Streams[0], SitemapFetcher_Walk_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WalkRequest, Item]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SitemapFetcher_WalkClient = grpc.ServerStreamingClient[Item]
+
+// SitemapFetcherServer is the server API for SitemapFetcher service.
+// All implementations must embed UnimplementedSitemapFetcherServer
+// for forward compatibility.
+//
+// SitemapFetcher walks sitemaps and streams their entries.
+type SitemapFetcherServer interface {
+	// Walk streams the items of one walk. The stream ends when the walk does;
+	// a failed walk ends it with a status carrying the walk's error, after the
+	// items found before the failure. Cancelling the call stops the walk.
+	Walk(*WalkRequest, grpc.ServerStreamingServer[Item]) error
+	mustEmbedUnimplementedSitemapFetcherServer()
+}
+
+// UnimplementedSitemapFetcherServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSitemapFetcherServer struct{}
+
+func (UnimplementedSitemapFetcherServer) Walk(*WalkRequest, grpc.ServerStreamingServer[Item]) error {
+	return status.Errorf(codes.Unimplemented, "method Walk not implemented")
+}
+func (UnimplementedSitemapFetcherServer) mustEmbedUnimplementedSitemapFetcherServer() {}
+func (UnimplementedSitemapFetcherServer) testEmbeddedByValue()                        {}
+
+// UnsafeSitemapFetcherServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SitemapFetcherServer will
+// result in compilation errors.
+type UnsafeSitemapFetcherServer interface {
+	mustEmbedUnimplementedSitemapFetcherServer()
+}
+
+func RegisterSitemapFetcherServer(s grpc.ServiceRegistrar, srv SitemapFetcherServer) {
+	// If the following call pancis, it indicates UnimplementedSitemapFetcherServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SitemapFetcher_ServiceDesc, srv)
+}
+
+func _SitemapFetcher_Walk_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WalkRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SitemapFetcherServer).Walk(m, &grpc.GenericServerStream[WalkRequest, Item]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SitemapFetcher_WalkServer = grpc.ServerStreamingServer[Item]
+
+// SitemapFetcher_ServiceDesc is the grpc.ServiceDesc for SitemapFetcher service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SitemapFetcher_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sitemapfetcher.v1.SitemapFetcher",
+	HandlerType: (*SitemapFetcherServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Walk",
+			Handler:       _SitemapFetcher_Walk_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sitemapfetcher/v1/fetcher.proto",
+}
@@ -0,0 +1,136 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+)
+
+// WalkHandle controls a walk started with Start.
+type WalkHandle struct {
+	cancel context.CancelFunc
+	gate   *pauseGate
+	done   chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	stopped bool
+}
+
+// Start runs Walk in a new goroutine and returns a handle that can pause,
+// resume, or stop it. Do not call Walk or Start on the same fetcher until the
+// handle is done.
+func (f *SitemapFetcher) Start(ctx context.Context, website *url.URL, yield func(Item) error) *WalkHandle {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	h := &WalkHandle{
+		cancel: cancel,
+		gate:   &pauseGate{},
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(h.done)
+		defer cancel()
+		err := f.walk(ctx, []*url.URL{website}, yield, walkHooks{gate: h.gate})
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.stopped && errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		h.err = err
+	}()
+	return h
+}
+
+// Pause stops fetching and item delivery at the next sitemap or item boundary.
+// In-flight responses stay open until Resume or Stop.
+func (h *WalkHandle) Pause() {
+	h.gate.pause()
+}
+
+// Resume continues a paused walk.
+func (h *WalkHandle) Resume() {
+	h.gate.resume()
+}
+
+// Paused reports whether the walk is currently paused.
+func (h *WalkHandle) Paused() bool {
+	return h.gate.paused()
+}
+
+// Stop cancels the walk, including a paused one. Wait then returns nil unless
+// the walk had already failed.
+func (h *WalkHandle) Stop() {
+	h.mu.Lock()
+	h.stopped = true
+	h.mu.Unlock()
+	h.cancel()
+}
+
+// Done is closed when the walk finishes.
+func (h *WalkHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait blocks until the walk finishes and returns its error.
+func (h *WalkHandle) Wait() error {
+	<-h.done
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// pauseGate blocks callers of wait while paused. A nil gate never blocks.
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is non-nil while paused and closed on resume.
+	resumed chan struct{}
+}
+
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+func (g *pauseGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// wait returns once the gate is open or ctx is done.
+func (g *pauseGate) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
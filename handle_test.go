@@ -0,0 +1,73 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_StartPauseResumeStop(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/one</loc></url>
+  <url><loc>/two</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	var count int32
+	var handle *WalkHandle
+	started := make(chan struct{})
+	handle = New(Options{}).Start(context.Background(), sitemapURL, func(Item) error {
+		if atomic.AddInt32(&count, 1) == 1 {
+			<-started
+			handle.Pause()
+		}
+		return nil
+	})
+	close(started)
+
+	time.Sleep(50 * time.Millisecond)
+	if !handle.Paused() {
+		t.Fatalf("expected walk to be paused")
+	}
+	if got := atomic.LoadInt32(&count); got != 1 {
+		t.Fatalf("expected 1 item while paused, got %d", got)
+	}
+	handle.Resume()
+	if err := handle.Wait(); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&count); got != 2 {
+		t.Fatalf("expected 2 items after resume, got %d", got)
+	}
+
+	handle = New(Options{}).Start(context.Background(), sitemapURL, func(Item) error {
+		return nil
+	})
+	handle.Pause()
+	handle.Stop()
+	select {
+	case <-handle.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected stopped walk to finish")
+	}
+	if err := handle.Wait(); err != nil {
+		t.Fatalf("expected nil error after Stop, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// hostLimiter caps concurrent sitemap downloads per host at
+// Options.ConcurrencyPerHost. Like hostPacer it is shared by every walk of a
+// SitemapFetcher.
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(opts Options) *hostLimiter {
+	if opts.ConcurrencyPerHost <= 0 {
+		return nil
+	}
+	return &hostLimiter{limit: opts.ConcurrencyPerHost, slots: map[string]chan struct{}{}}
+}
+
+// acquire blocks until a download from u's host may start and returns the
+// function that frees the slot. The release function is safe to call more
+// than once.
+func (l *hostLimiter) acquire(ctx context.Context, u *url.URL) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	key := u.Scheme + "://" + u.Host
+	l.mu.Lock()
+	slots, ok := l.slots[key]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.slots[key] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-slots }) }, nil
+}
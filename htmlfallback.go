@@ -0,0 +1,106 @@
+package gositemapfetcher
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// maxHTMLFallbackBytes bounds how much of an HTML page Options.HTMLFallback scans.
+const maxHTMLFallbackBytes = 1 << 20
+
+var (
+	htmlLinkTag  = regexp.MustCompile(`(?is)<(?:a|link)\b[^>]*>`)
+	htmlLinkAttr = regexp.MustCompile(`(?is)\b(href|rel)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// htmlSitemapsError reports the sitemap links found in an HTML page returned
+// for a sitemap URL under Options.HTMLFallback. err is what the page would
+// have caused without the fallback: nil for a probe, else ErrNotASitemap.
+type htmlSitemapsError struct {
+	links []*url.URL
+	err   error
+}
+
+func (e *htmlSitemapsError) Error() string {
+	return "HTML document with sitemap links"
+}
+
+// htmlSitemapLinks returns the sitemap references in the first
+// maxHTMLFallbackBytes of an HTML page, resolved against base, in page order
+// and without duplicates.
+func (f *SitemapFetcher) htmlSitemapLinks(body io.Reader, base *url.URL) []*url.URL {
+	page, _ := io.ReadAll(io.LimitReader(body, maxHTMLFallbackBytes))
+	var links []*url.URL
+	seen := map[string]bool{}
+	for _, tag := range htmlLinkTag.FindAll(page, -1) {
+		var href, rel string
+		for _, attr := range htmlLinkAttr.FindAllSubmatch(tag, -1) {
+			value := html.UnescapeString(string(attr[2]) + string(attr[3]) + string(attr[4]))
+			if strings.EqualFold(string(attr[1]), "href") {
+				href = value
+			} else {
+				rel = value
+			}
+		}
+		if href == "" {
+			continue
+		}
+		loc, err := resolveLocation(base, href)
+		if err != nil || !f.allowedScheme(loc) {
+			continue
+		}
+		if !relSitemap(rel) && !sitemapFileName(loc.Path) {
+			continue
+		}
+		if key := canonicalURLKey(loc); !seen[key] {
+			seen[key] = true
+			links = append(links, loc)
+		}
+	}
+	return links
+}
+
+// relSitemap reports whether a rel attribute lists the sitemap link type.
+func relSitemap(rel string) bool {
+	for _, token := range strings.Fields(rel) {
+		if strings.EqualFold(token, "sitemap") {
+			return true
+		}
+	}
+	return false
+}
+
+// sitemapFileName reports whether p names a file like sitemap.xml or
+// sitemap_index.xml.gz.
+func sitemapFileName(p string) bool {
+	name := strings.ToLower(path.Base(p))
+	return strings.Contains(name, "sitemap") && (strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".xml.gz"))
+}
+
+// responseURL returns the URL that answered resp after redirects, or loc.
+func responseURL(resp *http.Response, loc *url.URL) *url.URL {
+	if resp.Request != nil && resp.Request.URL != nil {
+		return resp.Request.URL
+	}
+	return loc
+}
+
+// followHTMLSitemaps queues the sitemaps linked from the HTML page returned
+// for current in its place, reporting current with EventSitemapSkipped.
+func (w *walk) followHTMLSitemaps(current sitemapTask, links []*url.URL) {
+	w.f.logger.WarnContext(
+		w.ctx,
+		"sitemap URL returned HTML; following its sitemap links",
+		"sitemap", current.loc.String(),
+		"links", len(links),
+	)
+	w.emitSkipped(current)
+	for _, loc := range links {
+		w.children = append(w.children, sitemapTask{loc: loc, depth: current.depth, allowMissing: true})
+	}
+}
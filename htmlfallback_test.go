@@ -0,0 +1,78 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const softNotFoundPage = `<!DOCTYPE html>
+<html><head>
+<link rel="alternate sitemap" type="application/xml" href="/maps/main.xml">
+<link rel="stylesheet" href="/site.css">
+</head><body>
+<p>Page not found.</p>
+<a href='/sitemap_index.xml.gz?v=2&amp;x=1'>Sitemap</a>
+<a href="/maps/main.xml#top">Again</a>
+<a href="/about.html">About</a>
+<a href="javascript:sitemap.xml">Bogus</a>
+</body></html>`
+
+func TestSitemapFetcher_HTMLSitemapLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/sitemap.xml")
+	links := New(Options{}).htmlSitemapLinks(strings.NewReader(softNotFoundPage), base)
+	var got []string
+	for _, link := range links {
+		got = append(got, link.String())
+	}
+	want := []string{"https://example.com/maps/main.xml", "https://example.com/sitemap_index.xml.gz?v=2&x=1"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSitemapFetcher_HTMLFallback(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(softNotFoundPage))
+		case "/maps/main.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	_, err = collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+	var notSitemap *ErrNotASitemap
+	if !errors.As(err, &notSitemap) {
+		t.Fatalf("expected ErrNotASitemap without the fallback, got %v", err)
+	}
+
+	fetcher := New(Options{IgnoreRobots: true, HTMLFallback: true})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if itemPaths(items) != "/a" {
+		t.Fatalf("expected the linked sitemap's URLs, got %s", itemPaths(items))
+	}
+	// The missing sitemap_index.xml.gz is ignored like a probe.
+	if skipped := fetcher.SkippedSitemaps(); len(skipped) != 0 {
+		t.Fatalf("expected nothing skipped, got %v", skipped)
+	}
+
+	// Fetch reads only the input and reports the page as before.
+	if _, err := fetcher.Fetch(context.Background(), sitemapURL); !errors.As(err, &notSitemap) {
+		t.Fatalf("expected Fetch to fail with ErrNotASitemap, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// SitemapState is what an incremental walk remembers about one sitemap from
+// the previous run (see Options.SitemapStates).
+type SitemapState struct {
+	// LastMod is the <lastmod> the parent sitemapindex declared for the sitemap.
+	LastMod *time.Time `json:"lastmod,omitempty"`
+	// ETag and LastModified are the response's validators, sent back as
+	// If-None-Match and If-Modified-Since.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Kind is the type of document the sitemap was. States without one,
+	// saved by earlier versions, are ignored.
+	Kind DocumentKind `json:"kind,omitempty"`
+	// Children lists the sitemaps a sitemapindex named, so a walk that finds
+	// the index unchanged still visits each of them, since shards change
+	// while their index stays byte-identical. Empty for a urlset.
+	Children []SitemapChild `json:"children,omitempty"`
+}
+
+// SitemapChild is one <sitemap> entry of an index saved in SitemapState.
+type SitemapChild struct {
+	Loc     string     `json:"loc"`
+	LastMod *time.Time `json:"lastmod,omitempty"`
+}
+
+// SitemapStateStore keeps SitemapState values between runs, keyed by sitemap
+// URL without fragment. Implementations must be safe for concurrent use.
+type SitemapStateStore interface {
+	// Load returns the state saved for sitemap; ok is false if there is none.
+	Load(ctx context.Context, sitemap string) (state SitemapState, ok bool, err error)
+	// Save records the state of sitemap, replacing any earlier one.
+	Save(ctx context.Context, sitemap string, state SitemapState) error
+}
+
+// MemorySitemapStateStore is the in-memory SitemapStateStore, for fetchers that
+// re-walk on a schedule within one process. The zero value is ready to use.
+type MemorySitemapStateStore struct {
+	mu     sync.Mutex
+	states map[string]SitemapState
+}
+
+// Load implements SitemapStateStore.
+func (s *MemorySitemapStateStore) Load(_ context.Context, sitemap string) (SitemapState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[sitemap]
+	return state, ok, nil
+}
+
+// Save implements SitemapStateStore.
+func (s *MemorySitemapStateStore) Save(_ context.Context, sitemap string, state SitemapState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = map[string]SitemapState{}
+	}
+	s.states[sitemap] = state
+	return nil
+}
+
+// Len returns the number of saved states.
+func (s *MemorySitemapStateStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.states)
+}
+
+// errSitemapUnchanged reports a 304 Not Modified answer to a conditional request.
+var errSitemapUnchanged = errors.New("sitemap not modified")
+
+// incremental reports whether the walk skips unchanged sitemaps. Fetch,
+// Validate, and WalkSitemaps always read every sitemap.
+func (w *walk) incremental() bool {
+	return w.f.opts.SitemapStates != nil && w.check == nil && w.sitemaps == nil && w.document == nil
+}
+
+// loadState attaches the previous run's state of task for conditional
+// requests and reports whether its index-declared <lastmod> is unchanged, in
+// which case the sitemap is skipped without a request.
+func (w *walk) loadState(task sitemapTask) (sitemapTask, bool, error) {
+	if !w.incremental() {
+		return task, false, nil
+	}
+	key := canonicalURLKey(task.loc)
+	state, ok, err := w.f.opts.SitemapStates.Load(w.ctx, key)
+	if err != nil {
+		return task, false, &ErrSitemapState{URL: key, Err: err}
+	}
+	if !ok || state.Kind == "" {
+		return task, false, nil
+	}
+	task.prior = &state
+	unchanged := task.lastMod != nil && state.LastMod != nil && task.lastMod.Equal(*state.LastMod)
+	return task, unchanged, nil
+}
+
+// rememberState records the state of a sitemap read in full, with the
+// children it listed, to be saved if the walk completes.
+func (w *walk) rememberState(task sitemapTask, header http.Header, kind DocumentKind, children []sitemapTask) {
+	if !w.incremental() {
+		return
+	}
+	state := SitemapState{
+		Kind:         kind,
+		LastMod:      task.lastMod,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+	for _, child := range children {
+		state.Children = append(state.Children, SitemapChild{Loc: child.loc.String(), LastMod: child.lastMod})
+	}
+	w.keepState(task, state)
+}
+
+// keepState records state for task, to be saved if the walk completes.
+func (w *walk) keepState(task sitemapTask, state SitemapState) {
+	if w.states == nil {
+		w.states = map[string]SitemapState{}
+	}
+	w.states[canonicalURLKey(task.loc)] = state
+}
+
+// skipUnchanged skips a sitemap found unchanged since the previous run. The
+// children an unchanged index listed then are queued as if it had been read,
+// each with its own lastmod and conditional check.
+func (w *walk) skipUnchanged(task sitemapTask) {
+	w.emitSkipped(task)
+	w.keepState(task, *task.prior)
+	for _, child := range task.prior.Children {
+		loc, err := url.Parse(child.Loc)
+		if err != nil {
+			continue
+		}
+		w.children = append(w.children, sitemapTask{loc: loc, depth: task.depth + 1, lastMod: child.LastMod})
+	}
+}
+
+// saveStates saves the states recorded by a walk that completed. A walk that
+// skipped sitemaps or stopped early saves nothing, since an unchanged index
+// would otherwise hide the sitemaps it missed from the next run.
+func (w *walk) saveStates() error {
+	if w.partial || w.f.SkippedSitemapCount() > 0 {
+		return nil
+	}
+	for key, state := range w.states {
+		if err := w.f.opts.SitemapStates.Save(w.ctx, key, state); err != nil {
+			return &ErrSitemapState{URL: key, Err: err}
+		}
+	}
+	return nil
+}
+
+// setConditional adds the validators of prior to req.
+func setConditional(req *http.Request, prior *SitemapState) {
+	if prior == nil {
+		return
+	}
+	if prior.ETag != "" {
+		req.Header.Set("If-None-Match", prior.ETag)
+	}
+	if prior.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prior.LastModified)
+	}
+}
@@ -0,0 +1,244 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// incrementalSite serves an index at /sitemap.xml listing /a.xml and /b.xml,
+// honoring If-None-Match on the index, and counts requests per path.
+type incrementalSite struct {
+	mu       sync.Mutex
+	etag     string
+	lastMods map[string]string
+	failB    bool
+	requests []string
+}
+
+func (s *incrementalSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.URL.Path)
+	switch r.URL.Path {
+	case "/sitemap.xml":
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+		fmt.Fprintf(w, `<sitemapindex><sitemap><loc>/a.xml</loc><lastmod>%s</lastmod></sitemap><sitemap><loc>/b.xml</loc><lastmod>%s</lastmod></sitemap></sitemapindex>`, s.lastMods["/a.xml"], s.lastMods["/b.xml"])
+	case "/a.xml", "/b.xml":
+		if s.failB && r.URL.Path == "/b.xml" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".xml")
+		fmt.Fprintf(w, `<urlset><url><loc>/%s1</loc></url></urlset>`, name)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// take returns the requested paths since the last call, sorted.
+func (s *incrementalSite) take() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	sort.Strings(requests)
+	return strings.Join(requests, ",")
+}
+
+func TestSitemapFetcher_SitemapStates(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("FetchConcurrency=%d", concurrency), func(t *testing.T) {
+			site := &incrementalSite{etag: `"v1"`, lastMods: map[string]string{"/a.xml": "2025-01-01", "/b.xml": "2025-01-01"}}
+			server := newTestServer(t, site)
+			defer server.Close()
+			sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+			if err != nil {
+				t.Fatalf("failed to parse sitemap URL: %v", err)
+			}
+			store := &MemorySitemapStateStore{}
+			fetcher := New(Options{IgnoreRobots: true, SitemapStates: store, FetchConcurrency: concurrency})
+			walk := func() string {
+				items, err := collectItems(fetcher, sitemapURL)
+				if err != nil {
+					t.Fatalf("walk failed: %v", err)
+				}
+				var paths []string
+				for _, item := range items {
+					paths = append(paths, item.Loc.Path)
+				}
+				return strings.Join(paths, ",")
+			}
+
+			if got := walk(); got != "/a1,/b1" {
+				t.Fatalf("expected every URL on the first walk, got %s", got)
+			}
+			if got := site.take(); got != "/a.xml,/b.xml,/sitemap.xml" {
+				t.Fatalf("unexpected requests on the first walk: %s", got)
+			}
+			if store.Len() != 3 {
+				t.Fatalf("expected 3 saved states, got %d", store.Len())
+			}
+
+			// The index answers 304 and the lastmods it listed are unchanged, so
+			// nothing below it is read.
+			if got := walk(); got != "" {
+				t.Fatalf("expected no URLs from an unchanged index, got %s", got)
+			}
+			if got := site.take(); got != "/sitemap.xml" {
+				t.Fatalf("expected only the conditional index request, got %s", got)
+			}
+
+			// Only the child whose lastmod moved is fetched.
+			site.mu.Lock()
+			site.etag = `"v2"`
+			site.lastMods["/b.xml"] = "2025-02-01"
+			site.mu.Unlock()
+			if got := walk(); got != "/b1" {
+				t.Fatalf("expected only the changed child's URLs, got %s", got)
+			}
+			if got := site.take(); got != "/b.xml,/sitemap.xml" {
+				t.Fatalf("expected the unchanged child to be skipped, got %s", got)
+			}
+		})
+	}
+}
+
+func TestSitemapFetcher_SitemapStatesUnchangedIndex(t *testing.T) {
+	var mu sync.Mutex
+	shard := "v1"
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			if r.Header.Get("If-None-Match") == `"index"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"index"`)
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			etag := `"` + shard + `"`
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			if shard == "v1" {
+				_, _ = w.Write([]byte(`<urlset><url><loc>/page1</loc></url></urlset>`))
+			} else {
+				_, _ = w.Write([]byte(`<urlset><url><loc>/page1</loc></url><url><loc>/page2</loc></url></urlset>`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{IgnoreRobots: true, SitemapStates: &MemorySitemapStateStore{}})
+
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil || itemPaths(items) != "/page1" {
+		t.Fatalf("unexpected first walk: %s (%v)", itemPaths(items), err)
+	}
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil || itemPaths(items) != "" {
+		t.Fatalf("expected nothing from an unchanged site, got %s (%v)", itemPaths(items), err)
+	}
+
+	// The index is byte-identical, but its shard changed.
+	mu.Lock()
+	shard = "v2"
+	mu.Unlock()
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil || itemPaths(items) != "/page1,/page2" {
+		t.Fatalf("expected the changed shard read under an unchanged index, got %s (%v)", itemPaths(items), err)
+	}
+}
+
+func TestSitemapFetcher_SitemapStatesLegacyState(t *testing.T) {
+	site := &incrementalSite{etag: `"v1"`, lastMods: map[string]string{"/a.xml": "2025-01-01", "/b.xml": "2025-01-01"}}
+	server := newTestServer(t, site)
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	// A state saved before Kind and Children were recorded cannot tell an
+	// index from a urlset, so it is ignored rather than hiding the shards.
+	store := &MemorySitemapStateStore{}
+	if err := store.Save(context.Background(), canonicalURLKey(sitemapURL), SitemapState{ETag: `"v1"`}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	items, err := collectItems(New(Options{IgnoreRobots: true, SitemapStates: store}), sitemapURL)
+	if err != nil || itemPaths(items) != "/a1,/b1" {
+		t.Fatalf("expected a full walk over a legacy state, got %s (%v)", itemPaths(items), err)
+	}
+}
+
+func TestSitemapFetcher_SitemapStatesPartialWalk(t *testing.T) {
+	site := &incrementalSite{etag: `"v1"`, lastMods: map[string]string{"/a.xml": "2025-01-01", "/b.xml": "2025-01-01"}, failB: true}
+	server := newTestServer(t, site)
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	store := &MemorySitemapStateStore{}
+	fetcher := New(Options{IgnoreRobots: true, SitemapStates: store, SkipNon200: true})
+	if _, err := collectItems(fetcher, sitemapURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if store.Len() != 0 {
+		t.Fatalf("expected a walk with skipped sitemaps to save nothing, got %d states", store.Len())
+	}
+
+	// Validate reads every sitemap and saves nothing either.
+	site.mu.Lock()
+	site.failB = false
+	site.mu.Unlock()
+	if _, err := fetcher.Validate(context.Background(), sitemapURL); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if store.Len() != 0 {
+		t.Fatalf("expected Validate to leave the store alone, got %d states", store.Len())
+	}
+}
+
+type failingStateStore struct {
+	MemorySitemapStateStore
+}
+
+func (s *failingStateStore) Save(context.Context, string, SitemapState) error {
+	return errors.New("disk full")
+}
+
+func TestSitemapFetcher_SitemapStatesSaveError(t *testing.T) {
+	site := &incrementalSite{etag: `"v1"`, lastMods: map[string]string{}}
+	server := newTestServer(t, site)
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{IgnoreRobots: true, SitemapStates: &failingStateStore{}})
+	_, err = collectItems(fetcher, sitemapURL)
+	var stateErr *ErrSitemapState
+	if !errors.As(err, &stateErr) || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected ErrSitemapState, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package gositemapfetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// DefaultIndexNowEndpoint shares submissions with every participating engine.
+	DefaultIndexNowEndpoint = "https://api.indexnow.org/indexnow"
+	// IndexNowMaxURLs is the protocol limit on URLs in one submission.
+	IndexNowMaxURLs = 10000
+)
+
+// IndexNow submits changed URLs to search engines with the IndexNow protocol.
+// Key must be served as a text file at KeyLocation, or at /<Key>.txt on each
+// submitted host when KeyLocation is empty.
+type IndexNow struct {
+	Key         string
+	KeyLocation string
+	// Endpoint defaults to DefaultIndexNowEndpoint.
+	Endpoint string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// BatchSize caps URLs per request (0 or more than IndexNowMaxURLs => IndexNowMaxURLs).
+	BatchSize int
+}
+
+type indexNowRequest struct {
+	Host        string   `json:"host"`
+	Key         string   `json:"key"`
+	KeyLocation string   `json:"keyLocation,omitempty"`
+	URLList     []string `json:"urlList"`
+}
+
+// Submit sends urls in batches, one host per request as the protocol requires.
+// Non-2xx responses are returned as *ErrHTTPStatus; every batch is attempted
+// and failures are returned together.
+func (n *IndexNow) Submit(ctx context.Context, urls []*url.URL) error {
+	if n.Key == "" {
+		return errors.New("indexnow: empty key")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var hosts []string
+	byHost := map[string][]string{}
+	for _, u := range urls {
+		if u == nil || !u.IsAbs() {
+			continue
+		}
+		if _, ok := byHost[u.Host]; !ok {
+			hosts = append(hosts, u.Host)
+		}
+		byHost[u.Host] = append(byHost[u.Host], u.String())
+	}
+
+	size := n.BatchSize
+	if size <= 0 || size > IndexNowMaxURLs {
+		size = IndexNowMaxURLs
+	}
+	var errs []error
+	for _, host := range hosts {
+		list := byHost[host]
+		for start := 0; start < len(list); start += size {
+			batch := list[start:min(start+size, len(list))]
+			if err := n.post(ctx, host, batch); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SubmitItems submits the Loc of every item, e.g. the items of a walk.
+func (n *IndexNow) SubmitItems(ctx context.Context, items []Item) error {
+	urls := make([]*url.URL, 0, len(items))
+	for _, item := range items {
+		urls = append(urls, item.Loc)
+	}
+	return n.Submit(ctx, urls)
+}
+
+// SubmitChanges submits the URLs of changes reported by Watch or Diff.
+// Removed URLs are included so engines recrawl them and drop them.
+func (n *IndexNow) SubmitChanges(ctx context.Context, changes []Change) error {
+	urls := make([]*url.URL, 0, len(changes))
+	for _, change := range changes {
+		urls = append(urls, change.Item.Loc)
+	}
+	return n.Submit(ctx, urls)
+}
+
+func (n *IndexNow) post(ctx context.Context, host string, batch []string) error {
+	endpoint := n.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultIndexNowEndpoint
+	}
+	body, err := json.Marshal(indexNowRequest{Host: host, Key: n.Key, KeyLocation: n.KeyLocation, URLList: batch})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &ErrHTTPStatus{URL: req.URL, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestIndexNow_Submit(t *testing.T) {
+	var mu sync.Mutex
+	var requests []indexNowRequest
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req indexNowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		if req.Host == "blocked.example.com" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	parse := func(raw string) *url.URL {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+		return u
+	}
+	client := &IndexNow{Key: "abc123", Endpoint: server.URL, BatchSize: 2}
+	changes := []Change{
+		{Kind: ChangeAdded, Item: Item{Loc: parse("https://example.com/a")}},
+		{Kind: ChangeModified, Item: Item{Loc: parse("https://example.com/b")}},
+		{Kind: ChangeRemoved, Item: Item{Loc: parse("https://example.com/c")}},
+		{Kind: ChangeAdded, Item: Item{Loc: parse("https://blocked.example.com/d")}},
+	}
+	err := client.SubmitChanges(context.Background(), changes)
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected ErrHTTPStatus for the rejected host, got %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(requests))
+	}
+	if requests[0].Host != "example.com" || len(requests[0].URLList) != 2 || requests[1].URLList[0] != "https://example.com/c" {
+		t.Fatalf("expected example.com URLs in batches of 2, got %+v", requests)
+	}
+	if requests[0].Key != "abc123" || requests[2].Host != "blocked.example.com" {
+		t.Fatalf("unexpected request %+v", requests)
+	}
+}
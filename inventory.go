@@ -0,0 +1,54 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// SitemapRef describes a sitemap file reported by WalkSitemaps.
+type SitemapRef struct {
+	Loc *url.URL
+	// LastMod is the <lastmod> the parent sitemapindex declared, if any.
+	LastMod *time.Time
+	// Depth is 0 for the input or discovered sitemaps, and one more than the
+	// parent index for listed ones.
+	Depth int
+	// Index reports whether the file is a sitemapindex, whose children are
+	// reported in turn. Listed sitemaps are not requested, so it is false for
+	// them unless Options.DetectNestedIndexes is set.
+	Index bool
+}
+
+// WalkSitemaps reads the sitemapindex files of website and calls fn with every
+// sitemap file it finds, without processing the URLs they list. Only the input
+// and discovered sitemaps are requested, read up to their root element unless
+// they are an index; the sitemaps an index lists are reported from its entries
+// without downloading them. Options.DetectNestedIndexes requests those as well
+// to follow nested indexes. Options such as limits, robots.txt, and skip
+// settings apply as for Walk; FetchConcurrency is ignored since it downloads
+// whole bodies. fn may return ErrSkipSitemap to skip an index's children, or
+// ErrStopWalk to end the walk.
+func (f *SitemapFetcher) WalkSitemaps(ctx context.Context, website *url.URL, fn func(SitemapRef) error) error {
+	if fn == nil {
+		return &ErrNilYield{}
+	}
+	return f.walk(ctx, []*url.URL{website}, func(Item) error { return nil }, walkHooks{sitemaps: fn})
+}
+
+// reportSitemap passes current to the WalkSitemaps callback.
+func (w *walk) reportSitemap(current sitemapTask, index bool) error {
+	ref := SitemapRef{Loc: cloneURL(current.loc), Depth: current.depth, Index: index}
+	if current.lastMod != nil {
+		lastMod := *current.lastMod
+		ref.LastMod = &lastMod
+	}
+	if err := w.sitemaps(ref); err != nil {
+		if errors.Is(err, ErrSkipSitemap) || errors.Is(err, ErrStopWalk) {
+			return err
+		}
+		return &ErrYield{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSitemapFetcher_WalkSitemaps(t *testing.T) {
+	large := "<urlset>" + strings.Repeat("<url><loc>/page</loc></url>", 50000) + "</urlset>"
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/nested.xml</loc><lastmod>2024-05-01</lastmod></sitemap>
+<sitemap><loc>/pages.xml</loc></sitemap>
+<sitemap><loc>/links.txt</loc></sitemap>
+</sitemapindex>`))
+		case "/nested.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/posts.xml</loc></sitemap></sitemapindex>`))
+		case "/pages.xml", "/posts.xml":
+			_, _ = w.Write([]byte(large))
+		case "/links.txt":
+			_, _ = w.Write([]byte("/one\n/two\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{Formats: []SitemapFormat{FormatText}})
+	var refs []SitemapRef
+	err = fetcher.WalkSitemaps(context.Background(), sitemapURL, func(ref SitemapRef) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRefs(t, refs, []wantRef{
+		{"/sitemap.xml", 0, true},
+		{"/nested.xml", 1, false},
+		{"/pages.xml", 1, false},
+		{"/links.txt", 1, false},
+	})
+	if refs[1].LastMod == nil || refs[1].LastMod.Format("2006-01-02") != "2024-05-01" {
+		t.Fatalf("expected lastmod from the parent index, got %v", refs[1].LastMod)
+	}
+	if requested := fetcher.SitemapStats(); len(requested) != 1 {
+		t.Fatalf("expected only the index to be requested, got %+v", requested)
+	}
+
+	fetcher = New(Options{Formats: []SitemapFormat{FormatText}, DetectNestedIndexes: true})
+	refs = nil
+	err = fetcher.WalkSitemaps(context.Background(), sitemapURL, func(ref SitemapRef) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRefs(t, refs, []wantRef{
+		{"/sitemap.xml", 0, true},
+		{"/nested.xml", 1, true},
+		{"/pages.xml", 1, false},
+		{"/links.txt", 1, false},
+		{"/posts.xml", 2, false},
+	})
+	for _, stat := range fetcher.SitemapStats() {
+		if stat.Bytes >= int64(len(large)) {
+			t.Fatalf("expected %s to be read only up to its root, read %d bytes", stat.URL, stat.Bytes)
+		}
+	}
+
+	refs = nil
+	err = fetcher.WalkSitemaps(context.Background(), sitemapURL, func(ref SitemapRef) error {
+		refs = append(refs, ref)
+		if ref.Loc.Path == "/nested.xml" {
+			return ErrSkipSitemap
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, ref := range refs {
+		if ref.Loc.Path == "/posts.xml" {
+			t.Fatal("expected ErrSkipSitemap to skip the nested index's children")
+		}
+	}
+
+	boom := errors.New("boom")
+	err = fetcher.WalkSitemaps(context.Background(), sitemapURL, func(SitemapRef) error { return boom })
+	var yieldErr *ErrYield
+	if !errors.As(err, &yieldErr) || !errors.Is(err, boom) {
+		t.Fatalf("expected ErrYield wrapping the callback error, got %v", err)
+	}
+}
+
+type wantRef struct {
+	path  string
+	depth int
+	index bool
+}
+
+func assertRefs(t *testing.T, refs []SitemapRef, want []wantRef) {
+	t.Helper()
+	if len(refs) != len(want) {
+		t.Fatalf("expected %d sitemaps, got %d: %+v", len(want), len(refs), refs)
+	}
+	for i, w := range want {
+		ref := refs[i]
+		if ref.Loc.Path != w.path || ref.Depth != w.depth || ref.Index != w.index {
+			t.Fatalf("sitemap %d: expected %s depth %d index %v, got %s depth %d index %v",
+				i, w.path, w.depth, w.index, ref.Loc.Path, ref.Depth, ref.Index)
+		}
+	}
+}
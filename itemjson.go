@@ -0,0 +1,184 @@
+package gositemapfetcher
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// itemJSON is the wire form of Item: URLs as strings, times in RFC 3339, and
+// nil or empty fields omitted.
+type itemJSON struct {
+	Loc              string         `json:"loc,omitempty"`
+	LastMod          *time.Time     `json:"lastmod,omitempty"`
+	LastModZoned     bool           `json:"lastmod_zoned,omitempty"`
+	ChangeFreq       ChangeFreq     `json:"changefreq,omitempty"`
+	Priority         *float64       `json:"priority,omitempty"`
+	Sitemap          string         `json:"sitemap,omitempty"`
+	RawLoc           string         `json:"raw_loc,omitempty"`
+	RawChangeFreq    string         `json:"raw_changefreq,omitempty"`
+	Mobile           bool           `json:"mobile,omitempty"`
+	Depth            int            `json:"depth"`
+	Position         int            `json:"position,omitempty"`
+	SitemapLastMod   *time.Time     `json:"sitemap_lastmod,omitempty"`
+	Extensions       []string       `json:"extensions,omitempty"`
+	LinkCheck        *linkCheckJSON `json:"link_check,omitempty"`
+	ValidationErrors []string       `json:"validation_errors,omitempty"`
+}
+
+// linkCheckJSON is the wire form of LinkCheck. Duration is a time.Duration
+// string such as "120ms", and Err is reduced to its message.
+type linkCheckJSON struct {
+	StatusCode int      `json:"status_code,omitempty"`
+	FinalURL   string   `json:"final_url,omitempty"`
+	Redirects  []string `json:"redirects,omitempty"`
+	Duration   string   `json:"duration,omitempty"`
+	Err        string   `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the item with snake_case keys, URLs as strings, and times
+// in RFC 3339. Extensions are encoded as XML fragments. Ext is not encoded,
+// since decoder output can be of any type; it can be rebuilt from Extensions.
+func (i Item) MarshalJSON() ([]byte, error) {
+	out := itemJSON{
+		LastMod:          i.LastMod,
+		LastModZoned:     i.LastModZoned,
+		ChangeFreq:       i.ChangeFreq,
+		Priority:         i.Priority,
+		RawLoc:           i.RawLoc,
+		RawChangeFreq:    i.RawChangeFreq,
+		Mobile:           i.Mobile,
+		Depth:            i.Depth,
+		Position:         i.Position,
+		SitemapLastMod:   i.SitemapLastMod,
+		ValidationErrors: i.ValidationErrors,
+	}
+	if i.Loc != nil {
+		out.Loc = i.Loc.String()
+	}
+	if i.Sitemap != nil {
+		out.Sitemap = i.Sitemap.String()
+	}
+	for _, ext := range i.Extensions {
+		fragment, err := ext.xml()
+		if err != nil {
+			return nil, err
+		}
+		out.Extensions = append(out.Extensions, fragment)
+	}
+	if check := i.LinkCheck; check != nil {
+		out.LinkCheck = &linkCheckJSON{
+			StatusCode: check.StatusCode,
+			Redirects:  check.Redirects,
+		}
+		if check.FinalURL != nil {
+			out.LinkCheck.FinalURL = check.FinalURL.String()
+		}
+		if check.Duration != 0 {
+			out.LinkCheck.Duration = check.Duration.String()
+		}
+		if check.Err != nil {
+			out.LinkCheck.Err = check.Err.Error()
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an item written by MarshalJSON. A LinkCheck error
+// comes back as a plain error carrying the original message.
+func (i *Item) UnmarshalJSON(data []byte) error {
+	var in itemJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	item := Item{
+		LastMod:          in.LastMod,
+		LastModZoned:     in.LastModZoned,
+		ChangeFreq:       in.ChangeFreq,
+		Priority:         in.Priority,
+		RawLoc:           in.RawLoc,
+		RawChangeFreq:    in.RawChangeFreq,
+		Mobile:           in.Mobile,
+		Depth:            in.Depth,
+		Position:         in.Position,
+		SitemapLastMod:   in.SitemapLastMod,
+		ValidationErrors: in.ValidationErrors,
+	}
+	var err error
+	if item.Loc, err = parseJSONURL("loc", in.Loc); err != nil {
+		return err
+	}
+	if item.Sitemap, err = parseJSONURL("sitemap", in.Sitemap); err != nil {
+		return err
+	}
+	for _, fragment := range in.Extensions {
+		ext, err := parseExtension(fragment)
+		if err != nil {
+			return err
+		}
+		item.Extensions = append(item.Extensions, ext)
+	}
+	if check := in.LinkCheck; check != nil {
+		item.LinkCheck = &LinkCheck{StatusCode: check.StatusCode, Redirects: check.Redirects}
+		if item.LinkCheck.FinalURL, err = parseJSONURL("link_check.final_url", check.FinalURL); err != nil {
+			return err
+		}
+		if check.Duration != "" {
+			if item.LinkCheck.Duration, err = time.ParseDuration(check.Duration); err != nil {
+				return fmt.Errorf("item link_check.duration: %w", err)
+			}
+		}
+		if check.Err != "" {
+			item.LinkCheck.Err = errors.New(check.Err)
+		}
+	}
+	*i = item
+	return nil
+}
+
+// parseJSONURL parses a URL field of itemJSON; an empty value is a nil URL.
+func parseJSONURL(field, value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("item %s: %w", field, err)
+	}
+	return parsed, nil
+}
+
+// xml re-encodes the extension element as a fragment; see MarshalXML.
+func (e Extension) xml() (string, error) {
+	out, err := xml.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// parseExtension decodes an XML fragment written by Extension.xml.
+func parseExtension(fragment string) (Extension, error) {
+	decoder := xml.NewDecoder(strings.NewReader(fragment))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return Extension{}, fmt.Errorf("item extension: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			var ext Extension
+			if err := ext.UnmarshalXML(decoder, start); err != nil {
+				return Extension{}, fmt.Errorf("item extension: %w", err)
+			}
+			return ext, nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package gositemapfetcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItem_JSONRoundTrip(t *testing.T) {
+	const sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+<url>
+  <loc>/a</loc>
+  <lastmod>2025-03-01T10:00:00+02:00</lastmod>
+  <changefreq>Daily</changefreq>
+  <priority>0.7</priority>
+  <image:image><image:loc>/a.png</image:loc></image:image>
+</url>
+</urlset>`
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err := collectItems(New(Options{KeepExtensions: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	item.LinkCheck = &LinkCheck{StatusCode: 200, FinalURL: item.Loc, Duration: 150 * time.Millisecond}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	for _, want := range []string{
+		`"loc":"` + server.URL + `/a"`,
+		`"lastmod":"2025-03-01T10:00:00+02:00"`,
+		`"changefreq":"daily"`,
+		`"raw_changefreq":"Daily"`,
+		`"sitemap":"` + server.URL + `/sitemap.xml"`,
+		`"duration":"150ms"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %s in %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "sitemap_lastmod") || strings.Contains(string(data), "validation_errors") {
+		t.Fatalf("expected nil fields to be omitted: %s", data)
+	}
+
+	var decoded Item
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if decoded.Loc.String() != item.Loc.String() || decoded.Sitemap.String() != item.Sitemap.String() {
+		t.Fatalf("unexpected URLs %s, %s", decoded.Loc, decoded.Sitemap)
+	}
+	if !decoded.LastMod.Equal(*item.LastMod) || *decoded.Priority != *item.Priority || decoded.ChangeFreq != item.ChangeFreq {
+		t.Fatalf("unexpected metadata %+v", decoded)
+	}
+	if decoded.LinkCheck == nil || !decoded.LinkCheck.OK() || decoded.LinkCheck.Duration != 150*time.Millisecond {
+		t.Fatalf("unexpected link check %+v", decoded.LinkCheck)
+	}
+	if len(decoded.Extensions) != 1 {
+		t.Fatalf("expected 1 extension, got %d", len(decoded.Extensions))
+	}
+	type image struct {
+		Loc string `xml:"http://www.google.com/schemas/sitemap-image/1.1 loc"`
+	}
+	var want, got image
+	if err := item.Extensions[0].Decode(&want); err != nil {
+		t.Fatalf("decode original extension: %v", err)
+	}
+	if err := decoded.Extensions[0].Decode(&got); err != nil {
+		t.Fatalf("decode round-tripped extension: %v", err)
+	}
+	if got != want || got.Loc != "/a.png" {
+		t.Fatalf("expected extension %+v, got %+v", want, got)
+	}
+
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("second marshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(again, data) {
+		t.Fatalf("expected stable encoding:\n%s\n%s", data, again)
+	}
+}
+
+func TestItem_UnmarshalJSONInvalidLoc(t *testing.T) {
+	var item Item
+	if err := json.Unmarshal([]byte(`{"raw_loc":"http://[::1/broken","depth":0,"validation_errors":["loc: bad"]}`), &item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.Loc != nil || item.RawLoc != "http://[::1/broken" || len(item.ValidationErrors) != 1 {
+		t.Fatalf("unexpected item %+v", item)
+	}
+	if err := json.Unmarshal([]byte(`{"loc":"http://[::1/broken"}`), &item); err == nil {
+		t.Fatal("expected an error for an unparseable loc")
+	}
+}
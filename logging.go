@@ -0,0 +1,163 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// levelHandler applies Options.LogLevel in place of the wrapped handler's level.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// walkIDHandler adds a walk_id attribute to records logged with a walk's context.
+type walkIDHandler struct {
+	slog.Handler
+}
+
+func (h *walkIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := WalkID(ctx); ok {
+		record = record.Clone()
+		record.AddAttrs(slog.String("walk_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *walkIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &walkIDHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *walkIDHandler) WithGroup(name string) slog.Handler {
+	return &walkIDHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// logSummary logs the Options.SummaryLevel record for a walk that began at
+// start and returned err; w is nil if the walk failed before it began.
+func (f *SitemapFetcher) logSummary(ctx context.Context, w *walk, start time.Time, err error) {
+	attrs := []slog.Attr{
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("skipped", f.SkippedSitemapCount()),
+	}
+	if w != nil {
+		attrs = append(attrs,
+			slog.Int("urls", w.urlCount),
+			slog.Int("sitemaps", w.sitemapCount),
+			slog.Int64("bytes", w.downloaded.Load()),
+		)
+	}
+	var slowest SitemapStat
+	for _, stat := range f.SitemapStats() {
+		if stat.total() > slowest.total() {
+			slowest = stat
+		}
+	}
+	if slowest.URL != "" {
+		attrs = append(attrs,
+			slog.String("slowest_sitemap", slowest.URL),
+			slog.Duration("slowest_duration", slowest.total()),
+		)
+	}
+	if f.ReachedMaxURLs() {
+		attrs = append(attrs, slog.Bool("reached_max_urls", true))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	f.logger.LogAttrs(ctx, f.opts.SummaryLevel.Level(), "walk finished", attrs...)
+}
+
+// progress tracks the Options.ProgressLogInterval records of a walk.
+type progress struct {
+	started time.Time
+	// logged is when the last record was logged, or the walk started; urls
+	// is the URL count at that point.
+	logged time.Time
+	urls   int
+}
+
+// logProgress logs a "walk progress" record if Options.ProgressLogInterval has
+// passed since the last one.
+func (w *walk) logProgress() {
+	interval := w.f.opts.ProgressLogInterval
+	if interval <= 0 {
+		return
+	}
+	now := time.Now()
+	since := now.Sub(w.progress.logged)
+	if since < interval {
+		return
+	}
+	rate := float64(w.urlCount-w.progress.urls) / since.Seconds()
+	w.f.logger.LogAttrs(
+		w.ctx,
+		slog.LevelInfo,
+		"walk progress",
+		slog.Int("urls", w.urlCount),
+		slog.Int("sitemaps", w.sitemapCount),
+		slog.Int("queued", len(w.queue)),
+		slog.Int64("bytes", w.downloaded.Load()),
+		slog.Duration("elapsed", now.Sub(w.progress.started)),
+		slog.Float64("urls_per_second", rate),
+	)
+	w.progress.logged = now
+	w.progress.urls = w.urlCount
+}
+
+// warnSlowSitemap logs stat if it took longer than Options.SlowFetchWarning.
+func (f *SitemapFetcher) warnSlowSitemap(ctx context.Context, stat SitemapStat) {
+	threshold := f.opts.SlowFetchWarning
+	if threshold <= 0 || stat.total() <= threshold {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("sitemap", stat.URL),
+		slog.Duration("duration", stat.total()),
+		slog.Duration("threshold", threshold),
+		slog.Duration("fetch", stat.FetchDuration),
+		slog.Duration("read", stat.ReadDuration),
+		slog.Duration("parse", stat.ParseDuration),
+		slog.Int64("bytes", stat.Bytes),
+	}
+	if network := stat.Network; network != nil {
+		attrs = append(attrs,
+			slog.Duration("dns", network.DNS),
+			slog.Duration("connect", network.Connect),
+			slog.Duration("tls", network.TLS),
+			slog.Duration("ttfb", network.TTFB),
+		)
+	}
+	f.logger.LogAttrs(ctx, slog.LevelWarn, "slow sitemap fetch", attrs...)
+}
+
+// warnOversizedSitemap logs stat if it exceeds Options.WarnURLsPerSitemap or
+// Options.WarnSitemapBytes.
+func (f *SitemapFetcher) warnOversizedSitemap(ctx context.Context, stat SitemapStat) {
+	tooMany := f.opts.WarnURLsPerSitemap > 0 && stat.Entries > f.opts.WarnURLsPerSitemap
+	tooLarge := f.opts.WarnSitemapBytes > 0 && stat.Bytes > f.opts.WarnSitemapBytes
+	if !tooMany && !tooLarge {
+		return
+	}
+	f.logger.WarnContext(
+		ctx,
+		"sitemap approaching protocol limits",
+		"sitemap", stat.URL,
+		"entries", stat.Entries,
+		"entries_limit", SpecMaxURLsPerSitemap,
+		"bytes", stat.Bytes,
+		"bytes_limit", SpecMaxSitemapBytes,
+	)
+}
@@ -0,0 +1,87 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// namespaceKind classifies the namespace of a urlset or sitemapindex root.
+type namespaceKind int
+
+const (
+	// namespaceCanonical is exactly SitemapNamespace.
+	namespaceCanonical namespaceKind = iota
+	// namespaceVariant is a common misspelling or legacy form of it.
+	namespaceVariant
+	// namespaceUnknown is any other namespace.
+	namespaceUnknown
+)
+
+// legacySitemapNamespaces are the scheme-less forms of namespaces that real
+// sitemaps use for the sitemaps.org schema, including the pre-0.9 Google ones.
+var legacySitemapNamespaces = map[string]bool{
+	"www.sitemaps.org/schemas/sitemap/0.9":  true,
+	"www.sitemaps.org/schemas/sitemap/0.90": true,
+	"sitemaps.org/schemas/sitemap/0.9":      true,
+	"www.google.com/schemas/sitemap/0.84":   true,
+	"www.google.com/schemas/sitemap/0.9":    true,
+}
+
+// classifyNamespace reports how space relates to SitemapNamespace. A missing
+// namespace, https instead of http, a trailing slash, different case, and the
+// legacy 0.84/0.90 namespaces are variants.
+func classifyNamespace(space string) namespaceKind {
+	if space == SitemapNamespace {
+		return namespaceCanonical
+	}
+	normalized := strings.ToLower(strings.TrimSpace(space))
+	if normalized == "" {
+		return namespaceVariant
+	}
+	normalized = strings.TrimPrefix(normalized, "http://")
+	normalized = strings.TrimPrefix(normalized, "https://")
+	normalized = strings.TrimRight(normalized, "/")
+	if legacySitemapNamespaces[normalized] {
+		return namespaceVariant
+	}
+	return namespaceUnknown
+}
+
+// checkNamespace applies Options.StrictNamespaces to the root element of an XML
+// sitemap. By default variants of SitemapNamespace are accepted and logged at
+// debug level, and any other namespace is logged as a warning while the
+// document is decoded by local element names; with StrictNamespaces anything
+// but SitemapNamespace fails the sitemap.
+func (f *SitemapFetcher) checkNamespace(ctx context.Context, sitemap *url.URL, name xml.Name) error {
+	if name.Local != "urlset" && name.Local != "sitemapindex" {
+		return nil
+	}
+	kind := classifyNamespace(name.Space)
+	if kind == namespaceCanonical {
+		return nil
+	}
+	if f.opts.StrictNamespaces {
+		return fmt.Errorf("%s namespace %q is not %s", name.Local, name.Space, SitemapNamespace)
+	}
+	if kind == namespaceVariant {
+		f.logger.DebugContext(
+			ctx,
+			"accepting non-canonical sitemap namespace",
+			"element", name.Local,
+			"namespace", name.Space,
+			"sitemap", sitemap.String(),
+		)
+		return nil
+	}
+	f.logger.WarnContext(
+		ctx,
+		"unknown sitemap namespace; decoding by element names",
+		"element", name.Local,
+		"namespace", name.Space,
+		"sitemap", sitemap.String(),
+	)
+	return nil
+}
@@ -0,0 +1,106 @@
+package gositemapfetcher
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestClassifyNamespace(t *testing.T) {
+	cases := map[string]namespaceKind{
+		SitemapNamespace: namespaceCanonical,
+		"https://www.sitemaps.org/schemas/sitemap/0.9": namespaceVariant,
+		"http://www.sitemaps.org/schemas/sitemap/0.9/": namespaceVariant,
+		"http://WWW.Sitemaps.org/schemas/sitemap/0.9":  namespaceVariant,
+		"http://www.sitemaps.org/schemas/sitemap/0.90": namespaceVariant,
+		"http://www.google.com/schemas/sitemap/0.84":   namespaceVariant,
+		"":                            namespaceVariant,
+		"http://www.w3.org/2005/Atom": namespaceUnknown,
+		"http://www.sitemaps.org/schemas/sitemap/0.9x":  namespaceUnknown,
+		"http://www.sitemaps.org/schemas/sitemap/image": namespaceUnknown,
+	}
+	for space, want := range cases {
+		if got := classifyNamespace(space); got != want {
+			t.Fatalf("classifyNamespace(%q) = %d, want %d", space, got, want)
+		}
+	}
+}
+
+func TestSitemapFetcher_NamespaceTolerance(t *testing.T) {
+	namespaces := map[string]string{
+		"/canonical.xml": SitemapNamespace,
+		"/https.xml":     "https://www.sitemaps.org/schemas/sitemap/0.9",
+		"/legacy.xml":    "http://www.google.com/schemas/sitemap/0.84",
+		"/missing.xml":   "",
+	}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace, ok := namespaces[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		xmlns := ""
+		if namespace != "" {
+			xmlns = fmt.Sprintf(` xmlns="%s"`, namespace)
+		}
+		fmt.Fprintf(w, `<urlset%s><url><loc>/page</loc></url></urlset>`, xmlns)
+	}))
+	defer server.Close()
+
+	for path := range namespaces {
+		sitemapURL, err := url.Parse(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to parse sitemap URL: %v", err)
+		}
+
+		items, err := collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+		if err != nil || len(items) != 1 {
+			t.Fatalf("%s: expected 1 item by default, got %d (%v)", path, len(items), err)
+		}
+
+		items, err = collectItems(New(Options{IgnoreRobots: true, StrictNamespaces: true}), sitemapURL)
+		if path == "/canonical.xml" {
+			if err != nil || len(items) != 1 {
+				t.Fatalf("%s: expected 1 item in strict mode, got %d (%v)", path, len(items), err)
+			}
+			continue
+		}
+		var parseErr *ErrSitemapParse
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("%s: expected ErrSitemapParse in strict mode, got %v", path, err)
+		}
+	}
+}
+
+func TestSitemapFetcher_UnknownNamespaceFallback(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprint(w, `<s:sitemapindex xmlns:s="http://example.com/schemas/sitemap"><s:sitemap><s:loc>/pages.xml</s:loc></s:sitemap></s:sitemapindex>`)
+		case "/pages.xml":
+			fmt.Fprint(w, `<urlset xmlns="urn:example:sitemap"><url><loc>/a</loc><lastmod>2025-01-02</lastmod></url><url><loc>/b</loc></url></urlset>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	items, err := collectItems(New(Options{IgnoreRobots: true, Logger: slog.New(handler)}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0].Loc.Path != "/a" || items[0].LastMod == nil {
+		t.Fatalf("expected both entries decoded by element names, got %+v", items)
+	}
+	if !handler.hasWarningContaining("unknown sitemap namespace") {
+		t.Fatal("expected a warning for the unknown namespace")
+	}
+}
@@ -0,0 +1,109 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// NetworkTimings breaks down where the time to a sitemap's response went, so a
+// slow walk can be attributed to the network or to the origin. Phases of every
+// redirect hop are summed; a reused connection has no DNS, connect, or TLS
+// time.
+type NetworkTimings struct {
+	// DNS is the time spent resolving host names.
+	DNS time.Duration
+	// Connect is the time spent establishing TCP connections.
+	Connect time.Duration
+	// TLS is the time spent in TLS handshakes.
+	TLS time.Duration
+	// TTFB is the time from sending the request to the first byte of the final
+	// response, including the phases above and any redirects.
+	TTFB time.Duration
+	// ReusedConn reports whether the final response came over a pooled
+	// connection.
+	ReusedConn bool
+}
+
+// Origin returns the part of TTFB not spent in DNS, connect, or TLS: request
+// transmission and the server's think time.
+func (t NetworkTimings) Origin() time.Duration {
+	return max(t.TTFB-t.DNS-t.Connect-t.TLS, 0)
+}
+
+// networkTrace collects NetworkTimings for one request. Dial hooks may run on
+// other goroutines, so it is guarded by a mutex.
+type networkTrace struct {
+	mu       sync.Mutex
+	start    time.Time
+	dnsStart time.Time
+	tlsStart time.Time
+	connects map[string]time.Time
+	timings  NetworkTimings
+}
+
+// withNetworkTrace returns ctx carrying an httptrace.ClientTrace that records
+// the request's timings, and the trace that receives them.
+func withNetworkTrace(ctx context.Context) (context.Context, *networkTrace) {
+	t := &networkTrace{start: time.Now(), connects: map[string]time.Time{}}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timings.DNS += time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			t.connects[network+" "+addr] = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			key := network + " " + addr
+			if start, ok := t.connects[key]; ok && err == nil {
+				t.timings.Connect += time.Since(start)
+			}
+			delete(t.connects, key)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timings.TLS += time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timings.ReusedConn = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.timings.TTFB = time.Since(t.start)
+			t.mu.Unlock()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// result returns a copy of the timings recorded so far.
+func (t *networkTrace) result() *NetworkTimings {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timings := t.timings
+	return &timings
+}
@@ -0,0 +1,125 @@
+package gositemapfetcher
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_NetworkTimings(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/slow.xml</loc></sitemap></sitemapindex>`))
+		case "/slow.xml":
+			time.Sleep(20 * time.Millisecond)
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	fetcher := New(Options{IgnoreRobots: true})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	for _, stat := range fetcher.SitemapStats() {
+		if stat.Network != nil {
+			t.Fatalf("expected no network timings by default, got %+v", stat.Network)
+		}
+	}
+
+	var finished []*SitemapStat
+	fetcher = New(Options{
+		IgnoreRobots:   true,
+		NetworkTimings: true,
+		HTTPClient:     &http.Client{Transport: &http.Transport{}},
+		OnEvent: func(event Event) {
+			if event.Kind == EventSitemapFinished {
+				finished = append(finished, event.Stat)
+			}
+		},
+	})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	stats := fetcher.SitemapStats()
+	if len(stats) != 2 || len(finished) != 2 {
+		t.Fatalf("expected 2 sitemap stats and events, got %d and %d", len(stats), len(finished))
+	}
+	index, slow := stats[0].Network, stats[1].Network
+	if index == nil || slow == nil || finished[1].Network == nil {
+		t.Fatalf("expected network timings for every sitemap, got %+v", stats)
+	}
+	if index.ReusedConn || index.Connect <= 0 || index.TTFB < index.Connect {
+		t.Fatalf("expected the first fetch to dial a connection, got %+v", index)
+	}
+	if !strings.HasSuffix(stats[1].URL, "/slow.xml") || !slow.ReusedConn || slow.Connect != 0 {
+		t.Fatalf("expected the second fetch to reuse the connection, got %+v", slow)
+	}
+	if slow.Origin() < 20*time.Millisecond {
+		t.Fatalf("expected the server delay attributed to the origin, got %v", slow.Origin())
+	}
+
+	// Bodies read ahead of their turn keep their timings.
+	fetcher = New(Options{IgnoreRobots: true, NetworkTimings: true, FetchConcurrency: 2})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	for _, stat := range fetcher.SitemapStats() {
+		if stat.Network == nil {
+			t.Fatalf("expected network timings under FetchConcurrency, got none for %s", stat.URL)
+		}
+	}
+}
+
+func TestSitemapFetcher_SlowFetchWarning(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/slow.xml</loc></sitemap></sitemapindex>`))
+		case "/slow.xml":
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	fetcher := New(Options{IgnoreRobots: true, Logger: slog.New(handler), SlowFetchWarning: 40 * time.Millisecond, NetworkTimings: true})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	var warnings []capturedRecord
+	for _, record := range handler.records {
+		if record.msg == "slow sitemap fetch" {
+			warnings = append(warnings, record)
+		}
+	}
+	if len(warnings) != 1 || warnings[0].level != slog.LevelWarn {
+		t.Fatalf("expected one slow sitemap warning, got %v", warnings)
+	}
+	warning := warnings[0]
+	if !strings.HasSuffix(warning.attrs["sitemap"].String(), "/slow.xml") {
+		t.Fatalf("expected the warning for /slow.xml, got %v", warning.attrs)
+	}
+	for _, key := range []string{"duration", "fetch", "read", "parse", "bytes", "ttfb"} {
+		if _, ok := warning.attrs[key]; !ok {
+			t.Fatalf("expected %q attr on the warning, got %v", key, warning.attrs)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const (
+	azureStorageResource = "https://storage.azure.com/"
+	imdsEndpoint         = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// ManagedIdentityOptions configures ManagedIdentity.
+type ManagedIdentityOptions struct {
+	// ClientID selects a user-assigned identity. Empty => the system-assigned one.
+	ClientID string
+	// Endpoint overrides the token endpoint. Empty => IDENTITY_ENDPOINT with
+	// IDENTITY_HEADER (App Service, Functions, Container Apps) when set, and
+	// the instance metadata service otherwise.
+	Endpoint string
+	// HTTPClient sends the token requests. nil => a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// ManagedIdentity returns a TokenSource that fetches Blob Storage tokens for
+// the Azure managed identity of the host, for AzureOptions.Tokens. Tokens are
+// fetched on every call; the Transport caches them until shortly before expiry.
+func ManagedIdentity(opts ManagedIdentityOptions) gositemapfetcher.TokenSource {
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return gositemapfetcher.TokenSourceFunc(func(ctx context.Context) (gositemapfetcher.Token, error) {
+		req, err := managedIdentityRequest(ctx, opts)
+		if err != nil {
+			return gositemapfetcher.Token{}, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return gositemapfetcher.Token{}, fmt.Errorf("objectstore: managed identity: %w", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return gositemapfetcher.Token{}, fmt.Errorf("objectstore: managed identity: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return gositemapfetcher.Token{}, fmt.Errorf("objectstore: managed identity: status %d: %s", resp.StatusCode, body)
+		}
+		var payload struct {
+			AccessToken string      `json:"access_token"`
+			TokenType   string      `json:"token_type"`
+			ExpiresOn   json.Number `json:"expires_on"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return gositemapfetcher.Token{}, fmt.Errorf("objectstore: managed identity: %w", err)
+		}
+		token := gositemapfetcher.Token{AccessToken: payload.AccessToken, TokenType: payload.TokenType}
+		if seconds, err := strconv.ParseInt(payload.ExpiresOn.String(), 10, 64); err == nil {
+			token.Expiry = time.Unix(seconds, 0)
+		}
+		return token, nil
+	})
+}
+
+func managedIdentityRequest(ctx context.Context, opts ManagedIdentityOptions) (*http.Request, error) {
+	endpoint, apiVersion, header := opts.Endpoint, "2018-02-01", ""
+	if endpoint == "" {
+		endpoint = imdsEndpoint
+		if env := os.Getenv("IDENTITY_ENDPOINT"); env != "" {
+			endpoint, apiVersion, header = env, "2019-08-01", os.Getenv("IDENTITY_HEADER")
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: managed identity endpoint: %w", err)
+	}
+	query := u.Query()
+	query.Set("api-version", apiVersion)
+	query.Set("resource", azureStorageResource)
+	if opts.ClientID != "" {
+		query.Set("client_id", opts.ClientID)
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if header != "" {
+		req.Header.Set("X-IDENTITY-HEADER", header)
+	} else {
+		req.Header.Set("Metadata", "true")
+	}
+	return req, nil
+}
@@ -0,0 +1,79 @@
+package objectstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func TestTransport_AzureSAS(t *testing.T) {
+	var query, version string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/staging/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query, version = r.URL.RawQuery, r.Header.Get("x-ms-version")
+		w.Write([]byte(`<urlset><url><loc>https://example.com/c</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	transport := NewTransport(Options{Azure: &AzureOptions{Endpoint: server.URL, SAS: "?sv=2021-08-06&sig=abc"}})
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+		HTTPClient:   &http.Client{Transport: transport},
+		IgnoreRobots: true,
+	})
+	locs := walkLocs(t, fetcher, "az://staging/sitemap.xml")
+	if strings.Join(locs, ",") != "https://example.com/c" {
+		t.Fatalf("unexpected URLs %v", locs)
+	}
+	if query != "sv=2021-08-06&sig=abc" || version != azureAPIVersion {
+		t.Fatalf("expected the SAS and x-ms-version, got %q and %q", query, version)
+	}
+}
+
+func TestTransport_AzureManagedIdentity(t *testing.T) {
+	var tokenRequests int
+	identity := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != azureStorageResource || r.URL.Query().Get("client_id") != "app" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		expires := time.Now().Add(time.Hour).Unix()
+		w.Write([]byte(`{"access_token":"eyJ0","token_type":"Bearer","expires_on":"` + strconv.FormatInt(expires, 10) + `"}`))
+	}))
+	defer identity.Close()
+	var auths []string
+	blobs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		if r.URL.Path == "/staging/pages.xml" {
+			w.Write([]byte(`<urlset><url><loc>https://example.com/d</loc></url></urlset>`))
+			return
+		}
+		w.Write([]byte(`<sitemapindex><sitemap><loc>az://staging/pages.xml</loc></sitemap></sitemapindex>`))
+	}))
+	defer blobs.Close()
+
+	tokens := ManagedIdentity(ManagedIdentityOptions{ClientID: "app", Endpoint: identity.URL})
+	transport := NewTransport(Options{Azure: &AzureOptions{Endpoint: blobs.URL, Tokens: tokens}})
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+		HTTPClient:     &http.Client{Transport: transport},
+		AllowedSchemes: AllowedSchemes,
+		IgnoreRobots:   true,
+	})
+	if locs := walkLocs(t, fetcher, "az://staging/sitemap.xml"); strings.Join(locs, ",") != "https://example.com/d" {
+		t.Fatalf("unexpected URLs %v", locs)
+	}
+	if len(auths) != 2 || auths[0] != "Bearer eyJ0" || auths[1] != "Bearer eyJ0" {
+		t.Fatalf("expected both blobs fetched with the token, got %v", auths)
+	}
+	if tokenRequests != 1 {
+		t.Fatalf("expected the token to be cached, got %d token requests", tokenRequests)
+	}
+}
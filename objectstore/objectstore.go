@@ -0,0 +1,315 @@
+// Package objectstore lets the fetcher read s3://, gs://, and az:// sitemap
+// URLs straight from object storage, for pipelines that stage sitemaps in a
+// bucket before publishing them.
+//
+// Transport is an http.RoundTripper that maps s3://bucket/key to the S3 REST
+// API (signed with package sigv4), gs://bucket/key to the Cloud Storage XML
+// API, and az://container/blob to Azure Blob Storage (with a SAS token or an
+// OAuth2 bearer token such as a managed identity's), and passes every other
+// request through. All three APIs are plain HTTPS, so no cloud SDK is needed;
+// SDK credentials plug in through sigv4.CredentialsFunc and
+// gositemapfetcher.TokenSourceFunc:
+//
+//	client := &http.Client{Transport: objectstore.NewTransport(objectstore.Options{
+//		S3: &objectstore.S3Options{Region: "eu-west-1", Credentials: sigv4.EnvCredentials{}},
+//	})}
+//	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+//		HTTPClient:     client,
+//		AllowedSchemes: objectstore.AllowedSchemes,
+//	})
+//
+// Responses keep the original object-store request, so relative <loc>
+// entries and FinalURL stay in object-store form.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/sigv4"
+)
+
+// AllowedSchemes is a value for gositemapfetcher.Options.AllowedSchemes that
+// lets a sitemapindex in a bucket list its children as object-store URLs.
+var AllowedSchemes = []string{"http", "https", "s3", "gs", "az"}
+
+const (
+	defaultGSEndpoint = "https://storage.googleapis.com"
+	// azureAPIVersion is sent as x-ms-version; bearer tokens need 2017-11-09 or later.
+	azureAPIVersion = "2021-08-06"
+)
+
+// S3Options configures s3:// URLs.
+type S3Options struct {
+	// Region is the buckets' region, such as "us-east-1". Required.
+	Region string
+	// Credentials signs the requests. nil => unsigned, for public buckets.
+	Credentials sigv4.CredentialsProvider
+	// Endpoint is the base URL of an S3-compatible store, such as
+	// "http://localhost:9000" for MinIO, addressed path-style
+	// (Endpoint/bucket/key). Empty => AWS virtual-host URLs
+	// (https://bucket.s3.Region.amazonaws.com/key).
+	Endpoint string
+}
+
+// GSOptions configures gs:// URLs.
+type GSOptions struct {
+	// Tokens supplies OAuth2 access tokens with a storage read scope.
+	// nil => anonymous, for public buckets.
+	Tokens gositemapfetcher.TokenSource
+	// Endpoint is the base URL of the XML API. Empty => "https://storage.googleapis.com".
+	Endpoint string
+}
+
+// AzureOptions configures az://container/blob URLs.
+type AzureOptions struct {
+	// Account is the storage account name. Required unless Endpoint is set.
+	Account string
+	// SAS is a shared access signature query string ("sv=...&sig=..."),
+	// appended to every request. It is not included in response URLs.
+	SAS string
+	// Tokens supplies OAuth2 access tokens for https://storage.azure.com/,
+	// such as ManagedIdentity. Ignored when SAS is set; nil with an empty SAS
+	// => anonymous, for public containers.
+	Tokens gositemapfetcher.TokenSource
+	// Endpoint is the Blob service base URL, for Azurite or sovereign clouds.
+	// Empty => "https://Account.blob.core.windows.net".
+	Endpoint string
+}
+
+// Options configures a Transport.
+type Options struct {
+	// S3 enables s3:// URLs; nil => they fail.
+	S3 *S3Options
+	// GS enables gs:// URLs; nil => they fail.
+	GS *GSOptions
+	// Azure enables az:// URLs; nil => they fail.
+	Azure *AzureOptions
+	// Next sends the rewritten requests and every request with another
+	// scheme. nil => http.DefaultTransport.
+	Next http.RoundTripper
+}
+
+// Transport serves s3://, gs://, and az:// requests from object storage. It
+// is safe for concurrent use.
+type Transport struct {
+	opts     Options
+	next     http.RoundTripper
+	s3Signer *sigv4.Signer
+	gsAuth   *gositemapfetcher.BearerAuth
+	gsBase   *url.URL
+	s3Base   *url.URL
+	azAuth   *gositemapfetcher.BearerAuth
+	azBase   *url.URL
+}
+
+// NewTransport returns a Transport with defaults applied. An invalid Endpoint
+// makes requests for its scheme fail.
+func NewTransport(opts Options) *Transport {
+	t := &Transport{opts: opts, next: opts.Next}
+	if t.next == nil {
+		t.next = http.DefaultTransport
+	}
+	if opts.S3 != nil {
+		var hosts []string
+		if opts.S3.Endpoint != "" {
+			t.s3Base, _ = parseEndpoint(opts.S3.Endpoint)
+			if t.s3Base != nil {
+				hosts = []string{t.s3Base.Host}
+			}
+		}
+		if opts.S3.Credentials != nil {
+			t.s3Signer = sigv4.New(sigv4.Options{Region: opts.S3.Region, Credentials: opts.S3.Credentials, Hosts: hosts})
+		}
+	}
+	if opts.GS != nil {
+		endpoint := opts.GS.Endpoint
+		if endpoint == "" {
+			endpoint = defaultGSEndpoint
+		}
+		t.gsBase, _ = parseEndpoint(endpoint)
+		if t.gsBase != nil && opts.GS.Tokens != nil {
+			t.gsAuth = gositemapfetcher.NewBearerAuth(opts.GS.Tokens, t.gsBase.Host)
+		}
+	}
+	if opts.Azure != nil {
+		endpoint := opts.Azure.Endpoint
+		if endpoint == "" && opts.Azure.Account != "" {
+			endpoint = "https://" + opts.Azure.Account + ".blob.core.windows.net"
+		}
+		t.azBase, _ = parseEndpoint(endpoint)
+		if t.azBase != nil && opts.Azure.SAS == "" && opts.Azure.Tokens != nil {
+			t.azAuth = gositemapfetcher.NewBearerAuth(opts.Azure.Tokens, t.azBase.Host)
+		}
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		target *url.URL
+		auth   func(ctx context.Context, out *http.Request) error
+		err    error
+	)
+	switch strings.ToLower(req.URL.Scheme) {
+	case "s3":
+		target, err = t.s3URL(req.URL)
+		if t.s3Signer != nil {
+			auth = t.s3Signer.Authorize
+		}
+	case "gs":
+		target, err = t.gsURL(req.URL)
+		if t.gsAuth != nil {
+			auth = t.gsAuth.Authorize
+		}
+	case "az":
+		target, err = t.azureURL(req.URL)
+		auth = t.authorizeAzure
+	default:
+		return t.next.RoundTrip(req)
+	}
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL = target
+	out.Host = ""
+	if auth != nil {
+		if err := auth(req.Context(), out); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// Invalidate drops cached Cloud Storage and Azure tokens, so the next request
+// fetches new ones.
+func (t *Transport) Invalidate() {
+	if t.gsAuth != nil {
+		t.gsAuth.Invalidate()
+	}
+	if t.azAuth != nil {
+		t.azAuth.Invalidate()
+	}
+}
+
+func (t *Transport) s3URL(u *url.URL) (*url.URL, error) {
+	if t.opts.S3 == nil {
+		return nil, errors.New("objectstore: s3:// URLs are not configured")
+	}
+	bucket, key, err := splitObjectURL(u)
+	if err != nil {
+		return nil, err
+	}
+	if t.opts.S3.Endpoint != "" {
+		if t.s3Base == nil {
+			return nil, fmt.Errorf("objectstore: invalid S3 endpoint %q", t.opts.S3.Endpoint)
+		}
+		return objectURL(t.s3Base, "/"+bucket+key, u.RawQuery), nil
+	}
+	if t.opts.S3.Region == "" {
+		return nil, errors.New("objectstore: missing S3 region")
+	}
+	base := &url.URL{Scheme: "https", Host: bucket + ".s3." + t.opts.S3.Region + ".amazonaws.com"}
+	return objectURL(base, key, u.RawQuery), nil
+}
+
+func (t *Transport) gsURL(u *url.URL) (*url.URL, error) {
+	if t.opts.GS == nil {
+		return nil, errors.New("objectstore: gs:// URLs are not configured")
+	}
+	if t.gsBase == nil {
+		return nil, fmt.Errorf("objectstore: invalid GS endpoint %q", t.opts.GS.Endpoint)
+	}
+	bucket, key, err := splitObjectURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return objectURL(t.gsBase, "/"+bucket+key, u.RawQuery), nil
+}
+
+func (t *Transport) azureURL(u *url.URL) (*url.URL, error) {
+	if t.opts.Azure == nil {
+		return nil, errors.New("objectstore: az:// URLs are not configured")
+	}
+	if t.azBase == nil {
+		if t.opts.Azure.Endpoint == "" {
+			return nil, errors.New("objectstore: missing Azure storage account")
+		}
+		return nil, fmt.Errorf("objectstore: invalid Azure endpoint %q", t.opts.Azure.Endpoint)
+	}
+	container, blob, err := splitObjectURL(u)
+	if err != nil {
+		return nil, err
+	}
+	query := u.RawQuery
+	if sas := strings.TrimPrefix(t.opts.Azure.SAS, "?"); sas != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += sas
+	}
+	return objectURL(t.azBase, "/"+container+blob, query), nil
+}
+
+// authorizeAzure sets the service version and, without a SAS, a bearer token.
+func (t *Transport) authorizeAzure(ctx context.Context, req *http.Request) error {
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	if t.azAuth == nil {
+		return nil
+	}
+	return t.azAuth.Authorize(ctx, req)
+}
+
+// splitObjectURL returns the bucket of scheme://bucket/key and the key with
+// its leading slash.
+func splitObjectURL(u *url.URL) (string, string, error) {
+	if u.Host == "" {
+		return "", "", fmt.Errorf("objectstore: missing bucket in %s", u.Redacted())
+	}
+	if u.Path == "" || u.Path == "/" {
+		return "", "", fmt.Errorf("objectstore: missing object key in %s", u.Redacted())
+	}
+	return u.Host, u.Path, nil
+}
+
+// objectURL appends path to base, keeping base's own path prefix.
+func objectURL(base *url.URL, path, rawQuery string) *url.URL {
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + path
+	target.RawPath = ""
+	target.RawQuery = rawQuery
+	return &target
+}
+
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("objectstore: endpoint %q is not an http(s) URL", endpoint)
+	}
+	return u, nil
+}
+
+// closeBody closes the request body, as RoundTrip must even on error.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,130 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/sigv4"
+)
+
+// bucketServer serves objects by path and records the Authorization header
+// of each request.
+type bucketServer struct {
+	mu      sync.Mutex
+	objects map[string]string
+	auth    map[string]string
+}
+
+func (s *bucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.auth == nil {
+		s.auth = map[string]string{}
+	}
+	s.auth[r.URL.Path] = r.Header.Get("Authorization")
+	body, ok := s.objects[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	fmt.Fprint(w, body)
+}
+
+func walkLocs(t *testing.T, fetcher *gositemapfetcher.SitemapFetcher, raw string) []string {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	var locs []string
+	err = fetcher.Walk(context.Background(), u, func(item gositemapfetcher.Item) error {
+		locs = append(locs, item.Loc.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	return locs
+}
+
+func TestTransport_S3(t *testing.T) {
+	bucket := &bucketServer{objects: map[string]string{
+		"/staging/sitemap.xml": `<sitemapindex><sitemap><loc>s3://staging/pages.xml</loc></sitemap></sitemapindex>`,
+		"/staging/pages.xml":   `<urlset><url><loc>https://example.com/a</loc></url></urlset>`,
+		"/staging/robots.txt":  "",
+	}}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+
+	transport := NewTransport(Options{S3: &S3Options{
+		Region:      "us-east-1",
+		Endpoint:    server.URL,
+		Credentials: sigv4.StaticCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+	}})
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+		HTTPClient:     &http.Client{Transport: transport},
+		AllowedSchemes: AllowedSchemes,
+	})
+	locs := walkLocs(t, fetcher, "s3://staging/sitemap.xml")
+	if strings.Join(locs, ",") != "https://example.com/a" {
+		t.Fatalf("unexpected URLs %v", locs)
+	}
+	for _, path := range []string{"/staging/sitemap.xml", "/staging/pages.xml"} {
+		if auth := bucket.auth[path]; !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			t.Fatalf("expected %s to be signed, got %q", path, auth)
+		}
+	}
+}
+
+func TestTransport_GS(t *testing.T) {
+	bucket := &bucketServer{objects: map[string]string{
+		"/staging/maps/sitemap.xml": `<urlset><url><loc>https://example.com/b</loc></url></urlset>`,
+	}}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+
+	tokens := gositemapfetcher.TokenSourceFunc(func(context.Context) (gositemapfetcher.Token, error) {
+		return gositemapfetcher.Token{AccessToken: "ya29"}, nil
+	})
+	transport := NewTransport(Options{GS: &GSOptions{Tokens: tokens, Endpoint: server.URL}})
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{
+		HTTPClient:   &http.Client{Transport: transport},
+		IgnoreRobots: true,
+	})
+	locs := walkLocs(t, fetcher, "gs://staging/maps/sitemap.xml")
+	if strings.Join(locs, ",") != "https://example.com/b" {
+		t.Fatalf("unexpected URLs %v", locs)
+	}
+	if auth := bucket.auth["/staging/maps/sitemap.xml"]; auth != "Bearer ya29" {
+		t.Fatalf("expected a bearer token, got %q", auth)
+	}
+}
+
+func TestTransport_URLs(t *testing.T) {
+	transport := NewTransport(Options{S3: &S3Options{Region: "eu-west-1"}})
+	u, _ := url.Parse("s3://my-bucket/dir/sitemap.xml")
+	target, err := transport.s3URL(u)
+	if err != nil {
+		t.Fatalf("s3URL failed: %v", err)
+	}
+	if got := target.String(); got != "https://my-bucket.s3.eu-west-1.amazonaws.com/dir/sitemap.xml" {
+		t.Fatalf("unexpected S3 URL %s", got)
+	}
+
+	for _, raw := range []string{"gs://bucket/sitemap.xml", "s3://my-bucket/"} {
+		req, err := http.NewRequest(http.MethodGet, raw, nil)
+		if err != nil {
+			t.Fatalf("failed to build request: %v", err)
+		}
+		if _, err := transport.RoundTrip(req); err == nil {
+			t.Fatalf("expected %s to fail", raw)
+		}
+	}
+}
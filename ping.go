@@ -0,0 +1,84 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// PingEngine is a search engine endpoint that accepts sitemap change
+// notifications as GET <Endpoint>?sitemap=<url>.
+type PingEngine struct {
+	Name     string
+	Endpoint string
+}
+
+// Well-known ping endpoints. Google and Bing have retired theirs in favor of
+// lastmod and IndexNow and answer 404 or 410, so Ping never uses them on its
+// own; they are kept for mirrors that still honor the protocol. Use IndexNow
+// to notify current engines, or pass a custom PingEngine.
+var (
+	PingGoogle = PingEngine{Name: "google", Endpoint: "https://www.google.com/ping"}
+	PingBing   = PingEngine{Name: "bing", Endpoint: "https://www.bing.com/ping"}
+)
+
+// Ping notifies each engine that the sitemap at sitemapURL changed, using the
+// fetcher's HTTP client, User-Agent, timeouts, and Delay. At least one engine
+// is required; without one Ping returns ErrNoPingEngines. Every engine is tried; failures are returned together, each as
+// *ErrHTTPStatus or a transport error wrapped with the engine name.
+func (f *SitemapFetcher) Ping(ctx context.Context, sitemapURL *url.URL, engines ...PingEngine) error {
+	if sitemapURL == nil || !sitemapURL.IsAbs() {
+		return &ErrInvalidURL{Err: errors.New("sitemap URL must be absolute")}
+	}
+	if f.transportErr != nil {
+		return f.transportErr
+	}
+	if len(engines) == 0 {
+		return ErrNoPingEngines
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var errs []error
+	for _, engine := range engines {
+		if err := f.ping(ctx, sitemapURL, engine); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *SitemapFetcher) ping(ctx context.Context, sitemapURL *url.URL, engine PingEngine) error {
+	endpoint, err := url.Parse(engine.Endpoint)
+	if err != nil {
+		return &ErrInvalidURL{URL: engine.Endpoint, Err: err}
+	}
+	query := endpoint.Query()
+	query.Set("sitemap", sitemapURL.String())
+	endpoint.RawQuery = query.Encode()
+
+	req, cancel, err := f.newRequest(ctx, http.MethodGet, endpoint)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping %s: %w", engine.Name, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &ErrHTTPStatus{URL: endpoint, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	f.logger.DebugContext(
+		ctx,
+		"pinged search engine",
+		"engine", engine.Name,
+		"sitemap", sitemapURL.String(),
+	)
+	return nil
+}
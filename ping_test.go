@@ -0,0 +1,55 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestSitemapFetcher_Ping(t *testing.T) {
+	var mu sync.Mutex
+	pinged := map[string]string{}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pinged[r.URL.Path] = r.URL.Query().Get("sitemap")
+		mu.Unlock()
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse("https://example.com/sitemap.xml?v=2")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{})
+
+	err = fetcher.Ping(context.Background(), sitemapURL,
+		PingEngine{Name: "ok", Endpoint: server.URL + "/ping"},
+		PingEngine{Name: "retired", Endpoint: server.URL + "/gone"},
+	)
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusGone {
+		t.Fatalf("expected ErrHTTPStatus for the retired engine, got %v", err)
+	}
+	if pinged["/ping"] != sitemapURL.String() || pinged["/gone"] != sitemapURL.String() {
+		t.Fatalf("expected both engines to receive the sitemap URL, got %v", pinged)
+	}
+
+	if err := fetcher.Ping(context.Background(), sitemapURL, PingEngine{Name: "ok", Endpoint: server.URL + "/ping"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := fetcher.Ping(context.Background(), sitemapURL); !errors.Is(err, ErrNoPingEngines) {
+		t.Fatalf("expected ErrNoPingEngines without engines, got %v", err)
+	}
+
+	var invalid *ErrInvalidURL
+	if err := fetcher.Ping(context.Background(), &url.URL{Path: "/sitemap.xml"}); !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidURL for a relative sitemap URL, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package gositemapfetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+)
+
+// prefetchedSitemap is a sitemap downloaded ahead of its turn by FetchConcurrency.
+type prefetchedSitemap struct {
+	done    chan struct{}
+	fetched *fetchedSitemap
+	err     error
+}
+
+// prefetch starts downloading the sitemaps queued after the one about to be
+// visited, so that up to FetchConcurrency bodies are in flight. Parsing and
+// delivery stay sequential, in queue order.
+func (w *walk) prefetch() {
+	f := w.f
+	if f.opts.FetchConcurrency <= 1 || w.sitemaps != nil {
+		return
+	}
+	if w.prefetched == nil {
+		w.prefetched = map[string]*prefetchedSitemap{}
+		w.prefetchCtx, w.stopPrefetch = context.WithCancelCause(w.ctx)
+	}
+	// The head of the queue is visited next; it counts toward the limit.
+	budget := f.opts.FetchConcurrency
+	for _, task := range w.queue {
+		if budget == 0 {
+			break
+		}
+		budget--
+		key := canonicalURLKey(task.loc)
+		if _, ok := w.prefetched[key]; ok {
+			continue
+		}
+		if w.sitemapVisited(key) {
+			continue
+		}
+		if f.opts.MaxDepth > 0 && task.depth > f.opts.MaxDepth {
+			continue
+		}
+		if f.opts.MaxSitemaps > 0 && w.sitemapCount+len(w.prefetched) >= f.opts.MaxSitemaps {
+			break
+		}
+		if f.opts.MaxTotalBytes > 0 && w.downloaded.Load() >= f.opts.MaxTotalBytes {
+			break
+		}
+		if !f.opts.IgnoreRobots {
+			if allowed, _ := f.allowedByRobots(w.ctx, task.loc, w.robotsCache); !allowed {
+				continue
+			}
+		}
+		task, unchanged, err := w.loadState(task)
+		if err != nil || unchanged {
+			continue
+		}
+		pending := &prefetchedSitemap{done: make(chan struct{})}
+		w.prefetched[key] = pending
+		w.prefetching.Add(1)
+		go func(ctx context.Context, task sitemapTask) {
+			defer w.prefetching.Done()
+			defer close(pending.done)
+			pending.fetched, pending.err = w.download(ctx, task)
+		}(w.prefetchCtx, task)
+	}
+}
+
+// download fetches task and reads its decoded body into memory, up to
+// SpecMaxSitemapBytes. Network bytes count toward MaxTotalBytes as they
+// arrive; the download that goes over the budget cancels every prefetch, and
+// they all fail with the ErrMaxTotalBytes when visited.
+func (w *walk) download(ctx context.Context, task sitemapTask) (*fetchedSitemap, error) {
+	fetched, err := w.f.fetchSitemap(ctx, task)
+	if err != nil || fetched == nil {
+		return fetched, w.prefetchErr(ctx, err)
+	}
+	defer fetched.Close()
+	var body io.Reader = fetched
+	budget := &prefetchBudget{reader: fetched, w: w, loc: task.loc, network: fetched.network}
+	if w.f.opts.MaxTotalBytes > 0 {
+		body = budget
+	}
+	data, err := io.ReadAll(io.LimitReader(body, SpecMaxSitemapBytes+1))
+	if err != nil {
+		return nil, w.prefetchErr(ctx, err)
+	}
+	if len(data) > SpecMaxSitemapBytes {
+		return nil, &ErrSitemapTooLarge{URL: cloneURL(task.loc), Limit: SpecMaxSitemapBytes}
+	}
+	return &fetchedSitemap{
+		ReadCloser:    io.NopCloser(bytes.NewReader(data)),
+		header:        fetched.header,
+		network:       fetched.network,
+		counted:       budget.counted,
+		fetchDuration: fetched.fetchDuration,
+		timings:       fetched.timings,
+	}, nil
+}
+
+// prefetchErr reports a download canceled because another one ran over
+// MaxTotalBytes as that budget error.
+func (w *walk) prefetchErr(ctx context.Context, err error) error {
+	var maxBytes *ErrMaxTotalBytes
+	if err != nil && errors.As(context.Cause(ctx), &maxBytes) {
+		return maxBytes
+	}
+	return err
+}
+
+// prefetchBudget adds a prefetch's network bytes to the walk's download count
+// as they are read, and stops every prefetch once the count passes
+// MaxTotalBytes.
+type prefetchBudget struct {
+	reader  io.Reader
+	w       *walk
+	loc     *url.URL
+	network *meteredReader
+	// counted is the part of network.bytes already added to w.downloaded.
+	counted int64
+}
+
+func (b *prefetchBudget) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	total := b.w.downloaded.Add(b.network.bytes - b.counted)
+	b.counted = b.network.bytes
+	if limit := b.w.f.opts.MaxTotalBytes; total > limit {
+		maxBytes := &ErrMaxTotalBytes{MaxTotalBytes: limit, Downloaded: total, URL: cloneURL(b.loc)}
+		b.w.stopPrefetch(maxBytes)
+		return 0, maxBytes
+	}
+	return n, err
+}
+
+// fetch returns current's prefetched body if there is one, and fetches it otherwise.
+func (w *walk) fetch(current sitemapTask) (*fetchedSitemap, error) {
+	key := canonicalURLKey(current.loc)
+	pending, ok := w.prefetched[key]
+	if !ok {
+		return w.f.fetchSitemap(w.ctx, current)
+	}
+	delete(w.prefetched, key)
+	select {
+	case <-pending.done:
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	}
+	return pending.fetched, pending.err
+}
+
+// closePrefetch cancels downloads that will not be visited and waits for them
+// to return, so no request outlives the walk.
+func (w *walk) closePrefetch() {
+	if w.stopPrefetch != nil {
+		w.stopPrefetch(nil)
+	}
+	w.prefetching.Wait()
+	for _, pending := range w.prefetched {
+		if pending.fetched != nil {
+			pending.fetched.Close()
+		}
+	}
+	w.prefetched = nil
+}
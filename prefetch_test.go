@@ -0,0 +1,206 @@
+package gositemapfetcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_FetchConcurrency(t *testing.T) {
+	const children = 4
+	var inFlight, maxInFlight int32
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sitemap.xml" {
+			var body strings.Builder
+			body.WriteString("<sitemapindex>")
+			for i := range children {
+				fmt.Fprintf(&body, "<sitemap><loc>/child-%d.xml</loc></sitemap>", i)
+			}
+			body.WriteString("</sitemapindex>")
+			_, _ = w.Write([]byte(body.String()))
+			return
+		}
+		var child int
+		if _, err := fmt.Sscanf(r.URL.Path, "/child-%d.xml", &child); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintf(w, "<urlset><url><loc>/%d/a</loc></url><url><loc>/%d/b</loc></url></urlset>", child, child)
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	sequential, err := collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("sequential walk failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got != 1 {
+		t.Fatalf("expected sequential fetches by default, got %d in flight", got)
+	}
+
+	start := time.Now()
+	concurrent, err := collectItems(New(Options{IgnoreRobots: true, FetchConcurrency: children}), sitemapURL)
+	if err != nil {
+		t.Fatalf("concurrent walk failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("expected concurrent fetches to overlap, took %s", elapsed)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got < 2 {
+		t.Fatalf("expected concurrent fetches, got %d in flight", got)
+	}
+	if len(concurrent) != len(sequential) || len(concurrent) != 2*children {
+		t.Fatalf("expected %d items, got %d", len(sequential), len(concurrent))
+	}
+	for i := range sequential {
+		if concurrent[i].Loc.String() != sequential[i].Loc.String() {
+			t.Fatalf("item %d: expected %s, got %s", i, sequential[i].Loc, concurrent[i].Loc)
+		}
+	}
+}
+
+func TestSitemapFetcher_ConcurrencyPerHost(t *testing.T) {
+	const perHost = 3
+	var inFlight, maxInFlight [2]int32
+	var hosts [2]string
+	handler := func(host int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/sitemap.xml" {
+				var body strings.Builder
+				body.WriteString("<sitemapindex>")
+				for i := range perHost {
+					for _, base := range hosts {
+						fmt.Fprintf(&body, "<sitemap><loc>%s/child-%d.xml</loc></sitemap>", base, i)
+					}
+				}
+				body.WriteString("</sitemapindex>")
+				_, _ = w.Write([]byte(body.String()))
+				return
+			}
+			current := atomic.AddInt32(&inFlight[host], 1)
+			defer atomic.AddInt32(&inFlight[host], -1)
+			for {
+				seen := atomic.LoadInt32(&maxInFlight[host])
+				if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight[host], seen, current) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			fmt.Fprintf(w, "<urlset><url><loc>%s</loc></url></urlset>", r.URL.Path)
+		})
+	}
+	first := newTestServer(t, handler(0))
+	defer first.Close()
+	second := newTestServer(t, handler(1))
+	defer second.Close()
+	hosts = [2]string{first.URL, second.URL}
+
+	sitemapURL, err := url.Parse(first.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err := collectItems(New(Options{
+		IgnoreRobots:       true,
+		FetchConcurrency:   2 * perHost,
+		ConcurrencyPerHost: 1,
+	}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 2*perHost {
+		t.Fatalf("expected %d items, got %d", 2*perHost, len(items))
+	}
+	for host := range hosts {
+		if got := atomic.LoadInt32(&maxInFlight[host]); got != 1 {
+			t.Fatalf("host %d: expected at most 1 download at a time, got %d", host, got)
+		}
+	}
+}
+
+func TestSitemapFetcher_FetchConcurrencyTooLarge(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/big.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		case "/big.xml":
+			_, _ = w.Write([]byte("<urlset>"))
+			padding := []byte(strings.Repeat(" ", 1<<20))
+			for range SpecMaxSitemapBytes>>20 + 1 {
+				if _, err := w.Write(padding); err != nil {
+					return
+				}
+			}
+			_, _ = w.Write([]byte("</urlset>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	_, err = collectItems(New(Options{IgnoreRobots: true, FetchConcurrency: 2}), sitemapURL)
+	var tooLarge *ErrSitemapTooLarge
+	if !errors.As(err, &tooLarge) || tooLarge.URL.Path != "/big.xml" {
+		t.Fatalf("expected ErrSitemapTooLarge for /big.xml, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_FetchConcurrencyMaxTotalBytes(t *testing.T) {
+	padding := strings.Repeat(" ", 64<<10)
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap><sitemap><loc>/c.xml</loc></sitemap><sitemap><loc>/d.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			// Visited first but slow, so the others download ahead of it.
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(2 * time.Second):
+			}
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		case "/b.xml", "/c.xml", "/d.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/b</loc></url>` + padding + `</urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	start := time.Now()
+	items, err := collectItems(New(Options{IgnoreRobots: true, FetchConcurrency: 4, MaxTotalBytes: 100 << 10}), sitemapURL)
+	var maxBytes *ErrMaxTotalBytes
+	if !errors.As(err, &maxBytes) || len(items) != 0 {
+		t.Fatalf("expected the read-ahead to exhaust the budget before /a.xml, got %d items (%v)", len(items), err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*time.Second {
+		t.Fatalf("expected the slow download canceled once over budget, took %s", elapsed)
+	}
+}
@@ -0,0 +1,134 @@
+package gositemapfetcher
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// HTTPProtocol selects the HTTP version used for requests.
+type HTTPProtocol int
+
+const (
+	// ProtocolAuto leaves the choice to the transport: with the standard
+	// transport, HTTP/2 when a TLS server offers it and HTTP/1.1 otherwise.
+	ProtocolAuto HTTPProtocol = iota
+	// ProtocolHTTP1 forces HTTP/1.1.
+	ProtocolHTTP1
+	// ProtocolHTTP2 forces HTTP/2, using prior knowledge (h2c) for http:// URLs.
+	ProtocolHTTP2
+	// ProtocolHTTP3 sends requests through Options.HTTP3Transport to hosts
+	// that advertise h3 in an Alt-Svc header, and falls back to the regular
+	// transport for other hosts or when an HTTP/3 request fails.
+	ProtocolHTTP3
+)
+
+func (p HTTPProtocol) String() string {
+	switch p {
+	case ProtocolAuto:
+		return "auto"
+	case ProtocolHTTP1:
+		return "http1"
+	case ProtocolHTTP2:
+		return "http2"
+	case ProtocolHTTP3:
+		return "http3"
+	default:
+		return fmt.Sprintf("HTTPProtocol(%d)", int(p))
+	}
+}
+
+// protocolTransport returns next adapted to Options.Protocol. ProtocolHTTP1 and
+// ProtocolHTTP2 need an *http.Transport (nil means http.DefaultTransport), which
+// is cloned, and ProtocolHTTP3 needs Options.HTTP3Transport; otherwise next is
+// returned unchanged with an *ErrUnsupportedTransport.
+func protocolTransport(next http.RoundTripper, opts Options) (http.RoundTripper, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	switch opts.Protocol {
+	case ProtocolHTTP1, ProtocolHTTP2:
+		base, ok := next.(*http.Transport)
+		if !ok {
+			return next, &ErrUnsupportedTransport{Option: "Protocol " + opts.Protocol.String(), Need: "an *http.Transport", Got: fmt.Sprintf("%T", next)}
+		}
+		transport := base.Clone()
+		transport.Protocols = new(http.Protocols)
+		if opts.Protocol == ProtocolHTTP1 {
+			transport.Protocols.SetHTTP1(true)
+			// A TLS config that already offers h2 via ALPN would still get it.
+			if transport.TLSClientConfig != nil {
+				transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+				transport.TLSClientConfig.NextProtos = slices.DeleteFunc(slices.Clone(transport.TLSClientConfig.NextProtos), func(proto string) bool {
+					return proto == "h2"
+				})
+			}
+		} else {
+			transport.Protocols.SetHTTP2(true)
+			transport.Protocols.SetUnencryptedHTTP2(true)
+		}
+		return transport, nil
+	case ProtocolHTTP3:
+		if opts.HTTP3Transport == nil {
+			return next, &ErrUnsupportedTransport{Option: "Protocol http3", Need: "Options.HTTP3Transport", Got: "nil"}
+		}
+		return &altSvcTransport{h3: opts.HTTP3Transport, next: next, hosts: map[string]bool{}}, nil
+	default:
+		return next, nil
+	}
+}
+
+// altSvcTransport routes requests to hosts that advertised HTTP/3 through h3.
+type altSvcTransport struct {
+	h3   http.RoundTripper
+	next http.RoundTripper
+
+	mu sync.Mutex
+	// hosts records, per host, whether HTTP/3 is advertised and still working.
+	hosts map[string]bool
+}
+
+func (t *altSvcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	t.mu.Lock()
+	useH3 := t.hosts[host]
+	t.mu.Unlock()
+	if useH3 && req.Body == nil {
+		resp, err := t.h3.RoundTrip(req)
+		if err == nil {
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.hosts[host] = false
+		t.mu.Unlock()
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == "https" && advertisesH3(resp.Header.Values("Alt-Svc")) {
+		t.mu.Lock()
+		if _, known := t.hosts[host]; !known {
+			t.hosts[host] = true
+		}
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// advertisesH3 reports whether an Alt-Svc header offers HTTP/3.
+func advertisesH3(values []string) bool {
+	for _, value := range values {
+		for _, service := range strings.Split(value, ",") {
+			if strings.HasPrefix(strings.TrimSpace(service), `h3=`) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+const protocolSitemap = `<urlset><url><loc>/a</loc></url></urlset>`
+
+func TestSitemapFetcher_ProtocolHTTP2PriorKnowledge(t *testing.T) {
+	var protoMajor atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protoMajor.Store(int32(r.ProtoMajor))
+		_, _ = w.Write([]byte(protocolSitemap))
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	if _, err := collectItems(New(Options{}), sitemapURL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := protoMajor.Load(); got != 1 {
+		t.Fatalf("expected HTTP/1.1 by default over cleartext, got HTTP/%d", got)
+	}
+	items, err := collectItems(New(Options{Protocol: ProtocolHTTP2}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if got := protoMajor.Load(); got != 2 {
+		t.Fatalf("expected HTTP/2 with prior knowledge, got HTTP/%d", got)
+	}
+}
+
+func TestSitemapFetcher_ProtocolHTTP1(t *testing.T) {
+	var protoMajor atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protoMajor.Store(int32(r.ProtoMajor))
+		_, _ = w.Write([]byte(protocolSitemap))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	if _, err := collectItems(New(Options{HTTPClient: server.Client()}), sitemapURL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := protoMajor.Load(); got != 2 {
+		t.Fatalf("expected HTTP/2 to be negotiated over TLS, got HTTP/%d", got)
+	}
+	if _, err := collectItems(New(Options{HTTPClient: server.Client(), Protocol: ProtocolHTTP1}), sitemapURL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := protoMajor.Load(); got != 1 {
+		t.Fatalf("expected HTTP/1.1 to be forced, got HTTP/%d", got)
+	}
+}
+
+type countingTransport struct {
+	next  http.RoundTripper
+	calls atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls.Add(1)
+	return t.next.RoundTrip(req)
+}
+
+func TestSitemapFetcher_ProtocolHTTP3AltSvc(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", `h3=":443"; ma=86400`)
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		default:
+			_, _ = w.Write([]byte(protocolSitemap))
+		}
+	}))
+	defer server.Close()
+
+	// Stand in for an HTTP/3 transport by counting the requests routed to it.
+	h3 := &countingTransport{next: server.Client().Transport}
+	fetcher := New(Options{
+		HTTPClient:     server.Client(),
+		Protocol:       ProtocolHTTP3,
+		HTTP3Transport: h3,
+		IgnoreRobots:   true,
+	})
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if got := h3.calls.Load(); got != 2 {
+		t.Fatalf("expected the 2 requests after the Alt-Svc advertisement to use HTTP/3, got %d", got)
+	}
+}
+
+func TestSitemapFetcher_ProtocolUnsupportedTransport(t *testing.T) {
+	sitemapURL, err := url.Parse("https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	for name, opts := range map[string]Options{
+		"http2 over a wrapper": {Protocol: ProtocolHTTP2, HTTPClient: &http.Client{Transport: &RecordTransport{Dir: t.TempDir()}}},
+		"http3 without h3":     {Protocol: ProtocolHTTP3},
+	} {
+		fetcher := New(opts)
+		_, err := collectItems(fetcher, sitemapURL)
+		var unsupported *ErrUnsupportedTransport
+		if !errors.As(err, &unsupported) {
+			t.Fatalf("%s: expected ErrUnsupportedTransport, got %v", name, err)
+		}
+		if err := fetcher.Ping(context.Background(), sitemapURL, PingEngine{Name: "example", Endpoint: "https://example.com/ping"}); !errors.As(err, &unsupported) {
+			t.Fatalf("%s: expected Ping to fail with ErrUnsupportedTransport, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// ===================== Link Report =====================
+
+// LinkReport summarizes Options.Verify results per sitemap. It is JSON-serializable.
+type LinkReport struct {
+	Totals   LinkCounts          `json:"totals"`
+	Sitemaps []SitemapLinkReport `json:"sitemaps"`
+}
+
+// LinkCounts tallies link check outcomes.
+type LinkCounts struct {
+	Checked      int `json:"checked"`
+	OK           int `json:"ok"`
+	NotFound     int `json:"not_found"`
+	ServerErrors int `json:"server_errors"`
+	Redirected   int `json:"redirected"`
+	Timeouts     int `json:"timeouts"`
+	// Errors counts other transport failures and non-2xx statuses.
+	Errors int `json:"errors"`
+}
+
+// SitemapLinkReport lists the problem links found in one sitemap.
+type SitemapLinkReport struct {
+	Sitemap string     `json:"sitemap"`
+	Counts  LinkCounts `json:"counts"`
+	// NotFound holds 404 and 410 responses.
+	NotFound     []BrokenLink `json:"not_found,omitempty"`
+	ServerErrors []BrokenLink `json:"server_errors,omitempty"`
+	// Redirected holds links that were redirected, whatever the final status.
+	Redirected []BrokenLink `json:"redirected,omitempty"`
+	Timeouts   []BrokenLink `json:"timeouts,omitempty"`
+	Errors     []BrokenLink `json:"errors,omitempty"`
+}
+
+// BrokenLink is a single problem link in a LinkReport.
+type BrokenLink struct {
+	Loc        string   `json:"loc"`
+	StatusCode int      `json:"status_code,omitempty"`
+	FinalURL   string   `json:"final_url,omitempty"`
+	Redirects  []string `json:"redirects,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// LinkReporter aggregates Item.LinkCheck results into a LinkReport. Call Add
+// from the Walk callback; it is safe for concurrent use.
+type LinkReporter struct {
+	mu      sync.Mutex
+	reports map[string]*SitemapLinkReport
+	order   []string
+}
+
+// Add records item's LinkCheck. Items without one are ignored.
+func (r *LinkReporter) Add(item Item) {
+	check := item.LinkCheck
+	if check == nil {
+		return
+	}
+	var sitemap string
+	if item.Sitemap != nil {
+		sitemap = item.Sitemap.String()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reports == nil {
+		r.reports = map[string]*SitemapLinkReport{}
+	}
+	report, ok := r.reports[sitemap]
+	if !ok {
+		report = &SitemapLinkReport{Sitemap: sitemap}
+		r.reports[sitemap] = report
+		r.order = append(r.order, sitemap)
+	}
+
+	link := BrokenLink{StatusCode: check.StatusCode, Redirects: check.Redirects}
+	if item.Loc != nil {
+		link.Loc = item.Loc.String()
+	}
+	if check.FinalURL != nil {
+		link.FinalURL = check.FinalURL.String()
+	}
+	if check.Err != nil {
+		link.Error = check.Err.Error()
+	}
+
+	report.Counts.Checked++
+	if len(check.Redirects) > 0 {
+		report.Counts.Redirected++
+		report.Redirected = append(report.Redirected, link)
+	}
+	switch {
+	case check.Err != nil && isTimeout(check.Err):
+		report.Counts.Timeouts++
+		report.Timeouts = append(report.Timeouts, link)
+	case check.Err != nil:
+		report.Counts.Errors++
+		report.Errors = append(report.Errors, link)
+	case check.StatusCode == http.StatusNotFound || check.StatusCode == http.StatusGone:
+		report.Counts.NotFound++
+		report.NotFound = append(report.NotFound, link)
+	case check.StatusCode >= 500:
+		report.Counts.ServerErrors++
+		report.ServerErrors = append(report.ServerErrors, link)
+	case check.OK():
+		report.Counts.OK++
+	default:
+		report.Counts.Errors++
+		report.Errors = append(report.Errors, link)
+	}
+}
+
+// Report returns a snapshot of the results so far, with sitemaps in the order
+// they were first seen.
+func (r *LinkReporter) Report() LinkReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := LinkReport{Sitemaps: make([]SitemapLinkReport, 0, len(r.order))}
+	for _, sitemap := range r.order {
+		report := *r.reports[sitemap]
+		report.NotFound = append([]BrokenLink(nil), report.NotFound...)
+		report.ServerErrors = append([]BrokenLink(nil), report.ServerErrors...)
+		report.Redirected = append([]BrokenLink(nil), report.Redirected...)
+		report.Timeouts = append([]BrokenLink(nil), report.Timeouts...)
+		report.Errors = append([]BrokenLink(nil), report.Errors...)
+		out.Sitemaps = append(out.Sitemaps, report)
+		out.Totals.add(report.Counts)
+	}
+	return out
+}
+
+func (c *LinkCounts) add(other LinkCounts) {
+	c.Checked += other.Checked
+	c.OK += other.OK
+	c.NotFound += other.NotFound
+	c.ServerErrors += other.ServerErrors
+	c.Redirected += other.Redirected
+	c.Timeouts += other.Timeouts
+	c.Errors += other.Errors
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ===================== Duplicate Report =====================
+
+// DuplicateReport lists Locs that appear in more than one sitemap file. It is JSON-serializable.
+type DuplicateReport struct {
+	// URLs is the number of distinct Locs seen.
+	URLs       int            `json:"urls"`
+	Duplicates []DuplicateURL `json:"duplicates"`
+}
+
+// DuplicateURL is a Loc listed by several sitemaps.
+type DuplicateURL struct {
+	Loc string `json:"loc"`
+	// Sitemaps lists the files containing Loc, in the order they were walked.
+	Sitemaps []string `json:"sitemaps"`
+}
+
+// DuplicateReporter records which sitemaps list each Loc. Call Add from the
+// Walk callback; it is safe for concurrent use. Memory grows with the number of
+// distinct URLs.
+type DuplicateReporter struct {
+	mu       sync.Mutex
+	sitemaps map[string][]string
+}
+
+// Add records that item.Loc appears in item.Sitemap.
+func (r *DuplicateReporter) Add(item Item) {
+	if item.Loc == nil {
+		return
+	}
+	key := item.Key()
+	var sitemap string
+	if item.Sitemap != nil {
+		sitemap = item.Sitemap.String()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sitemaps == nil {
+		r.sitemaps = map[string][]string{}
+	}
+	if !slices.Contains(r.sitemaps[key], sitemap) {
+		r.sitemaps[key] = append(r.sitemaps[key], sitemap)
+	}
+}
+
+// Report returns the Locs found in more than one sitemap, sorted by Loc.
+func (r *DuplicateReporter) Report() DuplicateReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := DuplicateReport{URLs: len(r.sitemaps), Duplicates: []DuplicateURL{}}
+	for loc, sitemaps := range r.sitemaps {
+		if len(sitemaps) > 1 {
+			out.Duplicates = append(out.Duplicates, DuplicateURL{Loc: loc, Sitemaps: slices.Clone(sitemaps)})
+		}
+	}
+	sort.Slice(out.Duplicates, func(i, j int) bool {
+		return out.Duplicates[i].Loc < out.Duplicates[j].Loc
+	})
+	return out
+}
@@ -0,0 +1,133 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestLinkReporter(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset>
+<url><loc>/ok</loc></url>
+<url><loc>/missing</loc></url>
+<url><loc>/gone</loc></url>
+<url><loc>/boom</loc></url>
+<url><loc>/moved</loc></url>
+<url><loc>/slow</loc></url>
+</urlset>`))
+		case "/ok":
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/boom":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/slow":
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	var reporter LinkReporter
+	fetcher := New(Options{
+		IgnoreRobots:      true,
+		PerRequestTimeout: 200 * time.Millisecond,
+		Verify:            &VerifyOptions{},
+	})
+	err = fetcher.Walk(context.Background(), sitemapURL, func(item Item) error {
+		reporter.Add(item)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report := reporter.Report()
+	want := LinkCounts{Checked: 6, OK: 2, NotFound: 2, ServerErrors: 1, Redirected: 1, Timeouts: 1}
+	if report.Totals != want {
+		t.Fatalf("expected totals %+v, got %+v", want, report.Totals)
+	}
+	if len(report.Sitemaps) != 1 || report.Sitemaps[0].Sitemap != sitemapURL.String() {
+		t.Fatalf("expected one sitemap report, got %+v", report.Sitemaps)
+	}
+	redirected := report.Sitemaps[0].Redirected
+	if len(redirected) != 1 || len(redirected[0].Redirects) != 2 || redirected[0].Redirects[1] != server.URL+"/ok" {
+		t.Fatalf("expected redirect chain to /ok, got %+v", redirected)
+	}
+	if timeouts := report.Sitemaps[0].Timeouts; len(timeouts) != 1 || timeouts[0].Error == "" {
+		t.Fatalf("expected a timeout with its error, got %+v", timeouts)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("failed to marshal report: %v", err)
+	}
+	var decoded LinkReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+	if decoded.Totals != want {
+		t.Fatalf("expected round-tripped totals %+v, got %+v", want, decoded.Totals)
+	}
+}
+
+func TestDuplicateReporter(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/one.xml</loc></sitemap><sitemap><loc>/two.xml</loc></sitemap></sitemapindex>`))
+		case "/one.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url><url><loc>/a</loc></url></urlset>`))
+		case "/two.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/c</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	var reporter DuplicateReporter
+	err = New(Options{IgnoreRobots: true}).Walk(context.Background(), sitemapURL, func(item Item) error {
+		reporter.Add(item)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report := reporter.Report()
+	if report.URLs != 3 {
+		t.Fatalf("expected 3 distinct URLs, got %d", report.URLs)
+	}
+	if len(report.Duplicates) != 1 {
+		t.Fatalf("expected 1 duplicate, got %+v", report.Duplicates)
+	}
+	duplicate := report.Duplicates[0]
+	if duplicate.Loc != server.URL+"/a" {
+		t.Fatalf("expected /a to be duplicated, got %s", duplicate.Loc)
+	}
+	if len(duplicate.Sitemaps) != 2 || duplicate.Sitemaps[0] != server.URL+"/one.xml" || duplicate.Sitemaps[1] != server.URL+"/two.xml" {
+		t.Fatalf("expected one.xml and two.xml, got %v", duplicate.Sitemaps)
+	}
+}
@@ -0,0 +1,219 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// RobotsFailurePolicy selects how a robots.txt that cannot be read is treated.
+type RobotsFailurePolicy int
+
+const (
+	// RobotsAssumeAllow treats the host as having no rules.
+	RobotsAssumeAllow RobotsFailurePolicy = iota
+	// RobotsAssumeDeny treats every path on the host as disallowed.
+	RobotsAssumeDeny
+	// RobotsFailError fails sitemaps on the host with ErrRobotsUnavailable
+	// (OnError may continue past it).
+	RobotsFailError
+)
+
+func (p RobotsFailurePolicy) String() string {
+	switch p {
+	case RobotsAssumeDeny:
+		return "deny"
+	case RobotsFailError:
+		return "error"
+	default:
+		return "allow"
+	}
+}
+
+// RobotsEntry is a fetched robots.txt file as stored in a RobotsCache.
+type RobotsEntry struct {
+	// StatusCode is the HTTP status of the robots.txt response. A 4xx status
+	// allows every path; a 5xx status is handled by Options.RobotsFailure.
+	StatusCode int
+	// Body is the decoded robots.txt content for a 200 OK response.
+	Body      []byte
+	FetchedAt time.Time
+}
+
+// RobotsCache stores robots.txt files by origin ("https://example.com") so that
+// repeated walks, and fetchers sharing one cache, do not refetch them. Entries
+// hold the raw file, so fetchers with different user agents can share a cache.
+// Implementations must be safe for concurrent use.
+type RobotsCache interface {
+	Get(origin string) (RobotsEntry, bool)
+	Set(origin string, entry RobotsEntry)
+}
+
+// NewRobotsCache returns an in-memory RobotsCache whose entries expire ttl
+// after they were fetched. A non-positive ttl keeps entries forever.
+func NewRobotsCache(ttl time.Duration) RobotsCache {
+	return &memoryRobotsCache{ttl: ttl, entries: map[string]RobotsEntry{}}
+}
+
+type memoryRobotsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]RobotsEntry
+}
+
+func (c *memoryRobotsCache) Get(origin string) (RobotsEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[origin]
+	if !ok {
+		return RobotsEntry{}, false
+	}
+	if c.ttl > 0 && time.Since(entry.FetchedAt) >= c.ttl {
+		delete(c.entries, origin)
+		return RobotsEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *memoryRobotsCache) Set(origin string, entry RobotsEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[origin] = entry
+}
+
+// getRobots returns the robots.txt rules for base's origin, consulting the
+// per-walk cache, then Options.RobotsCache, before fetching the file.
+func (f *SitemapFetcher) getRobots(ctx context.Context, base *url.URL, cache map[string]*robotsRules) (*robotsRules, error) {
+	key := base.Scheme + "://" + base.Host
+	if rules, ok := cache[key]; ok {
+		return rules, nil
+	}
+
+	robotsURL := base.ResolveReference(&url.URL{Path: "/robots.txt"})
+	var entry RobotsEntry
+	var ok bool
+	if f.opts.RobotsCache != nil {
+		entry, ok = f.opts.RobotsCache.Get(key)
+	}
+	var fetchErr error
+	if !ok {
+		var err error
+		entry, fetchErr, err = f.fetchRobots(ctx, robotsURL)
+		if err != nil {
+			return nil, err
+		}
+		// Failures are transient, so they are not shared across walks.
+		if !robotsFailed(entry) && f.opts.RobotsCache != nil {
+			f.opts.RobotsCache.Set(key, entry)
+		}
+	}
+
+	var rules *robotsRules
+	if robotsFailed(entry) {
+		rules = f.robotsFailure(ctx, robotsURL, entry, fetchErr)
+	} else {
+		rules = f.parseRobots(ctx, base, robotsURL, entry)
+	}
+	cache[key] = rules
+	return rules, nil
+}
+
+// robotsFailed reports whether entry is a robots.txt that could not be read.
+func robotsFailed(entry RobotsEntry) bool {
+	return entry.StatusCode == 0 || entry.StatusCode >= http.StatusInternalServerError
+}
+
+// robotsFailure applies Options.RobotsFailure to an unreadable robots.txt.
+func (f *SitemapFetcher) robotsFailure(ctx context.Context, robotsURL *url.URL, entry RobotsEntry, fetchErr error) *robotsRules {
+	switch f.opts.RobotsFailure {
+	case RobotsAssumeDeny:
+		f.logger.WarnContext(
+			ctx,
+			"robots.txt unavailable, treating host as disallowed",
+			"robots", robotsURL.String(),
+			"status", entry.StatusCode,
+		)
+		return &robotsRules{denyAll: true}
+	case RobotsFailError:
+		return &robotsRules{err: &ErrRobotsUnavailable{URL: cloneURL(robotsURL), StatusCode: entry.StatusCode, Err: fetchErr}}
+	default:
+		return &robotsRules{}
+	}
+}
+
+// fetchRobots downloads robots.txt. A zero StatusCode means the file could not
+// be fetched or decoded, with the cause in fetchErr; err is set only when the
+// request could not be made at all.
+func (f *SitemapFetcher) fetchRobots(ctx context.Context, robotsURL *url.URL) (entry RobotsEntry, fetchErr, err error) {
+	req, cancel, err := f.newRequest(ctx, http.MethodGet, robotsURL)
+	if err != nil {
+		return RobotsEntry{}, nil, err
+	}
+	defer cancel()
+	if err := f.authorize(ctx, req); err != nil {
+		return RobotsEntry{}, err, nil
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return RobotsEntry{}, err, nil
+	}
+	defer func() { resp.Body.Close() }()
+
+	entry = RobotsEntry{StatusCode: resp.StatusCode, FetchedAt: time.Now()}
+	if resp.StatusCode != http.StatusOK {
+		return entry, nil, nil
+	}
+	if err := f.decodeContent(resp); err != nil {
+		return RobotsEntry{}, err, nil
+	}
+	if entry.Body, err = io.ReadAll(resp.Body); err != nil {
+		return RobotsEntry{}, err, nil
+	}
+	return entry, nil, nil
+}
+
+// parseRobots builds the rules for RobotsUserAgent, or else UserAgent, from a
+// robots.txt entry.
+func (f *SitemapFetcher) parseRobots(ctx context.Context, base, robotsURL *url.URL, entry RobotsEntry) *robotsRules {
+	if entry.StatusCode != http.StatusOK {
+		return &robotsRules{}
+	}
+	data, err := robotstxt.FromBytes(entry.Body)
+	if err != nil {
+		return &robotsRules{}
+	}
+
+	agent := f.opts.RobotsUserAgent
+	if agent == "" {
+		agent = f.opts.UserAgent
+	}
+	rules := &robotsRules{group: data.FindGroup(agent)}
+	for _, loc := range data.Sitemaps {
+		parsed, err := url.Parse(strings.TrimSpace(loc))
+		if err != nil {
+			f.logger.DebugContext(
+				ctx,
+				"invalid sitemap URL in robots.txt",
+				"loc", loc,
+				"robots", robotsURL.String(),
+				"error", err.Error(),
+			)
+			continue
+		}
+		if !parsed.IsAbs() {
+			parsed = base.ResolveReference(parsed)
+		}
+		if !f.allowedScheme(parsed) {
+			continue
+		}
+		rules.sitemaps = append(rules.sitemaps, parsed)
+	}
+	return rules
+}
@@ -0,0 +1,191 @@
+package gositemapfetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_RobotsCache(t *testing.T) {
+	var robotsRequests int32
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			atomic.AddInt32(&robotsRequests, 1)
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/public</loc></url><url><loc>/private</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	walk := func(fetcher *SitemapFetcher) {
+		t.Helper()
+		items, err := collectItems(fetcher, sitemapURL)
+		if err != nil {
+			t.Fatalf("walk failed: %v", err)
+		}
+		if len(items) != 1 || items[0].Loc.Path != "/public" {
+			t.Fatalf("expected robots.txt to be applied, got %v", items)
+		}
+	}
+
+	fetcher := New(Options{RobotsCacheTTL: time.Hour})
+	walk(fetcher)
+	walk(fetcher)
+	if got := atomic.LoadInt32(&robotsRequests); got != 1 {
+		t.Fatalf("expected robots.txt to be fetched once across walks, got %d", got)
+	}
+
+	shared := NewRobotsCache(time.Hour)
+	atomic.StoreInt32(&robotsRequests, 0)
+	walk(New(Options{RobotsCache: shared}))
+	walk(New(Options{RobotsCache: shared, UserAgent: "other-bot"}))
+	if got := atomic.LoadInt32(&robotsRequests); got != 1 {
+		t.Fatalf("expected robots.txt to be fetched once across fetchers, got %d", got)
+	}
+
+	atomic.StoreInt32(&robotsRequests, 0)
+	walk(New(Options{}))
+	walk(New(Options{}))
+	if got := atomic.LoadInt32(&robotsRequests); got != 2 {
+		t.Fatalf("expected robots.txt to be fetched per walk without a cache, got %d", got)
+	}
+}
+
+func TestRobotsCache_TTL(t *testing.T) {
+	cache := NewRobotsCache(time.Minute)
+	cache.Set("https://example.com", RobotsEntry{StatusCode: http.StatusOK, FetchedAt: time.Now().Add(-2 * time.Minute)})
+	if _, ok := cache.Get("https://example.com"); ok {
+		t.Fatalf("expected expired entry to be dropped")
+	}
+	cache.Set("https://example.com", RobotsEntry{StatusCode: http.StatusOK, FetchedAt: time.Now()})
+	if _, ok := cache.Get("https://example.com"); !ok {
+		t.Fatalf("expected fresh entry to be returned")
+	}
+}
+
+func TestSitemapFetcher_RobotsUserAgent(t *testing.T) {
+	var userAgent atomic.Value
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: Googlebot\nDisallow: /private\n\nUser-agent: *\nDisallow:\n"))
+		case "/sitemap.xml":
+			userAgent.Store(r.Header.Get("User-Agent"))
+			_, _ = w.Write([]byte(`<urlset><url><loc>/public</loc></url><url><loc>/private</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	items, err := collectItems(New(Options{UserAgent: "examplebot/1.0"}), sitemapURL)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("expected the * group to apply, got %d items (%v)", len(items), err)
+	}
+
+	items, err = collectItems(New(Options{UserAgent: "examplebot/1.0", RobotsUserAgent: "Googlebot"}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Loc.Path != "/public" {
+		t.Fatalf("expected the Googlebot group to apply, got %v", items)
+	}
+	if got := userAgent.Load(); got != "examplebot/1.0" {
+		t.Fatalf("expected the request User-Agent to be unchanged, got %q", got)
+	}
+}
+
+func TestSitemapFetcher_ErrorOnRobotsDisallowed(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /sitemap\n"))
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/page</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	for _, input := range []string{server.URL, server.URL + "/sitemap.xml"} {
+		target, err := url.Parse(input)
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+
+		items, err := collectItems(New(Options{}), target)
+		if err != nil || len(items) != 0 {
+			t.Fatalf("%s: expected a silent empty walk by default, got %d items (%v)", input, len(items), err)
+		}
+
+		_, err = collectItems(New(Options{ErrorOnRobotsDisallowed: true}), target)
+		var disallowed *ErrRobotsDisallowed
+		if !errors.As(err, &disallowed) {
+			t.Fatalf("%s: expected ErrRobotsDisallowed, got %v", input, err)
+		}
+		if disallowed.URL.Path != "/sitemap.xml" {
+			t.Fatalf("%s: expected the blocked sitemap URL, got %s", input, disallowed.URL)
+		}
+	}
+}
+
+func TestSitemapFetcher_RobotsFailure(t *testing.T) {
+	var robotsStatus atomic.Int32
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.WriteHeader(int(robotsStatus.Load()))
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/page</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	robotsStatus.Store(http.StatusServiceUnavailable)
+	items, err := collectItems(New(Options{}), sitemapURL)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected assume-allow by default, got %d items (%v)", len(items), err)
+	}
+
+	items, err = collectItems(New(Options{RobotsFailure: RobotsAssumeDeny}), sitemapURL)
+	if err != nil || len(items) != 0 {
+		t.Fatalf("expected assume-deny to block the sitemap, got %d items (%v)", len(items), err)
+	}
+
+	_, err = collectItems(New(Options{RobotsFailure: RobotsFailError}), sitemapURL)
+	var unavailable *ErrRobotsUnavailable
+	if !errors.As(err, &unavailable) || unavailable.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected ErrRobotsUnavailable, got %v", err)
+	}
+
+	// A missing robots.txt is not a failure.
+	robotsStatus.Store(http.StatusNotFound)
+	items, err = collectItems(New(Options{RobotsFailure: RobotsFailError}), sitemapURL)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected 404 robots.txt to allow everything, got %d items (%v)", len(items), err)
+	}
+}
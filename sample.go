@@ -0,0 +1,118 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+)
+
+// sampler thins the emitted items per Options.SampleRate and Options.SampleN.
+type sampler struct {
+	rate float64
+	n    int
+	rng  *rand.Rand
+	// seen counts items that passed the rate sample, for reservoir sampling.
+	seen      int
+	reservoir []Item
+	order     []int
+}
+
+func newSampler(opts Options) *sampler {
+	if opts.SampleRate <= 0 && opts.SampleN <= 0 {
+		return nil
+	}
+	seed := opts.SampleSeed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &sampler{
+		rate: opts.SampleRate,
+		n:    opts.SampleN,
+		rng:  rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// offer reports whether item should be emitted now. With SampleN, selected
+// items are kept in a reservoir instead and emitted when the walk ends.
+func (s *sampler) offer(item Item) bool {
+	if s.rate > 0 && s.rate < 1 && s.rng.Float64() >= s.rate {
+		return false
+	}
+	if s.n <= 0 {
+		return true
+	}
+	s.seen++
+	if len(s.reservoir) < s.n {
+		s.reservoir = append(s.reservoir, item)
+		s.order = append(s.order, s.seen)
+		return false
+	}
+	if j := s.rng.IntN(s.seen); j < s.n {
+		s.reservoir[j] = item
+		s.order[j] = s.seen
+	}
+	return false
+}
+
+// flushSample emits the SampleN reservoir in walk order.
+func (w *walk) flushSample() error {
+	s := w.sample
+	if s == nil || len(s.reservoir) == 0 {
+		return nil
+	}
+	indexes := make([]int, len(s.reservoir))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(a, b int) bool {
+		return s.order[indexes[a]] < s.order[indexes[b]]
+	})
+	items := s.reservoir
+	s.reservoir, s.order = nil, nil
+	for _, i := range indexes {
+		var err error
+		if w.links != nil {
+			err = w.links.submit(items[i], 0)
+		} else {
+			err = w.deliver(items[i], 0)
+		}
+		if err != nil {
+			return sampleFlushError(err)
+		}
+	}
+	return sampleFlushError(w.links.flush())
+}
+
+// finishSample emits the SampleN reservoir when the walk ends with err. The
+// sample is part of the partial results a walk keeps on limits, timeouts,
+// and failed sitemaps, so it is dropped only after a callback error or when
+// the caller canceled. After WalkTimeout the items are delivered without
+// link checks, on a context that is no longer canceled.
+func (w *walk) finishSample(err error) error {
+	if w.sample == nil || len(w.sample.reservoir) == 0 {
+		return nil
+	}
+	var yieldErr *ErrYield
+	if errors.As(err, &yieldErr) {
+		return nil
+	}
+	if ctx := w.ctx; ctx.Err() != nil {
+		var timeout *ErrWalkTimeout
+		if !errors.As(context.Cause(ctx), &timeout) {
+			return nil
+		}
+		links := w.links
+		w.ctx, w.links = context.WithoutCancel(ctx), nil
+		defer func() { w.ctx, w.links = ctx, links }()
+	}
+	return w.flushSample()
+}
+
+// sampleFlushError maps callback sentinels to the end of the sample.
+func sampleFlushError(err error) error {
+	if errors.Is(err, ErrSkipSitemap) || errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSampleServer(t *testing.T, perSitemap int) *url.URL {
+	t.Helper()
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml", "/b.xml":
+			var body strings.Builder
+			body.WriteString("<urlset>")
+			for i := range perSitemap {
+				fmt.Fprintf(&body, "<url><loc>%s/%d</loc></url>", strings.TrimSuffix(r.URL.Path, ".xml"), i)
+			}
+			body.WriteString("</urlset>")
+			_, _ = w.Write([]byte(body.String()))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	return sitemapURL
+}
+
+func TestSitemapFetcher_SampleN(t *testing.T) {
+	sitemapURL := newSampleServer(t, 500)
+
+	walkSample := func() []string {
+		items, err := collectItems(New(Options{IgnoreRobots: true, SampleN: 10, SampleSeed: 42}), sitemapURL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var locs []string
+		for _, item := range items {
+			locs = append(locs, item.Loc.Path)
+		}
+		return locs
+	}
+
+	first := walkSample()
+	if len(first) != 10 {
+		t.Fatalf("expected 10 sampled items, got %d", len(first))
+	}
+	seen := map[string]bool{}
+	lastSitemap, lastIndex := "", -1
+	for _, loc := range first {
+		if seen[loc] {
+			t.Fatalf("duplicate sampled item %s", loc)
+		}
+		seen[loc] = true
+		var sitemap string
+		var index int
+		if _, err := fmt.Sscanf(loc, "/%1s/%d", &sitemap, &index); err != nil {
+			t.Fatalf("unexpected loc %s: %v", loc, err)
+		}
+		if sitemap < lastSitemap || (sitemap == lastSitemap && index <= lastIndex) {
+			t.Fatalf("expected sample in walk order, got %v", first)
+		}
+		lastSitemap, lastIndex = sitemap, index
+	}
+
+	if second := walkSample(); strings.Join(second, ",") != strings.Join(first, ",") {
+		t.Fatalf("expected the same sample for the same seed, got %v and %v", first, second)
+	}
+}
+
+func TestSitemapFetcher_SampleNPartialWalk(t *testing.T) {
+	sitemapURL := newSampleServer(t, 50)
+	items, err := collectItems(New(Options{IgnoreRobots: true, SampleN: 10, MaxSitemaps: 2}), sitemapURL)
+	var maxSitemaps *ErrMaxSitemaps
+	if !errors.As(err, &maxSitemaps) || len(items) != 10 {
+		t.Fatalf("expected the sample of the sitemaps read before MaxSitemaps, got %d items (%v)", len(items), err)
+	}
+	for _, item := range items {
+		if !strings.HasPrefix(item.Loc.Path, "/a/") {
+			t.Fatalf("expected only /a.xml items, got %s", item.Loc.Path)
+		}
+	}
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/slow.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a/1</loc></url><url><loc>/a/2</loc></url></urlset>`))
+		case "/slow.xml":
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	slowURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err = collectItems(New(Options{IgnoreRobots: true, SampleN: 10, WalkTimeout: 200 * time.Millisecond}), slowURL)
+	var timeout *ErrWalkTimeout
+	if !errors.As(err, &timeout) || len(items) != 2 {
+		t.Fatalf("expected the sample delivered after WalkTimeout, got %d items (%v)", len(items), err)
+	}
+
+	// A canceled walk discards it.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var delivered int
+	err = New(Options{IgnoreRobots: true, SampleN: 10}).Walk(ctx, slowURL, func(Item) error {
+		delivered++
+		return nil
+	})
+	if err == nil || delivered != 0 {
+		t.Fatalf("expected a canceled walk to deliver nothing, got %d items (%v)", delivered, err)
+	}
+}
+
+func TestSitemapFetcher_SampleRate(t *testing.T) {
+	sitemapURL := newSampleServer(t, 500)
+
+	items, err := collectItems(New(Options{IgnoreRobots: true, SampleRate: 0.1, SampleSeed: 7}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) < 50 || len(items) > 150 {
+		t.Fatalf("expected about 100 of 1000 items, got %d", len(items))
+	}
+}
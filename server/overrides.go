@@ -0,0 +1,147 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+// Overrides are the walk settings a client may request on top of an operator's
+// base options. The HTTP server builds them from a WalkRequest; other
+// front ends, such as the grpcserver module, can use Apply to combine client
+// options the same way. Zero values keep the base setting.
+type Overrides struct {
+	// Limits can lower the base's but not raise them, and can only lift a
+	// limit the base left at 0.
+	MaxDepth           int
+	MaxSitemaps        int
+	MaxURLs            int
+	MaxURLsPerSitemap  int
+	MaxTotalBytes      int64
+	PerRequestTimeout  time.Duration
+	WalkTimeout        time.Duration
+	FetchConcurrency   int
+	ConcurrencyPerHost int
+	// Delay can only grow the base's.
+	Delay time.Duration
+	// Booleans can turn a setting on but not off. IgnoreRobots is rejected
+	// unless the base already ignores robots.txt.
+	StopAtMaxURLs   bool
+	SkipNon200      bool
+	SkipFetchErrors bool
+	DedupeURLs      bool
+	Strict          bool
+	KeepExtensions  bool
+	IgnoreRobots    bool
+	// UserAgent and RobotsUserAgent apply only when the base leaves them empty.
+	UserAgent       string
+	RobotsUserAgent string
+	// TraversalOrder is a TraversalOrder name such as "depth-first".
+	TraversalOrder string
+	// Include and Exclude are regular expressions. Include is rejected when the
+	// base has its own; Exclude patterns are added to the base's.
+	Include []string
+	Exclude []string
+}
+
+// Apply validates o and merges it into base. The result gets no
+// VisitedStore, SitemapStates, RetainURLs, checkpointing, or resume state,
+// since those would leak between clients sharing the base: a later walk would
+// skip what an earlier one saw.
+func (o Overrides) Apply(base gositemapfetcher.Options) (gositemapfetcher.Options, error) {
+	opts := base
+	opts.MaxDepth = lower(opts.MaxDepth, o.MaxDepth)
+	opts.MaxSitemaps = lower(opts.MaxSitemaps, o.MaxSitemaps)
+	opts.MaxURLs = lower(opts.MaxURLs, o.MaxURLs)
+	opts.MaxURLsPerSitemap = lower(opts.MaxURLsPerSitemap, o.MaxURLsPerSitemap)
+	opts.MaxTotalBytes = lower(opts.MaxTotalBytes, o.MaxTotalBytes)
+	opts.PerRequestTimeout = lower(opts.PerRequestTimeout, o.PerRequestTimeout)
+	opts.WalkTimeout = lower(opts.WalkTimeout, o.WalkTimeout)
+	opts.FetchConcurrency = lower(opts.FetchConcurrency, o.FetchConcurrency)
+	opts.ConcurrencyPerHost = lower(opts.ConcurrencyPerHost, o.ConcurrencyPerHost)
+	opts.Delay = max(opts.Delay, o.Delay)
+	opts.StopAtMaxURLs = opts.StopAtMaxURLs || o.StopAtMaxURLs
+	opts.SkipNon200 = opts.SkipNon200 || o.SkipNon200
+	opts.SkipFetchErrors = opts.SkipFetchErrors || o.SkipFetchErrors
+	opts.DedupeURLs = opts.DedupeURLs || o.DedupeURLs
+	opts.Strict = opts.Strict || o.Strict
+	opts.KeepExtensions = opts.KeepExtensions || o.KeepExtensions
+	if o.IgnoreRobots && !opts.IgnoreRobots {
+		return base, errors.New("ignore_robots is not allowed by this server")
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = o.UserAgent
+	}
+	if opts.RobotsUserAgent == "" {
+		opts.RobotsUserAgent = o.RobotsUserAgent
+	}
+	if o.TraversalOrder != "" {
+		order, err := parseTraversalOrder(o.TraversalOrder)
+		if err != nil {
+			return base, err
+		}
+		opts.TraversalOrder = order
+	}
+	if len(o.Include) > 0 {
+		if len(opts.Include) > 0 {
+			return base, errors.New("include is fixed by this server")
+		}
+		include, err := compilePatterns(o.Include)
+		if err != nil {
+			return base, err
+		}
+		opts.Include = include
+	}
+	if len(o.Exclude) > 0 {
+		exclude, err := compilePatterns(o.Exclude)
+		if err != nil {
+			return base, err
+		}
+		opts.Exclude = append(append([]*regexp.Regexp(nil), opts.Exclude...), exclude...)
+	}
+	// Every walk gets its own fetcher; a shared store would leak between them.
+	opts.VisitedStore = nil
+	opts.SitemapStates = nil
+	opts.RetainURLs = false
+	opts.OnCheckpoint = nil
+	opts.Resume = nil
+	return opts, nil
+}
+
+// lower applies a requested limit under the operator's: 0 means unlimited on
+// either side, so the result is the smaller of the set values.
+func lower[T int | int64 | time.Duration](operator, requested T) T {
+	if requested <= 0 || (operator > 0 && operator < requested) {
+		return operator
+	}
+	return requested
+}
+
+func parseTraversalOrder(name string) (gositemapfetcher.TraversalOrder, error) {
+	for _, order := range []gositemapfetcher.TraversalOrder{
+		gositemapfetcher.TraversalBreadthFirst,
+		gositemapfetcher.TraversalDepthFirst,
+		gositemapfetcher.TraversalFreshestFirst,
+	} {
+		if order.String() == name {
+			return order, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid traversal_order %q", name)
+}
+
+// compilePatterns compiles include and exclude values.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,413 @@
+// Package server runs the fetcher as an HTTP service, so stacks that cannot
+// link Go code can use it as a sidecar:
+//
+//	srv := server.New(server.Options{Fetcher: gositemapfetcher.Options{UserAgent: "my-bot"}})
+//	defer srv.Close()
+//	log.Fatal(http.ListenAndServe(":8080", srv))
+//
+// The API has two endpoints:
+//
+//   - POST /walks starts a walk described by a JSON WalkRequest and answers
+//     202 Accepted with its Status.
+//   - GET /walks/{id} answers with the walk's Status, or, when the request
+//     accepts application/x-ndjson or has ?stream=1, streams its items as
+//     NDJSON (Item.MarshalJSON): those found so far, then new ones as they
+//     arrive, until the walk ends.
+//
+// Errors are JSON objects with an "error" field.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const (
+	defaultMaxRunning = 4
+	defaultRetention  = time.Hour
+	defaultMaxItems   = 1_000_000
+	defaultMaxBytes   = 256 << 20
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// errOutputLimit ends a walk whose buffered items reach Options.MaxItems or
+// Options.MaxBytes.
+var errOutputLimit = errors.New("walk output exceeds the server's item limit")
+
+// Options configures the server.
+type Options struct {
+	// Fetcher is the base configuration of every walk. Its limits (MaxDepth,
+	// MaxSitemaps, MaxURLs, MaxTotalBytes, WalkTimeout) are caps: a
+	// WalkRequest can lower them but not raise them, and can only lift a limit
+	// the operator left at 0. Requests add to its Exclude patterns, may set
+	// Include only when it has none, and may set ignore_robots only when
+	// IgnoreRobots is already true; see Overrides.
+	Fetcher gositemapfetcher.Options
+	// MaxRunning caps concurrent walks; further POSTs get 429 Too Many
+	// Requests. 0 => 4.
+	MaxRunning int
+	// Retention is how long a finished walk and its items stay available.
+	// 0 => 1h. Items are held in memory until then.
+	Retention time.Duration
+	// MaxItems and MaxBytes bound the items one walk buffers, counted as
+	// NDJSON lines and their bytes; a walk reaching either fails with what it
+	// found so far. 0 => 1,000,000 items and 256 MiB.
+	MaxItems int
+	MaxBytes int64
+}
+
+// WalkRequest is the JSON body of POST /walks. Zero values keep the setting of
+// Options.Fetcher; see there for how set values combine with it.
+type WalkRequest struct {
+	// URL is the website or sitemap URL to walk.
+	URL string `json:"url"`
+	// URLs walks several roots as one run (WalkAll), after URL if both are set.
+	URLs          []string `json:"urls,omitempty"`
+	MaxDepth      int      `json:"max_depth,omitempty"`
+	MaxSitemaps   int      `json:"max_sitemaps,omitempty"`
+	MaxURLs       int      `json:"max_urls,omitempty"`
+	MaxTotalBytes int64    `json:"max_total_bytes,omitempty"`
+	// Include and Exclude are regular expressions matched against URLs.
+	Include      []string `json:"include,omitempty"`
+	Exclude      []string `json:"exclude,omitempty"`
+	IgnoreRobots bool     `json:"ignore_robots,omitempty"`
+	DedupeURLs   bool     `json:"dedupe_urls,omitempty"`
+	// WalkTimeout is a Go duration such as "2m".
+	WalkTimeout string `json:"walk_timeout,omitempty"`
+}
+
+// Walk states reported in Status.State.
+const (
+	StateRunning = "running"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+// Status is the progress of a walk, as returned by both endpoints.
+type Status struct {
+	ID    string   `json:"id"`
+	URLs  []string `json:"urls"`
+	State string   `json:"state"`
+	// Items counts the items found so far.
+	Items int `json:"items"`
+	// Sitemaps and SkippedSitemaps count the sitemaps read and skipped so far.
+	Sitemaps        int        `json:"sitemaps"`
+	SkippedSitemaps int        `json:"skipped_sitemaps"`
+	Error           string     `json:"error,omitempty"`
+	Started         time.Time  `json:"started"`
+	Finished        *time.Time `json:"finished,omitempty"`
+}
+
+// Server is an http.Handler serving the walk API. It is safe for concurrent use.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu    sync.Mutex
+	walks map[string]*walkState
+}
+
+// New returns a server with defaults applied.
+func New(opts Options) *Server {
+	if opts.MaxRunning <= 0 {
+		opts.MaxRunning = defaultMaxRunning
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultRetention
+	}
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = defaultMaxItems
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxBytes
+	}
+	s := &Server{opts: opts, mux: http.NewServeMux(), walks: map[string]*walkState{}}
+	s.mux.HandleFunc("POST /walks", s.startWalk)
+	s.mux.HandleFunc("GET /walks/{id}", s.getWalk)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops every running walk and waits for them to finish. Streams end
+// once their walk has.
+func (s *Server) Close() {
+	s.mu.Lock()
+	walks := make([]*walkState, 0, len(s.walks))
+	for _, walk := range s.walks {
+		walks = append(walks, walk)
+	}
+	s.mu.Unlock()
+	for _, walk := range walks {
+		walk.cancel()
+	}
+	for _, walk := range walks {
+		<-walk.done
+	}
+}
+
+func (s *Server) startWalk(w http.ResponseWriter, r *http.Request) {
+	var req WalkRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	roots, opts, err := s.walkOptions(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.evict(time.Now())
+	running := 0
+	for _, walk := range s.walks {
+		if walk.running() {
+			running++
+		}
+	}
+	if running >= s.opts.MaxRunning {
+		s.mu.Unlock()
+		writeError(w, http.StatusTooManyRequests, fmt.Errorf("%d walks already running", running))
+		return
+	}
+	id := newID()
+	ctx, cancel := context.WithCancel(gositemapfetcher.WithWalkID(context.Background(), id))
+	walk := &walkState{
+		id:       id,
+		roots:    roots,
+		fetcher:  gositemapfetcher.New(opts),
+		maxItems: s.opts.MaxItems,
+		maxBytes: s.opts.MaxBytes,
+		cancel:   cancel,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+		changed:  make(chan struct{}),
+	}
+	s.walks[id] = walk
+	s.mu.Unlock()
+
+	go walk.run(ctx)
+	w.Header().Set("Location", "/walks/"+id)
+	writeJSON(w, http.StatusAccepted, walk.status())
+}
+
+func (s *Server) getWalk(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	walk, ok := s.walks[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("walk %q not found", r.PathValue("id")))
+		return
+	}
+	if r.URL.Query().Get("stream") == "1" || strings.Contains(r.Header.Get("Accept"), ndjsonContentType) {
+		walk.stream(r.Context(), w)
+		return
+	}
+	writeJSON(w, http.StatusOK, walk.status())
+}
+
+// walkOptions validates req and merges it into the base options.
+func (s *Server) walkOptions(req WalkRequest) ([]*url.URL, gositemapfetcher.Options, error) {
+	opts := s.opts.Fetcher
+	var roots []*url.URL
+	for _, raw := range append([]string{req.URL}, req.URLs...) {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, opts, fmt.Errorf("invalid URL %q: %w", raw, err)
+		}
+		roots = append(roots, u)
+	}
+	if len(roots) == 0 {
+		return nil, opts, errors.New("missing url")
+	}
+	overrides := Overrides{
+		MaxDepth:      req.MaxDepth,
+		MaxSitemaps:   req.MaxSitemaps,
+		MaxURLs:       req.MaxURLs,
+		MaxTotalBytes: req.MaxTotalBytes,
+		IgnoreRobots:  req.IgnoreRobots,
+		DedupeURLs:    req.DedupeURLs,
+		Include:       req.Include,
+		Exclude:       req.Exclude,
+	}
+	if req.WalkTimeout != "" {
+		timeout, err := time.ParseDuration(req.WalkTimeout)
+		if err != nil {
+			return nil, opts, fmt.Errorf("invalid walk_timeout: %w", err)
+		}
+		overrides.WalkTimeout = timeout
+	}
+	opts, err := overrides.Apply(opts)
+	if err != nil {
+		return nil, opts, err
+	}
+	return roots, opts, nil
+}
+
+// evict drops walks that finished more than Retention ago. s.mu must be held.
+func (s *Server) evict(now time.Time) {
+	for id, walk := range s.walks {
+		if finished := walk.finishedAt(); finished != nil && now.Sub(*finished) > s.opts.Retention {
+			delete(s.walks, id)
+		}
+	}
+}
+
+// walkState is one walk started through the API.
+type walkState struct {
+	id      string
+	roots   []*url.URL
+	fetcher *gositemapfetcher.SitemapFetcher
+	cancel  context.CancelFunc
+	started time.Time
+	done    chan struct{}
+	// maxItems and maxBytes bound lines; see Options.MaxItems.
+	maxItems int
+	maxBytes int64
+
+	mu       sync.Mutex
+	lines    [][]byte
+	bytes    int64
+	err      error
+	finished *time.Time
+	// changed is closed and replaced whenever lines grow or the walk ends.
+	changed chan struct{}
+}
+
+func (ws *walkState) run(ctx context.Context) {
+	defer close(ws.done)
+	defer ws.cancel()
+	err := ws.fetcher.WalkAll(ctx, ws.roots, func(item gositemapfetcher.Item) error {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		if len(ws.lines) >= ws.maxItems || ws.bytes+int64(len(line)) > ws.maxBytes {
+			return errOutputLimit
+		}
+		ws.lines = append(ws.lines, line)
+		ws.bytes += int64(len(line))
+		ws.notify()
+		return nil
+	})
+	now := time.Now()
+	ws.mu.Lock()
+	ws.err = err
+	ws.finished = &now
+	ws.notify()
+	ws.mu.Unlock()
+}
+
+// notify wakes streams waiting for changes. ws.mu must be held.
+func (ws *walkState) notify() {
+	close(ws.changed)
+	ws.changed = make(chan struct{})
+}
+
+func (ws *walkState) running() bool {
+	return ws.finishedAt() == nil
+}
+
+func (ws *walkState) finishedAt() *time.Time {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.finished
+}
+
+func (ws *walkState) status() Status {
+	status := Status{
+		ID:              ws.id,
+		State:           StateRunning,
+		Sitemaps:        len(ws.fetcher.SitemapStats()),
+		SkippedSitemaps: ws.fetcher.SkippedSitemapCount(),
+		Started:         ws.started,
+	}
+	for _, root := range ws.roots {
+		status.URLs = append(status.URLs, root.String())
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	status.Items = len(ws.lines)
+	status.Finished = ws.finished
+	switch {
+	case ws.finished == nil:
+	case ws.err != nil:
+		status.State = StateFailed
+		status.Error = ws.err.Error()
+	default:
+		status.State = StateDone
+	}
+	return status
+}
+
+// stream writes the walk's items as NDJSON until the walk ends or ctx is done.
+// An error ending the walk is not part of the stream; GET the status for it.
+func (ws *walkState) stream(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	sent := 0
+	for {
+		ws.mu.Lock()
+		lines := ws.lines[sent:]
+		finished := ws.finished != nil
+		changed := ws.changed
+		ws.mu.Unlock()
+		for _, line := range lines {
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+		}
+		sent += len(lines)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if finished {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func postWalk(t *testing.T, api *httptest.Server, body string) (*http.Response, Status) {
+	t.Helper()
+	resp, err := http.Post(api.URL+"/walks", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /walks failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var status Status
+	if resp.StatusCode == http.StatusAccepted {
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("failed to decode status: %v", err)
+		}
+	}
+	return resp, status
+}
+
+func getStatus(t *testing.T, api *httptest.Server, id string) Status {
+	t.Helper()
+	resp, err := http.Get(api.URL + "/walks/" + id)
+	if err != nil {
+		t.Fatalf("GET /walks/%s failed: %v", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	return status
+}
+
+func TestServer_WalkAndStream(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url><url><loc>/c</loc></url></urlset>`))
+	}))
+	defer origin.Close()
+	srv := New(Options{Fetcher: gositemapfetcher.Options{IgnoreRobots: true}})
+	defer srv.Close()
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	resp, status := postWalk(t, api, `{"url":"`+origin.URL+`/sitemap.xml","exclude":["/b$"]}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	if status.ID == "" || resp.Header.Get("Location") != "/walks/"+status.ID {
+		t.Fatalf("expected an ID and matching Location, got %+v, %q", status, resp.Header.Get("Location"))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, api.URL+"/walks/"+status.ID, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected NDJSON, got %q", ct)
+	}
+	var paths []string
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		var item gositemapfetcher.Item
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("failed to decode line %q: %v", scanner.Text(), err)
+		}
+		paths = append(paths, item.Loc.Path)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if got := strings.Join(paths, ","); got != "/a,/c" {
+		t.Fatalf("expected /a,/c, got %s", got)
+	}
+
+	// The stream ends with the walk, so the status is final.
+	status = getStatus(t, api, status.ID)
+	if status.State != StateDone || status.Items != 2 || status.Sitemaps != 1 || status.Finished == nil {
+		t.Fatalf("unexpected final status: %+v", status)
+	}
+}
+
+// streamPaths streams the items of walk id to its end and returns their paths.
+func streamPaths(t *testing.T, api *httptest.Server, id string) string {
+	t.Helper()
+	resp, err := http.Get(api.URL + "/walks/" + id + "?stream=1")
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var paths []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var item gositemapfetcher.Item
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("failed to decode line %q: %v", scanner.Text(), err)
+		}
+		paths = append(paths, item.Loc.Path)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestServer_WalksDoNotShareState(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/pages.xml</loc><lastmod>2024-01-01</lastmod></sitemap></sitemapindex>`))
+		case "/pages.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer origin.Close()
+	srv := New(Options{Fetcher: gositemapfetcher.Options{
+		IgnoreRobots:  true,
+		RetainURLs:    true,
+		SitemapStates: &gositemapfetcher.MemorySitemapStateStore{},
+	}})
+	defer srv.Close()
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	var results []string
+	for range 2 {
+		resp, status := postWalk(t, api, `{"url":"`+origin.URL+`/index.xml"}`)
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", resp.StatusCode)
+		}
+		results = append(results, streamPaths(t, api, status.ID))
+	}
+	if results[0] != "/a,/b" || results[1] != results[0] {
+		t.Fatalf("expected both walks to return /a,/b, got %q", results)
+	}
+}
+
+func TestServer_FailedWalk(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer origin.Close()
+	srv := New(Options{Fetcher: gositemapfetcher.Options{IgnoreRobots: true}})
+	defer srv.Close()
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	_, status := postWalk(t, api, `{"url":"`+origin.URL+`/sitemap.xml","ignore_robots":true}`)
+	srv.mu.Lock()
+	walk := srv.walks[status.ID]
+	srv.mu.Unlock()
+	<-walk.done
+	status = getStatus(t, api, status.ID)
+	if status.State != StateFailed || !strings.Contains(status.Error, "500") {
+		t.Fatalf("expected a failed walk with the HTTP status, got %+v", status)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	block := make(chan struct{})
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer origin.Close()
+	defer close(block)
+	srv := New(Options{MaxRunning: 1, Fetcher: gositemapfetcher.Options{IgnoreRobots: true}})
+	defer srv.Close()
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	for _, body := range []string{`{}`, `{"url":"https://example.com","max_urlz":1}`, `{"url":"https://example.com","include":["("]}`, `{"url":"https://example.com","walk_timeout":"soon"}`} {
+		if resp, _ := postWalk(t, api, body); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+	if resp, _ := postWalk(t, api, `{"url":"`+origin.URL+`/sitemap.xml","ignore_robots":true}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	if resp, _ := postWalk(t, api, `{"url":"`+origin.URL+`/other.xml","ignore_robots":true}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over MaxRunning, got %d", resp.StatusCode)
+	}
+	resp, err := http.Get(api.URL + "/walks/unknown")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_OperatorLimits(t *testing.T) {
+	srv := New(Options{Fetcher: gositemapfetcher.Options{
+		MaxURLs:     100,
+		WalkTimeout: time.Minute,
+		Include:     []*regexp.Regexp{regexp.MustCompile(`^https://example\.com/`)},
+		Exclude:     []*regexp.Regexp{regexp.MustCompile(`/private/`)},
+	}})
+	defer srv.Close()
+
+	_, opts, err := srv.walkOptions(WalkRequest{URL: "https://example.com", MaxURLs: 1000, MaxDepth: 2, WalkTimeout: "1h", Exclude: []string{"/tmp/"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.MaxURLs != 100 || opts.MaxDepth != 2 || opts.WalkTimeout != time.Minute || len(opts.Exclude) != 2 {
+		t.Fatalf("expected the operator's limits kept as caps, got max_urls=%d max_depth=%d walk_timeout=%s exclude=%v", opts.MaxURLs, opts.MaxDepth, opts.WalkTimeout, opts.Exclude)
+	}
+	if _, opts, _ = srv.walkOptions(WalkRequest{URL: "https://example.com", MaxURLs: 10}); opts.MaxURLs != 10 {
+		t.Fatalf("expected a request to lower max_urls, got %d", opts.MaxURLs)
+	}
+	for _, req := range []WalkRequest{
+		{URL: "https://example.com", IgnoreRobots: true},
+		{URL: "https://example.com", Include: []string{"."}},
+	} {
+		if _, _, err := srv.walkOptions(req); err == nil {
+			t.Fatalf("expected %+v to be rejected", req)
+		}
+	}
+}
+
+func TestServer_MaxItems(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url><url><loc>/c</loc></url></urlset>`))
+	}))
+	defer origin.Close()
+	srv := New(Options{MaxItems: 2, Fetcher: gositemapfetcher.Options{IgnoreRobots: true}})
+	defer srv.Close()
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	_, status := postWalk(t, api, `{"url":"`+origin.URL+`/sitemap.xml"}`)
+	srv.mu.Lock()
+	walk := srv.walks[status.ID]
+	srv.mu.Unlock()
+	<-walk.done
+	status = getStatus(t, api, status.ID)
+	if status.State != StateFailed || status.Items != 2 || !strings.Contains(status.Error, errOutputLimit.Error()) {
+		t.Fatalf("expected the walk to fail at MaxItems with 2 items kept, got %+v", status)
+	}
+}
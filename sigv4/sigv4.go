@@ -0,0 +1,255 @@
+// Package sigv4 signs fetcher requests with AWS Signature Version 4, so
+// sitemaps in private S3 buckets can be walked through their virtual-host
+// URLs (https://bucket.s3.region.amazonaws.com/sitemap.xml):
+//
+//	signer := sigv4.New(sigv4.Options{Region: "eu-west-1", Credentials: sigv4.EnvCredentials{}})
+//	fetcher := gositemapfetcher.New(gositemapfetcher.Options{Auth: signer})
+//
+// Only the standard library is used; an AWS SDK credentials chain plugs in
+// through CredentialsFunc.
+package sigv4
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const (
+	algorithm      = "AWS4-HMAC-SHA256"
+	defaultService = "s3"
+	amzDateFormat  = "20060102T150405Z"
+	// emptyPayloadHash is the SHA-256 of an empty body; fetcher requests have none.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Credentials are AWS access keys.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials and sent as X-Amz-Security-Token.
+	SessionToken string
+}
+
+// CredentialsProvider returns the credentials to sign with. It is called for
+// every request, so it should cache and refresh temporary credentials itself.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsFunc adapts a function to CredentialsProvider.
+type CredentialsFunc func(ctx context.Context) (Credentials, error)
+
+// Retrieve calls fn.
+func (fn CredentialsFunc) Retrieve(ctx context.Context) (Credentials, error) {
+	return fn(ctx)
+}
+
+// StaticCredentials always returns the same credentials.
+type StaticCredentials Credentials
+
+// Retrieve implements CredentialsProvider.
+func (c StaticCredentials) Retrieve(context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// EnvCredentials reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN on every call.
+type EnvCredentials struct{}
+
+// Retrieve implements CredentialsProvider.
+func (EnvCredentials) Retrieve(context.Context) (Credentials, error) {
+	creds := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return Credentials{}, errors.New("sigv4: AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY not set")
+	}
+	return creds, nil
+}
+
+// Options configures a Signer.
+type Options struct {
+	// Region is the bucket's region, such as "us-east-1". Required.
+	Region string
+	// Service is the signing name. Empty => "s3".
+	Service string
+	// Credentials supplies the keys. Required.
+	Credentials CredentialsProvider
+	// Hosts lists the hosts (as in URL.Host) whose requests are signed.
+	// nil => hosts ending in ".amazonaws.com"; requests elsewhere, such as
+	// robots.txt of other sites, are left untouched.
+	Hosts []string
+}
+
+// Signer is a gositemapfetcher.AuthProvider that signs requests with SigV4.
+// It is safe for concurrent use.
+type Signer struct {
+	opts  Options
+	hosts map[string]struct{}
+	now   func() time.Time
+}
+
+var _ gositemapfetcher.AuthProvider = (*Signer)(nil)
+
+// New returns a Signer with defaults applied.
+func New(opts Options) *Signer {
+	if opts.Service == "" {
+		opts.Service = defaultService
+	}
+	s := &Signer{opts: opts, now: time.Now}
+	if opts.Hosts != nil {
+		s.hosts = make(map[string]struct{}, len(opts.Hosts))
+		for _, host := range opts.Hosts {
+			s.hosts[strings.ToLower(host)] = struct{}{}
+		}
+	}
+	return s
+}
+
+// Authorize implements gositemapfetcher.AuthProvider. It signs the host and
+// X-Amz-* headers and an empty payload.
+func (s *Signer) Authorize(ctx context.Context, req *http.Request) error {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	if !s.signs(strings.ToLower(host)) {
+		return nil
+	}
+	if s.opts.Region == "" {
+		return errors.New("sigv4: missing region")
+	}
+	if s.opts.Credentials == nil {
+		return errors.New("sigv4: missing credentials")
+	}
+	creds, err := s.opts.Credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	scope := strings.Join([]string{amzDate[:8], s.opts.Region, s.opts.Service, "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.opts.Service == defaultService {
+		req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	}
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	} else {
+		req.Header.Del("X-Amz-Security-Token")
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, hashHex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, s.opts.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", algorithm+" Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
+
+func (s *Signer) signs(host string) bool {
+	if s.hosts != nil {
+		_, ok := s.hosts[host]
+		return ok
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return strings.HasSuffix(host, ".amazonaws.com")
+}
+
+// canonicalPath URI-encodes each segment of the decoded path once, as S3
+// expects.
+func canonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts the query by key, then value, encoding both.
+func canonicalQuery(query map[string][]string) string {
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters.
+func uriEncode(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			out.WriteByte(c)
+			continue
+		}
+		out.WriteByte('%')
+		out.WriteByte(hexDigits[c>>4])
+		out.WriteByte(hexDigits[c&0xF])
+	}
+	return out.String()
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
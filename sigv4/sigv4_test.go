@@ -0,0 +1,135 @@
+package sigv4
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+var exampleCreds = StaticCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func fixedClock(s *Signer) {
+	s.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+}
+
+// TestSigner_Vanilla checks the get-vanilla case of the AWS SigV4 test suite.
+func TestSigner_Vanilla(t *testing.T) {
+	signer := New(Options{Region: "us-east-1", Service: "service", Credentials: exampleCreds, Hosts: []string{"example.amazonaws.com"}})
+	fixedClock(signer)
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if err := signer.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("authorize failed: %v", err)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("unexpected Authorization:\n got %s\nwant %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Fatalf("unexpected X-Amz-Date %q", got)
+	}
+}
+
+func TestSigner_S3Headers(t *testing.T) {
+	creds := StaticCredentials(exampleCreds)
+	creds.SessionToken = "token"
+	signer := New(Options{Region: "eu-west-1", Credentials: creds})
+	fixedClock(signer)
+	req, err := http.NewRequest(http.MethodGet, "https://bucket.s3.eu-west-1.amazonaws.com/maps/site map.xml?versionId=2", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if err := signer.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("authorize failed: %v", err)
+	}
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != emptyPayloadHash {
+		t.Fatalf("unexpected X-Amz-Content-Sha256 %q", got)
+	}
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "token" {
+		t.Fatalf("unexpected X-Amz-Security-Token %q", got)
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, "/20150830/eu-west-1/s3/aws4_request") ||
+		!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("unexpected Authorization %q", auth)
+	}
+	if got := canonicalPath(req.URL.Path); got != "/maps/site%20map.xml" {
+		t.Fatalf("unexpected canonical path %q", got)
+	}
+}
+
+func TestSigner_SkipsOtherHosts(t *testing.T) {
+	called := false
+	signer := New(Options{Region: "us-east-1", Credentials: CredentialsFunc(func(context.Context) (Credentials, error) {
+		called = true
+		return Credentials{}, nil
+	})})
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/robots.txt", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if err := signer.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("authorize failed: %v", err)
+	}
+	if called || req.Header.Get("Authorization") != "" {
+		t.Fatalf("expected a request to another host to be left unsigned")
+	}
+}
+
+func TestSigner_Fetcher(t *testing.T) {
+	var signed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		signed = append(signed, r.URL.Path)
+		fmt.Fprintf(w, `<urlset><url><loc>https://example.com/a</loc></url></urlset>`)
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	signer := New(Options{Region: "us-east-1", Credentials: exampleCreds, Hosts: []string{sitemapURL.Host}})
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, Auth: signer})
+	var locs []string
+	err = fetcher.Walk(context.Background(), sitemapURL, func(item gositemapfetcher.Item) error {
+		locs = append(locs, item.Loc.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(locs) != 1 || len(signed) != 1 {
+		t.Fatalf("expected one signed request and one URL, got %v and %v", signed, locs)
+	}
+}
+
+func TestSigner_CredentialsError(t *testing.T) {
+	signer := New(Options{Region: "us-east-1", Credentials: CredentialsFunc(func(context.Context) (Credentials, error) {
+		return Credentials{}, errors.New("expired")
+	})})
+	req, err := http.NewRequest(http.MethodGet, "https://bucket.s3.amazonaws.com/sitemap.xml", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if err := signer.Authorize(context.Background(), req); err == nil || err.Error() != "expired" {
+		t.Fatalf("expected the credentials error, got %v", err)
+	}
+}
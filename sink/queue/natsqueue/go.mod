@@ -0,0 +1,19 @@
+module github.com/enot-style/go-sitemap-fetcher/sink/queue/natsqueue
+
+go 1.25.5
+
+require (
+	github.com/enot-style/go-sitemap-fetcher v0.0.0
+	github.com/nats-io/nats.go v1.48.0
+)
+
+require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/temoto/robotstxt v1.1.2 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+)
+
+replace github.com/enot-style/go-sitemap-fetcher => ../../..
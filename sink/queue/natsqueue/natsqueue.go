@@ -0,0 +1,63 @@
+// Package natsqueue is a queue.Publisher for NATS, the reference publisher for
+// the queue sink. It is a separate module so that the fetcher and package
+// queue do not depend on a NATS client.
+//
+//	conn, err := nats.Connect(nats.DefaultURL)
+//	...
+//	out := queue.New(ctx, natsqueue.New(conn, "sitemap.items"), queue.Options{BatchSize: 500})
+//	err = fetcher.Walk(ctx, website, out.Write)
+//	if closeErr := out.Close(); err == nil {
+//		err = closeErr
+//	}
+package natsqueue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/enot-style/go-sitemap-fetcher/sink/queue"
+	"github.com/nats-io/nats.go"
+)
+
+// KeyHeader is the message header that carries queue.Message.Key, so
+// consumers can route or deduplicate by URL without decoding the value.
+const KeyHeader = "Sitemap-Item-Key"
+
+// Publisher publishes each message of a batch to one subject, then waits for
+// the server to acknowledge the batch with a flush round trip. A batch that
+// fails part way is retried whole by the sink, so consumers may see a message
+// more than once.
+type Publisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// New returns a Publisher that sends to subject over conn. The connection
+// must support headers (NATS 2.2 or later). Closing conn is up to the caller.
+func New(conn *nats.Conn, subject string) *Publisher {
+	return &Publisher{conn: conn, subject: subject}
+}
+
+// Publish implements queue.Publisher. A ctx without a deadline waits for the
+// flush up to the client's default timeout.
+func (p *Publisher) Publish(ctx context.Context, batch []queue.Message) error {
+	for _, m := range batch {
+		msg := &nats.Msg{Subject: p.subject, Data: m.Value}
+		if len(m.Key) > 0 {
+			msg.Header = nats.Header{KeyHeader: []string{string(m.Key)}}
+		}
+		if err := p.conn.PublishMsg(msg); err != nil {
+			return fmt.Errorf("natsqueue: publish to %s: %w", p.subject, err)
+		}
+	}
+	var err error
+	if _, ok := ctx.Deadline(); ok {
+		err = p.conn.FlushWithContext(ctx)
+	} else {
+		err = p.conn.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("natsqueue: flush %s: %w", p.subject, err)
+	}
+	return nil
+}
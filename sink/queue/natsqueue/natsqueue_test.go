@@ -0,0 +1,180 @@
+package natsqueue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/sink/queue"
+	"github.com/nats-io/nats.go"
+)
+
+// published is a message received by fakeServer.
+type published struct {
+	subject string
+	header  string
+	data    []byte
+}
+
+// fakeServer speaks enough of the NATS client protocol to accept a connection
+// and record what is published on it.
+type fakeServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []published
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeServer{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_, _ = io.WriteString(conn, `INFO {"server_id":"fake","version":"2.10.0","proto":1,"headers":true,"max_payload":1048576}`+"\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			_, _ = io.WriteString(conn, "PONG\r\n")
+		case "PUB", "HPUB":
+			// PUB <subject> [reply] <size>; HPUB <subject> [reply] <header size> <size>.
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			headerSize := 0
+			if fields[0] == "HPUB" {
+				headerSize, _ = strconv.Atoi(fields[len(fields)-2])
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, published{
+				subject: fields[1],
+				header:  string(payload[:headerSize]),
+				data:    payload[headerSize:size],
+			})
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *fakeServer) received() []published {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]published(nil), s.messages...)
+}
+
+func TestPublisher(t *testing.T) {
+	server := newFakeServer(t)
+	conn, err := nats.Connect(server.url())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url><url><loc>/c</loc></url></urlset>`))
+	}))
+	defer site.Close()
+	sitemapURL, err := url.Parse(site.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	ctx := context.Background()
+	out := queue.New(ctx, New(conn, "sitemap.items"), queue.Options{BatchSize: 2})
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true})
+	var keys []string
+	err = fetcher.Walk(ctx, sitemapURL, func(item gositemapfetcher.Item) error {
+		keys = append(keys, item.Key())
+		return out.Write(item)
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	// The first batch of two was flushed by the server's PONG before Write
+	// returned; only the last item is still queued.
+	if n := len(server.received()); n != 2 {
+		t.Fatalf("expected the full batch to be acknowledged, got %d messages", n)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.subject != "sitemap.items" {
+			t.Fatalf("expected subject sitemap.items, got %q", msg.subject)
+		}
+		if !strings.Contains(msg.header, KeyHeader+": "+keys[i]+"\r\n") {
+			t.Fatalf("expected key %q in the headers, got %q", keys[i], msg.header)
+		}
+		var item gositemapfetcher.Item
+		if err := json.Unmarshal(msg.data, &item); err != nil {
+			t.Fatalf("failed to decode message %d: %v", i, err)
+		}
+		if item.Key() != keys[i] {
+			t.Fatalf("expected item %q, got %q", keys[i], item.Key())
+		}
+	}
+}
+
+func TestPublisher_ClosedConnection(t *testing.T) {
+	server := newFakeServer(t)
+	conn, err := nats.Connect(server.url())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	conn.Close()
+
+	pub := New(conn, "sitemap.items")
+	err = pub.Publish(context.Background(), []queue.Message{{Value: []byte(`{}`)}})
+	if !errors.Is(err, nats.ErrConnectionClosed) {
+		t.Fatalf("expected a closed-connection error, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package queue publishes walk results to a message queue such as Kafka or
+// NATS, in batches.
+//
+// The package does not link a client. The natsqueue module is a Publisher for
+// NATS; adapt other clients to Publisher, for example
+// github.com/segmentio/kafka-go:
+//
+//	pub := queue.PublisherFunc(func(ctx context.Context, batch []queue.Message) error {
+//		msgs := make([]kafka.Message, len(batch))
+//		for i, m := range batch {
+//			msgs[i] = kafka.Message{Key: m.Key, Value: m.Value}
+//		}
+//		return writer.WriteMessages(ctx, msgs...)
+//	})
+//	out := queue.New(ctx, pub, queue.Options{BatchSize: 500})
+//	err = fetcher.Walk(ctx, website, out.Write)
+//	if closeErr := out.Close(); err == nil {
+//		err = closeErr
+//	}
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const defaultBatchSize = 100
+
+// Message is one item ready for the queue.
+type Message struct {
+	// Key is Item.Key, so partitioned queues keep every copy of a URL on one
+	// partition. It is empty for items without a Loc.
+	Key []byte
+	// Value is the encoded item.
+	Value []byte
+}
+
+// Publisher delivers a batch of messages to the queue. The batch is only
+// valid for the duration of the call.
+type Publisher interface {
+	Publish(ctx context.Context, batch []Message) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, batch []Message) error
+
+// Publish calls fn.
+func (fn PublisherFunc) Publish(ctx context.Context, batch []Message) error {
+	return fn(ctx, batch)
+}
+
+// Options configures the queue sink.
+type Options struct {
+	// BatchSize is the number of messages published per call. 0 => 100.
+	BatchSize int
+	// FlushInterval publishes a partial batch once its oldest message is this
+	// old, so slow walks still feed the queue steadily. It is checked on each
+	// Write. 0 => only full batches are published before Flush or Close.
+	FlushInterval time.Duration
+	// Encode turns an item into a message value. nil => the item's JSON
+	// (Item.MarshalJSON), which consumers decode back into an Item.
+	Encode func(gositemapfetcher.Item) ([]byte, error)
+}
+
+// Sink batches items and hands them to a Publisher. Batches are published
+// from Write, which blocks the walk until Publish returns. It is not safe for
+// concurrent use.
+type Sink struct {
+	ctx     context.Context
+	pub     Publisher
+	opts    Options
+	batch   []Message
+	started time.Time
+}
+
+// New returns a sink whose Write method can be passed to Walk. Call Close to
+// publish the final batch.
+func New(ctx context.Context, pub Publisher, opts Options) *Sink {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Encode == nil {
+		opts.Encode = func(item gositemapfetcher.Item) ([]byte, error) {
+			return json.Marshal(item)
+		}
+	}
+	return &Sink{ctx: ctx, pub: pub, opts: opts}
+}
+
+// Write queues item and publishes the batch once it is full or, with
+// FlushInterval, old enough; pass the method value as the Walk callback.
+func (s *Sink) Write(item gositemapfetcher.Item) error {
+	value, err := s.opts.Encode(item)
+	if err != nil {
+		return fmt.Errorf("queue: encode %s: %w", item.Loc, err)
+	}
+	if len(s.batch) == 0 {
+		s.started = time.Now()
+	}
+	s.batch = append(s.batch, Message{Key: []byte(item.Key()), Value: value})
+	if len(s.batch) >= s.opts.BatchSize ||
+		(s.opts.FlushInterval > 0 && time.Since(s.started) >= s.opts.FlushInterval) {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush publishes the queued messages. On failure they stay queued, so a later
+// Flush or Close retries them; a Publisher that delivered part of the batch
+// before failing delivers that part again.
+func (s *Sink) Flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	if err := s.pub.Publish(s.ctx, s.batch); err != nil {
+		return fmt.Errorf("queue: publish %d messages: %w", len(s.batch), err)
+	}
+	s.batch = s.batch[:0]
+	return nil
+}
+
+// Close publishes the final batch. It does not close the Publisher.
+func (s *Sink) Close() error {
+	return s.Flush()
+}
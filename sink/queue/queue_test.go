@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func testItem(t *testing.T, raw string) gositemapfetcher.Item {
+	t.Helper()
+	loc, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	return gositemapfetcher.Item{Loc: loc, ChangeFreq: gositemapfetcher.ChangeFreqDaily}
+}
+
+// recorder keeps copies of published batches and fails while err is set.
+type recorder struct {
+	batches [][]Message
+	err     error
+}
+
+func (r *recorder) Publish(_ context.Context, batch []Message) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.batches = append(r.batches, append([]Message(nil), batch...))
+	return nil
+}
+
+func TestSink_Batches(t *testing.T) {
+	pub := &recorder{}
+	out := New(context.Background(), pub, Options{BatchSize: 2})
+	for _, raw := range []string{"https://Example.com/a#top", "https://example.com/b", "https://example.com/c"} {
+		if err := out.Write(testItem(t, raw)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(pub.batches) != 1 || len(pub.batches[0]) != 2 {
+		t.Fatalf("expected one full batch before Close, got %v", pub.batches)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if len(pub.batches) != 2 || len(pub.batches[1]) != 1 {
+		t.Fatalf("expected Close to publish the partial batch, got %v", pub.batches)
+	}
+
+	first := pub.batches[0][0]
+	if string(first.Key) != "https://example.com/a" {
+		t.Fatalf("expected the item key, got %q", first.Key)
+	}
+	var item gositemapfetcher.Item
+	if err := json.Unmarshal(first.Value, &item); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if item.Loc.String() != "https://Example.com/a#top" || item.ChangeFreq != gositemapfetcher.ChangeFreqDaily {
+		t.Fatalf("unexpected decoded item: %+v", item)
+	}
+}
+
+func TestSink_FlushInterval(t *testing.T) {
+	pub := &recorder{}
+	out := New(context.Background(), pub, Options{BatchSize: 100, FlushInterval: time.Millisecond})
+	if err := out.Write(testItem(t, "https://example.com/a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := out.Write(testItem(t, "https://example.com/b")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.batches) != 1 || len(pub.batches[0]) != 2 {
+		t.Fatalf("expected an aged partial batch to be published, got %v", pub.batches)
+	}
+}
+
+func TestSink_RetriesFailedBatch(t *testing.T) {
+	boom := errors.New("broker down")
+	pub := &recorder{err: boom}
+	out := New(context.Background(), pub, Options{BatchSize: 1})
+	if err := out.Write(testItem(t, "https://example.com/a")); !errors.Is(err, boom) {
+		t.Fatalf("expected publish error, got %v", err)
+	}
+	pub.err = nil
+	if err := out.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if len(pub.batches) != 1 || string(pub.batches[0][0].Key) != "https://example.com/a" {
+		t.Fatalf("expected the failed batch to be retried, got %v", pub.batches)
+	}
+}
+
+func TestSink_Encode(t *testing.T) {
+	pub := &recorder{}
+	out := New(context.Background(), pub, Options{Encode: func(item gositemapfetcher.Item) ([]byte, error) {
+		return []byte(item.Loc.Path), nil
+	}})
+	if err := out.Write(testItem(t, "https://example.com/a")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if got := string(pub.batches[0][0].Value); got != "/a" {
+		t.Fatalf("expected the custom encoding, got %q", got)
+	}
+}
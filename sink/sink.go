@@ -0,0 +1,114 @@
+// Package sink provides ready-made Walk callbacks that export sitemap items.
+//
+//	out := sink.NewNDJSON(os.Stdout)
+//	err := fetcher.Walk(ctx, website, out.Write)
+package sink
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+// Record is the flat representation of an item written by the sinks.
+type Record struct {
+	Loc        string     `json:"loc"`
+	LastMod    *time.Time `json:"lastmod,omitempty"`
+	ChangeFreq string     `json:"changefreq,omitempty"`
+	Priority   *float64   `json:"priority,omitempty"`
+	Sitemap    string     `json:"sitemap,omitempty"`
+	Depth      int        `json:"depth"`
+}
+
+// NewRecord flattens item into a Record.
+func NewRecord(item gositemapfetcher.Item) Record {
+	record := Record{
+		LastMod:    item.LastMod,
+		ChangeFreq: string(item.ChangeFreq),
+		Priority:   item.Priority,
+		Depth:      item.Depth,
+	}
+	if item.Loc != nil {
+		record.Loc = item.Loc.String()
+	}
+	if item.Sitemap != nil {
+		record.Sitemap = item.Sitemap.String()
+	}
+	return record
+}
+
+// ===================== NDJSON =====================
+
+// NDJSON writes one JSON object per item, newline-delimited.
+type NDJSON struct {
+	enc *json.Encoder
+}
+
+// NewNDJSON returns a sink writing to w.
+func NewNDJSON(w io.Writer) *NDJSON {
+	return &NDJSON{enc: json.NewEncoder(w)}
+}
+
+// Write encodes item; pass the method value as the Walk callback.
+func (s *NDJSON) Write(item gositemapfetcher.Item) error {
+	return s.enc.Encode(NewRecord(item))
+}
+
+// ===================== CSV =====================
+
+// CSVHeader lists the columns written by CSV.
+var CSVHeader = []string{"loc", "lastmod", "changefreq", "priority", "sitemap", "depth"}
+
+// CSV writes a header row followed by one row per item. Call Flush after the walk.
+type CSV struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSV returns a sink writing to w.
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+// Write appends a row for item; pass the method value as the Walk callback.
+func (s *CSV) Write(item gositemapfetcher.Item) error {
+	if !s.wroteHeader {
+		if err := s.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		s.wroteHeader = true
+	}
+	record := NewRecord(item)
+	var lastMod, priority string
+	if record.LastMod != nil {
+		lastMod = record.LastMod.Format(time.RFC3339)
+	}
+	if record.Priority != nil {
+		priority = strconv.FormatFloat(*record.Priority, 'f', -1, 64)
+	}
+	return s.w.Write([]string{
+		record.Loc,
+		lastMod,
+		record.ChangeFreq,
+		priority,
+		record.Sitemap,
+		strconv.Itoa(record.Depth),
+	})
+}
+
+// Flush writes buffered rows (and the header, if no item was written) and
+// reports any write error.
+func (s *CSV) Flush() error {
+	if !s.wroteHeader {
+		if err := s.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		s.wroteHeader = true
+	}
+	s.w.Flush()
+	return s.w.Error()
+}
@@ -0,0 +1,73 @@
+package sink
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func testItems(t *testing.T) []gositemapfetcher.Item {
+	t.Helper()
+	loc, err := url.Parse("https://example.com/a")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	sitemap, err := url.Parse("https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	lastMod := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	priority := 0.8
+	return []gositemapfetcher.Item{
+		{Loc: loc, LastMod: &lastMod, ChangeFreq: "daily", Priority: &priority, Sitemap: sitemap, Depth: 1},
+		{Loc: sitemap.ResolveReference(&url.URL{Path: "/b"}), Sitemap: sitemap, Depth: 1},
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewNDJSON(&buf)
+	for _, item := range testItems(t) {
+		if err := out.Write(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want := `{"loc":"https://example.com/a","lastmod":"2024-01-02T03:04:05Z","changefreq":"daily","priority":0.8,"sitemap":"https://example.com/sitemap.xml","depth":1}
+{"loc":"https://example.com/b","sitemap":"https://example.com/sitemap.xml","depth":1}
+`
+	if buf.String() != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewCSV(&buf)
+	for _, item := range testItems(t) {
+		if err := out.Write(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	want := "loc,lastmod,changefreq,priority,sitemap,depth\n" +
+		"https://example.com/a,2024-01-02T03:04:05Z,daily,0.8,https://example.com/sitemap.xml,1\n" +
+		"https://example.com/b,,,,https://example.com/sitemap.xml,1\n"
+	if buf.String() != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestCSV_EmptyWritesHeader(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewCSV(&buf).Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	if buf.String() != "loc,lastmod,changefreq,priority,sitemap,depth\n" {
+		t.Fatalf("expected header only, got %q", buf.String())
+	}
+}
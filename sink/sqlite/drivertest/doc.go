@@ -0,0 +1,4 @@
+// Package drivertest runs the sqlite sink and visited store against a real
+// SQLite driver. It is a separate module so that package sqlite itself links
+// no driver; its tests need cgo.
+package drivertest
@@ -0,0 +1,223 @@
+//go:build cgo
+
+package drivertest
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+	"github.com/enot-style/go-sitemap-fetcher/sink/sqlite"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openDB opens the SQLite database file at path.
+func openDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// newSite serves an index of two urlsets that share /two.
+func newSite(t *testing.T) *url.URL {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/one</loc><priority>0.5</priority></url><url><loc>/two</loc></url></urlset>`))
+		case "/b.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/two</loc></url><url><loc>/three</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+	return indexURL
+}
+
+func countRows(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	return n
+}
+
+func TestVisitedStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "visited.db")
+	db := openDB(t, path)
+	store, err := sqlite.OpenVisited(ctx, db, sqlite.VisitedOptions{Scope: "a", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("OpenVisited failed: %v", err)
+	}
+	other, err := sqlite.OpenVisited(ctx, db, sqlite.VisitedOptions{Scope: "b"})
+	if err != nil {
+		t.Fatalf("OpenVisited failed: %v", err)
+	}
+	for _, key := range []string{"x", "y", "z"} {
+		if seen, err := store.Visit(ctx, key); err != nil || seen {
+			t.Fatalf("Visit(%q): expected a new key, got %v, %v", key, seen, err)
+		}
+	}
+	if seen, err := store.Visit(ctx, "z"); err != nil || !seen {
+		t.Fatalf("Visit(z) again: expected seen, got %v, %v", seen, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if seen, err := other.Visited(ctx, "x"); err != nil || seen {
+		t.Fatalf("expected scope b not to see scope a's keys, got %v, %v", seen, err)
+	}
+	if err := other.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A new handle on the same file sees the committed keys.
+	restarted, err := sqlite.OpenVisited(ctx, openDB(t, path), sqlite.VisitedOptions{Scope: "a"})
+	if err != nil {
+		t.Fatalf("OpenVisited failed: %v", err)
+	}
+	for _, key := range []string{"x", "y", "z"} {
+		if seen, err := restarted.Visited(ctx, key); err != nil || !seen {
+			t.Fatalf("Visited(%q) after restart: expected seen, got %v, %v", key, seen, err)
+		}
+	}
+	if err := restarted.Reset(ctx); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if seen, err := restarted.Visited(ctx, "x"); err != nil || seen {
+		t.Fatalf("expected Reset to clear the scope, got %v, %v", seen, err)
+	}
+	if err := restarted.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestVisitedStore_SingleConnection(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t, filepath.Join(t.TempDir(), "visited.db"))
+	db.SetMaxOpenConns(1)
+	store, err := sqlite.OpenVisited(ctx, db, sqlite.VisitedOptions{})
+	if err != nil {
+		t.Fatalf("OpenVisited failed: %v", err)
+	}
+	var locs []string
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, DedupeURLs: true, VisitedStore: store})
+	err = fetcher.Walk(ctx, newSite(t), func(item gositemapfetcher.Item) error {
+		locs = append(locs, item.Loc.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(locs) != 3 {
+		t.Fatalf("expected /two once, got %v", locs)
+	}
+	// The open batch holds the only connection until Close commits it.
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM sitemap_visited`); n == 0 {
+		t.Fatal("expected the visited keys to be committed")
+	}
+}
+
+func TestSink(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := openDB(t, filepath.Join(dir, "items.db"))
+	db.SetMaxOpenConns(1)
+	out, err := sqlite.Open(ctx, db, sqlite.Options{WalkID: "run-1", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true})
+	if err := fetcher.Walk(ctx, newSite(t), out.Write); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM sitemap_items WHERE walk_id = ?`, "run-1"); n != 4 {
+		t.Fatalf("expected 4 rows, got %d", n)
+	}
+	var priority float64
+	var depth int
+	if err := db.QueryRow(`SELECT priority, depth FROM sitemap_items WHERE loc LIKE '%/one'`).Scan(&priority, &depth); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if priority != 0.5 || depth != 1 {
+		t.Fatalf("expected priority 0.5 at depth 1, got %v at %d", priority, depth)
+	}
+
+	// A VisitedStore in its own file does not contend for the sink's lock.
+	store, err := sqlite.OpenVisited(ctx, openDB(t, filepath.Join(dir, "visited.db")), sqlite.VisitedOptions{})
+	if err != nil {
+		t.Fatalf("OpenVisited failed: %v", err)
+	}
+	out, err = sqlite.Open(ctx, db, sqlite.Options{WalkID: "run-2", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	fetcher = gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, DedupeURLs: true, VisitedStore: store})
+	if err := fetcher.Walk(ctx, newSite(t), out.Write); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM sitemap_items WHERE walk_id = ?`, "run-2"); n != 3 {
+		t.Fatalf("expected 3 deduplicated rows, got %d", n)
+	}
+}
+
+func TestSink_InvalidLoc(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>http://[::1/broken</loc></url></urlset>`))
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	db := openDB(t, filepath.Join(t.TempDir(), "items.db"))
+	out, err := sqlite.Open(ctx, db, sqlite.Options{WalkID: "run-1"})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, InvalidLoc: gositemapfetcher.InvalidLocEmit})
+	if err := fetcher.Walk(ctx, sitemapURL, out.Write); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM sitemap_items WHERE loc = ?`, "http://[::1/broken"); n != 1 {
+		t.Fatalf("expected the invalid entry stored by its raw text, got %d rows", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM sitemap_items`); n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/enot-style/go-sitemap-fetcher/sink/sqlite/drivertest
+
+go 1.25.5
+
+require (
+	github.com/enot-style/go-sitemap-fetcher v0.0.0
+	github.com/mattn/go-sqlite3 v1.14.33
+)
+
+require github.com/temoto/robotstxt v1.1.2 // indirect
+
+replace github.com/enot-style/go-sitemap-fetcher => ../../..
@@ -0,0 +1,168 @@
+// Package sqlite persists walk results into a SQLite table through database/sql.
+//
+// The package does not link a driver; open db with the SQLite driver of your
+// choice (for example modernc.org/sqlite or github.com/mattn/go-sqlite3):
+//
+//	db, err := sql.Open("sqlite", "sitemap.db")
+//	...
+//	out, err := sqlite.Open(ctx, db, sqlite.Options{WalkID: runID})
+//	...
+//	err = fetcher.Walk(ctx, website, out.Write)
+//	if closeErr := out.Close(); err == nil {
+//		err = closeErr
+//	}
+//
+// OpenVisited stores the fetcher's visited keys in a SQLite table, so loop
+// detection and URL dedupe survive process restarts. It needs a database file
+// of its own; see VisitedStore.
+//
+// The drivertest module runs both against a real driver.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const (
+	defaultTable     = "sitemap_items"
+	defaultBatchSize = 1000
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Options configures the SQLite sink.
+type Options struct {
+	// Table is the table to create and insert into. Empty => "sitemap_items".
+	Table string
+	// WalkID is stored with every row so several walks can share one table.
+	WalkID string
+	// BatchSize is the number of rows committed per transaction. 0 => 1000.
+	BatchSize int
+}
+
+// Sink inserts items into a SQLite table. It is not safe for concurrent use.
+//
+// Rows are inserted in a transaction left open until BatchSize rows are
+// pending, which holds one of db's connections and SQLite's write lock between
+// Writes. Another writer on the same file, such as a VisitedStore, fails with
+// "database is locked" meanwhile, and with db.SetMaxOpenConns(1) any other use
+// of db blocks until the batch commits, which deadlocks if it happens from the
+// walk's own callback.
+type Sink struct {
+	ctx    context.Context
+	db     *sql.DB
+	insert string
+	opts   Options
+	tx     *sql.Tx
+	stmt   *sql.Stmt
+	rows   int
+}
+
+// Open creates the table (and an index on walk_id and loc) if needed and
+// returns a sink whose Write method can be passed to Walk. Call Close to commit
+// the final batch.
+func Open(ctx context.Context, db *sql.DB, opts Options) (*Sink, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Table == "" {
+		opts.Table = defaultTable
+	}
+	if !identifierPattern.MatchString(opts.Table) {
+		return nil, fmt.Errorf("sqlite: invalid table name %q", opts.Table)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	schema := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	walk_id    TEXT NOT NULL,
+	loc        TEXT NOT NULL,
+	lastmod    TEXT,
+	changefreq TEXT,
+	priority   REAL,
+	sitemap    TEXT,
+	depth      INTEGER NOT NULL
+)`, opts.Table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_walk_loc ON %s (walk_id, loc)`, opts.Table, opts.Table),
+	}
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("sqlite: create table: %w", err)
+		}
+	}
+	return &Sink{
+		ctx:    ctx,
+		db:     db,
+		opts:   opts,
+		insert: fmt.Sprintf(`INSERT INTO %s (walk_id, loc, lastmod, changefreq, priority, sitemap, depth) VALUES (?, ?, ?, ?, ?, ?, ?)`, opts.Table),
+	}, nil
+}
+
+// Write inserts item; pass the method value as the Walk callback. An entry
+// emitted under InvalidLocEmit has no Loc, so its RawLoc is stored instead.
+func (s *Sink) Write(item gositemapfetcher.Item) error {
+	if s.tx == nil {
+		tx, err := s.db.BeginTx(s.ctx, nil)
+		if err != nil {
+			return fmt.Errorf("sqlite: begin: %w", err)
+		}
+		stmt, err := tx.PrepareContext(s.ctx, s.insert)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("sqlite: prepare insert: %w", err)
+		}
+		s.tx, s.stmt = tx, stmt
+	}
+
+	var lastMod, sitemap, changeFreq any
+	var priority any
+	loc := item.RawLoc
+	if item.Loc != nil {
+		loc = item.Loc.String()
+	}
+	if item.LastMod != nil {
+		lastMod = item.LastMod.Format(time.RFC3339)
+	}
+	if item.ChangeFreq != "" {
+		changeFreq = string(item.ChangeFreq)
+	}
+	if item.Priority != nil {
+		priority = *item.Priority
+	}
+	if item.Sitemap != nil {
+		sitemap = item.Sitemap.String()
+	}
+	if _, err := s.stmt.ExecContext(s.ctx, s.opts.WalkID, loc, lastMod, changeFreq, priority, sitemap, item.Depth); err != nil {
+		return fmt.Errorf("sqlite: insert %s: %w", loc, err)
+	}
+	s.rows++
+	if s.rows >= s.opts.BatchSize {
+		return s.commit()
+	}
+	return nil
+}
+
+// Close commits any pending rows. It does not close the database.
+func (s *Sink) Close() error {
+	return s.commit()
+}
+
+func (s *Sink) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	tx, stmt := s.tx, s.stmt
+	s.tx, s.stmt, s.rows = nil, nil, 0
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+// recordingDriver is a minimal database/sql driver that records executed
+// statements, standing in for a real SQLite driver.
+type recordingDriver struct {
+	mu      sync.Mutex
+	execs   []recordedExec
+	commits int
+}
+
+type recordedExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d: d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return &recordingTx{d: c.d}, nil }
+
+type recordingTx struct{ d *recordingDriver }
+
+func (t *recordingTx) Commit() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	t.d.commits++
+	return nil
+}
+func (t *recordingTx) Rollback() error { return nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, recordedExec{query: s.query, args: args})
+	return driver.RowsAffected(1), nil
+}
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+var registerOnce sync.Once
+var testDriver = &recordingDriver{}
+
+func openTestDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("sqlite-recording", testDriver) })
+	testDriver.mu.Lock()
+	testDriver.execs, testDriver.commits = nil, 0
+	testDriver.mu.Unlock()
+	db, err := sql.Open("sqlite-recording", "")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, testDriver
+}
+
+func TestSink_InsertsItemsInBatches(t *testing.T) {
+	db, d := openTestDB(t)
+	out, err := Open(context.Background(), db, Options{Table: "items", WalkID: "run-1", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loc, _ := url.Parse("https://example.com/a")
+	sitemap, _ := url.Parse("https://example.com/sitemap.xml")
+	lastMod := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	priority := 0.5
+	items := []gositemapfetcher.Item{
+		{Loc: loc, LastMod: &lastMod, ChangeFreq: "weekly", Priority: &priority, Sitemap: sitemap},
+		{Loc: loc, Sitemap: sitemap, Depth: 1},
+		{Loc: loc, Sitemap: sitemap, Depth: 2},
+	}
+	for _, item := range items {
+		if err := out.Write(item); err != nil {
+			t.Fatalf("unexpected write error: %v", err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	if len(d.execs) != 5 {
+		t.Fatalf("expected 2 schema statements and 3 inserts, got %d", len(d.execs))
+	}
+	if !strings.HasPrefix(d.execs[0].query, "CREATE TABLE IF NOT EXISTS items") {
+		t.Fatalf("unexpected schema statement %q", d.execs[0].query)
+	}
+	first := d.execs[2]
+	if !strings.HasPrefix(first.query, "INSERT INTO items") {
+		t.Fatalf("unexpected insert statement %q", first.query)
+	}
+	want := []driver.Value{"run-1", "https://example.com/a", "2024-01-02T00:00:00Z", "weekly", 0.5, "https://example.com/sitemap.xml", int64(0)}
+	for i := range want {
+		if first.args[i] != want[i] {
+			t.Fatalf("arg %d: expected %v, got %v", i, want[i], first.args[i])
+		}
+	}
+	if d.execs[3].args[2] != nil || d.execs[3].args[4] != nil {
+		t.Fatalf("expected NULL lastmod and priority, got %v", d.execs[3].args)
+	}
+	if d.commits != 2 {
+		t.Fatalf("expected 2 commits, got %d", d.commits)
+	}
+}
+
+func TestOpen_RejectsInvalidTable(t *testing.T) {
+	db, _ := openTestDB(t)
+	if _, err := Open(context.Background(), db, Options{Table: "items; DROP TABLE x"}); err == nil {
+		t.Fatalf("expected error for invalid table name")
+	}
+}
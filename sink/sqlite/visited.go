@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const defaultVisitedTable = "sitemap_visited"
+
+// VisitedOptions configures a VisitedStore.
+type VisitedOptions struct {
+	// Table is the table to create and use. Empty => "sitemap_visited".
+	Table string
+	// Scope separates independent crawls sharing one table; keys recorded
+	// under one scope are invisible to the others.
+	Scope string
+	// BatchSize is the number of new keys committed per transaction. 0 => 1000.
+	// Keys recorded since the last commit are lost if the process dies, so a
+	// restarted crawl may fetch or emit them again.
+	BatchSize int
+}
+
+// VisitedStore is a gositemapfetcher.VisitedStore kept in a SQLite table, so
+// loop detection and Options.DedupeURLs survive process restarts:
+//
+//	store, err := sqlite.OpenVisited(ctx, db, sqlite.VisitedOptions{Scope: "example.com"})
+//	...
+//	fetcher := gositemapfetcher.New(gositemapfetcher.Options{VisitedStore: store, DedupeURLs: true})
+//	err = fetcher.Walk(ctx, website, handle)
+//	if closeErr := store.Close(); err == nil {
+//		err = closeErr
+//	}
+//
+// It is safe for concurrent use; calls are serialized on one transaction.
+// That transaction stays open between calls until BatchSize keys are pending,
+// holding one of db's connections and SQLite's write lock. Keep the store in a
+// database file of its own: a Sink or any other writer on the same file fails
+// with "database is locked" while a batch is open. With db.SetMaxOpenConns(1),
+// any other use of db blocks until the batch commits, which deadlocks if it
+// happens from the walk's own callback.
+type VisitedStore struct {
+	db      *sql.DB
+	opts    VisitedOptions
+	insert  string
+	lookup  string
+	reset   string
+	mu      sync.Mutex
+	tx      *sql.Tx
+	pending int
+}
+
+var _ gositemapfetcher.VisitedStore = (*VisitedStore)(nil)
+
+// OpenVisited creates the table if needed and returns a store backed by it.
+// Call Close to commit the final batch.
+func OpenVisited(ctx context.Context, db *sql.DB, opts VisitedOptions) (*VisitedStore, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Table == "" {
+		opts.Table = defaultVisitedTable
+	}
+	if !identifierPattern.MatchString(opts.Table) {
+		return nil, fmt.Errorf("sqlite: invalid table name %q", opts.Table)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	schema := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	scope TEXT NOT NULL,
+	key   TEXT NOT NULL,
+	PRIMARY KEY (scope, key)
+)`, opts.Table)
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("sqlite: create table: %w", err)
+	}
+	return &VisitedStore{
+		db:     db,
+		opts:   opts,
+		insert: fmt.Sprintf(`INSERT OR IGNORE INTO %s (scope, key) VALUES (?, ?)`, opts.Table),
+		lookup: fmt.Sprintf(`SELECT 1 FROM %s WHERE scope = ? AND key = ?`, opts.Table),
+		reset:  fmt.Sprintf(`DELETE FROM %s WHERE scope = ?`, opts.Table),
+	}, nil
+}
+
+// Visit implements gositemapfetcher.VisitedStore.
+func (s *VisitedStore) Visit(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	result, err := tx.ExecContext(ctx, s.insert, s.opts.Scope, key)
+	if err != nil {
+		return false, fmt.Errorf("sqlite: insert %q: %w", key, err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("sqlite: insert %q: %w", key, err)
+	}
+	if inserted == 0 {
+		return true, nil
+	}
+	s.pending++
+	if s.pending >= s.opts.BatchSize {
+		return false, s.commit()
+	}
+	return false, nil
+}
+
+// Visited implements gositemapfetcher.VisitedStore.
+func (s *VisitedStore) Visited(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	var one int
+	err = tx.QueryRowContext(ctx, s.lookup, s.opts.Scope, key).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("sqlite: lookup %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// Reset deletes every key of the store's scope.
+func (s *VisitedStore) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.commit(); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.reset, s.opts.Scope); err != nil {
+		return fmt.Errorf("sqlite: reset: %w", err)
+	}
+	return nil
+}
+
+// Close commits any pending keys. It does not close the database.
+func (s *VisitedStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit()
+}
+
+func (s *VisitedStore) begin(ctx context.Context) (*sql.Tx, error) {
+	if s.tx != nil {
+		return s.tx, nil
+	}
+	// The transaction outlives ctx, which belongs to a single call.
+	tx, err := s.db.BeginTx(context.WithoutCancel(ctx), nil)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: begin: %w", err)
+	}
+	s.tx = tx
+	return tx, nil
+}
+
+func (s *VisitedStore) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	tx := s.tx
+	s.tx, s.pending = nil, 0
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+// keyDriver is a database/sql driver that understands the statements issued
+// by VisitedStore, standing in for a real SQLite driver. Committed and
+// in-transaction rows are not distinguished.
+type keyDriver struct {
+	mu      sync.Mutex
+	rows    map[string]bool
+	commits int
+}
+
+func (d *keyDriver) Open(string) (driver.Conn, error) { return &keyConn{d: d}, nil }
+
+type keyConn struct{ d *keyDriver }
+
+func (c *keyConn) Prepare(query string) (driver.Stmt, error) {
+	return &keyStmt{d: c.d, query: query}, nil
+}
+func (c *keyConn) Close() error              { return nil }
+func (c *keyConn) Begin() (driver.Tx, error) { return &keyTx{d: c.d}, nil }
+
+type keyTx struct{ d *keyDriver }
+
+func (t *keyTx) Commit() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	t.d.commits++
+	return nil
+}
+func (t *keyTx) Rollback() error { return nil }
+
+type keyStmt struct {
+	d     *keyDriver
+	query string
+}
+
+func (s *keyStmt) Close() error  { return nil }
+func (s *keyStmt) NumInput() int { return -1 }
+
+func (s *keyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT OR IGNORE"):
+		row := fmt.Sprint(args[0], "\x00", args[1])
+		if s.d.rows[row] {
+			return driver.RowsAffected(0), nil
+		}
+		s.d.rows[row] = true
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		prefix := fmt.Sprint(args[0], "\x00")
+		for row := range s.d.rows {
+			if strings.HasPrefix(row, prefix) {
+				delete(s.d.rows, row)
+			}
+		}
+		return driver.RowsAffected(0), nil
+	}
+	return nil, fmt.Errorf("unexpected statement %q", s.query)
+}
+
+func (s *keyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return &keyRows{found: s.d.rows[fmt.Sprint(args[0], "\x00", args[1])]}, nil
+}
+
+type keyRows struct{ found bool }
+
+func (r *keyRows) Columns() []string { return []string{"1"} }
+func (r *keyRows) Close() error      { return nil }
+func (r *keyRows) Next(dest []driver.Value) error {
+	if !r.found {
+		return io.EOF
+	}
+	r.found = false
+	dest[0] = int64(1)
+	return nil
+}
+
+var registerKeyOnce sync.Once
+var keyTestDriver = &keyDriver{}
+
+func openKeyDB(t *testing.T) (*sql.DB, *keyDriver) {
+	t.Helper()
+	registerKeyOnce.Do(func() { sql.Register("sqlite-keys", keyTestDriver) })
+	keyTestDriver.mu.Lock()
+	keyTestDriver.rows, keyTestDriver.commits = map[string]bool{}, 0
+	keyTestDriver.mu.Unlock()
+	db, err := sql.Open("sqlite-keys", "")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, keyTestDriver
+}
+
+func TestVisitedStore_VisitAndScope(t *testing.T) {
+	ctx := context.Background()
+	db, d := openKeyDB(t)
+	store, err := OpenVisited(ctx, db, VisitedOptions{Scope: "a", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, want := range []bool{false, true} {
+		seen, err := store.Visit(ctx, "url:https://example.com/")
+		if err != nil {
+			t.Fatalf("visit %d: unexpected error: %v", i, err)
+		}
+		if seen != want {
+			t.Fatalf("visit %d: expected seen=%v, got %v", i, want, seen)
+		}
+	}
+	if seen, err := store.Visited(ctx, "url:https://example.com/other"); err != nil || seen {
+		t.Fatalf("expected an unknown key, got %v, %v", seen, err)
+	}
+	if _, err := store.Visit(ctx, "url:https://example.com/other"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.commits != 1 {
+		t.Fatalf("expected a commit after 2 new keys, got %d", d.commits)
+	}
+
+	other, err := OpenVisited(ctx, db, VisitedOptions{Scope: "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen, err := other.Visited(ctx, "url:https://example.com/"); err != nil || seen {
+		t.Fatalf("expected scopes to be independent, got %v, %v", seen, err)
+	}
+	if err := other.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	if err := store.Reset(ctx); err != nil {
+		t.Fatalf("unexpected reset error: %v", err)
+	}
+	if seen, err := store.Visited(ctx, "url:https://example.com/"); err != nil || seen {
+		t.Fatalf("expected Reset to forget keys, got %v, %v", seen, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+}
+
+func TestVisitedStore_SurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	sitemaps := map[string]string{
+		"/sitemap.xml": `<sitemapindex><sitemap><loc>/a.xml</loc></sitemap></sitemapindex>`,
+		"/a.xml":       `<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`,
+	}
+	var fetches int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := sitemaps[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	db, _ := openKeyDB(t)
+	walk := func() int {
+		store, err := OpenVisited(ctx, db, VisitedOptions{Scope: "crawl"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, VisitedStore: store, DedupeURLs: true})
+		var items int
+		err = fetcher.Walk(ctx, sitemapURL, func(gositemapfetcher.Item) error {
+			items++
+			return nil
+		})
+		if closeErr := store.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			t.Fatalf("walk failed: %v", err)
+		}
+		return items
+	}
+	if got := walk(); got != 2 {
+		t.Fatalf("expected 2 items on the first run, got %d", got)
+	}
+	if got := walk(); got != 0 {
+		t.Fatalf("expected the restarted crawl to skip recorded sitemaps, got %d items", got)
+	}
+	if fetches != 2 {
+		t.Fatalf("expected 2 sitemap fetches in total, got %d", fetches)
+	}
+}
+
+func TestOpenVisited_RejectsInvalidTable(t *testing.T) {
+	db, _ := openKeyDB(t)
+	if _, err := OpenVisited(context.Background(), db, VisitedOptions{Table: "visited; DROP TABLE x"}); err == nil {
+		t.Fatalf("expected error for invalid table name")
+	}
+}
@@ -2,19 +2,24 @@ package gositemapfetcher
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/temoto/robotstxt"
@@ -26,6 +31,10 @@ const (
 	maxRetryAttempts  = 3
 	defaultRetryDelay = 5 * time.Second
 	maxRetryDelay     = 30 * time.Second
+	maxRedirects      = 10
+	maxGzipLayers     = 3
+
+	defaultWatchInterval = time.Minute
 )
 
 // ===================== Configuration =====================
@@ -42,18 +51,590 @@ type Options struct {
 	UserAgent         string
 	PerRequestTimeout time.Duration
 	Logger            *slog.Logger
+	// WalkIDHeader, if set, names a request header (typically "X-Request-ID")
+	// that carries the walk ID on every request. Each walk generates an ID
+	// unless its context already has one from WithWalkID; log records from the
+	// walk carry it as walk_id.
+	WalkIDHeader string
+	// LogLevel replaces the minimum level of Logger's handler: slog.LevelDebug
+	// logs every request and parsed sitemap, while slog.LevelWarn keeps only
+	// warnings and errors. Nil leaves filtering to the handler.
+	LogLevel slog.Leveler
+	// SummaryLevel, if set, makes every walk log one "walk finished" record at
+	// that level when it returns: its duration, URL, sitemap, and skipped
+	// sitemap counts, bytes downloaded, the slowest sitemap, and the error, if
+	// any. slog.LevelInfo suits a one-line outcome per job; LogLevel still
+	// applies.
+	SummaryLevel slog.Leveler
+	// ProgressLogInterval, if positive, makes a walk log a "walk progress"
+	// record at info level at most this often: URLs yielded, sitemaps fetched
+	// and queued, bytes downloaded, elapsed time, and the URL rate since the
+	// previous record. Progress is checked as items are delivered and sitemaps
+	// finish, so a single slow download delays the next record.
+	ProgressLogInterval time.Duration
+	// TraceRequests logs every HTTP request the fetcher sends, including
+	// robots.txt, redirects, retries, and Verify checks, as one "http request"
+	// debug record when its body is closed: method, URL, attempt, status,
+	// protocol, time to headers, bytes read, and total duration. A request
+	// that fails before a response logs "http request failed" instead. The
+	// records need a debug-level Logger or LogLevel.
+	TraceRequests bool
+	// NetworkTimings records DNS, connect, TLS, and time-to-first-byte timings
+	// for each sitemap fetch with net/http/httptrace, reported as
+	// SitemapStat.Network in SitemapStats and EventSitemapFinished.
+	NetworkTimings bool
+	// SlowFetchWarning, if positive, logs a warning for every sitemap whose
+	// fetch, read, and parse time (excluding time in the callback) exceeds
+	// it, with the timing breakdown of its SitemapStat.
+	SlowFetchWarning time.Duration
+
+	// Protocol selects HTTP/1.1, HTTP/2, or HTTP/3 instead of letting the
+	// transport negotiate. ProtocolHTTP1 and ProtocolHTTP2 adapt a clone of
+	// HTTPClient's *http.Transport (or http.DefaultTransport). ProtocolHTTP3
+	// needs HTTP3Transport, an HTTP/3 RoundTripper the caller supplies, such as
+	// quic-go's http3.Transport, and uses it only for hosts that advertise h3
+	// via Alt-Svc. Otherwise walks fail with ErrUnsupportedTransport.
+	Protocol       HTTPProtocol
+	HTTP3Transport http.RoundTripper
+
+	// DialOverrides pins hosts to addresses, host → "IP:port" (or just "IP" to
+	// keep the URL's port), for fetching from one origin behind a load balancer
+	// or before a DNS cutover. Keys may also be "host:port" to pin a single
+	// port. The Host header and TLS server name still use the URL's host. It
+	// applies to a clone of HTTPClient's *http.Transport (or
+	// http.DefaultTransport); with any other transport, walks fail with
+	// ErrUnsupportedTransport rather than reach the resolved hosts.
+	DialOverrides map[string]string
+
+	// WalkTimeout bounds the whole traversal, unlike PerRequestTimeout. When it
+	// expires the walk stops and returns ErrWalkTimeout; items already yielded
+	// stand. 0 => no limit beyond the caller's context.
+	WalkTimeout time.Duration
+
+	// Delay is the minimum pause between successive requests to the same host,
+	// independent of any rate limiting, and DelayJitter adds a random extra pause
+	// of up to that much to each gap. Both apply across concurrent walks of one
+	// fetcher. 0 => no pause.
+	Delay       time.Duration
+	DelayJitter time.Duration
+
+	// RobotsFailure decides what a robots.txt that cannot be read (5xx status,
+	// timeout, unreachable host) means. RobotsAssumeAllow is the default; a 4xx
+	// status always allows everything.
+	RobotsFailure RobotsFailurePolicy
+
+	// ErrorOnRobotsDisallowed fails the walk with ErrRobotsDisallowed when
+	// robots.txt blocks a start sitemap (the input, or one listed in robots.txt),
+	// instead of silently yielding nothing; OnError may continue past it. Blocked
+	// well-known paths probed during discovery only fail the walk if no other
+	// sitemap was found.
+	ErrorOnRobotsDisallowed bool
+
+	// RobotsUserAgent is the token matched against robots.txt User-agent groups
+	// ("Googlebot"). The User-Agent header still carries UserAgent. "" => UserAgent.
+	RobotsUserAgent string
+
+	// RobotsCache keeps fetched robots.txt files across walks and can be shared
+	// between fetchers. If it is nil and RobotsCacheTTL > 0, the fetcher keeps
+	// its own in-memory cache with that TTL. Otherwise each walk fetches
+	// robots.txt once per host.
+	RobotsCache    RobotsCache
+	RobotsCacheTTL time.Duration
+
+	// StopAtMaxURLs ends the walk with a nil error once MaxURLs items have been
+	// yielded, instead of returning ErrMaxURLs. ReachedMaxURLs reports whether it fired.
+	StopAtMaxURLs bool
+
+	// OnCheckpoint receives a serializable snapshot of walk progress after every
+	// sitemap and, when CheckpointEvery > 0, after every CheckpointEvery items.
+	// Returning an error aborts the walk with ErrCheckpoint.
+	OnCheckpoint    func(Checkpoint) error
+	CheckpointEvery int
+	// Resume continues an interrupted walk from a checkpoint of the same input URL.
+	Resume *Checkpoint
+
+	// VisitedStore records visited sitemaps and, with DedupeURLs, emitted URLs.
+	// nil uses a fresh in-memory store per walk. A store kept across walks or
+	// runs (disk, Redis) makes later walks skip what earlier ones recorded;
+	// checkpoints then leave Visited empty, since the store has it.
+	VisitedStore VisitedStore
+	// DedupeURLs emits each Loc (compared by Item.Key) at most once per walk,
+	// or per VisitedStore when one is shared. Memory use of the default store
+	// grows with the number of distinct URLs.
+	DedupeURLs bool
+	// RetainURLs keeps the URLs delivered by each Walk on this fetcher, so
+	// later walks emit only URLs no earlier walk delivered, as for periodic
+	// re-walks. Sitemaps are still fetched on every walk. Reset forgets the
+	// retained set; memory grows with the number of distinct URLs.
+	RetainURLs bool
+	// SitemapStates turns on incremental walks. Each walk that completes saves,
+	// per sitemap read, the <lastmod> its index declared and the response's
+	// ETag and Last-Modified. Later walks skip a sitemap whose declared
+	// <lastmod> is unchanged without requesting it, and make conditional
+	// requests for the rest, skipping those answered 304 Not Modified. An
+	// unchanged index is not re-read, but the children it listed last time
+	// are still visited, each with its own checks. A walk that skips sitemaps
+	// or stops early saves nothing. nil reads every sitemap.
+	SitemapStates SitemapStateStore
+
+	// Strict fails a sitemap with ErrSpecViolation on the first protocol violation
+	// Validate would report (bad lastmod or priority, wrong-host loc, missing
+	// sitemap namespace, ...). OnError may continue past it.
+	Strict bool
+
+	// StrictNamespaces fails a urlset or sitemapindex whose namespace is not
+	// exactly SitemapNamespace with ErrSitemapParse. By default common variants
+	// (https, a trailing slash, legacy 0.84/0.90 namespaces, no namespace) are
+	// accepted, since real sitemaps often get the namespace subtly wrong, and
+	// a document in any other namespace is decoded by local element names
+	// with a warning.
+	StrictNamespaces bool
+
+	// Verify, if set, checks every emitted Loc with a HEAD (or ranged GET)
+	// request and attaches the outcome to Item.LinkCheck before yielding it.
+	Verify *VerifyOptions
+
+	// Archive, if set, is called for every successfully fetched sitemap and
+	// receives the raw response body as it streams through the parser. The
+	// writer is closed when the sitemap is done; if parsing stopped early, the
+	// rest of the body is read into it first, and a body that cannot be read
+	// in full goes to ArchiveAborter instead. ArchiveDir stores bodies on
+	// disk. Failures abort the walk with ErrArchive.
+	Archive func(ArchiveRecord) (io.WriteCloser, error)
+
+	// MaxURLsPerSitemap caps <url> entries in a single sitemap file (0 = no limit;
+	// SpecMaxURLsPerSitemap is the protocol limit). MaxURLsPerSitemapPolicy decides
+	// whether exceeding it fails, truncates, or only warns.
+	MaxURLsPerSitemap       int
+	MaxURLsPerSitemapPolicy LimitPolicy
+	// MaxTotalBytes caps the sitemap bytes downloaded by one walk, as received
+	// on the wire (0 = no limit). The walk ends with ErrMaxTotalBytes once the
+	// budget is exceeded; items yielded before that point stand. Bytes read
+	// ahead under FetchConcurrency count as they arrive, and downloads still
+	// in flight are canceled once the budget is gone.
+	MaxTotalBytes int64
+	// WarnURLsPerSitemap and WarnSitemapBytes are soft limits: a sitemap with
+	// more entries (<url> or, in an index, <sitemap>) or more uncompressed
+	// bytes than either is logged as a warning with its counts, so shards
+	// approaching SpecMaxURLsPerSitemap or SpecMaxSitemapBytes are noticed
+	// before they break. The walk is not affected. 0 => no warning.
+	WarnURLsPerSitemap int
+	WarnSitemapBytes   int64
+
+	// InvalidLoc decides what happens to a <loc> that cannot be parsed as a
+	// URL: InvalidLocSkip (default) drops it with a warning, InvalidLocEmit
+	// yields it with a nil Loc and the reason in ValidationErrors, and
+	// InvalidLocError fails the sitemap with ErrInvalidLoc.
+	InvalidLoc InvalidLocPolicy
+
+	// FlagChangeFreq records a <changefreq> outside the protocol's values in
+	// Item.ValidationErrors. The entry is still yielded with the value as given.
+	FlagChangeFreq bool
+
+	// PriorityRange decides what StageNormalize does with a <priority> outside
+	// 0.0–1.0: PriorityKeep (default) passes it through, PriorityClamp clamps
+	// it into range, PriorityDrop leaves Item.Priority nil, and PriorityReport
+	// keeps it and records the problem in Item.ValidationErrors.
+	PriorityRange PriorityPolicy
+
+	// DefaultChangeFreq and DefaultPriority are what StageNormalize sets
+	// Item.ChangeFreq and Item.Priority to for a <url> that omits (or leaves
+	// empty) <changefreq> or <priority>, so consumers need no nil handling.
+	// Item.RawChangeFreq stays empty for filled-in values. Zero values leave
+	// missing fields unset.
+	DefaultChangeFreq ChangeFreq
+	DefaultPriority   *float64
+
+	// LastModLocation converts LastMod and SitemapLastMod into this location
+	// (time.UTC for UTC) so values compare consistently. Values without an
+	// explicit offset, such as a bare date, are read as local to it. nil keeps
+	// values as parsed: their own offset, or UTC when they have none.
+	LastModLocation *time.Location
+
+	// AllowedSchemes lists the URL schemes a <loc> may use to be emitted or, in
+	// a sitemapindex or robots.txt, followed. Entries with other schemes, such as
+	// ftp: or javascript:, are dropped. Matching is case-insensitive.
+	// nil => http and https.
+	AllowedSchemes []string
 
 	Include []*regexp.Regexp // nil => include all
 	Exclude []*regexp.Regexp // nil => exclude none
+
+	// Transform rewrites or drops items before Include/Exclude, robots.txt, and
+	// the callback see them (e.g. to map staging hosts to production). Return
+	// false, or an Item with a nil Loc, to drop the item.
+	Transform func(Item) (Item, bool)
+
+	// SampleRate emits each item with this probability (0 => disabled), and
+	// SampleN emits a uniform random sample of at most N items, delivered in
+	// walk order once every sitemap has been read, or when the walk ends early
+	// with partial results (a limit, WalkTimeout, a failed sitemap); only a
+	// callback error or a canceled ctx discards it. Both consider items that
+	// passed filters and robots.txt; SampleSeed makes the choice reproducible
+	// (0 => random seed).
+	SampleRate float64
+	SampleN    int
+	SampleSeed uint64
+
+	// StripQueryParams names query parameters removed from every resolved Loc
+	// before filtering, e.g. DefaultTrackingParams. A trailing "*" matches any
+	// parameter with that prefix ("utm_*"). nil => keep all parameters.
+	StripQueryParams []string
+
+	// OnError is called when a sitemap fails to fetch or parse and the failure would
+	// otherwise end the walk. Return nil to continue with the next sitemap, or an
+	// error to abort the walk with it. Limit, yield, and context errors are not passed.
+	OnError func(sitemap *url.URL, err error) error
+
+	// Auth adds credentials to requests for sitemaps, robots.txt, and link
+	// checks, for sitemaps behind gateways that want short-lived tokens; see
+	// NewBearerAuth for OAuth2-style tokens. nil sends no credentials.
+	Auth AuthProvider
+
+	// OnEvent receives the walk's lifecycle events (sitemap started, finished,
+	// and skipped; item emitted; walk finished) in one stream, for progress UIs
+	// and observability. It runs synchronously, so a slow OnEvent slows the
+	// walk; SendEvents adapts a channel.
+	OnEvent func(Event)
+
+	// AggregateErrors makes an otherwise successful Walk return *WalkErrors listing
+	// every sitemap skipped by SkipNon200, StatusPolicy, SkipFetchErrors, or OnError.
+	AggregateErrors bool
+
+	// ReuseItems reuses the storage behind Item.LastMod and Item.Priority for
+	// every item of a sitemap, and shares one Item.Sitemap among them, saving a
+	// few allocations per item. Callbacks must not retain those pointers after
+	// returning. Item.Loc is still parsed into a new URL for each item and may be
+	// kept. LastMod and Priority are not reused when Verify, CallbackConcurrency,
+	// or SampleN hold items past the callback.
+	ReuseItems bool
+
+	// KeepExtensions attaches unrecognized child elements of <url> (image, video,
+	// PageMap, or custom namespaces) to Item.Extensions.
+	KeepExtensions bool
+
+	// ExtensionDecoders maps namespace URIs to decoders. Matching <url> child elements
+	// are decoded and attached to Item.Ext under their namespace.
+	ExtensionDecoders map[string]ExtensionDecoder
+
+	// ContentDecoders maps Content-Encoding tokens ("br", "zstd") to decoders
+	// for sitemap and robots.txt responses. gzip and deflate are built in;
+	// encodings.Decoders, in the separate encodings module, supplies brotli
+	// and zstd. Responses with any other encoding fail with
+	// ErrUnsupportedEncoding.
+	ContentDecoders map[string]ContentDecoder
+	// AdvertiseEncodings sends an Accept-Encoding header listing every
+	// ContentDecoders key plus gzip and deflate, so servers may pick brotli or
+	// zstd. By default the HTTP client's transport negotiates gzip itself.
+	AdvertiseEncodings bool
+	// HTMLFallback makes a sitemap URL that returns an HTML page, such as a
+	// soft-404, a source of sitemaps instead of a failure: the page is
+	// scanned for <link rel="sitemap"> elements and for links to files named
+	// like sitemaps (sitemap.xml, sitemap_index.xml.gz, ...), which are walked
+	// in its place. Missing ones are ignored, as for probes. Fetch does not
+	// follow them.
+	HTMLFallback bool
+	// ContentTypePolicy decides what happens to a sitemap response whose
+	// Content-Type is not a sitemap format, such as text/html or
+	// application/octet-stream. The default, ContentTypeSniff, parses the body
+	// by its content. WithContentTypePolicy overrides it for one walk.
+	ContentTypePolicy ContentTypePolicy
+	// AcceptEncoding, if set, is sent as the Accept-Encoding header of every
+	// request in place of the default negotiation and AdvertiseEncodings.
+	// "identity" asks for uncompressed responses, for origins that mishandle
+	// gzip. Encoded responses are still decoded.
+	AcceptEncoding string
+	// TransportDecoding leaves Content-Encoding to the HTTP client's
+	// transport, such as one that decompresses in middleware: the fetcher
+	// neither advertises nor decodes encodings itself, so ContentDecoders and
+	// AdvertiseEncodings are ignored, and a response that arrives still
+	// encoded fails with ErrUnsupportedEncoding. Gzip files (.xml.gz) are
+	// still recognized by their content.
+	TransportDecoding bool
+
+	// Formats lists the document formats to parse in addition to XML sitemaps, such as
+	// text or feed files declared in robots.txt. Other formats are recorded in
+	// SkippedSitemaps with ErrUnsupportedFormat. nil => XML only.
+	Formats []SitemapFormat
+
+	// Discovery controls how sitemaps are located when the input is not a sitemap URL.
+	Discovery DiscoveryMode
+
+	// DetectNestedIndexes makes WalkSitemaps request every sitemap an index
+	// lists, reading it up to its root element, to tell nested indexes apart
+	// and report their children too. Without it only the input and discovered
+	// sitemaps are requested, and listed ones are reported from the index
+	// entries with Index false.
+	DetectNestedIndexes bool
+
+	// FetchConcurrency downloads up to this many queued sitemaps at once. Bodies
+	// fetched ahead of their turn are buffered in memory, up to
+	// SpecMaxSitemapBytes each (a larger one fails with ErrSitemapTooLarge),
+	// and items are still delivered one at a time in the same order as a
+	// sequential walk. 0 or 1 => sequential. Archive must be safe for
+	// concurrent use when it is > 1.
+	FetchConcurrency int
+
+	// ConcurrencyPerHost caps concurrent sitemap downloads from any one host
+	// (scheme and host:port), across every walk of this fetcher. A slot is held
+	// until the body has been read, so with FetchConcurrency a multi-host walk
+	// can run wide while each origin sees at most this many downloads at once.
+	// 0 => no per-host limit.
+	ConcurrencyPerHost int
+
+	// CallbackConcurrency calls the Walk callback from up to this many goroutines
+	// at once, so slow per-item work does not serialize the walk. The callback
+	// must then be safe for concurrent use, and items may complete out of order.
+	// Walk waits for every callback before returning. 0 or 1 => one at a time.
+	CallbackConcurrency int
+	// CallbackBuffer is how many parsed items may wait for a free callback
+	// goroutine under CallbackConcurrency. Once it is full, parsing and
+	// fetching pause until a callback returns, so a slow callback throttles
+	// the walk instead of items piling up in memory. Buffered items are
+	// dropped when a callback stops the walk or skips their sitemap.
+	// 0 => items are handed straight to an idle callback.
+	CallbackBuffer int
+
+	// CircuitBreaker, if set, skips the remaining sitemaps on a host for a
+	// cool-down period after repeated consecutive failures against it, recording
+	// them in SkippedSitemaps with ErrCircuitOpen.
+	CircuitBreaker *CircuitBreakerOptions
+
+	// TraversalOrder selects whether nested sitemap indexes are walked
+	// breadth-first (the default) or depth-first.
+	TraversalOrder TraversalOrder
+
+	// StatusPolicy decides how non-2xx sitemap responses are handled. ActionDefault
+	// falls back to the built-in behavior (retry 429, then SkipNon200).
+	StatusPolicy func(statusCode int) Action
+
+	// Pipeline sets the order of per-URL processing stages. nil => DefaultPipeline.
+	// StageResolve is required; any other stage may be omitted to disable it.
+	Pipeline []PipelineStage
+}
+
+// ExtensionDecoder converts an extension element into a caller-defined value.
+type ExtensionDecoder func(ext Extension) (any, error)
+
+// SitemapFormat identifies the document format of a fetched sitemap.
+type SitemapFormat string
+
+const (
+	// FormatXML is a sitemaps.org urlset or sitemapindex document. It is always enabled.
+	FormatXML SitemapFormat = "xml"
+	// FormatText is a plain-text sitemap with one URL per line.
+	FormatText SitemapFormat = "text"
+	// FormatRSS is an RSS 2.0 or RSS 1.0 (RDF) feed; each item link is emitted.
+	FormatRSS SitemapFormat = "rss"
+	// FormatAtom is an Atom feed; each entry link is emitted.
+	FormatAtom SitemapFormat = "atom"
+)
+
+// Limits from the sitemaps.org protocol.
+const (
+	// SpecMaxURLsPerSitemap is the limit on URLs in one sitemap file.
+	SpecMaxURLsPerSitemap = 50000
+	// SpecMaxSitemapBytes is the limit on the uncompressed size of one sitemap file.
+	SpecMaxSitemapBytes = 50 * 1024 * 1024
+	// SpecMaxLocLength is the limit on the length of a <loc> value.
+	SpecMaxLocLength = 2048
+)
+
+// InvalidLocPolicy selects what happens to a <loc> that is not a valid URL.
+type InvalidLocPolicy int
+
+const (
+	// InvalidLocSkip logs a warning and drops the entry.
+	InvalidLocSkip InvalidLocPolicy = iota
+	// InvalidLocEmit yields the entry with a nil Loc, its text in RawLoc, and
+	// the parse error in ValidationErrors. robots.txt is not consulted for it,
+	// and Verify reports an error instead of making a request.
+	InvalidLocEmit
+	// InvalidLocError fails the sitemap with ErrInvalidLoc (OnError may continue past it).
+	InvalidLocError
+)
+
+func (p InvalidLocPolicy) String() string {
+	switch p {
+	case InvalidLocSkip:
+		return "skip"
+	case InvalidLocEmit:
+		return "emit"
+	case InvalidLocError:
+		return "error"
+	default:
+		return fmt.Sprintf("InvalidLocPolicy(%d)", int(p))
+	}
+}
+
+// PriorityPolicy selects what happens to a <priority> outside 0.0–1.0.
+type PriorityPolicy int
+
+const (
+	// PriorityKeep passes out-of-range values through unchanged.
+	PriorityKeep PriorityPolicy = iota
+	// PriorityClamp raises values below 0.0 to 0.0 and lowers values above
+	// 1.0 to 1.0. NaN is dropped.
+	PriorityClamp
+	// PriorityDrop leaves Item.Priority nil, as if the entry had no <priority>.
+	PriorityDrop
+	// PriorityReport keeps the value and records it in Item.ValidationErrors.
+	PriorityReport
+)
+
+func (p PriorityPolicy) String() string {
+	switch p {
+	case PriorityKeep:
+		return "keep"
+	case PriorityClamp:
+		return "clamp"
+	case PriorityDrop:
+		return "drop"
+	case PriorityReport:
+		return "report"
+	default:
+		return fmt.Sprintf("PriorityPolicy(%d)", int(p))
+	}
+}
+
+// LimitPolicy selects what happens when a per-sitemap limit is exceeded.
+type LimitPolicy int
+
+const (
+	// LimitError fails the sitemap with ErrMaxURLsPerSitemap (OnError may continue past it).
+	LimitError LimitPolicy = iota
+	// LimitTruncate logs a warning and ignores the rest of the sitemap.
+	LimitTruncate
+	// LimitWarn logs a warning once and keeps emitting URLs.
+	LimitWarn
+)
+
+func (p LimitPolicy) String() string {
+	switch p {
+	case LimitError:
+		return "error"
+	case LimitTruncate:
+		return "truncate"
+	case LimitWarn:
+		return "warn"
+	default:
+		return fmt.Sprintf("LimitPolicy(%d)", int(p))
+	}
+}
+
+// DiscoveryMode selects how Walk finds sitemaps for its input URL.
+type DiscoveryMode int
+
+const (
+	// DiscoveryAuto uses .xml/.xml.gz inputs directly and otherwise reads robots.txt
+	// Sitemap directives, falling back to well-known paths such as /sitemap.xml.
+	DiscoveryAuto DiscoveryMode = iota
+	// DiscoveryOff always treats the input URL as a sitemap.
+	DiscoveryOff
+	// DiscoveryRobotsOnly uses .xml/.xml.gz inputs directly and otherwise only
+	// the robots.txt Sitemap directives, never guessing well-known paths; a site
+	// that advertises none fails with ErrNoSitemaps. robots.txt is read for
+	// discovery even with IgnoreRobots set.
+	DiscoveryRobotsOnly
+)
+
+func (m DiscoveryMode) String() string {
+	switch m {
+	case DiscoveryAuto:
+		return "auto"
+	case DiscoveryOff:
+		return "off"
+	case DiscoveryRobotsOnly:
+		return "robots-only"
+	default:
+		return fmt.Sprintf("DiscoveryMode(%d)", int(m))
+	}
+}
+
+// TraversalOrder selects the order in which sitemaps listed by indexes are visited.
+type TraversalOrder int
+
+const (
+	// TraversalBreadthFirst visits every sitemap of one index level before the
+	// next level, so a bit of every shard is seen early.
+	TraversalBreadthFirst TraversalOrder = iota
+	// TraversalDepthFirst finishes the sitemaps listed by a nested index before
+	// moving on to the index's siblings.
+	TraversalDepthFirst
+	// TraversalFreshestFirst visits pending sitemaps in order of the <lastmod>
+	// their index declared, newest first, so a walk cut short by MaxURLs or
+	// WalkTimeout sees the freshest content. Sitemaps without a lastmod follow
+	// the dated ones in breadth-first order.
+	TraversalFreshestFirst
+)
+
+func (o TraversalOrder) String() string {
+	switch o {
+	case TraversalBreadthFirst:
+		return "breadth-first"
+	case TraversalDepthFirst:
+		return "depth-first"
+	case TraversalFreshestFirst:
+		return "freshest-first"
+	default:
+		return fmt.Sprintf("TraversalOrder(%d)", int(o))
+	}
 }
 
+// Action is the outcome a StatusPolicy selects for a non-2xx response.
+type Action int
+
+const (
+	// ActionDefault defers to the built-in handling for the status code.
+	ActionDefault Action = iota
+	// ActionError fails the walk with ErrHTTPStatus.
+	ActionError
+	// ActionSkip skips the sitemap and records it in SkippedSitemaps.
+	ActionSkip
+	// ActionRetry retries the request with backoff, honoring Retry-After.
+	ActionRetry
+)
+
+// PipelineStage names a processing step applied to every <url> entry.
+type PipelineStage string
+
+const (
+	// StageDecode unescapes entities left in <loc> by double-encoded sitemaps.
+	StageDecode PipelineStage = "decode"
+	// StageResolve parses <loc> and resolves it against the sitemap URL.
+	StageResolve PipelineStage = "resolve"
+	// StageNormalize parses lastmod, changefreq (trimmed and lowercased), and priority into Item fields.
+	StageNormalize PipelineStage = "normalize"
+	// StageValidate drops entries whose lastmod or priority cannot be parsed.
+	StageValidate PipelineStage = "validate"
+	// StageFilter applies Include/Exclude patterns. Before StageResolve it matches the raw <loc> text.
+	StageFilter PipelineStage = "filter"
+)
+
+// DefaultTrackingParams lists common analytics and ad-click parameters for Options.StripQueryParams.
+var DefaultTrackingParams = []string{"utm_*", "gclid", "gbraid", "wbraid", "dclid", "fbclid", "msclkid", "yclid", "twclid", "igshid", "mc_cid", "mc_eid", "_ga", "_gl"}
+
+// DefaultPipeline is the stage order used when Options.Pipeline is nil.
+var DefaultPipeline = []PipelineStage{StageResolve, StageNormalize, StageFilter}
+
 // SitemapFetcher streams sitemap URLs and implements SitemapWalker.
 type SitemapFetcher struct {
 	opts         Options
 	client       *http.Client
 	logger       *slog.Logger
+	pacer        *hostPacer
+	limiter      *hostLimiter
+	breaker      *hostBreaker
+	schemes      map[string]struct{}
+	emitted      *MemoryVisitedStore
 	statsMu      sync.Mutex
 	skippedStats []SkippedSitemap
+	sitemapStats []SitemapStat
+	// reachedMaxURLs records a StopAtMaxURLs soft stop during the last Walk.
+	reachedMaxURLs bool
+	// transportErr is an *ErrUnsupportedTransport from New, returned by every
+	// walk and ping.
+	transportErr error
 }
 
 type skippedSitemapError struct {
@@ -81,169 +662,957 @@ func New(opts Options) *SitemapFetcher {
 	if opts.HTTPClient == nil {
 		opts.HTTPClient = http.DefaultClient
 	}
+	client := *opts.HTTPClient
+	client.CheckRedirect = checkRedirect(opts.HTTPClient.CheckRedirect)
+	// transportErr fails every walk when an option cannot adapt the transport.
+	var transportErr error
+	if len(opts.DialOverrides) > 0 {
+		client.Transport, transportErr = dialOverrideTransport(client.Transport, opts.DialOverrides)
+	}
+	if opts.Protocol != ProtocolAuto {
+		var err error
+		if client.Transport, err = protocolTransport(client.Transport, opts); transportErr == nil {
+			transportErr = err
+		}
+	}
 	if opts.UserAgent == "" {
 		opts.UserAgent = defaultUserAgent
 	}
 	if opts.Logger == nil {
 		opts.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
+	handler := slog.Handler(&walkIDHandler{Handler: opts.Logger.Handler()})
+	if opts.LogLevel != nil {
+		handler = &levelHandler{Handler: handler, level: opts.LogLevel}
+	}
+	opts.Logger = slog.New(handler)
+	if opts.TraceRequests {
+		client.Transport = traceTransport(client.Transport, opts.Logger)
+	}
+	if opts.Pipeline == nil {
+		opts.Pipeline = DefaultPipeline
+	}
+	if opts.RobotsCache == nil && opts.RobotsCacheTTL > 0 {
+		opts.RobotsCache = NewRobotsCache(opts.RobotsCacheTTL)
+	}
+	if opts.ContentDecoders != nil {
+		decoders := make(map[string]ContentDecoder, len(opts.ContentDecoders))
+		for name, decode := range opts.ContentDecoders {
+			decoders[strings.ToLower(name)] = decode
+		}
+		opts.ContentDecoders = decoders
+	}
 	return &SitemapFetcher{
-		opts:   opts,
-		client: opts.HTTPClient,
-		logger: opts.Logger,
+		opts:    opts,
+		client:  &client,
+		logger:  opts.Logger,
+		pacer:   newHostPacer(opts),
+		limiter: newHostLimiter(opts),
+		breaker: newHostBreaker(opts.CircuitBreaker),
+		schemes: allowedSchemes(opts.AllowedSchemes),
+		emitted: newEmittedSet(opts),
+
+		transportErr: transportErr,
 	}
 }
 
 // Walk traverses sitemaps discovered from the given website or sitemap URL.
+//
+// Each sitemap is decoded token by token straight from the response stream, so
+// yield sees the first items while the body is still downloading and memory use
+// does not grow with sitemap size (a 50 MB, 50,000-URL file needs a few MB).
 func (f *SitemapFetcher) Walk(ctx context.Context, website *url.URL, yield func(Item) error) error {
+	return f.walk(ctx, []*url.URL{website}, yield, walkHooks{})
+}
+
+// WalkAll walks several websites or sitemap URLs, such as the Sitemap entries
+// of several domains' robots.txt, as one run. Their sitemaps share one queue,
+// so MaxURLs, MaxSitemaps, MaxTotalBytes, WalkTimeout, loop detection, and
+// DedupeURLs apply across all of them, requests share the fetcher's rate and
+// concurrency limits, and SkippedSitemaps and SitemapStats summarize the whole
+// run. A website without sitemaps contributes no items; ErrNoSitemaps is
+// returned only when none has any. Checkpoints list every input in Input, so
+// Resume needs the same websites in the same order.
+func (f *SitemapFetcher) WalkAll(ctx context.Context, websites []*url.URL, yield func(Item) error) error {
+	if len(websites) == 0 {
+		return &ErrInvalidURL{Err: errors.New("no URLs")}
+	}
+	return f.walk(ctx, websites, yield, walkHooks{})
+}
+
+// walkHooks carries the optional extensions used by Start and Validate.
+type walkHooks struct {
+	// gate blocks progress while a Start handle is paused.
+	gate *pauseGate
+	// check receives spec violations found while parsing; an error aborts the sitemap.
+	check func(Finding) error
+	// sitemaps receives each sitemap file for WalkSitemaps, which skips URL entries.
+	sitemaps func(SitemapRef) error
+	// document makes the walk fetch only the input sitemap for Fetch, filling it in.
+	document *Document
+}
+
+func (f *SitemapFetcher) walk(ctx context.Context, websites []*url.URL, yield func(Item) error, hooks walkHooks) (err error) {
 	if yield == nil {
 		return &ErrNilYield{}
 	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if _, ok := WalkID(ctx); !ok {
+		ctx = WithWalkID(ctx, newWalkID())
+	}
+	start := time.Now()
+	var w *walk
+	defer func() {
+		finished := Event{Kind: EventWalkFinished, Err: err}
+		if w != nil {
+			finished.URLs, finished.Sitemaps = w.urlCount, w.sitemapCount
+		}
+		f.emit(ctx, finished)
+		if f.opts.SummaryLevel != nil {
+			f.logSummary(ctx, w, start, err)
+		}
+	}()
+	if f.opts.WalkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, f.opts.WalkTimeout, &ErrWalkTimeout{Timeout: f.opts.WalkTimeout})
+		defer cancel()
+		defer func() {
+			var timeout *ErrWalkTimeout
+			if err != nil && errors.As(context.Cause(ctx), &timeout) {
+				err = timeout
+			}
+		}()
+	}
 	f.resetStats()
 
-	inputURL, baseURL, err := normalizeInputURL(website)
-	if err != nil {
+	if err := validatePipeline(f.opts.Pipeline); err != nil {
 		return err
 	}
-
-	robotsCache := map[string]*robotsRules{}
-	var baseRobots *robotsRules
-	if !f.opts.IgnoreRobots && !isLikelySitemapURL(inputURL) {
-		baseRobots, _ = f.getRobots(ctx, baseURL, robotsCache)
+	if f.transportErr != nil {
+		return f.transportErr
 	}
 
-	initial := f.initialSitemaps(inputURL, baseURL, baseRobots)
-	if len(initial) == 0 {
-		return &ErrNoSitemaps{URL: baseURL}
+	inputs := make([]*url.URL, len(websites))
+	bases := make([]*url.URL, len(websites))
+	for i, website := range websites {
+		if inputs[i], bases[i], err = normalizeInputURL(website); err != nil {
+			return err
+		}
+	}
+	ctx = withRootHosts(ctx, inputs)
+
+	w = &walk{
+		f:           f,
+		ctx:         ctx,
+		yield:       yield,
+		inputs:      inputs,
+		visited:     f.opts.VisitedStore,
+		robotsCache: map[string]*robotsRules{},
+		gate:        hooks.gate,
+		check:       hooks.check,
+		sitemaps:    hooks.sitemaps,
+		document:    hooks.document,
+		progress:    progress{started: start, logged: start},
+	}
+	if f.opts.Verify != nil {
+		w.links = newLinkChecker(w, *f.opts.Verify)
+		defer w.links.close()
+	}
+	if w.visited == nil {
+		w.visited = &MemoryVisitedStore{}
+	}
+	w.sample = newSampler(f.opts)
+	defer w.closePrefetch()
+	if w.check == nil && f.opts.Strict {
+		w.check = func(finding Finding) error {
+			return &ErrSpecViolation{Finding: finding}
+		}
+	}
+	if w.document != nil {
+		w.document.URL = cloneURL(inputs[0])
+		w.queue = []sitemapTask{{loc: inputs[0]}}
+		return w.finish(w.run())
+	}
+	if f.opts.Resume != nil {
+		if err := w.restore(f.opts.Resume); err != nil {
+			return err
+		}
+		w.progress.urls = w.urlCount
+		return w.finish(w.run())
 	}
 
-	queue := make([]sitemapTask, 0, len(initial))
-	for _, task := range initial {
-		queue = append(queue, task)
+	for i, input := range inputs {
+		var baseRobots *robotsRules
+		readRobots := !f.opts.IgnoreRobots || f.opts.Discovery == DiscoveryRobotsOnly
+		if readRobots && f.opts.Discovery != DiscoveryOff && !isLikelySitemapURL(input) {
+			baseRobots, _ = f.getRobots(ctx, bases[i], w.robotsCache)
+		}
+		w.queue = append(w.queue, f.initialSitemaps(input, bases[i], baseRobots)...)
 	}
+	if len(w.queue) == 0 {
+		if len(bases) > 1 {
+			return &ErrNoSitemaps{}
+		}
+		return &ErrNoSitemaps{URL: bases[0]}
+	}
+	return w.finish(w.run())
+}
 
-	seen := make(map[string]struct{}, len(initial))
-	var sitemapCount int
-	var urlCount int
+// walk holds the mutable state of a single Walk call.
+type walk struct {
+	f     *SitemapFetcher
+	ctx   context.Context
+	yield func(Item) error
+	// inputs holds the normalized start URLs: one for Walk, several for WalkAll.
+	inputs []*url.URL
+	queue  []sitemapTask
+	// children holds the sitemaps listed by the index being parsed.
+	children    []sitemapTask
+	visited     VisitedStore
+	robotsCache map[string]*robotsRules
+	// gate blocks progress while a Start handle is paused; nil for Walk.
+	gate *pauseGate
+	// check receives spec violations; nil unless validating.
+	check func(Finding) error
+	// sitemaps receives each sitemap file in place of its entries; nil unless
+	// running WalkSitemaps.
+	sitemaps func(SitemapRef) error
+	// document collects the single sitemap read by Fetch; nil otherwise.
+	document *Document
+	// links runs Options.Verify checks ahead of delivery; nil when disabled.
+	links *linkChecker
+	// sample applies SampleRate and SampleN; nil when disabled.
+	sample *sampler
+	// callbacks runs yield concurrently when CallbackConcurrency > 1.
+	callbacks *callbackPool
+	// prefetched holds downloads started by FetchConcurrency, by canonical URL.
+	prefetched   map[string]*prefetchedSitemap
+	prefetchCtx  context.Context
+	stopPrefetch context.CancelCauseFunc
+	prefetching  sync.WaitGroup
+
+	// callbackTime is the time spent in yield (and waiting on link checks)
+	// for the sitemap being parsed, excluded from its ParseDuration.
+	callbackTime time.Duration
+
+	sitemapCount int
+	urlCount     int
+	// downloaded is the network byte count of the sitemaps visited so far,
+	// plus what FetchConcurrency downloads have read ahead of their turn.
+	downloaded atomic.Int64
+	// found records that some sitemap was fetched; blockedProbe is the first
+	// well-known sitemap path robots.txt kept us from probing.
+	found        bool
+	blockedProbe *url.URL
+
+	// current and entries track the sitemap being parsed for checkpoints.
+	current *sitemapTask
+	entries int
+	// skipEntries is the number of leading entries of the first sitemap already
+	// processed before a resumed walk was checkpointed.
+	skipEntries int
+
+	// states holds the states of sitemaps read in full under
+	// Options.SitemapStates, saved when the walk completes; partial records
+	// that the walk stopped early.
+	states  map[string]SitemapState
+	partial bool
+
+	// progress tracks Options.ProgressLogInterval records.
+	progress progress
+}
 
-	for len(queue) > 0 {
-		if err := ctx.Err(); err != nil {
+func (w *walk) run() error {
+	if w.f.opts.CallbackConcurrency > 1 && w.document == nil {
+		w.callbacks = newCallbackPool(w.f.opts.CallbackConcurrency, w.f.opts.CallbackBuffer, w.yield)
+	}
+	for len(w.queue) > 0 {
+		if err := w.gate.wait(w.ctx); err != nil {
 			return err
 		}
-		current := queue[0]
-		queue = queue[1:]
+		w.prefetch()
+		current := w.queue[0]
+		w.queue = w.queue[1:]
 
-		if f.opts.MaxDepth > 0 && current.depth > f.opts.MaxDepth {
-			return &ErrMaxDepth{MaxDepth: f.opts.MaxDepth, URL: current.loc}
+		err := w.visit(current)
+		if w.document != nil {
+			w.collectChildren()
 		}
-
-		key := canonicalURLKey(current.loc)
-		if _, ok := seen[key]; ok {
-			continue
+		w.queue = w.pendingQueue()
+		w.children = nil
+		w.logProgress()
+		if err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				w.partial = true
+				return w.f.aggregateError()
+			}
+			return err
 		}
-		seen[key] = struct{}{}
+		if err := w.checkpoint(); err != nil {
+			return err
+		}
+	}
 
-		if !f.opts.IgnoreRobots {
-			allowed, err := f.allowedByRobots(ctx, current.loc, robotsCache)
-			if err != nil {
+	if w.blockedProbe != nil && !w.found {
+		return &ErrRobotsDisallowed{URL: w.blockedProbe}
+	}
+	return w.f.aggregateError()
+}
+
+// newItem builds the Item for the <url> entry at position in current.
+// sitemapRef is the Item.Sitemap shared by every item when ReuseItems is set.
+func (w *walk) newItem(current sitemapTask, position int, sitemapRef *url.URL, state *entryState) Item {
+	f := w.f
+	item := Item{
+		Loc:            state.loc,
+		LastMod:        state.lastMod,
+		ChangeFreq:     state.changeFreq,
+		Priority:       state.priority,
+		Sitemap:        sitemapRef,
+		RawLoc:         state.rawLoc,
+		RawChangeFreq:  state.raw.ChangeFreq,
+		Mobile:         state.raw.Mobile != nil,
+		LastModZoned:   state.lastModZoned,
+		Depth:          current.depth,
+		Position:       position,
+		SitemapLastMod: current.lastMod,
+		Ext:            f.decodeExtensions(w.ctx, current.loc, state.raw.extensions),
+	}
+	if f.opts.KeepExtensions {
+		item.Extensions = state.raw.extensions
+	}
+	if state.locErr != nil {
+		item.ValidationErrors = []string{"loc: " + state.locErr.Error()}
+	}
+	if f.opts.FlagChangeFreq && item.ChangeFreq != "" && !item.ChangeFreq.IsValid() {
+		item.ValidationErrors = append(item.ValidationErrors, fmt.Sprintf("changefreq: invalid value %q", state.raw.ChangeFreq))
+	}
+	if state.priorityOutOfRange {
+		item.ValidationErrors = append(item.ValidationErrors, fmt.Sprintf("priority: %q is outside 0.0 to 1.0", strings.TrimSpace(state.raw.Priority)))
+	}
+	if !f.opts.ReuseItems {
+		item.Sitemap = cloneURL(current.loc)
+	}
+	return item
+}
+
+// deliver yields item, the entries-th entry of the current sitemap, and applies
+// the per-item bookkeeping that follows a successful callback.
+func (w *walk) deliver(item Item, entries int) error {
+	f := w.f
+	if err := w.gate.wait(w.ctx); err != nil {
+		return err
+	}
+	yieldStart := time.Now()
+	if w.callbacks != nil {
+		err := w.callbacks.dispatch(item)
+		w.callbackTime += time.Since(yieldStart)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := w.yield(item)
+		w.callbackTime += time.Since(yieldStart)
+		if err != nil {
+			if errors.Is(err, ErrSkipSitemap) || errors.Is(err, ErrStopWalk) {
+				w.urlCount++
+				w.retain(item)
+				w.emitItem(item)
 				return err
 			}
-			if !allowed {
-				f.logger.Debug(fmt.Sprintf("robots.txt disallows sitemap %s", current.loc))
-				continue
-			}
+			return &ErrYield{Err: err}
 		}
+	}
+	w.urlCount++
+	w.retain(item)
+	w.emitItem(item)
+	w.logProgress()
+	if f.opts.CheckpointEvery > 0 && w.urlCount%f.opts.CheckpointEvery == 0 {
+		// With link checks the parser may be ahead of the item being delivered.
+		parsed := w.entries
+		w.entries = entries
+		err := w.checkpoint()
+		w.entries = parsed
+		if err != nil {
+			return err
+		}
+	}
+	if f.opts.StopAtMaxURLs && f.opts.MaxURLs > 0 && w.urlCount >= f.opts.MaxURLs {
+		f.setReachedMaxURLs()
+		return ErrStopWalk
+	}
+	return nil
+}
+
+// pendingQueue returns the sitemaps left to visit, with the children found in
+// the current sitemap placed according to TraversalOrder.
+func (w *walk) pendingQueue() []sitemapTask {
+	if len(w.children) == 0 {
+		return w.queue
+	}
+	switch w.f.opts.TraversalOrder {
+	case TraversalDepthFirst:
+		return append(append([]sitemapTask(nil), w.children...), w.queue...)
+	case TraversalFreshestFirst:
+		queue := append(append([]sitemapTask(nil), w.queue...), w.children...)
+		slices.SortStableFunc(queue, fresherFirst)
+		return queue
+	}
+	return append(w.queue, w.children...)
+}
+
+// fresherFirst orders sitemap tasks by declared lastmod, newest first, with
+// undated tasks last.
+func fresherFirst(a, b sitemapTask) int {
+	switch {
+	case a.lastMod == nil && b.lastMod == nil:
+		return 0
+	case a.lastMod == nil:
+		return 1
+	case b.lastMod == nil:
+		return -1
+	}
+	return b.lastMod.Compare(*a.lastMod)
+}
 
-		if f.opts.MaxSitemaps > 0 && sitemapCount >= f.opts.MaxSitemaps {
-			return &ErrMaxSitemaps{MaxSitemaps: f.opts.MaxSitemaps}
+// checkpoint reports the current progress to OnCheckpoint, if set.
+func (w *walk) checkpoint() error {
+	if w.f.opts.OnCheckpoint == nil {
+		return nil
+	}
+	if w.callbacks != nil {
+		// Only report items whose callbacks have finished.
+		w.callbacks.wait()
+	}
+	cp := Checkpoint{
+		URLs:     w.urlCount,
+		Sitemaps: w.sitemapCount,
+	}
+	if inputs := w.inputKeys(); len(inputs) == 1 {
+		cp.Input = inputs[0]
+	} else {
+		cp.Inputs = inputs
+	}
+	var currentKey string
+	if w.current != nil {
+		task := newCheckpointTask(*w.current)
+		cp.Current = &task
+		cp.Position = w.entries
+		currentKey = canonicalURLKey(w.current.loc)
+	}
+	// A custom VisitedStore keeps its own record of visited sitemaps.
+	if store, ok := w.visited.(*MemoryVisitedStore); ok {
+		for _, key := range store.withPrefix(visitedSitemapPrefix) {
+			if key != currentKey {
+				cp.Visited = append(cp.Visited, key)
+			}
 		}
-		sitemapCount++
+	}
+	for _, task := range w.pendingQueue() {
+		cp.Queue = append(cp.Queue, newCheckpointTask(task))
+	}
+	if err := w.f.opts.OnCheckpoint(cp); err != nil {
+		return &ErrCheckpoint{Err: err}
+	}
+	return nil
+}
 
-		reader, err := f.fetchSitemap(ctx, current.loc, current.allowMissing)
+// inputKeys identifies the walk's start URLs in checkpoints by their
+// normalized form.
+func (w *walk) inputKeys() []string {
+	keys := make([]string, len(w.inputs))
+	for i, input := range w.inputs {
+		keys[i] = input.String()
+	}
+	return keys
+}
+
+// restore seeds the walk from a checkpoint taken by an earlier Walk of the same input.
+func (w *walk) restore(cp *Checkpoint) error {
+	saved := cp.Inputs
+	if len(saved) == 0 && cp.Input != "" {
+		saved = []string{cp.Input}
+	}
+	if inputs := w.inputKeys(); !slices.Equal(saved, inputs) {
+		return &ErrInvalidCheckpoint{Reason: fmt.Sprintf("checkpoint is for %q, not %q", saved, inputs)}
+	}
+	for _, key := range cp.Visited {
+		if _, err := w.visitSitemap(key); err != nil {
+			return err
+		}
+	}
+	if cp.Current != nil {
+		task, err := cp.Current.task()
 		if err != nil {
-			var skipped *skippedSitemapError
-			if errors.As(err, &skipped) {
-				f.recordSkippedSitemap(current.loc, skipped.err)
-				continue
-			}
-			if f.shouldSkipSitemapError(ctx, err) {
-				f.recordSkippedSitemap(current.loc, err)
-				f.logger.Warn(
-					"skipping sitemap due to fetch error",
-					"sitemap", current.loc.String(),
-					"error", err.Error(),
-				)
-				continue
-			}
 			return err
 		}
-		if reader == nil {
-			continue
+		w.queue = append(w.queue, task)
+		w.skipEntries = cp.Position
+	}
+	for _, saved := range cp.Queue {
+		task, err := saved.task()
+		if err != nil {
+			return err
 		}
+		w.queue = append(w.queue, task)
+	}
+	w.urlCount = cp.URLs
+	w.sitemapCount = cp.Sitemaps
+	if cp.Current != nil && w.sitemapCount > 0 {
+		// The in-progress sitemap was already counted and is fetched again.
+		w.sitemapCount--
+	}
+	return nil
+}
 
-		err = parseSitemap(ctx, reader, func(entry xmlURLEntry) error {
-			loc, err := resolveLocation(current.loc, entry.Loc)
-			if err != nil {
-				f.logger.Debug(fmt.Sprintf("invalid URL %q in %s: %v", entry.Loc, current.loc, err))
-				return nil
-			}
-			if !f.opts.IgnoreRobots {
-				allowed, err := f.allowedByRobots(ctx, loc, robotsCache)
-				if err != nil {
-					return err
-				}
-				if !allowed {
-					f.logger.Debug(fmt.Sprintf("robots.txt disallows URL %s", loc))
+// visit fetches and parses one sitemap. A nil error continues with the next queued sitemap.
+func (w *walk) visit(current sitemapTask) error {
+	f := w.f
+	ctx := w.ctx
+	robotsCache := w.robotsCache
+
+	if f.opts.MaxDepth > 0 && current.depth > f.opts.MaxDepth {
+		return &ErrMaxDepth{MaxDepth: f.opts.MaxDepth, URL: current.loc}
+	}
+
+	seen, err := w.visitSitemap(canonicalURLKey(current.loc))
+	if err != nil || seen {
+		return err
+	}
+
+	if !f.opts.IgnoreRobots {
+		allowed, err := f.allowedByRobots(ctx, current.loc, robotsCache)
+		if err != nil {
+			return f.handleSitemapError(ctx, current.loc, err)
+		}
+		if !allowed {
+			f.logger.DebugContext(
+				ctx,
+				"robots.txt disallows sitemap",
+				"sitemap", current.loc.String(),
+				"depth", current.depth,
+			)
+			if f.opts.ErrorOnRobotsDisallowed && current.depth == 0 {
+				if current.allowMissing {
+					if w.blockedProbe == nil {
+						w.blockedProbe = cloneURL(current.loc)
+					}
 					return nil
 				}
+				return f.handleSitemapError(ctx, current.loc, &ErrRobotsDisallowed{URL: cloneURL(current.loc)})
 			}
-			if !f.shouldInclude(loc) {
-				return nil
+			w.emitSkipped(current)
+			return nil
+		}
+	}
+
+	current, unchanged, err := w.loadState(current)
+	if err != nil {
+		return err
+	}
+	if unchanged {
+		f.logger.DebugContext(
+			ctx,
+			"skipping sitemap with unchanged lastmod",
+			"sitemap", current.loc.String(),
+			"depth", current.depth,
+		)
+		w.skipUnchanged(current)
+		return nil
+	}
+
+	if f.opts.MaxSitemaps > 0 && w.sitemapCount >= f.opts.MaxSitemaps {
+		return &ErrMaxSitemaps{MaxSitemaps: f.opts.MaxSitemaps}
+	}
+	w.sitemapCount++
+	f.emit(ctx, Event{Kind: EventSitemapStarted, Sitemap: cloneURL(current.loc)})
+
+	reader, err := w.fetch(current)
+	if errors.Is(err, errSitemapUnchanged) {
+		f.logger.DebugContext(
+			ctx,
+			"skipping sitemap not modified since the last walk",
+			"sitemap", current.loc.String(),
+			"depth", current.depth,
+		)
+		w.skipUnchanged(current)
+		return nil
+	}
+	var htmlSitemaps *htmlSitemapsError
+	if errors.As(err, &htmlSitemaps) {
+		if w.document == nil {
+			w.followHTMLSitemaps(current, htmlSitemaps.links)
+			return nil
+		}
+		err = htmlSitemaps.err
+	}
+	if err != nil {
+		var notSitemap *ErrNotASitemap
+		if errors.As(err, &notSitemap) && current.depth == 0 && f.opts.Discovery == DiscoveryOff {
+			notSitemap.Hint = "use DiscoveryAuto with the site root URL to find sitemaps via robots.txt and /sitemap.xml"
+		}
+		var archiveErr *ErrArchive
+		if errors.As(err, &archiveErr) {
+			return err
+		}
+		var maxBytes *ErrMaxTotalBytes
+		if errors.As(err, &maxBytes) {
+			return err
+		}
+		var skipped *skippedSitemapError
+		if errors.As(err, &skipped) {
+			f.recordSkippedSitemap(ctx, current.loc, skipped.err)
+			return nil
+		}
+		if f.shouldSkipSitemapError(ctx, err) {
+			f.recordSkippedSitemap(ctx, current.loc, err)
+			f.logger.WarnContext(
+				ctx,
+				"skipping sitemap due to fetch error",
+				"sitemap", current.loc.String(),
+				"depth", current.depth,
+				"error", err.Error(),
+			)
+			return nil
+		}
+		if err := f.handleSitemapError(ctx, current.loc, err); err != nil {
+			return err
+		}
+		return nil
+	}
+	if reader == nil {
+		return nil
+	}
+	w.found = true
+	defer func() { w.downloaded.Add(reader.network.bytes - reader.counted) }()
+
+	var body io.Reader = reader
+	if f.opts.MaxTotalBytes > 0 {
+		body = &budgetReader{reader: reader, w: w, loc: current.loc, network: reader.network, counted: reader.counted}
+	}
+	decoded := &meteredReader{reader: body}
+	buffered := bufio.NewReaderSize(decoded, defaultBufSize)
+	format := detectFormat(buffered)
+	if !f.formatEnabled(format) {
+		reader.Close()
+		unsupported := &ErrUnsupportedFormat{URL: current.loc, Format: format}
+		f.recordSkippedSitemap(ctx, current.loc, unsupported)
+		f.logger.WarnContext(
+			ctx,
+			"skipping sitemap with unsupported format",
+			"sitemap", current.loc.String(),
+			"depth", current.depth,
+			"format", string(format),
+		)
+		return nil
+	}
+
+	var entries, position int
+	skipEntries := w.skipEntries
+	w.skipEntries = 0
+	w.current = &current
+	defer func() {
+		w.current = nil
+		w.entries = 0
+	}()
+	// With ReuseItems every item from this sitemap shares one Sitemap URL and one entry state.
+	var sharedState entryState
+	sitemapRef := cloneURL(current.loc)
+	w.callbackTime = 0
+	parseStart := time.Now()
+	onURL := func(entry xmlURLEntry) error {
+		entries++
+		position++
+		w.entries = entries
+		if entries <= skipEntries {
+			return nil
+		}
+		if w.check != nil {
+			if err := checkURLEntry(current.loc, position, entry, w.check); err != nil {
+				return err
 			}
-			if f.opts.MaxURLs > 0 && urlCount >= f.opts.MaxURLs {
-				return &ErrMaxURLs{MaxURLs: f.opts.MaxURLs}
+		}
+		if f.opts.MaxURLsPerSitemap > 0 && position > f.opts.MaxURLsPerSitemap {
+			if err := f.overPerSitemapLimit(ctx, current.loc, position); err != nil {
+				return err
 			}
-			item := Item{
-				Loc:        loc,
-				LastMod:    parseTimeValue(entry.LastMod),
-				ChangeFreq: strings.TrimSpace(entry.ChangeFreq),
-				Priority:   parsePriority(entry.Priority),
-				Sitemap:    cloneURL(current.loc),
+		}
+		state := &sharedState
+		if !f.opts.ReuseItems || w.links != nil || w.callbacks != nil || f.opts.SampleN > 0 {
+			state = &entryState{}
+		}
+		var transformed *Item
+		var transform func(*entryState) bool
+		if f.opts.Transform != nil {
+			transform = func(state *entryState) bool {
+				item, keep := f.opts.Transform(w.newItem(current, position, sitemapRef, state))
+				if !keep || item.Loc == nil {
+					return false
+				}
+				state.loc, state.lastMod, state.changeFreq, state.priority = item.Loc, item.LastMod, item.ChangeFreq, item.Priority
+				transformed = &item
+				return true
 			}
-			if err := yield(item); err != nil {
-				return &ErrYield{Err: err}
+		}
+		if !f.runPipeline(ctx, current.loc, entry, state, transform) {
+			if state.locErr != nil && f.opts.InvalidLoc == InvalidLocError {
+				return &ErrInvalidLoc{Sitemap: cloneURL(current.loc), Position: position, Loc: state.rawLoc, Err: state.locErr}
 			}
-			urlCount++
 			return nil
-		}, func(entry xmlSitemapEntry) error {
-			loc, err := resolveLocation(current.loc, entry.Loc)
+		}
+		if !f.opts.IgnoreRobots && state.loc != nil {
+			allowed, err := f.allowedByRobots(ctx, state.loc, robotsCache)
 			if err != nil {
-				f.logger.Debug(fmt.Sprintf("invalid sitemap URL %q in %s: %v", entry.Loc, current.loc, err))
+				return err
+			}
+			if !allowed {
+				f.logger.DebugContext(
+					ctx,
+					"robots.txt disallows URL",
+					"url", state.loc.String(),
+					"sitemap", current.loc.String(),
+				)
+				return nil
+			}
+		}
+		if f.opts.MaxURLs > 0 && w.urlCount+w.links.pending() >= f.opts.MaxURLs {
+			return &ErrMaxURLs{MaxURLs: f.opts.MaxURLs}
+		}
+		if f.opts.DedupeURLs && state.loc != nil {
+			seen, err := w.visitURL(Item{Loc: state.loc})
+			if err != nil {
+				return err
+			}
+			if seen {
+				f.logger.DebugContext(
+					ctx,
+					"skipping duplicate URL",
+					"url", state.loc.String(),
+					"sitemap", current.loc.String(),
+				)
 				return nil
 			}
-			queue = append(queue, sitemapTask{loc: loc, depth: current.depth + 1})
+		}
+		var item Item
+		if transformed != nil {
+			item = *transformed
+		} else {
+			item = w.newItem(current, position, sitemapRef, state)
+		}
+		if w.emittedBefore(item) {
+			f.logger.DebugContext(
+				ctx,
+				"skipping URL delivered by an earlier walk",
+				"url", item.Loc.String(),
+				"sitemap", current.loc.String(),
+			)
 			return nil
-		})
-		reader.Close()
+		}
+		if w.sample != nil && !w.sample.offer(item) {
+			return nil
+		}
+		if w.links != nil {
+			return w.links.submit(item, entries)
+		}
+		return w.deliver(item, entries)
+	}
+	onSitemap := func(entry xmlSitemapEntry) error {
+		entries++
+		w.entries = entries
+		if entries <= skipEntries {
+			return nil
+		}
+		loc, err := resolveLocation(current.loc, entry.Loc)
 		if err != nil {
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			f.logger.DebugContext(
+				ctx,
+				"invalid sitemap URL",
+				"loc", entry.Loc,
+				"sitemap", current.loc.String(),
+				"depth", current.depth,
+				"error", err.Error(),
+			)
+			return nil
+		}
+		if !f.allowedScheme(loc) {
+			f.logger.DebugContext(
+				ctx,
+				"skipping sitemap URL with disallowed scheme",
+				"loc", entry.Loc,
+				"sitemap", current.loc.String(),
+			)
+			return nil
+		}
+		child := sitemapTask{loc: loc, depth: current.depth + 1, lastMod: f.parseLastMod(entry.LastMod)}
+		if w.sitemaps != nil && !f.opts.DetectNestedIndexes {
+			// A listed sitemap is reported without requesting it; skipping it
+			// has nothing left to skip.
+			if err := w.reportSitemap(child, false); err != nil && !errors.Is(err, ErrSkipSitemap) {
 				return err
 			}
-			var maxURLs *ErrMaxURLs
-			if errors.As(err, &maxURLs) {
+			return nil
+		}
+		w.children = append(w.children, child)
+		return nil
+	}
+	// kind is the type of document parsed, remembered under SitemapStates.
+	var kind DocumentKind
+	switch {
+	case w.sitemaps != nil && format != FormatXML:
+		err = w.reportSitemap(current, false)
+	case format == FormatText:
+		kind = DocumentText
+		if w.document != nil {
+			w.document.Kind = DocumentText
+		}
+		err = parseTextSitemap(ctx, buffered, onURL)
+	case format == FormatRSS || format == FormatAtom:
+		kind = DocumentFeed
+		if w.document != nil {
+			w.document.Kind = DocumentFeed
+		}
+		err = parseFeed(ctx, buffered, onURL)
+	default:
+		keepExtensions := f.opts.KeepExtensions || len(f.opts.ExtensionDecoders) > 0
+		onRoot := func(name xml.Name) error {
+			if err := f.checkNamespace(ctx, current.loc, name); err != nil {
+				return err
+			}
+			kind = DocumentKind(name.Local)
+			if w.document != nil {
+				w.document.Kind = kind
+			}
+			if w.sitemaps != nil {
+				index := name.Local == "sitemapindex"
+				if err := w.reportSitemap(current, index); err != nil {
+					return err
+				}
+				if !index {
+					return errTruncateSitemap
+				}
+			}
+			if w.check != nil {
+				return checkRoot(current.loc, name, w.check)
+			}
+			return nil
+		}
+		err = parseSitemap(ctx, buffered, keepExtensions, onRoot, onURL, onSitemap)
+	}
+	// Release the connection before waiting on outstanding link checks.
+	reader.Close()
+	if flushErr := w.links.flush(); flushErr != nil {
+		err = flushErr
+	}
+	walkID, _ := WalkID(ctx)
+	stat := SitemapStat{
+		URL:           current.loc.String(),
+		WalkID:        walkID,
+		FetchDuration: reader.fetchDuration,
+		ReadDuration:  reader.network.wait,
+		ParseDuration: max(time.Since(parseStart)-reader.network.wait-w.callbackTime, 0),
+		Bytes:         decoded.bytes,
+		Entries:       entries,
+		Network:       reader.timings,
+	}
+	f.recordSitemapStat(stat)
+	f.warnSlowSitemap(ctx, stat)
+	f.warnOversizedSitemap(ctx, stat)
+	finished := Event{Kind: EventSitemapFinished, Sitemap: cloneURL(current.loc), Stat: &stat}
+	if err != nil && !errors.Is(err, ErrStopWalk) && !errors.Is(err, ErrSkipSitemap) && !errors.Is(err, errTruncateSitemap) {
+		finished.Err = err
+	}
+	f.emit(ctx, finished)
+	f.logger.DebugContext(
+		ctx,
+		"parsed sitemap",
+		"sitemap", stat.URL,
+		"depth", current.depth,
+		"format", string(format),
+		"bytes", reader.network.bytes,
+		"decoded_bytes", stat.Bytes,
+		"entries", entries,
+		"duration", reader.fetchDuration+time.Since(parseStart),
+	)
+	if w.check != nil && err == nil && decoded.bytes > SpecMaxSitemapBytes {
+		err = w.check(Finding{
+			Rule:    RuleTooLarge,
+			Sitemap: current.loc.String(),
+			Value:   strconv.FormatInt(decoded.bytes, 10),
+			Message: fmt.Sprintf("uncompressed size %d bytes exceeds %d", decoded.bytes, SpecMaxSitemapBytes),
+		})
+	}
+	if err == nil && skipEntries == 0 {
+		w.rememberState(current, reader.header, kind, w.children)
+	}
+	if errors.Is(err, ErrStopWalk) {
+		return err
+	}
+	if errors.Is(err, ErrSkipSitemap) {
+		f.logger.DebugContext(
+			ctx,
+			"callback skipped remainder of sitemap",
+			"sitemap", current.loc.String(),
+			"entries", entries,
+		)
+		return nil
+	}
+	if errors.Is(err, errTruncateSitemap) {
+		return nil
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		var perSitemap *ErrMaxURLsPerSitemap
+		if errors.As(err, &perSitemap) {
+			if err := f.handleSitemapError(ctx, current.loc, err); err != nil {
 				return err
 			}
-			var yieldErr *ErrYield
-			if errors.As(err, &yieldErr) {
+			return nil
+		}
+		var maxURLs *ErrMaxURLs
+		if errors.As(err, &maxURLs) {
+			return err
+		}
+		var maxBytes *ErrMaxTotalBytes
+		if errors.As(err, &maxBytes) {
+			return err
+		}
+		var yieldErr *ErrYield
+		if errors.As(err, &yieldErr) {
+			return err
+		}
+		var checkpointErr *ErrCheckpoint
+		if errors.As(err, &checkpointErr) {
+			return err
+		}
+		var storeErr *ErrVisitedStore
+		if errors.As(err, &storeErr) {
+			return err
+		}
+		var stateErr *ErrSitemapState
+		if errors.As(err, &stateErr) {
+			return err
+		}
+		var archiveErr *ErrArchive
+		if errors.As(err, &archiveErr) {
+			return err
+		}
+		var violation *ErrSpecViolation
+		var robotsErr *ErrRobotsUnavailable
+		var invalidLoc *ErrInvalidLoc
+		if errors.As(err, &violation) || errors.As(err, &robotsErr) || errors.As(err, &invalidLoc) {
+			if err := f.handleSitemapError(ctx, current.loc, err); err != nil {
 				return err
 			}
-			return &ErrSitemapParse{URL: current.loc, Err: err}
+			return nil
+		}
+		if err := f.handleSitemapError(ctx, current.loc, &ErrSitemapParse{URL: current.loc, Err: err}); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
@@ -261,20 +1630,61 @@ func (f *SitemapFetcher) SkippedSitemapCount() int {
 	return len(f.skippedStats)
 }
 
+// SitemapStats returns per-sitemap fetch and parse timings recorded during the last Walk.
+func (f *SitemapFetcher) SitemapStats() []SitemapStat {
+	f.statsMu.Lock()
+	defer f.statsMu.Unlock()
+	return append([]SitemapStat(nil), f.sitemapStats...)
+}
+
+// aggregateError returns the skipped sitemaps as *WalkErrors when AggregateErrors is set.
+func (f *SitemapFetcher) aggregateError() error {
+	if !f.opts.AggregateErrors {
+		return nil
+	}
+	skipped := f.SkippedSitemaps()
+	if len(skipped) == 0 {
+		return nil
+	}
+	return &WalkErrors{Sitemaps: skipped}
+}
+
+// ReachedMaxURLs reports whether the last Walk stopped early because StopAtMaxURLs hit MaxURLs.
+func (f *SitemapFetcher) ReachedMaxURLs() bool {
+	f.statsMu.Lock()
+	defer f.statsMu.Unlock()
+	return f.reachedMaxURLs
+}
+
 func (f *SitemapFetcher) resetStats() {
 	f.statsMu.Lock()
 	defer f.statsMu.Unlock()
 	f.skippedStats = nil
+	f.sitemapStats = nil
+	f.reachedMaxURLs = false
 }
 
-func (f *SitemapFetcher) recordSkippedSitemap(loc *url.URL, err error) {
+func (f *SitemapFetcher) setReachedMaxURLs() {
 	f.statsMu.Lock()
 	defer f.statsMu.Unlock()
+	f.reachedMaxURLs = true
+}
+
+func (f *SitemapFetcher) recordSitemapStat(stat SitemapStat) {
+	f.statsMu.Lock()
+	defer f.statsMu.Unlock()
+	f.sitemapStats = append(f.sitemapStats, stat)
+}
+
+func (f *SitemapFetcher) recordSkippedSitemap(ctx context.Context, loc *url.URL, err error) {
 	entry := SkippedSitemap{Err: err}
 	if loc != nil {
 		entry.URL = loc.String()
 	}
+	f.statsMu.Lock()
 	f.skippedStats = append(f.skippedStats, entry)
+	f.statsMu.Unlock()
+	f.emit(ctx, Event{Kind: EventSitemapSkipped, Sitemap: cloneURL(loc), Err: err})
 }
 
 func (f *SitemapFetcher) shouldSkipSitemapError(ctx context.Context, err error) bool {
@@ -287,6 +1697,49 @@ func (f *SitemapFetcher) shouldSkipSitemapError(ctx context.Context, err error)
 	return true
 }
 
+// errTruncateSitemap ends parsing of a sitemap early without an error: one that
+// exceeded MaxURLsPerSitemap, or a urlset seen by WalkSitemaps.
+var errTruncateSitemap = errors.New("sitemap truncated")
+
+// overPerSitemapLimit applies MaxURLsPerSitemapPolicy to the URL entry at position.
+func (f *SitemapFetcher) overPerSitemapLimit(ctx context.Context, loc *url.URL, position int) error {
+	switch f.opts.MaxURLsPerSitemapPolicy {
+	case LimitWarn:
+		if position == f.opts.MaxURLsPerSitemap+1 {
+			f.logger.WarnContext(
+				ctx,
+				"sitemap exceeds per-sitemap URL limit",
+				"sitemap", loc.String(),
+				"limit", f.opts.MaxURLsPerSitemap,
+			)
+		}
+		return nil
+	case LimitTruncate:
+		f.logger.WarnContext(
+			ctx,
+			"truncating sitemap at per-sitemap URL limit",
+			"sitemap", loc.String(),
+			"limit", f.opts.MaxURLsPerSitemap,
+		)
+		return errTruncateSitemap
+	default:
+		return &ErrMaxURLsPerSitemap{URL: cloneURL(loc), MaxURLsPerSitemap: f.opts.MaxURLsPerSitemap}
+	}
+}
+
+// handleSitemapError passes a per-sitemap failure to OnError. It returns nil when
+// the walk should continue, recording the sitemap as skipped.
+func (f *SitemapFetcher) handleSitemapError(ctx context.Context, loc *url.URL, err error) error {
+	if f.opts.OnError == nil || ctx.Err() != nil {
+		return err
+	}
+	if abortErr := f.opts.OnError(cloneURL(loc), err); abortErr != nil {
+		return abortErr
+	}
+	f.recordSkippedSitemap(ctx, loc, err)
+	return nil
+}
+
 // ===================== Internal Types =====================
 
 type sitemapTask struct {
@@ -294,11 +1747,19 @@ type sitemapTask struct {
 	depth int
 	// allowMissing treats 404 responses as a non-fatal probe miss.
 	allowMissing bool
+	// lastMod is the <lastmod> the parent sitemapindex declared for this sitemap.
+	lastMod *time.Time
+	// prior is the previous run's state under Options.SitemapStates, if any.
+	prior *SitemapState
 }
 
 type robotsRules struct {
 	group    *robotstxt.Group
 	sitemaps []*url.URL
+	// denyAll blocks every path under RobotsAssumeDeny.
+	denyAll bool
+	// err is returned for every check under RobotsFailError.
+	err error
 }
 
 type xmlURLEntry struct {
@@ -306,6 +1767,17 @@ type xmlURLEntry struct {
 	LastMod    string `xml:"lastmod"`
 	ChangeFreq string `xml:"changefreq"`
 	Priority   string `xml:"priority"`
+	// Mobile is set by <mobile:mobile/>. Like the fields above it matches any
+	// namespace, since the prefix is often left undeclared.
+	Mobile *struct{} `xml:"mobile"`
+
+	extensions []Extension
+}
+
+// xmlURLEntryWithExtensions also captures unrecognized child elements of <url>.
+type xmlURLEntryWithExtensions struct {
+	xmlURLEntry
+	Extensions []Extension `xml:",any"`
 }
 
 type xmlSitemapEntry struct {
@@ -313,20 +1785,53 @@ type xmlSitemapEntry struct {
 	LastMod string `xml:"lastmod"`
 }
 
-type readCloser struct {
+// fetchedSitemap is an open sitemap body plus transport metadata.
+type fetchedSitemap struct {
+	io.ReadCloser
+	header http.Header
+	// network meters the raw response body as read from the connection.
+	network *meteredReader
+	// counted is the part of network already added to walk.downloaded by a
+	// prefetch.
+	counted       int64
+	fetchDuration time.Duration
+	// timings is set under Options.NetworkTimings.
+	timings *NetworkTimings
+}
+
+// meteredReader counts bytes and the time spent blocked in Read.
+type meteredReader struct {
 	reader io.Reader
-	close  func() error
+	bytes  int64
+	wait   time.Duration
 }
 
-func (r *readCloser) Read(p []byte) (int, error) {
-	return r.reader.Read(p)
+func (m *meteredReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := m.reader.Read(p)
+	m.wait += time.Since(start)
+	m.bytes += int64(n)
+	return n, err
 }
 
-func (r *readCloser) Close() error {
-	if r.close == nil {
-		return nil
+// budgetReader fails reads once the walk has downloaded more than MaxTotalBytes.
+// The check uses network bytes, so a gzip body counts at its compressed size.
+type budgetReader struct {
+	reader  io.Reader
+	w       *walk
+	loc     *url.URL
+	network *meteredReader
+	counted int64
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	limit := b.w.f.opts.MaxTotalBytes
+	if total := b.w.downloaded.Load() + b.network.bytes - b.counted; total > limit {
+		// Drop the chunk that went over so nothing past the budget is parsed.
+		return 0, &ErrMaxTotalBytes{MaxTotalBytes: limit, Downloaded: total, URL: cloneURL(b.loc)}
 	}
-	return r.close()
+	return n, err
 }
 
 type cancelCloser struct {
@@ -378,7 +1883,7 @@ func normalizeInputURL(website *url.URL) (*url.URL, *url.URL, error) {
 }
 
 func (f *SitemapFetcher) initialSitemaps(input, base *url.URL, robots *robotsRules) []sitemapTask {
-	if isLikelySitemapURL(input) {
+	if f.opts.Discovery == DiscoveryOff || isLikelySitemapURL(input) {
 		return []sitemapTask{{loc: cloneURL(input), depth: 0}}
 	}
 	if robots != nil && len(robots.sitemaps) > 0 {
@@ -388,68 +1893,326 @@ func (f *SitemapFetcher) initialSitemaps(input, base *url.URL, robots *robotsRul
 		}
 		return tasks
 	}
+	if f.opts.Discovery == DiscoveryRobotsOnly {
+		return nil
+	}
 	paths := defaultSitemaps(base)
 	tasks := make([]sitemapTask, 0, len(paths))
 	for _, loc := range paths {
 		tasks = append(tasks, sitemapTask{loc: loc, depth: 0, allowMissing: true})
 	}
-	return tasks
+	return tasks
+}
+
+func isLikelySitemapURL(u *url.URL) bool {
+	if u == nil {
+		return false
+	}
+	path := strings.ToLower(u.Path)
+	return strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz")
+}
+
+func defaultSitemaps(base *url.URL) []*url.URL {
+	candidates := []string{
+		"/sitemap.xml",
+		"/sitemap_index.xml",
+		"/sitemap-index.xml",
+		"/sitemap.xml.gz",
+		"/sitemap_index.xml.gz",
+		"/sitemap-index.xml.gz",
+	}
+	out := make([]*url.URL, 0, len(candidates))
+	for _, path := range candidates {
+		out = append(out, base.ResolveReference(&url.URL{Path: path}))
+	}
+	return out
+}
+
+// ===================== Filtering =====================
+
+func (f *SitemapFetcher) shouldInclude(candidate string) bool {
+	if len(f.opts.Include) > 0 {
+		matched := false
+		for _, re := range f.opts.Include {
+			if re != nil && re.MatchString(candidate) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, re := range f.opts.Exclude {
+		if re != nil && re.MatchString(candidate) {
+			return false
+		}
+	}
+	return true
+}
+
+// ===================== Entry Pipeline =====================
+
+// entryState carries a <url> entry through the configured pipeline stages.
+type entryState struct {
+	raw        xmlURLEntry
+	loc        *url.URL
+	lastMod    *time.Time
+	changeFreq ChangeFreq
+	priority   *float64
+
+	// rawLoc is raw.Loc as parsed, kept because StageDecode rewrites raw.Loc.
+	rawLoc string
+	// locErr is why StageResolve could not parse rawLoc.
+	locErr error
+	// priorityOutOfRange marks a priority kept under PriorityReport.
+	priorityOutOfRange bool
+	// lastModZoned records whether the parsed lastmod carried an offset.
+	lastModZoned bool
+
+	// Backing storage for lastMod and priority, so a reused state allocates nothing.
+	lastModValue  time.Time
+	priorityValue float64
+}
+
+// filterCandidate is the value Include/Exclude patterns are matched against.
+func (s *entryState) filterCandidate() string {
+	if s.loc != nil {
+		return s.loc.String()
+	}
+	return strings.TrimSpace(s.raw.Loc)
+}
+
+func validatePipeline(stages []PipelineStage) error {
+	seen := make(map[PipelineStage]struct{}, len(stages))
+	for _, stage := range stages {
+		switch stage {
+		case StageDecode, StageResolve, StageNormalize, StageValidate, StageFilter:
+		default:
+			return &ErrInvalidPipeline{Reason: fmt.Sprintf("unknown stage %q", stage)}
+		}
+		if _, ok := seen[stage]; ok {
+			return &ErrInvalidPipeline{Reason: fmt.Sprintf("duplicate stage %q", stage)}
+		}
+		seen[stage] = struct{}{}
+	}
+	if _, ok := seen[StageResolve]; !ok {
+		return &ErrInvalidPipeline{Reason: "missing required stage \"resolve\""}
+	}
+	return nil
+}
+
+// runPipeline applies the configured stages to entry and reports whether it should be emitted.
+// transform, if not nil, runs once the entry is resolved, before the first
+// StageFilter that follows StageResolve, or after the last stage.
+func (f *SitemapFetcher) runPipeline(ctx context.Context, sitemap *url.URL, entry xmlURLEntry, state *entryState, transform func(*entryState) bool) bool {
+	*state = entryState{raw: entry, rawLoc: entry.Loc, changeFreq: ChangeFreq(entry.ChangeFreq)}
+	for _, stage := range f.opts.Pipeline {
+		if stage == StageFilter && transform != nil && state.loc != nil {
+			if !transform(state) {
+				return false
+			}
+			transform = nil
+		}
+		switch stage {
+		case StageDecode:
+			state.raw.Loc = html.UnescapeString(state.raw.Loc)
+		case StageResolve:
+			loc, err := resolveLocation(sitemap, state.raw.Loc)
+			if err == nil && !f.allowedScheme(loc) {
+				f.logger.DebugContext(
+					ctx,
+					"skipping URL with disallowed scheme",
+					"loc", state.raw.Loc,
+					"sitemap", sitemap.String(),
+				)
+				return false
+			}
+			if err != nil {
+				state.locErr = err
+				switch f.opts.InvalidLoc {
+				case InvalidLocEmit:
+					continue
+				case InvalidLocError:
+					return false
+				}
+				f.logger.WarnContext(
+					ctx,
+					"skipping invalid URL",
+					"loc", state.raw.Loc,
+					"sitemap", sitemap.String(),
+					"error", err.Error(),
+				)
+				return false
+			}
+			if len(f.opts.StripQueryParams) > 0 {
+				loc.RawQuery = stripQueryParams(loc.RawQuery, f.opts.StripQueryParams)
+			}
+			state.loc = loc
+		case StageNormalize:
+			if parsed, zoned, ok := parseTimeIn(state.raw.LastMod, f.opts.LastModLocation); ok {
+				state.lastModValue = parsed
+				state.lastMod = &state.lastModValue
+				state.lastModZoned = zoned
+			}
+			state.changeFreq = ChangeFreq(strings.ToLower(strings.TrimSpace(state.raw.ChangeFreq)))
+			if state.changeFreq == "" {
+				state.changeFreq = f.opts.DefaultChangeFreq
+			}
+			if parsed, ok := parsePriorityValue(state.raw.Priority); ok {
+				if parsed, ok = f.priorityInRange(state, parsed); ok {
+					state.priorityValue = parsed
+					state.priority = &state.priorityValue
+				}
+			} else if f.opts.DefaultPriority != nil && strings.TrimSpace(state.raw.Priority) == "" {
+				state.priorityValue = *f.opts.DefaultPriority
+				state.priority = &state.priorityValue
+			}
+		case StageValidate:
+			if err := validateEntryValues(state.raw); err != nil {
+				f.logger.DebugContext(
+					ctx,
+					"invalid entry",
+					"loc", state.raw.Loc,
+					"sitemap", sitemap.String(),
+					"error", err.Error(),
+				)
+				return false
+			}
+		case StageFilter:
+			if !f.shouldInclude(state.filterCandidate()) {
+				return false
+			}
+		}
+	}
+	if transform != nil && state.loc != nil {
+		return transform(state)
+	}
+	return true
+}
+
+// parseLastMod parses a sitemapindex <lastmod> into Options.LastModLocation.
+func (f *SitemapFetcher) parseLastMod(value string) *time.Time {
+	parsed, _, ok := parseTimeIn(value, f.opts.LastModLocation)
+	if !ok {
+		return nil
+	}
+	return &parsed
+}
+
+// priorityInRange applies Options.PriorityRange to a parsed priority and
+// reports whether the entry keeps one.
+func (f *SitemapFetcher) priorityInRange(state *entryState, priority float64) (float64, bool) {
+	if priority >= 0 && priority <= 1 {
+		return priority, true
+	}
+	switch f.opts.PriorityRange {
+	case PriorityClamp:
+		if math.IsNaN(priority) {
+			return 0, false
+		}
+		return min(max(priority, 0), 1), true
+	case PriorityDrop:
+		return 0, false
+	case PriorityReport:
+		state.priorityOutOfRange = true
+	}
+	return priority, true
+}
+
+// decodeExtensions runs registered decoders over exts, keyed by namespace.
+func (f *SitemapFetcher) decodeExtensions(ctx context.Context, sitemap *url.URL, exts []Extension) map[string][]any {
+	if len(f.opts.ExtensionDecoders) == 0 {
+		return nil
+	}
+	var out map[string][]any
+	for _, ext := range exts {
+		decode, ok := f.opts.ExtensionDecoders[ext.Name.Space]
+		if !ok || decode == nil {
+			continue
+		}
+		value, err := decode(ext)
+		if err != nil {
+			f.logger.DebugContext(
+				ctx,
+				"failed to decode extension",
+				"namespace", ext.Name.Space,
+				"element", ext.Name.Local,
+				"sitemap", sitemap.String(),
+				"error", err.Error(),
+			)
+			continue
+		}
+		if out == nil {
+			out = make(map[string][]any)
+		}
+		out[ext.Name.Space] = append(out[ext.Name.Space], value)
+	}
+	return out
+}
+
+// stripQueryParams removes the parameters matching names from rawQuery,
+// keeping the order and encoding of the others.
+func stripQueryParams(rawQuery string, names []string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, part := range parts {
+		key, _, _ := strings.Cut(part, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !matchesParam(key, names) {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "&")
 }
 
-func isLikelySitemapURL(u *url.URL) bool {
-	if u == nil {
-		return false
+func matchesParam(key string, names []string) bool {
+	for _, name := range names {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == name {
+			return true
+		}
 	}
-	path := strings.ToLower(u.Path)
-	return strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz")
+	return false
 }
 
-func defaultSitemaps(base *url.URL) []*url.URL {
-	candidates := []string{
-		"/sitemap.xml",
-		"/sitemap_index.xml",
-		"/sitemap-index.xml",
-		"/sitemap.xml.gz",
-		"/sitemap_index.xml.gz",
-		"/sitemap-index.xml.gz",
+func validateEntryValues(entry xmlURLEntry) error {
+	if value := strings.TrimSpace(entry.LastMod); value != "" && parseTimeValue(value) == nil {
+		return fmt.Errorf("invalid lastmod %q", value)
 	}
-	out := make([]*url.URL, 0, len(candidates))
-	for _, path := range candidates {
-		out = append(out, base.ResolveReference(&url.URL{Path: path}))
+	if value := strings.TrimSpace(entry.Priority); value != "" && parsePriority(value) == nil {
+		return fmt.Errorf("invalid priority %q", value)
 	}
-	return out
+	return nil
 }
 
-// ===================== Filtering =====================
-
-func (f *SitemapFetcher) shouldInclude(u *url.URL) bool {
-	if u == nil {
-		return false
-	}
-	candidate := u.String()
-	if len(f.opts.Include) > 0 {
-		matched := false
-		for _, re := range f.opts.Include {
-			if re != nil && re.MatchString(candidate) {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			return false
-		}
+func patternStrings(patterns []*regexp.Regexp) []string {
+	if len(patterns) == 0 {
+		return nil
 	}
-	for _, re := range f.opts.Exclude {
-		if re != nil && re.MatchString(candidate) {
-			return false
+	out := make([]string, 0, len(patterns))
+	for _, re := range patterns {
+		if re != nil {
+			out = append(out, re.String())
 		}
 	}
-	return true
+	return out
 }
 
 // ===================== HTTP Helpers =====================
 
 func (f *SitemapFetcher) newRequest(ctx context.Context, method string, u *url.URL) (*http.Request, context.CancelFunc, error) {
+	if err := f.pacer.wait(ctx, u); err != nil {
+		return nil, nil, err
+	}
 	if f.opts.PerRequestTimeout > 0 {
 		ctx, cancel := context.WithTimeout(ctx, f.opts.PerRequestTimeout)
 		req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
@@ -457,138 +2220,309 @@ func (f *SitemapFetcher) newRequest(ctx context.Context, method string, u *url.U
 			cancel()
 			return nil, nil, err
 		}
-		req.Header.Set("User-Agent", f.opts.UserAgent)
+		f.setHeaders(req)
 		return req, cancel, nil
 	}
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
 	if err != nil {
 		return nil, nil, err
 	}
-	req.Header.Set("User-Agent", f.opts.UserAgent)
+	f.setHeaders(req)
 	return req, func() {}, nil
 }
 
-func (f *SitemapFetcher) fetchSitemap(ctx context.Context, loc *url.URL, allowMissing bool) (io.ReadCloser, error) {
+// setHeaders sets the headers every fetcher request carries.
+func (f *SitemapFetcher) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	f.setAcceptEncoding(req)
+	if f.opts.WalkIDHeader != "" {
+		if id, ok := WalkID(req.Context()); ok {
+			req.Header.Set(f.opts.WalkIDHeader, id)
+		}
+	}
+}
+
+func (f *SitemapFetcher) fetchSitemap(ctx context.Context, task sitemapTask) (*fetchedSitemap, error) {
+	loc, allowMissing := task.loc, task.allowMissing
+	reauthorized := false
+	// lastErr is the response a retry was made for, returned if the loop ends.
+	var lastErr error
 	for attempt := 0; attempt <= maxRetryAttempts; attempt++ {
-		req, cancel, err := f.newRequest(ctx, http.MethodGet, loc)
+		if err := f.breaker.allow(loc); err != nil {
+			f.logger.WarnContext(
+				ctx,
+				"skipping sitemap on failing host",
+				"sitemap", loc.String(),
+				"error", err.Error(),
+			)
+			return nil, &skippedSitemapError{err: err}
+		}
+		release, err := f.limiter.acquire(ctx, loc)
+		if err != nil {
+			return nil, err
+		}
+		req, cancel, err := f.newRequest(withAttempt(ctx, attempt+1), http.MethodGet, loc)
 		if err != nil {
 			if cancel != nil {
 				cancel()
 			}
+			release()
+			return nil, err
+		}
+		setConditional(req, task.prior)
+		// The host slot is freed with the request: on failure, before a retry
+		// delay, or when the body is closed.
+		cancelRequest := cancel
+		cancel = func() {
+			cancelRequest()
+			release()
+		}
+		if err := f.authorize(ctx, req); err != nil {
+			cancel()
 			return nil, err
 		}
 
+		var trace *networkTrace
+		if f.opts.NetworkTimings {
+			var traceCtx context.Context
+			traceCtx, trace = withNetworkTrace(req.Context())
+			req = req.WithContext(traceCtx)
+		}
+		start := time.Now()
 		resp, err := f.client.Do(req)
+		f.recordFetch(ctx, loc, resp, err)
 		if err != nil {
+			f.logger.DebugContext(
+				ctx,
+				"sitemap request failed",
+				"sitemap", loc.String(),
+				"attempt", attempt+1,
+				"duration", time.Since(start),
+				"error", err.Error(),
+			)
 			if cancel != nil {
 				cancel()
 			}
+			var redirectErr *ErrRedirectLoop
+			if errors.As(err, &redirectErr) {
+				return nil, redirectErr
+			}
 			return nil, err
 		}
-		if resp.StatusCode == http.StatusTooManyRequests {
-			delay := retryAfterDelay(resp)
+		f.logger.DebugContext(
+			ctx,
+			"sitemap response",
+			"sitemap", loc.String(),
+			"attempt", attempt+1,
+			"status", resp.StatusCode,
+			"duration", time.Since(start),
+		)
+		if resp.StatusCode == http.StatusNotModified && task.prior != nil {
 			resp.Body.Close()
-			if cancel != nil {
-				cancel()
-			}
-			if attempt == maxRetryAttempts {
-				return nil, &ErrHTTPStatus{URL: loc, StatusCode: resp.StatusCode, Status: resp.Status}
-			}
-			if delay <= 0 {
-				delay = defaultRetryDelay
-			}
-			if delay > maxRetryDelay {
-				delay = maxRetryDelay
-			}
-			f.logger.Debug(fmt.Sprintf("received 429 for %s, retrying in %s", loc, delay))
-			if err := sleepWithContext(ctx, delay); err != nil {
-				return nil, err
-			}
+			cancel()
+			return nil, errSitemapUnchanged
+		}
+		if resp.StatusCode == http.StatusUnauthorized && !reauthorized && f.invalidateAuth() {
+			resp.Body.Close()
+			cancel()
+			reauthorized = true
+			lastErr = &ErrHTTPStatus{URL: loc, StatusCode: resp.StatusCode, Status: resp.Status}
+			f.logger.DebugContext(
+				ctx,
+				"retrying sitemap with new credentials",
+				"sitemap", loc.String(),
+				"attempt", attempt+1,
+			)
+			// The retry with fresh credentials does not use up an attempt.
+			attempt--
 			continue
 		}
 		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			delay := retryAfterDelay(resp)
 			resp.Body.Close()
 			if cancel != nil {
 				cancel()
 			}
-			if f.opts.SkipNon200 {
-				f.logger.Warn(
+			statusErr := &ErrHTTPStatus{URL: loc, StatusCode: resp.StatusCode, Status: resp.Status}
+			switch f.statusAction(resp.StatusCode) {
+			case ActionRetry:
+				if attempt == maxRetryAttempts {
+					return nil, statusErr
+				}
+				if delay <= 0 {
+					delay = defaultRetryDelay
+				}
+				if delay > maxRetryDelay {
+					delay = maxRetryDelay
+				}
+				f.logger.DebugContext(
+					ctx,
+					"retrying sitemap",
+					"sitemap", loc.String(),
+					"attempt", attempt+1,
+					"status", resp.StatusCode,
+					"delay", delay,
+				)
+				if err := sleepWithContext(ctx, delay); err != nil {
+					return nil, err
+				}
+				lastErr = statusErr
+				continue
+			case ActionSkip:
+				f.logger.WarnContext(
+					ctx,
 					"skipping sitemap due to non-200 response",
 					"sitemap", loc.String(),
 					"status", resp.Status,
 				)
-				return nil, &skippedSitemapError{err: &ErrHTTPStatus{URL: loc, StatusCode: resp.StatusCode, Status: resp.Status}}
+				return nil, &skippedSitemapError{err: statusErr}
 			}
 			if allowMissing && resp.StatusCode == http.StatusNotFound {
-				f.logger.Debug(fmt.Sprintf("sitemap not found (probe) %s", loc))
+				f.logger.DebugContext(
+					ctx,
+					"sitemap not found (probe)",
+					"sitemap", loc.String(),
+				)
 				return nil, nil
 			}
-			return nil, &ErrHTTPStatus{URL: loc, StatusCode: resp.StatusCode, Status: resp.Status}
+			return nil, statusErr
 		}
 
-		reader, err := wrapReader(resp, cancel)
-		if err != nil {
+		if typeErr := f.checkContentType(ctx, loc, resp.Header); typeErr != nil {
+			resp.Body.Close()
+			cancel()
+			if allowMissing {
+				f.logger.DebugContext(
+					ctx,
+					"sitemap probe returned unexpected content type",
+					"sitemap", loc.String(),
+					"content_type", typeErr.ContentType,
+				)
+				return nil, nil
+			}
+			if f.contentTypePolicy(ctx) == ContentTypeSkip {
+				f.logger.WarnContext(
+					ctx,
+					"skipping sitemap due to unexpected content type",
+					"sitemap", loc.String(),
+					"content_type", typeErr.ContentType,
+				)
+				return nil, &skippedSitemapError{err: typeErr}
+			}
+			return nil, typeErr
+		}
+
+		fetchDuration := time.Since(start)
+		if f.opts.Archive != nil {
+			if err := f.archiveBody(loc, resp); err != nil {
+				resp.Body.Close()
+				if cancel != nil {
+					cancel()
+				}
+				return nil, err
+			}
+		}
+		network := &meteredReader{reader: resp.Body}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{network, resp.Body}
+		if err := f.decodeContent(resp); err != nil {
 			resp.Body.Close()
 			if cancel != nil {
 				cancel()
 			}
+			var encodingErr *ErrUnsupportedEncoding
+			if errors.As(err, &encodingErr) {
+				encodingErr.URL = cloneURL(loc)
+			}
+			return nil, err
+		}
+		reader, err := wrapReader(resp, cancel)
+		if err != nil {
+			var links []*url.URL
+			if reader != nil {
+				if f.opts.HTMLFallback && errors.Is(err, errHTMLDocument) {
+					links = f.htmlSitemapLinks(reader, responseURL(resp, loc))
+				}
+				reader.Close()
+			} else {
+				resp.Body.Close()
+				if cancel != nil {
+					cancel()
+				}
+			}
+			if errors.Is(err, errHTMLDocument) {
+				var notSitemap error
+				if allowMissing {
+					f.logger.DebugContext(
+						ctx,
+						"sitemap probe returned HTML",
+						"sitemap", loc.String(),
+					)
+				} else {
+					notSitemap = &ErrNotASitemap{URL: loc, ContentType: resp.Header.Get("Content-Type")}
+				}
+				if len(links) > 0 {
+					return nil, &htmlSitemapsError{links: links, err: notSitemap}
+				}
+				return nil, notSitemap
+			}
 			return nil, err
 		}
-		return reader, nil
+		return &fetchedSitemap{
+			ReadCloser:    reader,
+			header:        resp.Header,
+			network:       network,
+			fetchDuration: fetchDuration,
+			timings:       trace.result(),
+		}, nil
 	}
 
-	return nil, &ErrHTTPStatus{URL: loc, StatusCode: http.StatusTooManyRequests, Status: http.StatusText(http.StatusTooManyRequests)}
+	return nil, lastErr
 }
 
-func (f *SitemapFetcher) getRobots(ctx context.Context, base *url.URL, cache map[string]*robotsRules) (*robotsRules, error) {
-	key := base.Scheme + "://" + base.Host
-	if rules, ok := cache[key]; ok {
-		return rules, nil
-	}
-
-	robotsURL := base.ResolveReference(&url.URL{Path: "/robots.txt"})
-	req, cancel, err := f.newRequest(ctx, http.MethodGet, robotsURL)
-	if err != nil {
-		return nil, err
-	}
-	defer cancel()
-
-	resp, err := f.client.Do(req)
-	if err != nil {
-		rules := &robotsRules{}
-		cache[key] = rules
-		return rules, nil
+// statusAction resolves how a non-2xx sitemap response is handled.
+func (f *SitemapFetcher) statusAction(code int) Action {
+	if f.opts.StatusPolicy != nil {
+		if action := f.opts.StatusPolicy(code); action != ActionDefault {
+			return action
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		rules := &robotsRules{}
-		cache[key] = rules
-		return rules, nil
+	if code == http.StatusTooManyRequests {
+		return ActionRetry
 	}
-
-	data, err := robotstxt.FromResponse(resp)
-	if err != nil {
-		rules := &robotsRules{}
-		cache[key] = rules
-		return rules, nil
+	if f.opts.SkipNon200 {
+		return ActionSkip
 	}
+	return ActionError
+}
 
-	rules := &robotsRules{group: data.FindGroup(f.opts.UserAgent)}
-	for _, loc := range data.Sitemaps {
-		parsed, err := url.Parse(strings.TrimSpace(loc))
-		if err != nil {
-			f.logger.Debug(fmt.Sprintf("invalid sitemap URL %q in robots.txt %s: %v", loc, robotsURL, err))
-			continue
+// checkRedirect wraps next with loop and chain-length detection.
+func checkRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		key := canonicalURLKey(req.URL)
+		for i, prev := range via {
+			if canonicalURLKey(prev.URL) == key {
+				return &ErrRedirectLoop{URL: cloneURL(via[0].URL), Chain: redirectChain(via[i:], req)}
+			}
 		}
-		if !parsed.IsAbs() {
-			parsed = base.ResolveReference(parsed)
+		if len(via) >= maxRedirects {
+			return &ErrRedirectLoop{URL: cloneURL(via[0].URL), Chain: redirectChain(via, req), TooLong: true}
 		}
-		rules.sitemaps = append(rules.sitemaps, parsed)
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
 	}
+}
 
-	cache[key] = rules
-	return rules, nil
+func redirectChain(via []*http.Request, req *http.Request) []string {
+	chain := make([]string, 0, len(via)+1)
+	for _, prev := range via {
+		chain = append(chain, prev.URL.String())
+	}
+	return append(chain, req.URL.String())
 }
 
 func (f *SitemapFetcher) allowedByRobots(ctx context.Context, loc *url.URL, cache map[string]*robotsRules) (bool, error) {
@@ -597,7 +2531,13 @@ func (f *SitemapFetcher) allowedByRobots(ctx context.Context, loc *url.URL, cach
 	if err != nil {
 		return true, nil
 	}
-	if rules == nil || rules.group == nil {
+	if rules.err != nil {
+		return false, rules.err
+	}
+	if rules.denyAll {
+		return false, nil
+	}
+	if rules.group == nil {
 		return true, nil
 	}
 	path := loc.EscapedPath()
@@ -607,25 +2547,70 @@ func (f *SitemapFetcher) allowedByRobots(ctx context.Context, loc *url.URL, cach
 	return rules.group.Test(path), nil
 }
 
+// wrapReader decompresses gzip bodies by their 0x1f 0x8b magic prefix rather
+// than the URL extension or Content-Type, since .xml URLs serving gzipped bytes
+// and .gz URLs served as text/xml are both common. Up to maxGzipLayers nested
+// layers are removed, for .xml.gz files that a CDN compressed again without a
+// Content-Encoding the transport could undo. For an HTML page it returns the
+// body along with errHTMLDocument.
 func wrapReader(resp *http.Response, cancel context.CancelFunc) (io.ReadCloser, error) {
 	reader := bufio.NewReaderSize(resp.Body, defaultBufSize)
-	peek, err := reader.Peek(2)
-	if err == nil && len(peek) == 2 && peek[0] == 0x1f && peek[1] == 0x8b {
+	var layers []io.Closer
+	for len(layers) < maxGzipLayers {
+		peek, err := reader.Peek(2)
+		if err != nil || peek[0] != 0x1f || peek[1] != 0x8b {
+			break
+		}
 		gz, err := gzip.NewReader(reader)
 		if err != nil {
 			return nil, err
 		}
-		return &multiCloser{reader: gz, closers: []io.Closer{gz, resp.Body, cancelCloser{cancel: cancel}}}, nil
+		layers = append(layers, gz)
+		reader = bufio.NewReaderSize(gz, defaultBufSize)
+	}
+	closers := make([]io.Closer, 0, len(layers)+2)
+	for i := len(layers) - 1; i >= 0; i-- {
+		closers = append(closers, layers[i])
+	}
+	closers = append(closers, resp.Body, cancelCloser{cancel: cancel})
+	body := &multiCloser{reader: reader, closers: closers}
+	if looksLikeHTML(reader) {
+		return body, errHTMLDocument
+	}
+	return body, nil
+}
+
+// peekHead returns the start of the body, up to n bytes, without consuming it.
+// It waits for more bytes only while decided reports that head cannot be
+// classified yet, so sniffing stops at EOF, at n bytes, or as soon as the first
+// element is in, and never holds back a body streamed in small pieces.
+func peekHead(reader *bufio.Reader, n int, decided func(head []byte) bool) []byte {
+	for want := 1; ; {
+		_, err := reader.Peek(want)
+		head, _ := reader.Peek(min(reader.Buffered(), n))
+		if err != nil || len(head) >= n || decided(head) {
+			return head
+		}
+		want = len(head) + 1
 	}
-	return &readCloser{
-		reader: reader,
-		close: func() error {
-			if cancel != nil {
-				cancel()
-			}
-			return resp.Body.Close()
-		},
-	}, nil
+}
+
+// trimHead drops a byte order mark and leading whitespace from a sniffed head.
+func trimHead(head []byte) []byte {
+	return bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+}
+
+// errHTMLDocument is reported by wrapReader when the body is an HTML page.
+var errHTMLDocument = errors.New("HTML document")
+
+// looksLikeHTML reports whether the body starts like an HTML page.
+func looksLikeHTML(reader *bufio.Reader) bool {
+	head := peekHead(reader, 512, func(head []byte) bool {
+		head = trimHead(head)
+		return len(head) >= len("<!doctype html") || (len(head) > 0 && head[0] != '<')
+	})
+	head = bytes.ToLower(trimHead(head))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
 }
 
 func retryAfterDelay(resp *http.Response) time.Duration {
@@ -662,12 +2647,152 @@ func sleepWithContext(ctx context.Context, delay time.Duration) error {
 	}
 }
 
+// ===================== Document Formats =====================
+
+func (f *SitemapFetcher) formatEnabled(format SitemapFormat) bool {
+	if format == FormatXML {
+		return true
+	}
+	for _, enabled := range f.opts.Formats {
+		if enabled == format {
+			return true
+		}
+	}
+	return false
+}
+
+// detectFormat classifies the document from its first bytes without consuming them.
+func detectFormat(reader *bufio.Reader) SitemapFormat {
+	head := peekHead(reader, 1024, func(head []byte) bool {
+		_, ok := sniffFormat(head)
+		return ok
+	})
+	format, _ := sniffFormat(head)
+	return format
+}
+
+// sniffFormat classifies head, reporting false while it ends before the root
+// element; the format is then FormatXML.
+func sniffFormat(head []byte) (SitemapFormat, bool) {
+	head = trimHead(head)
+	if len(head) == 0 {
+		return FormatXML, false
+	}
+	if head[0] != '<' {
+		return FormatText, true
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(head))
+	decoder.Strict = false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return FormatXML, false
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "rss", "RDF":
+			return FormatRSS, true
+		case "feed":
+			return FormatAtom, true
+		}
+		return FormatXML, true
+	}
+}
+
+func parseTextSitemap(ctx context.Context, reader io.Reader, onURL func(xmlURLEntry) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), defaultBufSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := onURL(xmlURLEntry{Loc: line}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type xmlFeedItem struct {
+	Link    string `xml:"link"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"`
+}
+
+type xmlFeedEntry struct {
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Updated string `xml:"updated"`
+}
+
+// parseFeed emits the link of every RSS <item> and Atom <entry>.
+func parseFeed(ctx context.Context, reader io.Reader, onURL func(xmlURLEntry) error) error {
+	decoder := xml.NewDecoder(reader)
+	decoder.Strict = false
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tok, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var entry xmlURLEntry
+		switch start.Name.Local {
+		case "item":
+			var item xmlFeedItem
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				return err
+			}
+			entry = xmlURLEntry{Loc: item.Link, LastMod: item.PubDate}
+			if entry.LastMod == "" {
+				entry.LastMod = item.Date
+			}
+		case "entry":
+			var item xmlFeedEntry
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				return err
+			}
+			for _, link := range item.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					entry.Loc = link.Href
+					break
+				}
+			}
+			entry.LastMod = item.Updated
+		default:
+			continue
+		}
+		if err := onURL(entry); err != nil {
+			return err
+		}
+	}
+}
+
 // ===================== XML Parsing =====================
 
-func parseSitemap(ctx context.Context, reader io.Reader, onURL func(xmlURLEntry) error, onSitemap func(xmlSitemapEntry) error) error {
+func parseSitemap(ctx context.Context, reader io.Reader, keepExtensions bool, onRoot func(xml.Name) error, onURL func(xmlURLEntry) error, onSitemap func(xmlSitemapEntry) error) error {
 	decoder := xml.NewDecoder(reader)
 	decoder.Strict = false
 
+	sawRoot := false
 	for {
 		if err := ctx.Err(); err != nil {
 			return err
@@ -683,10 +2808,25 @@ func parseSitemap(ctx context.Context, reader io.Reader, onURL func(xmlURLEntry)
 		if !ok {
 			continue
 		}
+		if !sawRoot {
+			sawRoot = true
+			if onRoot != nil {
+				if err := onRoot(start.Name); err != nil {
+					return err
+				}
+			}
+		}
 		switch start.Name.Local {
 		case "url":
 			var entry xmlURLEntry
-			if err := decoder.DecodeElement(&entry, &start); err != nil {
+			if keepExtensions {
+				var extended xmlURLEntryWithExtensions
+				if err := decoder.DecodeElement(&extended, &start); err != nil {
+					return err
+				}
+				entry = extended.xmlURLEntry
+				entry.extensions = extended.Extensions
+			} else if err := decoder.DecodeElement(&entry, &start); err != nil {
 				return err
 			}
 			if onURL != nil {
@@ -727,36 +2867,94 @@ func resolveLocation(base *url.URL, loc string) (*url.URL, error) {
 }
 
 func parseTimeValue(value string) *time.Time {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
+	parsed, ok := parseTime(value)
+	if !ok {
 		return nil
 	}
-	layouts := []string{
-		time.RFC3339Nano,
-		time.RFC3339,
-		"2006-01-02",
-		"2006-01-02T15:04:05",
-		time.RFC1123,
-		time.RFC1123Z,
+	return &parsed
+}
+
+func parseTime(value string) (time.Time, bool) {
+	parsed, _, ok := parseTimeIn(value, nil)
+	return parsed, ok
+}
+
+// timeLayouts are the accepted <lastmod> forms; zoned marks layouts that carry
+// an explicit offset or zone.
+var timeLayouts = []struct {
+	layout string
+	zoned  bool
+}{
+	{time.RFC3339Nano, true},
+	{time.RFC3339, true},
+	{"2006-01-02", false},
+	{"2006-01-02T15:04:05", false},
+	{time.RFC1123, true},
+	{time.RFC1123Z, true},
+}
+
+// parseTimeIn parses a <lastmod> value and reports whether it carried an
+// explicit offset. With a non-nil loc, the result is in loc and values without
+// an offset are read as local to it.
+func parseTimeIn(value string, loc *time.Location) (parsed time.Time, zoned, ok bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return time.Time{}, false, false
 	}
-	for _, layout := range layouts {
-		if parsed, err := time.Parse(layout, trimmed); err == nil {
-			return &parsed
+	for _, candidate := range timeLayouts {
+		var err error
+		if loc == nil {
+			parsed, err = time.Parse(candidate.layout, trimmed)
+		} else {
+			parsed, err = time.ParseInLocation(candidate.layout, trimmed, loc)
+		}
+		if err != nil {
+			continue
 		}
+		if loc != nil {
+			parsed = parsed.In(loc)
+		}
+		return parsed, candidate.zoned, true
 	}
-	return nil
+	return time.Time{}, false, false
 }
 
 func parsePriority(value string) *float64 {
+	parsed, ok := parsePriorityValue(value)
+	if !ok {
+		return nil
+	}
+	return &parsed
+}
+
+func parsePriorityValue(value string) (float64, bool) {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
-		return nil
+		return 0, false
 	}
 	parsed, err := strconv.ParseFloat(trimmed, 64)
 	if err != nil {
-		return nil
+		return 0, false
 	}
-	return &parsed
+	return parsed, true
+}
+
+// allowedSchemes builds the Options.AllowedSchemes lookup, defaulting to http and https.
+func allowedSchemes(schemes []string) map[string]struct{} {
+	if schemes == nil {
+		schemes = []string{"http", "https"}
+	}
+	set := make(map[string]struct{}, len(schemes))
+	for _, scheme := range schemes {
+		set[strings.ToLower(strings.TrimSuffix(scheme, ":"))] = struct{}{}
+	}
+	return set
+}
+
+// allowedScheme reports whether u may be emitted or followed under AllowedSchemes.
+func (f *SitemapFetcher) allowedScheme(u *url.URL) bool {
+	_, ok := f.schemes[strings.ToLower(u.Scheme)]
+	return ok
 }
 
 func canonicalURLKey(u *url.URL) string {
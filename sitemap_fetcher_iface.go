@@ -2,6 +2,9 @@ package gositemapfetcher
 
 import (
 	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/url"
 	"time"
 )
@@ -16,13 +19,180 @@ type SitemapWalker interface {
 type Item struct {
 	Loc        *url.URL
 	LastMod    *time.Time
-	ChangeFreq string
+	ChangeFreq ChangeFreq
 	Priority   *float64
 	Sitemap    *url.URL
+	// LastModZoned reports whether the <lastmod> text carried an explicit UTC
+	// offset or zone. Without one, LastMod is read as UTC or, if set, as local
+	// to Options.LastModLocation.
+	LastModZoned bool
+	// RawChangeFreq is the <changefreq> text as the sitemap gave it; ChangeFreq
+	// is that value trimmed and lowercased by StageNormalize.
+	RawChangeFreq string
+	// RawLoc is the <loc> text exactly as the sitemap gave it once XML entities
+	// are decoded: before trimming, HTML unescaping, resolution against Sitemap,
+	// and normalization. Validators can use it to report the original value.
+	RawLoc string
+	// Mobile reports a <mobile:mobile/> element, which marks a page built for
+	// feature phones. The element is not repeated in Extensions.
+	Mobile bool
+	// Depth is the index depth of Sitemap; 0 for the sitemap Walk started from.
+	Depth int
+	// Position is the 1-based ordinal of the <url> entry within Sitemap, counting filtered entries.
+	Position int
+	// SitemapLastMod is the <lastmod> the parent sitemapindex declared for Sitemap, if any.
+	SitemapLastMod *time.Time
+	// Extensions holds unrecognized <url> child elements when Options.KeepExtensions is set.
+	Extensions []Extension
+	// Ext holds values produced by Options.ExtensionDecoders, keyed by namespace URI.
+	Ext map[string][]any
+	// LinkCheck is the outcome of the Options.Verify request for Loc, if enabled.
+	LinkCheck *LinkCheck
+	// ValidationErrors lists problems with an entry yielded despite them, such
+	// as an unparseable <loc> under InvalidLocEmit (in which case Loc is nil) or
+	// an unknown <changefreq> under Options.FlagChangeFreq.
+	ValidationErrors []string
 }
 
-// SkippedSitemap describes a sitemap fetch/open failure skipped by SkipNon200 or SkipFetchErrors.
+// ChangeFreq is a <changefreq> value. Sitemaps in the wild use values outside
+// the protocol's set, so a ChangeFreq may hold any string; IsValid reports
+// whether it is one of the constants below.
+type ChangeFreq string
+
+// The <changefreq> values defined by the sitemap protocol.
+const (
+	ChangeFreqAlways  ChangeFreq = "always"
+	ChangeFreqHourly  ChangeFreq = "hourly"
+	ChangeFreqDaily   ChangeFreq = "daily"
+	ChangeFreqWeekly  ChangeFreq = "weekly"
+	ChangeFreqMonthly ChangeFreq = "monthly"
+	ChangeFreqYearly  ChangeFreq = "yearly"
+	ChangeFreqNever   ChangeFreq = "never"
+)
+
+// IsValid reports whether c is one of the protocol's values. The comparison is
+// exact; StageNormalize lowercases values before they reach Item.ChangeFreq.
+func (c ChangeFreq) IsValid() bool {
+	switch c {
+	case ChangeFreqAlways, ChangeFreqHourly, ChangeFreqDaily, ChangeFreqWeekly, ChangeFreqMonthly, ChangeFreqYearly, ChangeFreqNever:
+		return true
+	}
+	return false
+}
+
+// Extension is an unrecognized child element of <url>, kept as namespace-resolved tokens.
+type Extension struct {
+	Name   xml.Name
+	Tokens []xml.Token
+}
+
+// UnmarshalXML records the element and all of its descendants as tokens.
+func (e *Extension) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	e.Name = start.Name
+	e.Tokens = append(e.Tokens[:0], start.Copy())
+	for depth := 1; depth > 0; {
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		e.Tokens = append(e.Tokens, xml.CopyToken(tok))
+	}
+	return nil
+}
+
+// MarshalXML writes the recorded element in place of start, so an Extension
+// can be passed to xml.Encoder.Encode or xml.Marshal. Namespace declarations
+// kept from the source document are dropped: the encoder declares each
+// element's namespace from its resolved name, and would otherwise write them
+// twice.
+func (e Extension) MarshalXML(encoder *xml.Encoder, _ xml.StartElement) error {
+	for _, tok := range e.Tokens {
+		if start, ok := tok.(xml.StartElement); ok {
+			attrs := start.Attr[:0:0]
+			for _, attr := range start.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			start.Attr = attrs
+			tok = start
+		}
+		if err := encoder.EncodeToken(tok); err != nil {
+			return fmt.Errorf("encode extension %s: %w", e.Name.Local, err)
+		}
+	}
+	return nil
+}
+
+// Decode unmarshals the extension element into v using encoding/xml rules.
+// Namespaces are resolved, so tags such as `xml:"http://www.google.com/schemas/sitemap-image/1.1 loc"` match.
+func (e Extension) Decode(v any) error {
+	return xml.NewTokenDecoder(&tokenReader{tokens: e.Tokens}).Decode(v)
+}
+
+type tokenReader struct {
+	tokens []xml.Token
+}
+
+func (r *tokenReader) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	tok := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return tok, nil
+}
+
+// SkippedSitemap describes a sitemap fetch/open failure skipped by SkipNon200, StatusPolicy, SkipFetchErrors, or OnError.
 type SkippedSitemap struct {
 	URL string
 	Err error
 }
+
+// SitemapStat records fetch and parse timings for one sitemap document.
+type SitemapStat struct {
+	URL string
+	// WalkID identifies the walk that fetched the sitemap.
+	WalkID string
+	// FetchDuration is the time from sending the request to receiving response headers.
+	FetchDuration time.Duration
+	// ReadDuration is the time spent waiting on the network while streaming the body.
+	ReadDuration time.Duration
+	// ParseDuration is decode time, excluding ReadDuration and time spent in the yield callback.
+	ParseDuration time.Duration
+	// Bytes is the decoded (decompressed) document size.
+	Bytes int64
+	// Entries is the number of <url> and <sitemap> elements decoded.
+	Entries int
+	// Network breaks FetchDuration down by phase; nil unless
+	// Options.NetworkTimings is set.
+	Network *NetworkTimings
+}
+
+// total returns the time spent fetching, reading, and parsing the sitemap.
+func (s SitemapStat) total() time.Duration {
+	return s.FetchDuration + s.ReadDuration + s.ParseDuration
+}
+
+// BytesPerSecond returns decoded bytes per second of ParseDuration.
+func (s SitemapStat) BytesPerSecond() float64 {
+	if s.ParseDuration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.ParseDuration.Seconds()
+}
+
+// EntriesPerSecond returns decoded entries per second of ParseDuration.
+func (s SitemapStat) EntriesPerSecond() float64 {
+	if s.ParseDuration <= 0 {
+		return 0
+	}
+	return float64(s.Entries) / s.ParseDuration.Seconds()
+}
@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/xml"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -113,6 +117,90 @@ func TestSitemapFetcher_Walk_IndexAndNested(t *testing.T) {
 	}
 }
 
+func TestSitemapFetcher_SitemapStats(t *testing.T) {
+	const pages = `<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/pages.xml</loc></sitemap></sitemapindex>`))
+		case "/pages.xml":
+			_, _ = w.Write([]byte(pages))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+	fetcher := New(Options{})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	stats := fetcher.SitemapStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 sitemap stats, got %d", len(stats))
+	}
+	if stats[0].URL != indexURL.String() || stats[0].Entries != 1 || stats[0].Bytes == 0 {
+		t.Fatalf("unexpected index stat: %+v", stats[0])
+	}
+	if !strings.HasSuffix(stats[1].URL, "/pages.xml") || stats[1].Entries != 2 || stats[1].Bytes != int64(len(pages)) {
+		t.Fatalf("unexpected urlset stat: %+v", stats[1])
+	}
+}
+
+func TestSitemapFetcher_ItemProvenance(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/dated.xml</loc><lastmod>2024-03-04</lastmod></sitemap>
+<sitemap><loc>/undated.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/dated.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url></urlset>`))
+		case "/undated.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/c</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+	items, err := collectItems(New(Options{}), indexURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+	for i, want := range []struct {
+		path     string
+		position int
+		lastMod  string
+	}{
+		{"/a", 1, "2024-03-04"},
+		{"/b", 2, "2024-03-04"},
+		{"/c", 1, ""},
+	} {
+		item := items[i]
+		var lastMod string
+		if item.SitemapLastMod != nil {
+			lastMod = item.SitemapLastMod.Format("2006-01-02")
+		}
+		if item.Loc.Path != want.path || item.Depth != 1 || item.Position != want.position || lastMod != want.lastMod {
+			t.Fatalf("item %d: expected %s depth 1 position %d sitemap lastmod %q, got %s depth %d position %d sitemap lastmod %q",
+				i, want.path, want.position, want.lastMod, item.Loc.Path, item.Depth, item.Position, lastMod)
+		}
+	}
+}
+
 func TestSitemapFetcher_Walk_Gzip(t *testing.T) {
 	const nested = `<?xml version="1.0" encoding="UTF-8"?>
 <urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
@@ -150,6 +238,92 @@ func TestSitemapFetcher_Walk_Gzip(t *testing.T) {
 	}
 }
 
+func TestSitemapFetcher_Walk_GzipMagic(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(`<urlset><url><loc>/gzip-page</loc></url></urlset>`))
+	_ = gzipWriter.Close()
+
+	contentTypes := map[string]string{
+		"/sitemap.xml":    "application/xml",
+		"/sitemap":        "application/octet-stream",
+		"/sitemap.txt":    "text/plain",
+		"/sitemap.xml.gz": "text/xml",
+	}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType, ok := contentTypes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(gzipped.Bytes())
+	}))
+	defer server.Close()
+
+	for path := range contentTypes {
+		sitemapURL, err := url.Parse(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to parse sitemap URL: %v", err)
+		}
+		fetcher := New(Options{Discovery: DiscoveryOff})
+		items, err := collectItems(fetcher, sitemapURL)
+		if err != nil {
+			t.Fatalf("walk of %s failed: %v", path, err)
+		}
+		if len(items) != 1 || items[0].Loc.Path != "/gzip-page" {
+			t.Fatalf("expected gzipped sitemap at %s to be decoded, got %v", path, items)
+		}
+	}
+}
+
+func TestSitemapFetcher_Walk_DoubleGzip(t *testing.T) {
+	gzipBytes := func(data []byte) []byte {
+		var buf bytes.Buffer
+		gzipWriter := gzip.NewWriter(&buf)
+		_, _ = gzipWriter.Write(data)
+		_ = gzipWriter.Close()
+		return buf.Bytes()
+	}
+	file := gzipBytes([]byte(`<urlset><url><loc>/gzip-page</loc></url></urlset>`))
+	twice := gzipBytes(file)
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/encoded.xml.gz":
+			// A .xml.gz file compressed again by the CDN and labeled as such.
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(twice)
+		case "/unlabeled.xml.gz":
+			// The same, with the Content-Encoding header lost along the way.
+			_, _ = w.Write(twice)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	clients := map[string]*http.Client{
+		"transport decoding":    http.DefaultClient,
+		"no transport decoding": {Transport: &http.Transport{DisableCompression: true}},
+	}
+	for _, path := range []string{"/encoded.xml.gz", "/unlabeled.xml.gz"} {
+		for name, client := range clients {
+			sitemapURL, err := url.Parse(server.URL + path)
+			if err != nil {
+				t.Fatalf("failed to parse sitemap URL: %v", err)
+			}
+			items, err := collectItems(New(Options{HTTPClient: client, IgnoreRobots: true}), sitemapURL)
+			if err != nil {
+				t.Fatalf("%s with %s: walk failed: %v", path, name, err)
+			}
+			if len(items) != 1 || items[0].Loc.Path != "/gzip-page" {
+				t.Fatalf("%s with %s: expected both layers removed, got %v", path, name, items)
+			}
+		}
+	}
+}
+
 func TestSitemapFetcher_RespectRobots_Default(t *testing.T) {
 	const robots = "User-agent: *\nDisallow: /sitemap.xml\n"
 	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
@@ -232,23 +406,1412 @@ func TestSitemapFetcher_IgnoreRobots(t *testing.T) {
 	}
 }
 
-func TestSitemapFetcher_IncludeExclude(t *testing.T) {
-	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
-<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
-  <url>
-    <loc>/keep</loc>
-  </url>
-  <url>
-    <loc>/skip</loc>
-  </url>
-</urlset>`
-
+func TestSitemapFetcher_RawLoc(t *testing.T) {
+	const sitemap = `<urlset>
+<url><loc>  /search?q=a&amp;amp;page=2 </loc></url>
+<url><loc>https://other.example/b</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err := collectItems(New(Options{}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].RawLoc != "  /search?q=a&amp;page=2 " {
+		t.Fatalf("expected the untrimmed, unresolved loc, got %q", items[0].RawLoc)
+	}
+	if !strings.HasPrefix(items[0].Loc.String(), server.URL+"/search?") {
+		t.Fatalf("expected the resolved loc, got %s", items[0].Loc)
+	}
+	if items[1].RawLoc != "https://other.example/b" {
+		t.Fatalf("unexpected raw loc %q", items[1].RawLoc)
+	}
+}
+
+func TestSitemapFetcher_ChangeFreq(t *testing.T) {
+	const sitemap = `<urlset>
+<url><loc>/a</loc><changefreq> Daily </changefreq></url>
+<url><loc>/b</loc><changefreq>fortnightly</changefreq></url>
+<url><loc>/c</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err := collectItems(New(Options{FlagChangeFreq: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+	if items[0].ChangeFreq != ChangeFreqDaily || !items[0].ChangeFreq.IsValid() {
+		t.Fatalf("expected normalized daily, got %q", items[0].ChangeFreq)
+	}
+	if items[0].RawChangeFreq != " Daily " {
+		t.Fatalf("expected the raw changefreq, got %q", items[0].RawChangeFreq)
+	}
+	if len(items[0].ValidationErrors) != 0 {
+		t.Fatalf("expected no validation errors, got %v", items[0].ValidationErrors)
+	}
+	if items[1].ChangeFreq != "fortnightly" || items[1].ChangeFreq.IsValid() {
+		t.Fatalf("expected an invalid fortnightly value, got %q", items[1].ChangeFreq)
+	}
+	if len(items[1].ValidationErrors) != 1 || !strings.HasPrefix(items[1].ValidationErrors[0], "changefreq: ") {
+		t.Fatalf("expected a changefreq validation error, got %v", items[1].ValidationErrors)
+	}
+	if items[2].ChangeFreq != "" || len(items[2].ValidationErrors) != 0 {
+		t.Fatalf("expected a missing changefreq to pass, got %q %v", items[2].ChangeFreq, items[2].ValidationErrors)
+	}
+}
+
+func TestSitemapFetcher_PriorityRange(t *testing.T) {
+	const sitemap = `<urlset>
+<url><loc>/low</loc><priority>-0.5</priority></url>
+<url><loc>/ok</loc><priority>0.8</priority></url>
+<url><loc>/high</loc><priority>3</priority></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	format := func(items []Item) string {
+		var parts []string
+		for _, item := range items {
+			value := "nil"
+			if item.Priority != nil {
+				value = strconv.FormatFloat(*item.Priority, 'g', -1, 64)
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s/%d", item.Loc.Path, value, len(item.ValidationErrors)))
+		}
+		return strings.Join(parts, ",")
+	}
+	for policy, want := range map[PriorityPolicy]string{
+		PriorityKeep:   "/low=-0.5/0,/ok=0.8/0,/high=3/0",
+		PriorityClamp:  "/low=0/0,/ok=0.8/0,/high=1/0",
+		PriorityDrop:   "/low=nil/0,/ok=0.8/0,/high=nil/0",
+		PriorityReport: "/low=-0.5/1,/ok=0.8/0,/high=3/1",
+	} {
+		items, err := collectItems(New(Options{PriorityRange: policy}), sitemapURL)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", policy, err)
+		}
+		if got := format(items); got != want {
+			t.Fatalf("%s: expected %s, got %s", policy, want, got)
+		}
+	}
+}
+
+func TestSitemapFetcher_DefaultChangeFreqPriority(t *testing.T) {
+	const sitemap = `<urlset>
+<url><loc>/bare</loc></url>
+<url><loc>/empty</loc><changefreq> </changefreq><priority></priority></url>
+<url><loc>/set</loc><changefreq>Hourly</changefreq><priority>0.9</priority></url>
+<url><loc>/bad</loc><priority>high</priority></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	format := func(items []Item) string {
+		var parts []string
+		for _, item := range items {
+			value := "nil"
+			if item.Priority != nil {
+				value = strconv.FormatFloat(*item.Priority, 'g', -1, 64)
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s/%s", item.Loc.Path, item.ChangeFreq, value))
+		}
+		return strings.Join(parts, ",")
+	}
+
+	items, err := collectItems(New(Options{}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := format(items), "/bare=/nil,/empty=/nil,/set=hourly/0.9,/bad=/nil"; got != want {
+		t.Fatalf("expected %s without defaults, got %s", want, got)
+	}
+
+	priority := 0.5
+	items, err = collectItems(New(Options{DefaultChangeFreq: ChangeFreqWeekly, DefaultPriority: &priority}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := format(items), "/bare=weekly/0.5,/empty=weekly/0.5,/set=hourly/0.9,/bad=weekly/nil"; got != want {
+		t.Fatalf("expected %s with defaults, got %s", want, got)
+	}
+	if items[0].RawChangeFreq != "" {
+		t.Fatalf("expected RawChangeFreq to stay empty, got %q", items[0].RawChangeFreq)
+	}
+}
+
+func TestSitemapFetcher_LastModLocation(t *testing.T) {
+	const sitemap = `<urlset>
+<url><loc>/zoned</loc><lastmod>2025-03-01T23:30:00-05:00</lastmod></url>
+<url><loc>/date</loc><lastmod>2025-03-01</lastmod></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	items, err := collectItems(New(Options{LastModLocation: time.UTC}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if got := items[0].LastMod.Format(time.RFC3339); got != "2025-03-02T04:30:00Z" || !items[0].LastModZoned {
+		t.Fatalf("expected a zoned value converted to UTC, got %s (zoned %v)", got, items[0].LastModZoned)
+	}
+	if got := items[1].LastMod.Format(time.RFC3339); got != "2025-03-01T00:00:00Z" || items[1].LastModZoned {
+		t.Fatalf("expected a bare date at UTC midnight, got %s (zoned %v)", got, items[1].LastModZoned)
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	items, err = collectItems(New(Options{LastModLocation: tokyo}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := items[0].LastMod.Format(time.RFC3339); got != "2025-03-02T13:30:00+09:00" {
+		t.Fatalf("expected a zoned value converted to JST, got %s", got)
+	}
+	if got := items[1].LastMod.Format(time.RFC3339); got != "2025-03-01T00:00:00+09:00" {
+		t.Fatalf("expected a bare date read as JST midnight, got %s", got)
+	}
+}
+
+func TestSitemapFetcher_Mobile(t *testing.T) {
+	const sitemap = `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:mobile="http://www.google.com/schemas/sitemap-mobile/1.0">
+<url><loc>/m</loc><mobile:mobile/></url>
+<url><loc>/desktop</loc></url>
+<url><loc>/undeclared</loc><mobile /></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	for _, keep := range []bool{false, true} {
+		items, err := collectItems(New(Options{KeepExtensions: keep}), sitemapURL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(items) != 3 {
+			t.Fatalf("expected 3 items, got %d", len(items))
+		}
+		if !items[0].Mobile || items[1].Mobile || !items[2].Mobile {
+			t.Fatalf("keep extensions %v: unexpected mobile flags %v %v %v", keep, items[0].Mobile, items[1].Mobile, items[2].Mobile)
+		}
+		if len(items[0].Extensions) != 0 {
+			t.Fatalf("expected mobile not to be repeated in Extensions, got %d", len(items[0].Extensions))
+		}
+	}
+}
+
+func TestSitemapFetcher_InvalidLoc(t *testing.T) {
+	const sitemap = `<urlset>
+<url><loc>/a</loc></url>
+<url><loc>http://[::1/broken</loc></url>
+<url><loc>/b</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	items, err := collectItems(New(Options{Logger: slog.New(handler)}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected the invalid entry to be skipped, got %d items", len(items))
+	}
+	if !handler.hasWarningContaining("invalid URL") {
+		t.Fatal("expected a warning for the skipped entry")
+	}
+
+	items, err = collectItems(New(Options{InvalidLoc: InvalidLocEmit}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+	invalid := items[1]
+	if invalid.Loc != nil || invalid.RawLoc != "http://[::1/broken" || len(invalid.ValidationErrors) != 1 {
+		t.Fatalf("expected the invalid entry with its error, got %+v", invalid)
+	}
+	if len(items[0].ValidationErrors) != 0 || items[2].Loc == nil {
+		t.Fatalf("expected valid entries untouched, got %+v and %+v", items[0], items[2])
+	}
+
+	items, err = collectItems(New(Options{InvalidLoc: InvalidLocError}), sitemapURL)
+	var locErr *ErrInvalidLoc
+	if !errors.As(err, &locErr) {
+		t.Fatalf("expected ErrInvalidLoc, got %v", err)
+	}
+	if locErr.Position != 2 || locErr.Loc != "http://[::1/broken" {
+		t.Fatalf("unexpected error details: %+v", locErr)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item before the invalid entry, got %d", len(items))
+	}
+}
+
+func TestSitemapFetcher_AllowedSchemes(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/pages.xml</loc></sitemap>
+<sitemap><loc>ftp://files.example/sitemap.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/pages.xml":
+			_, _ = w.Write([]byte(`<urlset>
+<url><loc>/a</loc></url>
+<url><loc>javascript:alert(1)</loc></url>
+<url><loc>FTP://files.example/b</loc></url>
+<url><loc>https://example.com/c</loc></url>
+</urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	items, err := collectItems(New(Options{}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0].Loc.Path != "/a" || items[1].Loc.Host != "example.com" {
+		t.Fatalf("expected only the http(s) items, got %+v", items)
+	}
+
+	items, err = collectItems(New(Options{AllowedSchemes: []string{"http", "https", "ftp:"}, SkipFetchErrors: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 3 || items[1].Loc.Scheme != "ftp" {
+		t.Fatalf("expected ftp items to be allowed, got %+v", items)
+	}
+}
+
+func TestSitemapFetcher_IncludeExclude(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>/keep</loc>
+  </url>
+  <url>
+    <loc>/skip</loc>
+  </url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		Include: []*regexp.Regexp{regexp.MustCompile("keep")},
+		Exclude: []*regexp.Regexp{regexp.MustCompile("skip")},
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if !strings.HasSuffix(items[0].Loc.String(), "/keep") {
+		t.Fatalf("expected /keep URL, got %s", items[0].Loc.String())
+	}
+}
+
+func TestSitemapFetcher_StripQueryParams(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>/page?id=7&amp;utm_source=mail&amp;utm_medium=email&amp;gclid=abc&amp;sort=asc</loc>
+  </url>
+  <url>
+    <loc>/landing?fbclid=xyz</loc>
+  </url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		StripQueryParams: DefaultTrackingParams,
+		Exclude:          []*regexp.Regexp{regexp.MustCompile(`utm_`)},
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if got := items[0].Loc.RequestURI(); got != "/page?id=7&sort=asc" {
+		t.Fatalf("expected tracking parameters stripped, got %s", got)
+	}
+	if got := items[1].Loc.String(); got != server.URL+"/landing" {
+		t.Fatalf("expected empty query to be dropped, got %s", got)
+	}
+}
+
+func TestSitemapFetcher_Transform(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://staging.example.com/keep</loc>
+  </url>
+  <url>
+    <loc>https://staging.example.com/drop</loc>
+  </url>
+  <url>
+    <loc>https://staging.example.com/private</loc>
+  </url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		IgnoreRobots: true,
+		// Filters see the transformed URL.
+		Include: []*regexp.Regexp{regexp.MustCompile(`^https://www\.example\.com/`)},
+		Exclude: []*regexp.Regexp{regexp.MustCompile(`/private$`)},
+		Transform: func(item Item) (Item, bool) {
+			if item.Loc.Path == "/drop" {
+				return item, false
+			}
+			loc := *item.Loc
+			loc.Host = "www.example.com"
+			item.Loc = &loc
+			item.ChangeFreq = "daily"
+			return item, true
+		},
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if got := items[0].Loc.String(); got != "https://www.example.com/keep" {
+		t.Fatalf("expected rewritten host, got %s", got)
+	}
+	if items[0].ChangeFreq != "daily" || items[0].Position != 1 {
+		t.Fatalf("expected transformed item fields to be kept, got %+v", items[0])
+	}
+}
+
+func TestSitemapFetcher_MaxTotalBytes(t *testing.T) {
+	const index = `<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`
+	const child = `<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(index))
+		case "/a.xml", "/b.xml":
+			_, _ = w.Write([]byte(child))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	budget := int64(len(index) + len(child) + len(child)/2)
+	items, err := collectItems(New(Options{MaxTotalBytes: budget}), sitemapURL)
+	var maxErr *ErrMaxTotalBytes
+	if !errors.As(err, &maxErr) {
+		t.Fatalf("expected ErrMaxTotalBytes, got %v", err)
+	}
+	if maxErr.MaxTotalBytes != budget || maxErr.Downloaded <= budget {
+		t.Fatalf("unexpected budget error: %+v", maxErr)
+	}
+	if maxErr.URL == nil || maxErr.URL.Path != "/b.xml" {
+		t.Fatalf("expected budget to run out at /b.xml, got %v", maxErr.URL)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected the 2 items of /a.xml, got %d", len(items))
+	}
+
+	items, err = collectItems(New(Options{MaxTotalBytes: int64(len(index) + 2*len(child))}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error within budget: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(items))
+	}
+}
+
+func TestSitemapFetcher_MaxURLs(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>/one</loc>
+  </url>
+  <url>
+    <loc>/two</loc>
+  </url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{MaxURLs: 1})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	var maxErr *ErrMaxURLs
+	if !errors.As(err, &maxErr) {
+		t.Fatalf("expected ErrMaxURLs, got %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+}
+
+func TestSitemapFetcher_StopAtMaxURLs(t *testing.T) {
+	var second atomic.Int32
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/first.xml</loc></sitemap><sitemap><loc>/second.xml</loc></sitemap></sitemapindex>`))
+		case "/first.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url><url><loc>/three</loc></url></urlset>`))
+		case "/second.xml":
+			second.Add(1)
+			_, _ = w.Write([]byte(`<urlset><url><loc>/four</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	fetcher := New(Options{MaxURLs: 2, StopAtMaxURLs: true})
+	items, err := collectItems(fetcher, indexURL)
+	if err != nil {
+		t.Fatalf("expected nil error with StopAtMaxURLs, got %v", err)
+	}
+	if got := itemPaths(items); got != "/one,/two" {
+		t.Fatalf("expected /one,/two, got %s", got)
+	}
+	if !fetcher.ReachedMaxURLs() {
+		t.Fatalf("expected ReachedMaxURLs to be set")
+	}
+	if second.Load() != 0 {
+		t.Fatal("expected the walk to stop before /second.xml")
+	}
+
+	// A walk under the limit does not report it.
+	fetcher = New(Options{MaxURLs: 10, StopAtMaxURLs: true})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if fetcher.ReachedMaxURLs() {
+		t.Fatalf("expected ReachedMaxURLs to be unset under the limit")
+	}
+}
+
+func TestSitemapFetcher_Pipeline_FilterRawBeforeResolve(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>/relative</loc>
+  </url>
+  <url>
+    <loc>https://example.com/absolute?a=1&amp;amp;b=2</loc>
+    <lastmod>not-a-date</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/dated</loc>
+    <lastmod>2024-01-02</lastmod>
+  </url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		Include:  []*regexp.Regexp{regexp.MustCompile(`^https://`)},
+		Pipeline: []PipelineStage{StageFilter, StageDecode, StageResolve, StageNormalize},
+	})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if got := items[0].Loc.RawQuery; got != "a=1&b=2" {
+		t.Fatalf("expected decoded query a=1&b=2, got %q", got)
+	}
+
+	fetcher = New(Options{
+		Pipeline: []PipelineStage{StageResolve, StageValidate, StageNormalize},
+	})
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected invalid lastmod entry to be dropped, got %d items", len(items))
+	}
+}
+
+func TestSitemapFetcher_Pipeline_Invalid(t *testing.T) {
+	sitemapURL, err := url.Parse("https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	for _, stages := range [][]PipelineStage{
+		{StageNormalize, StageFilter},
+		{StageResolve, StageResolve},
+		{StageResolve, "unknown"},
+	} {
+		fetcher := New(Options{Pipeline: stages})
+		_, err := collectItems(fetcher, sitemapURL)
+		var pipelineErr *ErrInvalidPipeline
+		if !errors.As(err, &pipelineErr) {
+			t.Fatalf("expected ErrInvalidPipeline for %v, got %v", stages, err)
+		}
+	}
+}
+
+func TestSitemapFetcher_RedirectLoop(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.xml":
+			http.Redirect(w, r, "/b.xml", http.StatusFound)
+		case "/b.xml":
+			http.Redirect(w, r, "/a.xml", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/a.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{})
+	_, err = collectItems(fetcher, sitemapURL)
+	var loopErr *ErrRedirectLoop
+	if !errors.As(err, &loopErr) {
+		t.Fatalf("expected ErrRedirectLoop, got %v", err)
+	}
+	if loopErr.TooLong {
+		t.Fatalf("expected cycle, got too-long chain")
+	}
+	if len(loopErr.Chain) != 3 || loopErr.Chain[0] != loopErr.Chain[2] {
+		t.Fatalf("expected cycle a -> b -> a, got %v", loopErr.Chain)
+	}
+}
+
+func TestSitemapFetcher_StatusPolicy(t *testing.T) {
+	const index = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/missing.xml</loc></sitemap>
+  <sitemap><loc>/forbidden.xml</loc></sitemap>
+</sitemapindex>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(index))
+		case "/forbidden.xml":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	fetcher := New(Options{
+		SkipNon200: true,
+		StatusPolicy: func(code int) Action {
+			switch code {
+			case http.StatusNotFound:
+				return ActionSkip
+			case http.StatusForbidden:
+				return ActionError
+			}
+			return ActionDefault
+		},
+	})
+	_, err = collectItems(fetcher, indexURL)
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected ErrHTTPStatus, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, statusErr.StatusCode)
+	}
+	if got := fetcher.SkippedSitemapCount(); got != 1 {
+		t.Fatalf("expected 1 skipped sitemap, got %d", got)
+	}
+}
+
+func TestSitemapFetcher_CallbackSentinels(t *testing.T) {
+	const index = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/first.xml</loc></sitemap>
+  <sitemap><loc>/second.xml</loc></sitemap>
+</sitemapindex>`
+	const first = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/first-a</loc></url>
+  <url><loc>/first-b</loc></url>
+</urlset>`
+	const second = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/second-a</loc></url>
+  <url><loc>/second-b</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(index))
+		case "/first.xml":
+			_, _ = w.Write([]byte(first))
+		case "/second.xml":
+			_, _ = w.Write([]byte(second))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	fetcher := New(Options{})
+	var seen []string
+	err = fetcher.Walk(context.Background(), indexURL, func(item Item) error {
+		seen = append(seen, item.Loc.Path)
+		switch item.Loc.Path {
+		case "/first-a":
+			return ErrSkipSitemap
+		case "/second-a":
+			return ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if strings.Join(seen, ",") != "/first-a,/second-a" {
+		t.Fatalf("unexpected items %v", seen)
+	}
+}
+
+func TestSitemapFetcher_NotASitemap(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/products" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("\n<!DOCTYPE html><html><body>shop</body></html>"))
+	}))
+	defer server.Close()
+
+	pageURL, err := url.Parse(server.URL + "/products")
+	if err != nil {
+		t.Fatalf("failed to parse page URL: %v", err)
+	}
+
+	fetcher := New(Options{Discovery: DiscoveryOff})
+	_, err = collectItems(fetcher, pageURL)
+	var notSitemap *ErrNotASitemap
+	if !errors.As(err, &notSitemap) {
+		t.Fatalf("expected ErrNotASitemap, got %v", err)
+	}
+	if !strings.HasPrefix(notSitemap.ContentType, "text/html") {
+		t.Fatalf("expected text/html content type, got %q", notSitemap.ContentType)
+	}
+	if notSitemap.Hint == "" {
+		t.Fatalf("expected discovery hint")
+	}
+}
+
+func TestSitemapFetcher_DiscoveryRobotsOnly(t *testing.T) {
+	var mu sync.Mutex
+	robots := "User-agent: *\nAllow: /\n"
+	var requested []string
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte(robots))
+		case "/sitemap.xml", "/advertised.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>` + r.URL.Path + `-page</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	siteURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("failed to parse site URL: %v", err)
+	}
+	_, err = collectItems(New(Options{Discovery: DiscoveryRobotsOnly}), siteURL)
+	var noSitemaps *ErrNoSitemaps
+	if !errors.As(err, &noSitemaps) {
+		t.Fatalf("expected ErrNoSitemaps without Sitemap directives, got %v", err)
+	}
+	if strings.Join(requested, ",") != "/robots.txt" {
+		t.Fatalf("expected only robots.txt to be requested, got %v", requested)
+	}
+
+	mu.Lock()
+	robots += "Sitemap: " + server.URL + "/advertised.xml\n"
+	mu.Unlock()
+	for _, ignoreRobots := range []bool{false, true} {
+		items, err := collectItems(New(Options{Discovery: DiscoveryRobotsOnly, IgnoreRobots: ignoreRobots}), siteURL)
+		if err != nil || len(items) != 1 || items[0].Loc.Path != "/advertised.xml-page" {
+			t.Fatalf("IgnoreRobots=%v: expected the advertised sitemap only, got %v (%v)", ignoreRobots, items, err)
+		}
+	}
+}
+
+func TestSitemapFetcher_OnError(t *testing.T) {
+	const index = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/broken.xml</loc></sitemap>
+  <sitemap><loc>/gone.xml</loc></sitemap>
+  <sitemap><loc>/ok.xml</loc></sitemap>
+</sitemapindex>`
+	const ok = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/page-ok</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(index))
+		case "/broken.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/x</loc></url`))
+		case "/ok.xml":
+			_, _ = w.Write([]byte(ok))
+		default:
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	var failures []error
+	fetcher := New(Options{
+		OnError: func(sitemap *url.URL, err error) error {
+			failures = append(failures, err)
+			return nil
+		},
+	})
+	items, err := collectItems(fetcher, indexURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %d", len(failures))
+	}
+	var parseErr *ErrSitemapParse
+	if !errors.As(failures[0], &parseErr) {
+		t.Fatalf("expected ErrSitemapParse, got %v", failures[0])
+	}
+	var statusErr *ErrHTTPStatus
+	if !errors.As(failures[1], &statusErr) {
+		t.Fatalf("expected ErrHTTPStatus, got %v", failures[1])
+	}
+
+	abort := errors.New("abort")
+	fetcher = New(Options{
+		OnError: func(*url.URL, error) error {
+			return abort
+		},
+	})
+	if _, err := collectItems(fetcher, indexURL); !errors.Is(err, abort) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+}
+
+func TestSitemapFetcher_RobotsDeclaredFormats(t *testing.T) {
+	const rss = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+  <item><link>https://example.com/rss-post</link><pubDate>Tue, 02 Jan 2024 15:04:05 GMT</pubDate></item>
+</channel></rss>`
+	const atom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><link href="https://example.com/atom-post"/><updated>2024-01-02T00:00:00Z</updated></entry>
+</feed>`
+
+	var robots string
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte(robots))
+		case "/feed.rss":
+			_, _ = w.Write([]byte(rss))
+		case "/feed.atom":
+			_, _ = w.Write([]byte(atom))
+		case "/urls.txt":
+			_, _ = w.Write([]byte("https://example.com/text-a\n\nhttps://example.com/text-b\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	robots = "User-agent: *\nSitemap: " + server.URL + "/feed.rss\nSitemap: " + server.URL + "/urls.txt\nSitemap: " + server.URL + "/feed.atom\n"
+
+	baseURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse base URL: %v", err)
+	}
+
+	fetcher := New(Options{Formats: []SitemapFormat{FormatText, FormatRSS}})
+	items, err := collectItems(fetcher, baseURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+	if items[0].Loc.Path != "/rss-post" || items[0].LastMod == nil {
+		t.Fatalf("expected RSS item with lastmod, got %+v", items[0])
+	}
+	skipped := fetcher.SkippedSitemaps()
+	if len(skipped) != 1 {
+		t.Fatalf("expected 1 skipped sitemap, got %d", len(skipped))
+	}
+	var unsupported *ErrUnsupportedFormat
+	if !errors.As(skipped[0].Err, &unsupported) || unsupported.Format != FormatAtom {
+		t.Fatalf("expected unsupported atom format, got %v", skipped[0].Err)
+	}
+}
+
+func TestSitemapFetcher_KeepExtensions(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>/gallery</loc>
+    <image:image><image:loc>https://example.com/photo.jpg</image:loc></image:image>
+  </url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	items, err := collectItems(New(Options{}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 || len(items[0].Extensions) != 0 {
+		t.Fatalf("expected no extensions by default, got %+v", items)
+	}
+
+	items, err = collectItems(New(Options{KeepExtensions: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 || len(items[0].Extensions) != 1 {
+		t.Fatalf("expected 1 extension, got %+v", items)
+	}
+	ext := items[0].Extensions[0]
+	if ext.Name.Space != "http://www.google.com/schemas/sitemap-image/1.1" || ext.Name.Local != "image" {
+		t.Fatalf("unexpected extension name %v", ext.Name)
+	}
+	var image struct {
+		Loc string `xml:"http://www.google.com/schemas/sitemap-image/1.1 loc"`
+	}
+	if err := ext.Decode(&image); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if image.Loc != "https://example.com/photo.jpg" {
+		t.Fatalf("expected image loc, got %q", image.Loc)
+	}
+
+	const imageNS = "http://www.google.com/schemas/sitemap-image/1.1"
+	items, err = collectItems(New(Options{
+		ExtensionDecoders: map[string]ExtensionDecoder{
+			imageNS: func(ext Extension) (any, error) {
+				var image struct {
+					Loc string `xml:"http://www.google.com/schemas/sitemap-image/1.1 loc"`
+				}
+				err := ext.Decode(&image)
+				return image.Loc, err
+			},
+		},
+	}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(items) != 1 || len(items[0].Extensions) != 0 {
+		t.Fatalf("expected raw extensions to stay off, got %+v", items)
+	}
+	if got := items[0].Ext[imageNS]; len(got) != 1 || got[0] != "https://example.com/photo.jpg" {
+		t.Fatalf("expected decoded image loc, got %v", got)
+	}
+}
+
+func TestExtension_MarshalXML(t *testing.T) {
+	var ext Extension
+	err := xml.Unmarshal([]byte(`<image:image xmlns:image="http://www.google.com/schemas/sitemap-image/1.1"><image:loc>/a.png</image:loc></image:image>`), &ext)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	out, err := xml.Marshal(ext)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	const want = `<image xmlns="http://www.google.com/schemas/sitemap-image/1.1"><loc xmlns="http://www.google.com/schemas/sitemap-image/1.1">/a.png</loc></image>`
+	if string(out) != want {
+		t.Fatalf("expected %s, got %s", want, out)
+	}
+}
+
+func TestSitemapFetcher_StreamsBeforeBodyCompletes(t *testing.T) {
+	firstSeen := make(chan struct{})
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>/first</loc></url>`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-firstSeen:
+		case <-time.After(5 * time.Second):
+			return
+		}
+		_, _ = w.Write([]byte(`<url><loc>/second</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	var count int
+	err = New(Options{}).Walk(context.Background(), sitemapURL, func(item Item) error {
+		count++
+		if count == 1 {
+			close(firstSeen)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 items, got %d", count)
+	}
+}
+
+func TestSitemapFetcher_ReuseItems(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/one</loc><lastmod>2024-01-01</lastmod><priority>0.1</priority></url>
+  <url><loc>/two</loc><lastmod>2024-02-02</lastmod><priority>0.2</priority></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	var lastMods []*time.Time
+	var locs []*url.URL
+	var dates []string
+	var priorities []float64
+	err = New(Options{ReuseItems: true}).Walk(context.Background(), sitemapURL, func(item Item) error {
+		lastMods = append(lastMods, item.LastMod)
+		locs = append(locs, item.Loc)
+		dates = append(dates, item.LastMod.Format("2006-01-02"))
+		priorities = append(priorities, *item.Priority)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(lastMods) != 2 || lastMods[0] != lastMods[1] {
+		t.Fatalf("expected LastMod storage to be reused")
+	}
+	if strings.Join(dates, ",") != "2024-01-01,2024-02-02" {
+		t.Fatalf("unexpected lastmods seen in callback: %v", dates)
+	}
+	if priorities[0] != 0.1 || priorities[1] != 0.2 {
+		t.Fatalf("unexpected priorities seen in callback: %v", priorities)
+	}
+	if locs[0].Path != "/one" || locs[1].Path != "/two" {
+		t.Fatalf("expected Loc to stay valid after the callback, got %v", locs)
+	}
+}
+
+func TestSitemapFetcher_MaxURLsPerSitemap(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>/one</loc></url>
+  <url><loc>/two</loc></url>
+  <url><loc>/three</loc></url>
+</urlset>`
+
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	items, err := collectItems(New(Options{MaxURLsPerSitemap: 2}), sitemapURL)
+	var perSitemap *ErrMaxURLsPerSitemap
+	if !errors.As(err, &perSitemap) {
+		t.Fatalf("expected ErrMaxURLsPerSitemap, got %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items before error, got %d", len(items))
+	}
+
+	items, err = collectItems(New(Options{MaxURLsPerSitemap: 2, MaxURLsPerSitemapPolicy: LimitTruncate}), sitemapURL)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("expected 2 items and nil error when truncating, got %d, %v", len(items), err)
+	}
+
+	handler := &captureHandler{}
+	items, err = collectItems(New(Options{
+		MaxURLsPerSitemap:       2,
+		MaxURLsPerSitemapPolicy: LimitWarn,
+		Logger:                  slog.New(handler),
+	}), sitemapURL)
+	if err != nil || len(items) != 3 {
+		t.Fatalf("expected 3 items and nil error when warning, got %d, %v", len(items), err)
+	}
+	if !handler.hasWarningContaining("exceeds per-sitemap URL limit") {
+		t.Fatalf("expected per-sitemap limit warning")
+	}
+}
+
+func TestSitemapFetcher_OversizedSitemapWarning(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/small.xml</loc></sitemap><sitemap><loc>/big.xml</loc></sitemap></sitemapindex>`))
+		case "/small.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		case "/big.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/b</loc></url><url><loc>/c</loc></url><url><loc>/d</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	warnings := func(opts Options) []capturedRecord {
+		t.Helper()
+		handler := &captureHandler{}
+		opts.Logger = slog.New(handler)
+		items, err := collectItems(New(opts), indexURL)
+		if err != nil || len(items) != 4 {
+			t.Fatalf("expected the walk unaffected, got %d items (%v)", len(items), err)
+		}
+		var found []capturedRecord
+		for _, record := range handler.records {
+			if record.msg == "sitemap approaching protocol limits" {
+				found = append(found, record)
+			}
+		}
+		return found
+	}
+
+	if found := warnings(Options{}); len(found) != 0 {
+		t.Fatalf("expected no warnings by default, got %v", found)
+	}
+	found := warnings(Options{WarnURLsPerSitemap: 2})
+	if len(found) != 1 || !strings.HasSuffix(found[0].attrs["sitemap"].String(), "/big.xml") || found[0].attrs["entries"].Int64() != 3 {
+		t.Fatalf("expected one warning for /big.xml, got %v", found)
+	}
+	if found[0].level != slog.LevelWarn || found[0].attrs["entries_limit"].Int64() != SpecMaxURLsPerSitemap {
+		t.Fatalf("unexpected warning record: %v", found[0])
+	}
+	found = warnings(Options{WarnSitemapBytes: 60})
+	if len(found) != 2 || found[0].attrs["bytes"].Int64() <= 60 {
+		t.Fatalf("expected warnings for the index and /big.xml, got %v", found)
+	}
+}
+
+func TestSitemapFetcher_TraversalOrder(t *testing.T) {
+	sitemaps := map[string]string{
+		"/sitemap.xml": `<sitemapindex><sitemap><loc>/nested.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`,
+		"/nested.xml":  `<sitemapindex><sitemap><loc>/a1.xml</loc></sitemap><sitemap><loc>/a2.xml</loc></sitemap></sitemapindex>`,
+		"/a1.xml":      `<urlset><url><loc>/a1</loc></url></urlset>`,
+		"/a2.xml":      `<urlset><url><loc>/a2</loc></url></urlset>`,
+		"/b.xml":       `<urlset><url><loc>/b</loc></url></urlset>`,
+	}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := sitemaps[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	for order, want := range map[TraversalOrder]string{
+		TraversalBreadthFirst: "/b,/a1,/a2",
+		TraversalDepthFirst:   "/a1,/a2,/b",
+	} {
+		items, err := collectItems(New(Options{IgnoreRobots: true, TraversalOrder: order}), sitemapURL)
+		if err != nil {
+			t.Fatalf("%s: walk failed: %v", order, err)
+		}
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Loc.Path)
+		}
+		if strings.Join(got, ",") != want {
+			t.Fatalf("%s: expected %s, got %s", order, want, strings.Join(got, ","))
+		}
+	}
+}
+
+func TestSitemapFetcher_TraversalFreshestFirst(t *testing.T) {
+	sitemaps := map[string]string{
+		"/sitemap.xml": `<sitemapindex>
+<sitemap><loc>/old.xml</loc><lastmod>2024-01-01</lastmod></sitemap>
+<sitemap><loc>/undated.xml</loc></sitemap>
+<sitemap><loc>/nested.xml</loc><lastmod>2024-06-01</lastmod></sitemap>
+<sitemap><loc>/new.xml</loc><lastmod>2025-03-01T10:00:00Z</lastmod></sitemap>
+</sitemapindex>`,
+		"/nested.xml": `<sitemapindex>
+<sitemap><loc>/mid.xml</loc><lastmod>2024-09-01</lastmod></sitemap>
+<sitemap><loc>/newest.xml</loc><lastmod>2025-05-01</lastmod></sitemap>
+</sitemapindex>`,
+		"/old.xml":     `<urlset><url><loc>/old</loc></url></urlset>`,
+		"/undated.xml": `<urlset><url><loc>/undated</loc></url></urlset>`,
+		"/new.xml":     `<urlset><url><loc>/new</loc></url></urlset>`,
+		"/mid.xml":     `<urlset><url><loc>/mid</loc></url></urlset>`,
+		"/newest.xml":  `<urlset><url><loc>/newest</loc></url></urlset>`,
+	}
 	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/sitemap.xml" {
+		body, ok := sitemaps[r.URL.Path]
+		if !ok {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		_, _ = w.Write([]byte(sitemap))
+		_, _ = w.Write([]byte(body))
 	}))
 	defer server.Close()
 
@@ -256,59 +1819,143 @@ func TestSitemapFetcher_IncludeExclude(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to parse sitemap URL: %v", err)
 	}
+	items, err := collectItems(New(Options{IgnoreRobots: true, TraversalOrder: TraversalFreshestFirst}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Loc.Path)
+	}
+	// /new.xml is fetched before /nested.xml reveals /newest.xml.
+	if want := "/new,/newest,/mid,/old,/undated"; strings.Join(got, ",") != want {
+		t.Fatalf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestSitemapFetcher_WalkAll(t *testing.T) {
+	var shared string
+	serverA := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a1</loc></url><url><loc>` + shared + `</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer serverA.Close()
+	serverB := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/shared</loc></url><url><loc>/b1</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer serverB.Close()
+	shared = serverB.URL + "/shared"
+
+	var roots []*url.URL
+	for _, raw := range []string{serverA.URL + "/sitemap.xml", serverB.URL + "/sitemap.xml", serverB.URL + "/missing.xml"} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+		roots = append(roots, u)
+	}
 
+	var checkpoints []Checkpoint
 	fetcher := New(Options{
-		Include: []*regexp.Regexp{regexp.MustCompile("keep")},
-		Exclude: []*regexp.Regexp{regexp.MustCompile("skip")},
+		IgnoreRobots: true,
+		DedupeURLs:   true,
+		SkipNon200:   true,
+		OnCheckpoint: func(cp Checkpoint) error {
+			checkpoints = append(checkpoints, cp)
+			return nil
+		},
+	})
+	var got []string
+	err := fetcher.WalkAll(context.Background(), roots, func(item Item) error {
+		got = append(got, item.Loc.String())
+		return nil
 	})
-	items, err := collectItems(fetcher, sitemapURL)
 	if err != nil {
 		t.Fatalf("walk failed: %v", err)
 	}
-	if len(items) != 1 {
-		t.Fatalf("expected 1 item, got %d", len(items))
+	want := []string{serverA.URL + "/a1", shared, serverB.URL + "/b1"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, got)
 	}
-	if !strings.HasSuffix(items[0].Loc.String(), "/keep") {
-		t.Fatalf("expected /keep URL, got %s", items[0].Loc.String())
+	if n := fetcher.SkippedSitemapCount(); n != 1 {
+		t.Fatalf("expected the missing sitemap in the run summary, got %d skipped", n)
+	}
+	if len(fetcher.SitemapStats()) != 2 {
+		t.Fatalf("expected stats for both fetched sitemaps, got %d", len(fetcher.SitemapStats()))
+	}
+	wantInputs := []string{roots[0].String(), roots[1].String(), roots[2].String()}
+	if len(checkpoints) == 0 || checkpoints[0].Input != "" || !slices.Equal(checkpoints[0].Inputs, wantInputs) {
+		t.Fatalf("expected checkpoint inputs %q, got %+v", wantInputs, checkpoints)
+	}
+	resumed := New(Options{IgnoreRobots: true, Resume: &checkpoints[0]})
+	var checkpointErr *ErrInvalidCheckpoint
+	if err := resumed.WalkAll(context.Background(), roots[:2], func(Item) error { return nil }); !errors.As(err, &checkpointErr) {
+		t.Fatalf("expected ErrInvalidCheckpoint for other roots, got %v", err)
 	}
-}
 
-func TestSitemapFetcher_MaxURLs(t *testing.T) {
-	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
-<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
-  <url>
-    <loc>/one</loc>
-  </url>
-  <url>
-    <loc>/two</loc>
-  </url>
-</urlset>`
+	// Limits apply to the run as a whole.
+	fetcher = New(Options{IgnoreRobots: true, MaxURLs: 3})
+	err = fetcher.WalkAll(context.Background(), roots[:2], func(Item) error { return nil })
+	var maxErr *ErrMaxURLs
+	if !errors.As(err, &maxErr) {
+		t.Fatalf("expected ErrMaxURLs across roots, got %v", err)
+	}
+
+	var invalid *ErrInvalidURL
+	if err := fetcher.WalkAll(context.Background(), nil, func(Item) error { return nil }); !errors.As(err, &invalid) {
+		t.Fatalf("expected ErrInvalidURL for no URLs, got %v", err)
+	}
+}
 
+func TestSitemapFetcher_SniffsHeadAcrossReads(t *testing.T) {
+	// Each body arrives in pieces, split inside the bytes that identify it.
+	bodies := map[string][]string{
+		"/feed.xml": {"\n<", `rss version="2.0"><channel><item><link>/post</link></item></channel></rss>`},
+		"/page":     {"<!DOC", "TYPE html><html><body>shop</body></html>"},
+	}
 	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/sitemap.xml" {
+		pieces, ok := bodies[r.URL.Path]
+		if !ok {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		_, _ = w.Write([]byte(sitemap))
+		for _, piece := range pieces {
+			_, _ = w.Write([]byte(piece))
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
 	}))
 	defer server.Close()
 
-	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	feedURL, err := url.Parse(server.URL + "/feed.xml")
 	if err != nil {
-		t.Fatalf("failed to parse sitemap URL: %v", err)
+		t.Fatalf("failed to parse feed URL: %v", err)
 	}
-
-	fetcher := New(Options{MaxURLs: 1})
-	items, err := collectItems(fetcher, sitemapURL)
-	if err == nil {
-		t.Fatalf("expected error, got nil")
+	items, err := collectItems(New(Options{Formats: []SitemapFormat{FormatRSS}}), feedURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
 	}
-	var maxErr *ErrMaxURLs
-	if !errors.As(err, &maxErr) {
-		t.Fatalf("expected ErrMaxURLs, got %v", err)
+	if itemPaths(items) != "/post" {
+		t.Fatalf("expected the feed item, got %s", itemPaths(items))
 	}
-	if len(items) != 1 {
-		t.Fatalf("expected 1 item, got %d", len(items))
+
+	pageURL, err := url.Parse(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("failed to parse page URL: %v", err)
+	}
+	_, err = collectItems(New(Options{Discovery: DiscoveryOff}), pageURL)
+	var notSitemap *ErrNotASitemap
+	if !errors.As(err, &notSitemap) {
+		t.Fatalf("expected ErrNotASitemap, got %v", err)
 	}
 }
 
@@ -391,6 +2038,84 @@ func TestSitemapFetcher_PerRequestTimeout(t *testing.T) {
 	}
 }
 
+func TestSitemapFetcher_WalkTimeout(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/fast.xml</loc></sitemap><sitemap><loc>/slow.xml</loc></sitemap></sitemapindex>`))
+		case "/fast.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/fast</loc></url></urlset>`))
+		case "/slow.xml":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			_, _ = w.Write([]byte(`<urlset><url><loc>/slow</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{WalkTimeout: 100 * time.Millisecond, SkipFetchErrors: true})
+	items, err := collectItems(fetcher, sitemapURL)
+	var timeoutErr *ErrWalkTimeout
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrWalkTimeout, got %v", err)
+	}
+	if len(items) != 1 || items[0].Loc.Path != "/fast" {
+		t.Fatalf("expected items from before the deadline, got %v", items)
+	}
+}
+
+func TestSitemapFetcher_Delay(t *testing.T) {
+	var mu sync.Mutex
+	var requests []time.Time
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml", "/b.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>` + r.URL.Path + `.html</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	const delay = 40 * time.Millisecond
+	fetcher := New(Options{Delay: delay, DelayJitter: 10 * time.Millisecond})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	// robots.txt, the index, and both child sitemaps.
+	if len(requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(requests))
+	}
+	for i := 1; i < len(requests); i++ {
+		if gap := requests[i].Sub(requests[i-1]); gap < delay-5*time.Millisecond {
+			t.Fatalf("expected at least %s between requests, got %s before request %d", delay, gap, i)
+		}
+	}
+}
+
 func TestSitemapFetcher_SkipNon200_WarnsAndSkips(t *testing.T) {
 	const index = `<?xml version="1.0" encoding="UTF-8"?>
 <sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
@@ -460,6 +2185,48 @@ func TestSitemapFetcher_SkipNon200_WarnsAndSkips(t *testing.T) {
 	}
 }
 
+func TestSitemapFetcher_AggregateErrors(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/ok.xml</loc></sitemap>
+<sitemap><loc>/bad.xml</loc></sitemap>
+<sitemap><loc>/gone.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/ok.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/page-ok</loc></url></urlset>`))
+		case "/bad.xml":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	fetcher := New(Options{SkipNon200: true, AggregateErrors: true})
+	items, err := collectItems(fetcher, indexURL)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	var walkErrs *WalkErrors
+	if !errors.As(err, &walkErrs) {
+		t.Fatalf("expected WalkErrors, got %v", err)
+	}
+	if len(walkErrs.Sitemaps) != 2 || !strings.HasSuffix(walkErrs.Sitemaps[0].URL, "/bad.xml") || !strings.HasSuffix(walkErrs.Sitemaps[1].URL, "/gone.xml") {
+		t.Fatalf("expected /bad.xml and /gone.xml in WalkErrors, got %+v", walkErrs.Sitemaps)
+	}
+	var statusErr *ErrHTTPStatus
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected WalkErrors to unwrap to the first ErrHTTPStatus, got %v", err)
+	}
+}
+
 func TestSitemapFetcher_SkipFetchErrors_WarnsAndSkips(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -591,6 +2358,173 @@ func TestSitemapFetcher_SkippedSitemaps_ResetBetweenWalks(t *testing.T) {
 	}
 }
 
+func TestSitemapFetcher_LogLevel(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/missing.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/one</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	fetcher := New(Options{SkipNon200: true, Logger: slog.New(handler), LogLevel: slog.LevelDebug})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	response, ok := handler.find("sitemap response")
+	if !ok {
+		t.Fatal("expected a debug record per request")
+	}
+	for _, key := range []string{"sitemap", "attempt", "status", "duration"} {
+		if _, ok := response.attrs[key]; !ok {
+			t.Fatalf("expected %q attr on request record, got %v", key, response.attrs)
+		}
+	}
+	parsed, ok := handler.find("parsed sitemap")
+	if !ok {
+		t.Fatal("expected a debug record per parsed sitemap")
+	}
+	if parsed.attrs["depth"].Int64() != 0 || parsed.attrs["bytes"].Int64() == 0 || parsed.attrs["entries"].Int64() != 2 {
+		t.Fatalf("unexpected parsed sitemap attrs: %v", parsed.attrs)
+	}
+
+	handler = &captureHandler{}
+	fetcher = New(Options{SkipNon200: true, Logger: slog.New(handler), LogLevel: slog.LevelWarn})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if _, ok := handler.find("sitemap response"); ok {
+		t.Fatal("expected no debug records at LogLevel warn")
+	}
+	if !handler.hasWarningContaining("non-200") {
+		t.Fatal("expected the skipped sitemap warning at LogLevel warn")
+	}
+}
+
+func TestSitemapFetcher_SummaryLevel(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/missing.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	if _, err := collectItems(New(Options{SkipNon200: true, Logger: slog.New(handler)}), indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if _, ok := handler.find("walk finished"); ok {
+		t.Fatal("expected no summary without SummaryLevel")
+	}
+
+	handler = &captureHandler{}
+	fetcher := New(Options{SkipNon200: true, Logger: slog.New(handler), SummaryLevel: slog.LevelInfo})
+	if _, err := collectItems(fetcher, indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	summary, ok := handler.find("walk finished")
+	if !ok {
+		t.Fatal("expected a summary record")
+	}
+	if summary.level != slog.LevelInfo {
+		t.Fatalf("expected the summary at info level, got %v", summary.level)
+	}
+	if summary.attrs["urls"].Int64() != 2 || summary.attrs["sitemaps"].Int64() != 3 || summary.attrs["skipped"].Int64() != 1 {
+		t.Fatalf("unexpected summary counts: %v", summary.attrs)
+	}
+	for _, key := range []string{"duration", "bytes", "slowest_sitemap", "slowest_duration", "walk_id"} {
+		if _, ok := summary.attrs[key]; !ok {
+			t.Fatalf("expected %q attr on the summary, got %v", key, summary.attrs)
+		}
+	}
+	if _, ok := summary.attrs["error"]; ok {
+		t.Fatalf("expected no error attr for a successful walk, got %v", summary.attrs)
+	}
+
+	handler = &captureHandler{}
+	fetcher = New(Options{Logger: slog.New(handler), SummaryLevel: slog.LevelInfo})
+	if _, err := collectItems(fetcher, indexURL); err == nil {
+		t.Fatal("expected the missing sitemap to fail the walk")
+	}
+	summary, ok = handler.find("walk finished")
+	if !ok || summary.attrs["error"].String() == "" {
+		t.Fatalf("expected the summary to carry the walk error, got %v", summary.attrs)
+	}
+}
+
+func TestSitemapFetcher_ProgressLogInterval(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml", "/b.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexURL, err := url.Parse(server.URL + "/index.xml")
+	if err != nil {
+		t.Fatalf("failed to parse index URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	if _, err := collectItems(New(Options{Logger: slog.New(handler), ProgressLogInterval: time.Hour}), indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if _, ok := handler.find("walk progress"); ok {
+		t.Fatal("expected no progress record before the interval passed")
+	}
+
+	handler = &captureHandler{}
+	if _, err := collectItems(New(Options{Logger: slog.New(handler), ProgressLogInterval: time.Nanosecond}), indexURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	record, ok := handler.find("walk progress")
+	if !ok {
+		t.Fatal("expected progress records")
+	}
+	if record.level != slog.LevelInfo {
+		t.Fatalf("expected progress at info level, got %v", record.level)
+	}
+	for _, key := range []string{"urls", "sitemaps", "queued", "bytes", "elapsed", "urls_per_second"} {
+		if _, ok := record.attrs[key]; !ok {
+			t.Fatalf("expected %q attr on the progress record, got %v", key, record.attrs)
+		}
+	}
+	var last capturedRecord
+	for _, r := range handler.records {
+		if r.msg == "walk progress" {
+			last = r
+		}
+	}
+	if last.attrs["urls"].Int64() != 4 || last.attrs["sitemaps"].Int64() != 3 || last.attrs["queued"].Int64() != 0 {
+		t.Fatalf("expected the final progress record to show the whole walk, got %v", last.attrs)
+	}
+}
+
 type captureHandler struct {
 	mu      sync.Mutex
 	records []capturedRecord
@@ -599,6 +2533,7 @@ type captureHandler struct {
 type capturedRecord struct {
 	level slog.Level
 	msg   string
+	attrs map[string]slog.Value
 }
 
 func (h *captureHandler) Enabled(context.Context, slog.Level) bool {
@@ -608,9 +2543,15 @@ func (h *captureHandler) Enabled(context.Context, slog.Level) bool {
 func (h *captureHandler) Handle(_ context.Context, record slog.Record) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	attrs := map[string]slog.Value{}
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value
+		return true
+	})
 	h.records = append(h.records, capturedRecord{
 		level: record.Level,
 		msg:   record.Message,
+		attrs: attrs,
 	})
 	return nil
 }
@@ -623,6 +2564,18 @@ func (h *captureHandler) WithGroup(string) slog.Handler {
 	return h
 }
 
+// find returns the first record with message msg.
+func (h *captureHandler) find(msg string) (capturedRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, record := range h.records {
+		if record.msg == msg {
+			return record, true
+		}
+	}
+	return capturedRecord{}, false
+}
+
 func (h *captureHandler) hasWarningContaining(message string) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
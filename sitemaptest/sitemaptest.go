@@ -0,0 +1,292 @@
+// Package sitemaptest provides a fake sitemap server and an item recorder for
+// testing code that consumes walks, without hand-written httptest handlers:
+//
+//	site := sitemaptest.NewServer(t)
+//	site.Index("/sitemap.xml", "/posts.xml", "/pages.xml")
+//	site.URLSet("/posts.xml", "/posts/1", "/posts/2").Gzip()
+//	site.URLSet("/pages.xml", "/about").Latency(50 * time.Millisecond)
+//
+//	var rec sitemaptest.Recorder
+//	err := fetcher.Walk(ctx, site.URL("/sitemap.xml"), rec.Record)
+package sitemaptest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+// Server is an httptest.Server serving routes defined in Go code. Paths
+// without a route answer 404, including /robots.txt unless Robots is called.
+// It is closed when the test ends.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]*Route
+	requests map[string]int
+}
+
+// NewServer starts an empty fake sitemap server.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{routes: map[string]*Route{}, requests: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Route is the response served for one path. Its methods return the Route so
+// they can be chained; they may be called while a walk is running.
+type Route struct {
+	mu      sync.Mutex
+	status  int
+	body    []byte
+	header  http.Header
+	gzip    bool
+	latency time.Duration
+}
+
+// Status sets the response status code (default 200).
+func (r *Route) Status(code int) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = code
+	return r
+}
+
+// Gzip serves the body gzip-compressed, as a .xml.gz file would be.
+func (r *Route) Gzip() *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gzip = true
+	return r
+}
+
+// Latency delays the response by d, or until the request is canceled.
+func (r *Route) Latency(d time.Duration) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latency = d
+	return r
+}
+
+// Header sets a response header.
+func (r *Route) Header(key, value string) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header.Set(key, value)
+	return r
+}
+
+// Handle serves body at path and returns its Route.
+func (s *Server) Handle(path string, body []byte) *Route {
+	route := &Route{status: http.StatusOK, body: body, header: http.Header{}}
+	s.mu.Lock()
+	s.routes[path] = route
+	s.mu.Unlock()
+	return route
+}
+
+// URLSet serves a urlset listing locs at path. Locs starting with "/" are
+// made absolute against the server URL.
+func (s *Server) URLSet(path string, locs ...string) *Route {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	body.WriteString(`<urlset xmlns="` + gositemapfetcher.SitemapNamespace + `">` + "\n")
+	for _, loc := range locs {
+		fmt.Fprintf(&body, "  <url><loc>%s</loc></url>\n", escape(s.abs(loc)))
+	}
+	body.WriteString("</urlset>\n")
+	return s.Handle(path, []byte(body.String())).Header("Content-Type", "application/xml")
+}
+
+// Index serves a sitemapindex listing the child sitemaps at path. Children
+// starting with "/" are made absolute against the server URL.
+func (s *Server) Index(path string, children ...string) *Route {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	body.WriteString(`<sitemapindex xmlns="` + gositemapfetcher.SitemapNamespace + `">` + "\n")
+	for _, child := range children {
+		fmt.Fprintf(&body, "  <sitemap><loc>%s</loc></sitemap>\n", escape(s.abs(child)))
+	}
+	body.WriteString("</sitemapindex>\n")
+	return s.Handle(path, []byte(body.String())).Header("Content-Type", "application/xml")
+}
+
+// Robots serves body as /robots.txt.
+func (s *Server) Robots(body string) *Route {
+	return s.Handle("/robots.txt", []byte(body)).Header("Content-Type", "text/plain")
+}
+
+// URL returns the absolute URL of path on the server.
+func (s *Server) URL(path string) *url.URL {
+	u, err := url.Parse(s.abs(path))
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// Requests returns how many requests path has received.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) abs(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return s.Server.URL + path
+	}
+	return path
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	s.requests[req.URL.Path]++
+	route := s.routes[req.URL.Path]
+	s.mu.Unlock()
+	if route == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	route.mu.Lock()
+	status, body, latency, compress := route.status, route.body, route.latency, route.gzip
+	header := route.header.Clone()
+	route.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+	if compress {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write(body)
+		_ = gz.Close()
+		body = buf.Bytes()
+	}
+	for key, values := range header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// Recorder collects the items of a walk. Its Record method is a Walk callback
+// and is safe for concurrent use, e.g. with Options.CallbackConcurrency.
+type Recorder struct {
+	mu    sync.Mutex
+	items []gositemapfetcher.Item
+}
+
+// Record stores a copy of item that stays valid under Options.ReuseItems.
+func (r *Recorder) Record(item gositemapfetcher.Item) error {
+	item.Loc = cloneURL(item.Loc)
+	item.Sitemap = cloneURL(item.Sitemap)
+	if item.LastMod != nil {
+		lastMod := *item.LastMod
+		item.LastMod = &lastMod
+	}
+	if item.Priority != nil {
+		priority := *item.Priority
+		item.Priority = &priority
+	}
+	if item.SitemapLastMod != nil {
+		sitemapLastMod := *item.SitemapLastMod
+		item.SitemapLastMod = &sitemapLastMod
+	}
+	r.mu.Lock()
+	r.items = append(r.items, item)
+	r.mu.Unlock()
+	return nil
+}
+
+// Items returns the recorded items in the order they were recorded.
+func (r *Recorder) Items() []gositemapfetcher.Item {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]gositemapfetcher.Item(nil), r.items...)
+}
+
+// Locs returns the Loc of every recorded item as a string, or its RawLoc when
+// Loc is nil.
+func (r *Recorder) Locs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	locs := make([]string, 0, len(r.items))
+	for _, item := range r.items {
+		if item.Loc == nil {
+			locs = append(locs, item.RawLoc)
+			continue
+		}
+		locs = append(locs, item.Loc.String())
+	}
+	return locs
+}
+
+// Paths returns the Loc path of every recorded item, convenient for asserting
+// against a Server whose host changes on every run. An item without a Loc has
+// an empty path.
+func (r *Recorder) Paths() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	paths := make([]string, 0, len(r.items))
+	for _, item := range r.items {
+		if item.Loc == nil {
+			paths = append(paths, "")
+			continue
+		}
+		paths = append(paths, item.Loc.Path)
+	}
+	return paths
+}
+
+// Len returns the number of recorded items.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.items)
+}
+
+// Reset discards the recorded items.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = nil
+}
+
+func cloneURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	clone := *u
+	if u.User != nil {
+		user := *u.User
+		clone.User = &user
+	}
+	return &clone
+}
@@ -0,0 +1,79 @@
+package sitemaptest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func TestServer(t *testing.T) {
+	site := NewServer(t)
+	site.Robots("User-agent: *\nDisallow: /private\n")
+	site.Index("/sitemap.xml", "/posts.xml.gz", "/pages.xml", "/missing.xml")
+	site.URLSet("/posts.xml.gz", "/posts/1", "/posts/2").Gzip()
+	site.URLSet("/pages.xml", "/about", "/private").Latency(10 * time.Millisecond)
+	site.Handle("/missing.xml", nil).Status(http.StatusNotFound)
+
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{SkipNon200: true})
+	var rec Recorder
+	if err := fetcher.Walk(context.Background(), site.URL("/sitemap.xml"), rec.Record); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+
+	want := []string{"/posts/1", "/posts/2", "/about"}
+	if got := rec.Paths(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if rec.Locs()[0] != site.URL("/posts/1").String() {
+		t.Fatalf("expected absolute locs, got %v", rec.Locs())
+	}
+	if site.Requests("/missing.xml") != 1 || site.Requests("/robots.txt") != 1 {
+		t.Fatalf("expected request counts to be recorded")
+	}
+	if skipped := fetcher.SkippedSitemaps(); len(skipped) != 1 {
+		t.Fatalf("expected the 404 sitemap to be skipped, got %v", skipped)
+	}
+}
+
+func TestServer_Latency(t *testing.T) {
+	site := NewServer(t)
+	site.URLSet("/sitemap.xml", "/a").Latency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var rec Recorder
+	err := gositemapfetcher.New(gositemapfetcher.Options{}).Walk(ctx, site.URL("/sitemap.xml"), rec.Record)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the slow route to time out, got %v", err)
+	}
+	if rec.Len() != 0 {
+		t.Fatalf("expected no items, got %d", rec.Len())
+	}
+}
+
+func TestRecorder_ReuseItems(t *testing.T) {
+	site := NewServer(t)
+	site.Handle("/sitemap.xml", []byte(`<urlset>
+<url><loc>/a</loc><lastmod>2024-01-01</lastmod><priority>0.1</priority></url>
+<url><loc>/b</loc><lastmod>2024-02-01</lastmod><priority>0.9</priority></url>
+</urlset>`))
+
+	var rec Recorder
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{ReuseItems: true})
+	if err := fetcher.Walk(context.Background(), site.URL("/sitemap.xml"), rec.Record); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	items := rec.Items()
+	if len(items) != 2 || *items[0].Priority != 0.1 || items[0].LastMod.Month() != time.January {
+		t.Fatalf("expected recorded items to survive storage reuse, got %+v", items)
+	}
+	rec.Reset()
+	if rec.Len() != 0 {
+		t.Fatalf("expected Reset to discard items")
+	}
+}
@@ -0,0 +1,89 @@
+package sitemapwriter
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+// Merge combines item sets, such as the results of several walks, into one
+// set without duplicate Locs. Items keep the position of their first
+// occurrence; a later duplicate with a newer LastMod replaces its metadata.
+// Items without a Loc are dropped.
+func Merge(sets ...[]gositemapfetcher.Item) []gositemapfetcher.Item {
+	var merged []gositemapfetcher.Item
+	index := map[string]int{}
+	for _, set := range sets {
+		for _, item := range set {
+			if item.Loc == nil {
+				continue
+			}
+			key := item.Key()
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, item)
+				continue
+			}
+			if newer(item.LastMod, merged[i].LastMod) {
+				merged[i] = item
+			}
+		}
+	}
+	return merged
+}
+
+func newer(a, b *time.Time) bool {
+	return a != nil && (b == nil || a.After(*b))
+}
+
+// WriteShards writes items into compliant shard files in dir, named with
+// pattern as in Files, plus an index file indexName listing every shard under
+// baseURL with the newest LastMod of its items. It returns the number of shards.
+//
+//	base, _ := url.Parse("https://example.com/sitemaps/")
+//	n, err := sitemapwriter.WriteShards("public/sitemaps", "sitemap-%d.xml.gz", "index.xml", base, items, sitemapwriter.Options{})
+func WriteShards(dir, pattern, indexName string, baseURL *url.URL, items []gositemapfetcher.Item, opts Options) (int, error) {
+	if baseURL == nil || !baseURL.IsAbs() {
+		return 0, fmt.Errorf("sitemapwriter: shard base URL must be absolute")
+	}
+	w := New(Files(dir, pattern), opts)
+	var lastMods []*time.Time
+	for _, item := range items {
+		if err := w.Write(item); err != nil {
+			w.Close()
+			return w.Parts(), err
+		}
+		if len(lastMods) < w.Parts() {
+			lastMods = append(lastMods, nil)
+		}
+		if newer(item.LastMod, lastMods[len(lastMods)-1]) {
+			lastMods[len(lastMods)-1] = item.LastMod
+		}
+	}
+	if err := w.Close(); err != nil {
+		return w.Parts(), err
+	}
+
+	entries := make([]IndexEntry, 0, w.Parts())
+	for n := 1; n <= w.Parts(); n++ {
+		loc := baseURL.ResolveReference(&url.URL{Path: fmt.Sprintf(pattern, n)})
+		entries = append(entries, IndexEntry{Loc: loc, LastMod: lastMods[n-1]})
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return w.Parts(), err
+	}
+	file, err := os.Create(filepath.Join(dir, indexName))
+	if err != nil {
+		return w.Parts(), err
+	}
+	err = WriteIndex(file, entries)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return w.Parts(), err
+}
@@ -0,0 +1,74 @@
+package sitemapwriter
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+func TestMerge(t *testing.T) {
+	parse := func(raw string) *url.URL {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+		return u
+	}
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newest := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	merged := Merge(
+		[]gositemapfetcher.Item{
+			{Loc: parse("https://example.com/a"), LastMod: &older},
+			{Loc: parse("https://example.com/b")},
+		},
+		[]gositemapfetcher.Item{
+			{Loc: parse("https://EXAMPLE.com/a#top"), LastMod: &newest, ChangeFreq: "daily"},
+			{Loc: parse("https://example.com/c")},
+			{},
+		},
+	)
+	if len(merged) != 3 {
+		t.Fatalf("expected 3 unique items, got %d", len(merged))
+	}
+	if merged[0].ChangeFreq != "daily" || !merged[0].LastMod.Equal(newest) {
+		t.Fatalf("expected the newer duplicate to win, got %+v", merged[0])
+	}
+	if merged[1].Loc.Path != "/b" || merged[2].Loc.Path != "/c" {
+		t.Fatalf("expected first-occurrence order, got %v", merged)
+	}
+}
+
+func TestWriteShards(t *testing.T) {
+	dir := t.TempDir()
+	base, _ := url.Parse("https://example.com/sitemaps/")
+	n, err := WriteShards(dir, "sitemap-%d.xml", "index.xml", base, testItems(t, 5), Options{MaxURLs: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 shards, got %d", n)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.xml"))
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		want := fmt.Sprintf("<loc>https://example.com/sitemaps/sitemap-%d.xml</loc>", i)
+		if !strings.Contains(string(index), want) {
+			t.Fatalf("expected %s in index:\n%s", want, index)
+		}
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("sitemap-%d.xml", i))); err != nil {
+			t.Fatalf("expected shard %d: %v", i, err)
+		}
+	}
+	if strings.Count(string(index), "<lastmod>2024-01-02</lastmod>") != 3 {
+		t.Fatalf("expected each shard to carry its newest lastmod:\n%s", index)
+	}
+}
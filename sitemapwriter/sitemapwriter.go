@@ -0,0 +1,304 @@
+// Package sitemapwriter serializes sitemap items back into sitemaps.org XML,
+// splitting output into several files at the protocol's 50,000-URL and 50 MB
+// limits, so fetched sitemaps can be filtered and re-published:
+//
+//	w := sitemapwriter.New(sitemapwriter.Files("public", "sitemap-%d.xml.gz"), sitemapwriter.Options{})
+//	err := fetcher.Walk(ctx, website, w.Write)
+//	if err == nil {
+//		err = w.Close()
+//	}
+package sitemapwriter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+const (
+	xmlHeader    = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	urlsetOpen   = `<urlset xmlns="` + gositemapfetcher.SitemapNamespace + `">` + "\n"
+	urlsetClose  = "</urlset>\n"
+	indexOpen    = `<sitemapindex xmlns="` + gositemapfetcher.SitemapNamespace + `">` + "\n"
+	indexClose   = "</sitemapindex>\n"
+	partOverhead = len(xmlHeader) + len(urlsetOpen) + len(urlsetClose)
+)
+
+// Options limits the size of each file written by a Writer.
+type Options struct {
+	// MaxURLs caps <url> entries per file. 0 => gositemapfetcher.SpecMaxURLsPerSitemap.
+	MaxURLs int
+	// MaxBytes caps the uncompressed size of each file. 0 => gositemapfetcher.SpecMaxSitemapBytes.
+	MaxBytes int
+}
+
+// CreateFunc opens the destination of the 1-based part n.
+type CreateFunc func(n int) (io.WriteCloser, error)
+
+// Writer streams items into one or more urlset documents, starting a new part
+// whenever the next entry would exceed Options.MaxURLs or Options.MaxBytes.
+// It is not safe for concurrent use.
+type Writer struct {
+	create   CreateFunc
+	maxURLs  int
+	maxBytes int
+
+	out     io.WriteCloser
+	buf     *bufio.Writer
+	parts   int
+	urls    int
+	bytes   int
+	entry   bytes.Buffer
+	enc     *xml.Encoder
+	written int
+}
+
+// New returns a Writer that opens each part with create.
+func New(create CreateFunc, opts Options) *Writer {
+	w := &Writer{create: create, maxURLs: opts.MaxURLs, maxBytes: opts.MaxBytes}
+	if w.maxURLs <= 0 {
+		w.maxURLs = gositemapfetcher.SpecMaxURLsPerSitemap
+	}
+	if w.maxBytes <= 0 {
+		w.maxBytes = gositemapfetcher.SpecMaxSitemapBytes
+	}
+	w.enc = xml.NewEncoder(&w.entry)
+	return w
+}
+
+// Write appends item; pass the method value as the Walk callback.
+func (w *Writer) Write(item gositemapfetcher.Item) error {
+	w.entry.Reset()
+	if err := encodeURL(&w.entry, w.enc, item); err != nil {
+		return err
+	}
+	size := w.entry.Len()
+	if size+partOverhead > w.maxBytes {
+		return fmt.Errorf("sitemapwriter: entry for %s is %d bytes, larger than MaxBytes", item.Loc, size)
+	}
+	if w.out != nil && (w.urls >= w.maxURLs || w.bytes+size+len(urlsetClose) > w.maxBytes) {
+		if err := w.closePart(); err != nil {
+			return err
+		}
+	}
+	if w.out == nil {
+		if err := w.openPart(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.buf.Write(w.entry.Bytes()); err != nil {
+		return err
+	}
+	w.urls++
+	w.bytes += size
+	w.written++
+	return nil
+}
+
+// Close finishes the current part. A Writer that received no items writes
+// nothing.
+func (w *Writer) Close() error {
+	if w.out == nil {
+		return nil
+	}
+	return w.closePart()
+}
+
+// Parts returns the number of parts opened so far.
+func (w *Writer) Parts() int {
+	return w.parts
+}
+
+// URLs returns the number of items written so far.
+func (w *Writer) URLs() int {
+	return w.written
+}
+
+func (w *Writer) openPart() error {
+	out, err := w.create(w.parts + 1)
+	if err != nil {
+		return err
+	}
+	w.parts++
+	w.out = out
+	w.buf = bufio.NewWriter(out)
+	w.urls = 0
+	w.bytes = len(xmlHeader) + len(urlsetOpen)
+	_, err = w.buf.WriteString(xmlHeader + urlsetOpen)
+	return err
+}
+
+func (w *Writer) closePart() error {
+	out := w.out
+	w.out = nil
+	_, err := w.buf.WriteString(urlsetClose)
+	if err == nil {
+		err = w.buf.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteURLSet writes items to dst as a single urlset document without
+// enforcing size limits.
+func WriteURLSet(dst io.Writer, items []gositemapfetcher.Item) error {
+	buf := bufio.NewWriter(dst)
+	if _, err := buf.WriteString(xmlHeader + urlsetOpen); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(buf)
+	for _, item := range items {
+		if err := encodeURL(buf, enc, item); err != nil {
+			return err
+		}
+	}
+	if _, err := buf.WriteString(urlsetClose); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// IndexEntry is one <sitemap> of a sitemapindex.
+type IndexEntry struct {
+	Loc     *url.URL
+	LastMod *time.Time
+}
+
+// WriteIndex writes entries to dst as a sitemapindex document.
+func WriteIndex(dst io.Writer, entries []IndexEntry) error {
+	if len(entries) > gositemapfetcher.SpecMaxURLsPerSitemap {
+		return fmt.Errorf("sitemapwriter: %d index entries exceed %d", len(entries), gositemapfetcher.SpecMaxURLsPerSitemap)
+	}
+	buf := bufio.NewWriter(dst)
+	if _, err := buf.WriteString(xmlHeader + indexOpen); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Loc == nil {
+			return errors.New("sitemapwriter: index entry without Loc")
+		}
+		buf.WriteString("  <sitemap>\n")
+		writeElement(buf, "loc", entry.Loc.String())
+		if entry.LastMod != nil {
+			writeElement(buf, "lastmod", formatTime(*entry.LastMod))
+		}
+		buf.WriteString("  </sitemap>\n")
+	}
+	if _, err := buf.WriteString(indexClose); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// Files returns a CreateFunc writing part n to dir/fmt.Sprintf(pattern, n),
+// e.g. "sitemap-%d.xml". Parts are gzip-compressed when pattern ends in ".gz".
+func Files(dir, pattern string) CreateFunc {
+	return func(n int) (io.WriteCloser, error) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf(pattern, n)))
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasSuffix(pattern, ".gz") {
+			return file, nil
+		}
+		return &gzipFile{Writer: gzip.NewWriter(file), file: file}, nil
+	}
+}
+
+// Gzip wraps create so that every part is gzip-compressed.
+func Gzip(create CreateFunc) CreateFunc {
+	return func(n int) (io.WriteCloser, error) {
+		out, err := create(n)
+		if err != nil {
+			return nil, err
+		}
+		return &gzipFile{Writer: gzip.NewWriter(out), file: out}, nil
+	}
+}
+
+type gzipFile struct {
+	*gzip.Writer
+	file io.Closer
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Writer.Close()
+	if closeErr := g.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ===================== Encoding =====================
+
+// stringWriter is satisfied by *bufio.Writer and *bytes.Buffer.
+type stringWriter interface {
+	io.Writer
+	WriteString(string) (int, error)
+}
+
+// encodeURL writes one <url> element; enc must write to dst.
+func encodeURL(dst stringWriter, enc *xml.Encoder, item gositemapfetcher.Item) error {
+	if item.Loc == nil {
+		return errors.New("sitemapwriter: item without Loc")
+	}
+	dst.WriteString("  <url>\n")
+	writeElement(dst, "loc", item.Loc.String())
+	if item.LastMod != nil {
+		writeElement(dst, "lastmod", formatTime(*item.LastMod))
+	}
+	if item.ChangeFreq != "" {
+		writeElement(dst, "changefreq", string(item.ChangeFreq))
+	}
+	if item.Priority != nil {
+		writeElement(dst, "priority", strconv.FormatFloat(*item.Priority, 'f', -1, 64))
+	}
+	if item.Mobile {
+		dst.WriteString(`    <mobile xmlns="` + gositemapfetcher.MobileNamespace + `"></mobile>` + "\n")
+	}
+	if len(item.Extensions) > 0 {
+		dst.WriteString("    ")
+		for _, ext := range item.Extensions {
+			if err := enc.Encode(ext); err != nil {
+				return err
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+		dst.WriteString("\n")
+	}
+	_, err := dst.WriteString("  </url>\n")
+	return err
+}
+
+func writeElement(dst stringWriter, name, value string) {
+	dst.WriteString("    <" + name + ">")
+	_ = xml.EscapeText(dst, []byte(value))
+	dst.WriteString("</" + name + ">\n")
+}
+
+// formatTime renders t as a W3C Datetime, dropping the clock for midnight UTC dates.
+func formatTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 && t.Location() == time.UTC {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,270 @@
+package sitemapwriter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gositemapfetcher "github.com/enot-style/go-sitemap-fetcher"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func testItems(t *testing.T, n int) []gositemapfetcher.Item {
+	t.Helper()
+	items := make([]gositemapfetcher.Item, 0, n)
+	lastMod := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	priority := 0.5
+	for i := range n {
+		loc, err := url.Parse(fmt.Sprintf("https://example.com/page?id=%d&a=b", i))
+		if err != nil {
+			t.Fatalf("failed to parse URL: %v", err)
+		}
+		items = append(items, gositemapfetcher.Item{Loc: loc, LastMod: &lastMod, ChangeFreq: "daily", Priority: &priority})
+	}
+	return items
+}
+
+func TestWriteURLSet(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteURLSet(&buf, testItems(t, 1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/page?id=0&amp;a=b</loc>
+    <lastmod>2024-01-02</lastmod>
+    <changefreq>daily</changefreq>
+    <priority>0.5</priority>
+  </url>
+</urlset>
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestWriter_Splits(t *testing.T) {
+	var parts []*bytes.Buffer
+	create := func(n int) (io.WriteCloser, error) {
+		if n != len(parts)+1 {
+			t.Fatalf("expected part %d, got %d", len(parts)+1, n)
+		}
+		buf := &bytes.Buffer{}
+		parts = append(parts, buf)
+		return nopCloser{buf}, nil
+	}
+
+	w := New(create, Options{MaxURLs: 2})
+	for _, item := range testItems(t, 5) {
+		if err := w.Write(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Parts() != 3 || len(parts) != 3 || w.URLs() != 5 {
+		t.Fatalf("expected 5 URLs in 3 parts, got %d in %d", w.URLs(), w.Parts())
+	}
+	if got := strings.Count(parts[2].String(), "<url>"); got != 1 {
+		t.Fatalf("expected 1 URL in the last part, got %d", got)
+	}
+
+	// A byte limit that fits two entries per part splits the same way.
+	parts = nil
+	entry := (len(twoEntryURLSet(t)) - partOverhead) / 2
+	w = New(create, Options{MaxBytes: partOverhead + 2*entry})
+	for _, item := range testItems(t, 5) {
+		if err := w.Write(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, part := range parts {
+		if part.Len() > partOverhead+2*entry {
+			t.Fatalf("part %d is %d bytes, over the limit", i+1, part.Len())
+		}
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts by size, got %d", len(parts))
+	}
+}
+
+// twoEntryURLSet returns a urlset with two test entries.
+func twoEntryURLSet(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := WriteURLSet(&buf, testItems(t, 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestFiles_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	w := New(Files(dir, "sitemap-%d.xml.gz"), Options{MaxURLs: 3})
+	for _, item := range testItems(t, 4) {
+		if err := w.Write(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	var entries []IndexEntry
+	for n := 1; n <= w.Parts(); n++ {
+		loc, _ := url.Parse(fmt.Sprintf("%s/sitemap-%d.xml.gz", server.URL, n))
+		entries = append(entries, IndexEntry{Loc: loc})
+	}
+	index, err := os.Create(filepath.Join(dir, "index.xml"))
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	if err := WriteIndex(index, entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index.Close()
+
+	file, err := os.Open(filepath.Join(dir, "sitemap-1.xml.gz"))
+	if err != nil {
+		t.Fatalf("failed to open part: %v", err)
+	}
+	defer file.Close()
+	if _, err := gzip.NewReader(file); err != nil {
+		t.Fatalf("expected a gzip part: %v", err)
+	}
+
+	indexURL, _ := url.Parse(server.URL + "/index.xml")
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true})
+	var got []gositemapfetcher.Item
+	err = fetcher.Walk(context.Background(), indexURL, func(item gositemapfetcher.Item) error {
+		got = append(got, item)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(got) != 4 || got[3].Loc.String() != "https://example.com/page?id=3&a=b" || got[0].ChangeFreq != "daily" {
+		t.Fatalf("expected written items to round-trip, got %v", got)
+	}
+}
+
+func TestWriteURLSet_Extensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+<url><loc>/a</loc><image:image><image:loc>https://cdn.example.com/a.png</image:loc></image:image></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, _ := url.Parse(server.URL + "/sitemap.xml")
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, KeepExtensions: true})
+	var items []gositemapfetcher.Item
+	err := fetcher.Walk(context.Background(), sitemapURL, func(item gositemapfetcher.Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteURLSet(&buf, items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `<image xmlns="http://www.google.com/schemas/sitemap-image/1.1"><loc xmlns="http://www.google.com/schemas/sitemap-image/1.1">https://cdn.example.com/a.png</loc></image>`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected the image extension to be written, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteURLSet_ExtensionNamespaceDecls(t *testing.T) {
+	var site bytes.Buffer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/copy.xml" {
+			_, _ = w.Write(site.Bytes())
+			return
+		}
+		// The extension declares its own namespaces, prefixed and default.
+		_, _ = w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>/a</loc><video:video xmlns:video="http://www.google.com/schemas/sitemap-video/1.1"><video:title>A</video:title></video:video><news xmlns="http://www.google.com/schemas/sitemap-news/0.9"><title>B</title></news></url></urlset>`))
+	}))
+	defer server.Close()
+
+	walk := func(path string) []gositemapfetcher.Item {
+		t.Helper()
+		sitemapURL, _ := url.Parse(server.URL + path)
+		fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true, KeepExtensions: true})
+		var items []gositemapfetcher.Item
+		if err := fetcher.Walk(context.Background(), sitemapURL, func(item gositemapfetcher.Item) error {
+			items = append(items, item)
+			return nil
+		}); err != nil {
+			t.Fatalf("walk of %s failed: %v", path, err)
+		}
+		return items
+	}
+
+	if err := WriteURLSet(&site, walk("/sitemap.xml")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(site.String(), "xmlns:video") || strings.Count(site.String(), `<video xmlns=`) != 1 {
+		t.Fatalf("expected each namespace declared once per element, got:\n%s", site.String())
+	}
+	items := walk("/copy.xml")
+	if len(items) != 1 || len(items[0].Extensions) != 2 {
+		t.Fatalf("expected the written extensions to parse back, got %+v", items)
+	}
+}
+
+func TestWriteURLSet_Mobile(t *testing.T) {
+	items := testItems(t, 1)
+	items[0].Mobile = true
+	var buf bytes.Buffer
+	if err := WriteURLSet(&buf, items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<mobile xmlns="http://www.google.com/schemas/sitemap-mobile/1.0"></mobile>`) {
+		t.Fatalf("expected a mobile element:\n%s", buf.String())
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	var got []gositemapfetcher.Item
+	fetcher := gositemapfetcher.New(gositemapfetcher.Options{IgnoreRobots: true})
+	if err := fetcher.Walk(context.Background(), sitemapURL, func(item gositemapfetcher.Item) error {
+		got = append(got, item)
+		return nil
+	}); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if len(got) != 1 || !got[0].Mobile {
+		t.Fatalf("expected the mobile flag to round-trip, got %+v", got)
+	}
+}
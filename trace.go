@@ -0,0 +1,84 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type attemptKey struct{}
+
+// withAttempt records in a request context which try of a retried fetch the
+// request is, for Options.TraceRequests.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// traceTransport wraps next so that each request it sends is logged at debug
+// level under Options.TraceRequests.
+func traceTransport(next http.RoundTripper, logger *slog.Logger) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &tracingTransport{next: next, logger: logger}
+}
+
+type tracingTransport struct {
+	next   http.RoundTripper
+	logger *slog.Logger
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !t.logger.Enabled(ctx, slog.LevelDebug) {
+		return t.next.RoundTrip(req)
+	}
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+	if !ok {
+		attempt = 1
+	}
+	attrs := []any{"method", req.Method, "url", req.URL.String(), "attempt", attempt}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.logger.DebugContext(ctx, "http request failed", append(attrs, "duration", time.Since(start), "error", err.Error())...)
+		return nil, err
+	}
+	attrs = append(attrs, "status", resp.StatusCode, "proto", resp.Proto, "headers_after", time.Since(start))
+	resp.Body = &tracedBody{ReadCloser: resp.Body, done: func(bytes int64, readErr error) {
+		record := append(attrs, "bytes", bytes, "duration", time.Since(start))
+		if readErr != nil {
+			record = append(record, "error", readErr.Error())
+		}
+		t.logger.DebugContext(ctx, "http request", record...)
+	}}
+	return resp, nil
+}
+
+// tracedBody counts the bytes read from a response body and reports them once,
+// when the body is closed.
+type tracedBody struct {
+	io.ReadCloser
+	bytes   int64
+	readErr error
+	once    sync.Once
+	done    func(bytes int64, readErr error)
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	if err != nil && err != io.EOF {
+		b.readErr = err
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.bytes, b.readErr) })
+	return err
+}
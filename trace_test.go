@@ -0,0 +1,72 @@
+package gositemapfetcher
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSitemapFetcher_TraceRequests(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old.xml":
+			http.Redirect(w, r, "/sitemap.xml", http.StatusMovedPermanently)
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sitemapURL, err := url.Parse(server.URL + "/old.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	if _, err := collectItems(New(Options{Logger: slog.New(handler), LogLevel: slog.LevelDebug}), sitemapURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if _, ok := handler.find("http request"); ok {
+		t.Fatal("expected no request traces without TraceRequests")
+	}
+
+	handler = &captureHandler{}
+	fetcher := New(Options{Logger: slog.New(handler), LogLevel: slog.LevelDebug, TraceRequests: true})
+	if _, err := collectItems(fetcher, sitemapURL); err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	var traces []capturedRecord
+	for _, record := range handler.records {
+		if record.msg == "http request" {
+			traces = append(traces, record)
+		}
+	}
+	// robots.txt, then both hops of the redirect.
+	if len(traces) != 3 {
+		t.Fatalf("expected 3 traced requests, got %d", len(traces))
+	}
+	if !strings.HasSuffix(traces[0].attrs["url"].String(), "/robots.txt") || traces[0].attrs["status"].Int64() != http.StatusNotFound {
+		t.Fatalf("expected the robots.txt request traced, got %v", traces[0].attrs)
+	}
+	if traces[1].attrs["status"].Int64() != http.StatusMovedPermanently {
+		t.Fatalf("expected the redirect hop traced, got %v", traces[1].attrs)
+	}
+	last := traces[2]
+	if last.level != slog.LevelDebug || last.attrs["method"].String() != http.MethodGet {
+		t.Fatalf("unexpected trace record: %v", last.attrs)
+	}
+	if !strings.HasSuffix(last.attrs["url"].String(), "/sitemap.xml") || last.attrs["status"].Int64() != http.StatusOK || last.attrs["attempt"].Int64() != 1 {
+		t.Fatalf("expected the sitemap response, got %v", last.attrs)
+	}
+	if last.attrs["bytes"].Int64() == 0 {
+		t.Fatalf("expected the body bytes read, got %v", last.attrs)
+	}
+	for _, key := range []string{"proto", "headers_after", "duration", "walk_id"} {
+		if _, ok := last.attrs[key]; !ok {
+			t.Fatalf("expected %q attr on the trace, got %v", key, last.attrs)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SitemapNamespace is the XML namespace of sitemaps.org urlset and sitemapindex documents.
+const SitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// MobileNamespace is the XML namespace of Google's <mobile:mobile/> extension.
+const MobileNamespace = "http://www.google.com/schemas/sitemap-mobile/1.0"
+
+// FindingRule names the sitemaps.org rule a Finding violates.
+type FindingRule string
+
+const (
+	// RuleWrongHost is a <loc> on a different scheme or host than its sitemap.
+	RuleWrongHost FindingRule = "wrong-host"
+	// RuleLocTooLong is a <loc> longer than SpecMaxLocLength characters.
+	RuleLocTooLong FindingRule = "loc-too-long"
+	// RuleTooManyURLs is a sitemap with more than SpecMaxURLsPerSitemap entries.
+	RuleTooManyURLs FindingRule = "too-many-urls"
+	// RuleTooLarge is a sitemap larger than SpecMaxSitemapBytes uncompressed.
+	RuleTooLarge FindingRule = "too-large"
+	// RuleInvalidLastMod is a <lastmod> that is not a W3C Datetime.
+	RuleInvalidLastMod FindingRule = "invalid-lastmod"
+	// RuleInvalidChangeFreq is a <changefreq> outside the protocol's values.
+	RuleInvalidChangeFreq FindingRule = "invalid-changefreq"
+	// RuleInvalidNamespace is a urlset or sitemapindex without SitemapNamespace.
+	RuleInvalidNamespace FindingRule = "invalid-namespace"
+	// RuleInvalidPriority is a <priority> that is not a number from 0.0 to 1.0.
+	RuleInvalidPriority FindingRule = "invalid-priority"
+)
+
+// Finding is a protocol violation reported by Validate.
+type Finding struct {
+	Rule FindingRule `json:"rule"`
+	// Sitemap is the sitemap URL the violation was found in.
+	Sitemap string `json:"sitemap"`
+	// Position is the 1-based index of the offending <url> entry, or 0 for
+	// findings about the sitemap as a whole.
+	Position int    `json:"position,omitempty"`
+	Loc      string `json:"loc,omitempty"`
+	// Value is the offending value as found in the sitemap.
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Position == 0 {
+		return fmt.Sprintf("%s: %s: %s", f.Sitemap, f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%s #%d: %s: %s", f.Sitemap, f.Position, f.Rule, f.Message)
+}
+
+// Validate walks website like Walk but, instead of emitting items, reports every
+// sitemaps.org protocol violation it finds. The options still apply, so limits,
+// robots.txt, and skip settings decide which sitemaps are checked. Findings are
+// returned in the order they were found, along with any error that ended the walk.
+func (f *SitemapFetcher) Validate(ctx context.Context, website *url.URL) ([]Finding, error) {
+	var findings []Finding
+	err := f.walk(ctx, []*url.URL{website}, func(Item) error { return nil }, walkHooks{
+		check: func(finding Finding) error {
+			findings = append(findings, finding)
+			return nil
+		},
+	})
+	return findings, err
+}
+
+// w3cDatetimeLayouts are the W3C Datetime forms accepted for <lastmod>.
+var w3cDatetimeLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+func isW3CDatetime(value string) bool {
+	for _, layout := range w3cDatetimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRoot reports a sitemap whose root element is not in SitemapNamespace.
+func checkRoot(sitemap *url.URL, name xml.Name, report func(Finding) error) error {
+	if name.Space == SitemapNamespace {
+		return nil
+	}
+	if name.Local != "urlset" && name.Local != "sitemapindex" {
+		return nil
+	}
+	value := name.Space
+	if value == "" {
+		value = "(none)"
+	}
+	return report(Finding{
+		Rule:    RuleInvalidNamespace,
+		Sitemap: sitemap.String(),
+		Value:   name.Space,
+		Message: fmt.Sprintf("%s namespace is %s, want %s", name.Local, value, SitemapNamespace),
+	})
+}
+
+// checkURLEntry reports the protocol violations of the <url> entry at position.
+func checkURLEntry(sitemap *url.URL, position int, entry xmlURLEntry, report func(Finding) error) error {
+	loc := strings.TrimSpace(entry.Loc)
+	finding := func(rule FindingRule, value, message string) error {
+		return report(Finding{
+			Rule:     rule,
+			Sitemap:  sitemap.String(),
+			Position: position,
+			Loc:      loc,
+			Value:    value,
+			Message:  message,
+		})
+	}
+
+	if position == SpecMaxURLsPerSitemap+1 {
+		if err := report(Finding{
+			Rule:     RuleTooManyURLs,
+			Sitemap:  sitemap.String(),
+			Position: position,
+			Message:  fmt.Sprintf("sitemap has more than %d URLs", SpecMaxURLsPerSitemap),
+		}); err != nil {
+			return err
+		}
+	}
+	if len(loc) > SpecMaxLocLength {
+		if err := finding(RuleLocTooLong, "", fmt.Sprintf("loc is %d characters, limit is %d", len(loc), SpecMaxLocLength)); err != nil {
+			return err
+		}
+	}
+	if resolved, err := resolveLocation(sitemap, loc); err == nil {
+		if !strings.EqualFold(resolved.Scheme, sitemap.Scheme) || !strings.EqualFold(resolved.Host, sitemap.Host) {
+			if err := finding(RuleWrongHost, resolved.Scheme+"://"+resolved.Host, fmt.Sprintf("loc is not on %s://%s", sitemap.Scheme, sitemap.Host)); err != nil {
+				return err
+			}
+		}
+	}
+	if value := strings.TrimSpace(entry.LastMod); value != "" && !isW3CDatetime(value) {
+		if err := finding(RuleInvalidLastMod, value, "lastmod is not a W3C Datetime"); err != nil {
+			return err
+		}
+	}
+	if value := strings.TrimSpace(entry.ChangeFreq); value != "" {
+		if !ChangeFreq(value).IsValid() {
+			if err := finding(RuleInvalidChangeFreq, value, "changefreq is not always, hourly, daily, weekly, monthly, yearly, or never"); err != nil {
+				return err
+			}
+		}
+	}
+	if value := strings.TrimSpace(entry.Priority); value != "" {
+		if priority, ok := parsePriorityValue(value); !ok || priority < 0 || priority > 1 {
+			if err := finding(RuleInvalidPriority, value, "priority is not a number from 0.0 to 1.0"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
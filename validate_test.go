@@ -0,0 +1,154 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSitemapFetcher_Validate(t *testing.T) {
+	longLoc := "/" + strings.Repeat("a", SpecMaxLocLength)
+	body := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>/ok</loc><lastmod>2024-01-02</lastmod><changefreq>daily</changefreq><priority>0.5</priority></url>
+<url><loc>https://other.example.com/x</loc></url>
+<url><loc>` + longLoc + `</loc></url>
+<url><loc>/bad-lastmod</loc><lastmod>Tue, 02 Jan 2024 00:00:00 GMT</lastmod></url>
+<url><loc>/bad-changefreq</loc><changefreq>sometimes</changefreq></url>
+<url><loc>/bad-priority</loc><priority>1.5</priority></url>
+</urlset>`
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	findings, err := New(Options{}).Validate(context.Background(), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		rule     FindingRule
+		position int
+	}{
+		{RuleWrongHost, 2},
+		{RuleLocTooLong, 3},
+		{RuleInvalidLastMod, 4},
+		{RuleInvalidChangeFreq, 5},
+		{RuleInvalidPriority, 6},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("expected %d findings, got %v", len(want), findings)
+	}
+	for i, finding := range findings {
+		if finding.Rule != want[i].rule || finding.Position != want[i].position {
+			t.Fatalf("finding %d: expected %s at %d, got %s", i, want[i].rule, want[i].position, finding)
+		}
+		if finding.Sitemap != sitemapURL.String() {
+			t.Fatalf("finding %d: expected sitemap %s, got %s", i, sitemapURL, finding.Sitemap)
+		}
+	}
+	if findings[4].Value != "1.5" {
+		t.Fatalf("expected offending value 1.5, got %q", findings[4].Value)
+	}
+}
+
+func TestSitemapFetcher_ValidateTooManyURLs(t *testing.T) {
+	var body strings.Builder
+	body.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for range SpecMaxURLsPerSitemap + 2 {
+		body.WriteString("<url><loc>/p</loc></url>")
+	}
+	body.WriteString("</urlset>")
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	findings, err := New(Options{IgnoreRobots: true}).Validate(context.Background(), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Rule != RuleTooManyURLs {
+		t.Fatalf("expected a single too-many-urls finding, got %v", findings)
+	}
+}
+
+func TestSitemapFetcher_ValidateNamespace(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	findings, err := New(Options{IgnoreRobots: true}).Validate(context.Background(), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Rule != RuleInvalidNamespace || findings[0].Position != 0 {
+		t.Fatalf("expected a single invalid-namespace finding, got %v", findings)
+	}
+}
+
+func TestSitemapFetcher_Strict(t *testing.T) {
+	sitemaps := map[string]string{
+		"/valid.xml":   `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>/a</loc><priority>0.4</priority></url></urlset>`,
+		"/invalid.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>/b</loc></url><url><loc>/c</loc><priority>2</priority></url></urlset>`,
+		"/no-ns.xml":   `<urlset><url><loc>/d</loc></url></urlset>`,
+	}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := sitemaps[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	for path, want := range map[string]FindingRule{"/invalid.xml": RuleInvalidPriority, "/no-ns.xml": RuleInvalidNamespace} {
+		sitemapURL, err := url.Parse(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to parse sitemap URL: %v", err)
+		}
+		_, err = collectItems(New(Options{Strict: true, IgnoreRobots: true}), sitemapURL)
+		var violation *ErrSpecViolation
+		if !errors.As(err, &violation) {
+			t.Fatalf("%s: expected ErrSpecViolation, got %v", path, err)
+		}
+		if violation.Finding.Rule != want {
+			t.Fatalf("%s: expected rule %s, got %s", path, want, violation.Finding.Rule)
+		}
+	}
+
+	sitemapURL, err := url.Parse(server.URL + "/valid.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	items, err := collectItems(New(Options{Strict: true, IgnoreRobots: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+}
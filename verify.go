@@ -0,0 +1,236 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultVerifyConcurrency = 4
+
+// VerifyOptions configures the liveness check run for every emitted Loc.
+type VerifyOptions struct {
+	// Concurrency is the number of checks in flight at once. 0 => 4.
+	// Items are still yielded one at a time, in sitemap order.
+	Concurrency int
+	// Interval is the minimum time between the start of two checks. 0 => no limit.
+	Interval time.Duration
+	// UseGET sends GET with "Range: bytes=0-0" instead of HEAD. Without it, a
+	// HEAD answered with 405 or 501 is retried once as a ranged GET.
+	UseGET bool
+}
+
+// LinkCheck is the outcome of checking an item's Loc.
+type LinkCheck struct {
+	// StatusCode is the status of the final response, after redirects.
+	StatusCode int
+	// FinalURL is the URL that produced the final response.
+	FinalURL *url.URL
+	// Redirects lists every URL requested, from Loc to FinalURL, when the check
+	// was redirected; nil otherwise.
+	Redirects []string
+	// Duration covers the whole check, including redirects.
+	Duration time.Duration
+	// Err is set when no response was received (DNS, TLS, timeout, redirect loop, ...).
+	Err error
+}
+
+// OK reports whether the check received a 2xx response.
+func (c *LinkCheck) OK() bool {
+	return c != nil && c.Err == nil && c.StatusCode >= 200 && c.StatusCode < 300
+}
+
+// linkChecker runs link checks ahead of delivery while keeping yield order.
+type linkChecker struct {
+	w           *walk
+	opts        VerifyOptions
+	concurrency int
+	queue       []*pendingLinkCheck
+
+	// ctx ends with the walk, so checks still running when it stops are cancelled.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+
+	mu        sync.Mutex
+	nextStart time.Time
+}
+
+type pendingLinkCheck struct {
+	item    Item
+	entries int
+	result  LinkCheck
+	done    chan struct{}
+}
+
+func newLinkChecker(w *walk, opts VerifyOptions) *linkChecker {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultVerifyConcurrency
+	}
+	ctx, cancel := context.WithCancel(w.ctx)
+	return &linkChecker{w: w, opts: opts, concurrency: concurrency, ctx: ctx, cancel: cancel}
+}
+
+// pending returns the number of items checked or being checked but not yet yielded.
+func (c *linkChecker) pending() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.queue)
+}
+
+// errNoLoc is the LinkCheck error for an item emitted without a valid Loc.
+var errNoLoc = errors.New("no valid loc to check")
+
+// submit starts checking item and yields the oldest items once Concurrency
+// checks are outstanding.
+func (c *linkChecker) submit(item Item, entries int) error {
+	pending := &pendingLinkCheck{item: item, entries: entries, done: make(chan struct{})}
+	if item.Loc == nil {
+		// Nothing to request for an entry emitted under InvalidLocEmit.
+		pending.result = LinkCheck{Err: errNoLoc}
+		close(pending.done)
+	} else {
+		c.running.Add(1)
+		go func() {
+			defer c.running.Done()
+			defer close(pending.done)
+			pending.result = c.check(item.Loc)
+		}()
+	}
+	c.queue = append(c.queue, pending)
+	for len(c.queue) >= c.concurrency {
+		if err := c.deliverOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush yields every outstanding item of the current sitemap.
+func (c *linkChecker) flush() error {
+	if c == nil {
+		return nil
+	}
+	for len(c.queue) > 0 {
+		if err := c.deliverOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *linkChecker) deliverOldest() error {
+	pending := c.queue[0]
+	c.queue = c.queue[1:]
+	waitStart := time.Now()
+	select {
+	case <-pending.done:
+	case <-c.w.ctx.Done():
+		c.queue = nil
+		return c.w.ctx.Err()
+	}
+	c.w.callbackTime += time.Since(waitStart)
+	pending.item.LinkCheck = &pending.result
+	if err := c.w.deliver(pending.item, pending.entries); err != nil {
+		// The rest of the sitemap is abandoned; its checks finish on their own,
+		// or are cancelled by close when the walk ends.
+		c.queue = nil
+		return err
+	}
+	return nil
+}
+
+// close cancels the checks still in flight and waits for them to return.
+func (c *linkChecker) close() {
+	if c == nil {
+		return
+	}
+	c.cancel()
+	c.running.Wait()
+	c.queue = nil
+}
+
+// wait blocks until the next check may start under Interval.
+func (c *linkChecker) wait() error {
+	if c.opts.Interval <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	now := time.Now()
+	start := c.nextStart
+	if start.Before(now) {
+		start = now
+	}
+	c.nextStart = start.Add(c.opts.Interval)
+	c.mu.Unlock()
+	return sleepWithContext(c.ctx, time.Until(start))
+}
+
+func (c *linkChecker) check(loc *url.URL) LinkCheck {
+	if err := c.wait(); err != nil {
+		return LinkCheck{Err: err}
+	}
+	start := time.Now()
+	method := http.MethodHead
+	if c.opts.UseGET {
+		method = http.MethodGet
+	}
+	result := c.request(method, loc)
+	if method == http.MethodHead && result.Err == nil &&
+		(result.StatusCode == http.StatusMethodNotAllowed || result.StatusCode == http.StatusNotImplemented) {
+		result = c.request(http.MethodGet, loc)
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (c *linkChecker) request(method string, loc *url.URL) LinkCheck {
+	f := c.w.f
+	req, cancel, err := f.newRequest(c.ctx, method, loc)
+	if err != nil {
+		return LinkCheck{Err: err}
+	}
+	defer cancel()
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	if err := f.authorize(c.ctx, req); err != nil {
+		return LinkCheck{Err: err}
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return LinkCheck{Err: err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return LinkCheck{
+		StatusCode: resp.StatusCode,
+		FinalURL:   cloneURL(resp.Request.URL),
+		Redirects:  redirectHops(resp),
+	}
+}
+
+// redirectHops returns the URLs requested to obtain resp, oldest first, or nil
+// if there were no redirects.
+func redirectHops(resp *http.Response) []string {
+	var hops []string
+	for req := resp.Request; req != nil; {
+		hops = append(hops, req.URL.String())
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	if len(hops) < 2 {
+		return nil
+	}
+	slices.Reverse(hops)
+	return hops
+}
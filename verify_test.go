@@ -0,0 +1,111 @@
+package gositemapfetcher
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_Verify(t *testing.T) {
+	var mu sync.Mutex
+	methods := map[string][]string{}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods[r.URL.Path] = append(methods[r.URL.Path], r.Method)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<urlset>
+<url><loc>/slow</loc></url>
+<url><loc>/ok</loc></url>
+<url><loc>/missing</loc></url>
+<url><loc>/moved</loc></url>
+<url><loc>/no-head</loc></url>
+</urlset>`))
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/ok":
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("Range") != "bytes=0-0" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{IgnoreRobots: true, Verify: &VerifyOptions{Concurrency: 3}})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		path   string
+		status int
+		final  string
+	}{
+		{"/slow", http.StatusOK, "/slow"},
+		{"/ok", http.StatusOK, "/ok"},
+		{"/missing", http.StatusNotFound, "/missing"},
+		{"/moved", http.StatusOK, "/ok"},
+		{"/no-head", http.StatusOK, "/no-head"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d items, got %d", len(want), len(items))
+	}
+	for i, item := range items {
+		if item.Loc.Path != want[i].path {
+			t.Fatalf("item %d: expected %s, got %s (order must be preserved)", i, want[i].path, item.Loc.Path)
+		}
+		check := item.LinkCheck
+		if check == nil || check.Err != nil {
+			t.Fatalf("item %d: expected a successful check, got %+v", i, check)
+		}
+		if check.StatusCode != want[i].status || check.FinalURL.Path != want[i].final {
+			t.Fatalf("item %d: expected %d at %s, got %d at %s", i, want[i].status, want[i].final, check.StatusCode, check.FinalURL)
+		}
+	}
+	if got := methods["/no-head"]; len(got) != 2 || got[0] != http.MethodHead || got[1] != http.MethodGet {
+		t.Fatalf("expected HEAD then GET for /no-head, got %v", got)
+	}
+}
+
+func TestSitemapFetcher_VerifyRespectsMaxURLs(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sitemap.xml" {
+			_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url><url><loc>/b</loc></url><url><loc>/c</loc></url></urlset>`))
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	fetcher := New(Options{IgnoreRobots: true, MaxURLs: 2, StopAtMaxURLs: true, Verify: &VerifyOptions{Concurrency: 4}})
+	items, err := collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[0].Loc.Path != "/a" || items[1].Loc.Path != "/b" {
+		t.Fatalf("expected /a and /b, got %v", items)
+	}
+	if !items[1].LinkCheck.OK() {
+		t.Fatalf("expected a successful check, got %+v", items[1].LinkCheck)
+	}
+}
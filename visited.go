@@ -0,0 +1,148 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// VisitedStore records what a walk has already processed, for loop detection
+// and dedupe. Sitemaps are recorded under "sitemap:" followed by their URL
+// without fragment, and, with Options.DedupeURLs, emitted entries under "url:"
+// followed by Item.Key. Implementations must be safe for concurrent use.
+//
+// The default is a fresh MemoryVisitedStore per walk. A store shared by several
+// walks, or persisted between runs, makes later walks skip whatever earlier
+// ones recorded.
+type VisitedStore interface {
+	// Visit records key and reports whether it had been recorded before.
+	Visit(ctx context.Context, key string) (bool, error)
+	// Visited reports whether key has been recorded, without recording it.
+	Visited(ctx context.Context, key string) (bool, error)
+}
+
+const (
+	visitedSitemapPrefix = "sitemap:"
+	visitedURLPrefix     = "url:"
+)
+
+// MemoryVisitedStore is the in-memory VisitedStore. The zero value is ready to
+// use; memory grows with the number of keys.
+type MemoryVisitedStore struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// Visit implements VisitedStore.
+func (s *MemoryVisitedStore) Visit(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[key]; ok {
+		return true, nil
+	}
+	if s.keys == nil {
+		s.keys = map[string]struct{}{}
+	}
+	s.keys[key] = struct{}{}
+	return false, nil
+}
+
+// Visited implements VisitedStore.
+func (s *MemoryVisitedStore) Visited(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[key]
+	return ok, nil
+}
+
+// Len returns the number of recorded keys.
+func (s *MemoryVisitedStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+// Reset forgets every recorded key.
+func (s *MemoryVisitedStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = nil
+}
+
+// withPrefix returns the recorded keys that start with prefix, with the prefix
+// removed, sorted.
+func (s *MemoryVisitedStore) withPrefix(prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for key := range s.keys {
+		if rest, ok := strings.CutPrefix(key, prefix); ok {
+			out = append(out, rest)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// visitSitemap records the sitemap at key and reports whether it was already visited.
+func (w *walk) visitSitemap(key string) (bool, error) {
+	seen, err := w.visited.Visit(w.ctx, visitedSitemapPrefix+key)
+	if err != nil {
+		return false, &ErrVisitedStore{Key: visitedSitemapPrefix + key, Err: err}
+	}
+	return seen, nil
+}
+
+// sitemapVisited reports whether the sitemap at key was visited. Store errors
+// count as not visited; visit reports them when it gets to the sitemap.
+func (w *walk) sitemapVisited(key string) bool {
+	seen, err := w.visited.Visited(w.ctx, visitedSitemapPrefix+key)
+	return err == nil && seen
+}
+
+// visitURL records an emitted entry under Options.DedupeURLs and reports
+// whether it was emitted before.
+func (w *walk) visitURL(item Item) (bool, error) {
+	key := visitedURLPrefix + item.Key()
+	seen, err := w.visited.Visit(w.ctx, key)
+	if err != nil {
+		return false, &ErrVisitedStore{Key: key, Err: err}
+	}
+	return seen, nil
+}
+
+// newEmittedSet returns the fetcher's cross-walk URL set, or nil unless
+// Options.RetainURLs is set.
+func newEmittedSet(opts Options) *MemoryVisitedStore {
+	if !opts.RetainURLs {
+		return nil
+	}
+	return &MemoryVisitedStore{}
+}
+
+// emittedBefore reports whether an earlier walk on the fetcher delivered item
+// under Options.RetainURLs. Items without a Loc are never retained.
+func (w *walk) emittedBefore(item Item) bool {
+	if w.f.emitted == nil || item.Loc == nil {
+		return false
+	}
+	seen, _ := w.f.emitted.Visited(w.ctx, item.Key())
+	return seen
+}
+
+// retain records a delivered item under Options.RetainURLs.
+func (w *walk) retain(item Item) {
+	if w.f.emitted == nil || item.Loc == nil {
+		return
+	}
+	_, _ = w.f.emitted.Visit(w.ctx, item.Key())
+}
+
+// Reset forgets the URLs retained by Options.RetainURLs, so the next walk
+// emits every URL again. It does not touch Options.VisitedStore.
+func (f *SitemapFetcher) Reset() {
+	if f.emitted != nil {
+		f.emitted.Reset()
+	}
+}
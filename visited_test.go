@@ -0,0 +1,169 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSitemapFetcher_DedupeURLs(t *testing.T) {
+	sitemaps := map[string]string{
+		"/sitemap.xml": `<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`,
+		"/a.xml":       `<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`,
+		"/b.xml":       `<urlset><url><loc>/two#dup</loc></url><url><loc>/three</loc></url><url><loc>/one</loc></url></urlset>`,
+	}
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := sitemaps[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	paths := func(items []Item) string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Loc.Path)
+		}
+		return strings.Join(out, ",")
+	}
+
+	items, err := collectItems(New(Options{IgnoreRobots: true}), sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if got := paths(items); got != "/one,/two,/two,/three,/one" {
+		t.Fatalf("expected duplicates without DedupeURLs, got %s", got)
+	}
+
+	store := &MemoryVisitedStore{}
+	fetcher := New(Options{IgnoreRobots: true, DedupeURLs: true, VisitedStore: store})
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if got := paths(items); got != "/one,/two,/three" {
+		t.Fatalf("expected each URL once, got %s", got)
+	}
+	if store.Len() != 6 {
+		t.Fatalf("expected 3 sitemaps and 3 URLs in the store, got %d", store.Len())
+	}
+
+	// The shared store already holds every sitemap, so a second walk does nothing.
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("second walk failed: %v", err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("expected the shared store to skip visited sitemaps, got %s", paths(items))
+	}
+	store.Reset()
+	items, err = collectItems(fetcher, sitemapURL)
+	if err != nil {
+		t.Fatalf("walk after reset failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items after Reset, got %d", len(items))
+	}
+}
+
+type failingVisitedStore struct {
+	MemoryVisitedStore
+	failOn string
+}
+
+func (s *failingVisitedStore) Visit(ctx context.Context, key string) (bool, error) {
+	if strings.HasPrefix(key, s.failOn) {
+		return false, errors.New("store down")
+	}
+	return s.MemoryVisitedStore.Visit(ctx, key)
+}
+
+func TestSitemapFetcher_VisitedStoreError(t *testing.T) {
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	for _, prefix := range []string{"sitemap:", "url:"} {
+		fetcher := New(Options{
+			IgnoreRobots: true,
+			DedupeURLs:   true,
+			SkipNon200:   true,
+			VisitedStore: &failingVisitedStore{failOn: prefix},
+		})
+		_, err := collectItems(fetcher, sitemapURL)
+		var storeErr *ErrVisitedStore
+		if !errors.As(err, &storeErr) || !strings.HasPrefix(storeErr.Key, prefix) {
+			t.Fatalf("%s: expected ErrVisitedStore, got %v", prefix, err)
+		}
+	}
+}
+
+func TestSitemapFetcher_RetainURLs(t *testing.T) {
+	urlset := `<urlset><url><loc>/one</loc></url><url><loc>/two</loc></url></urlset>`
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(urlset))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	fetcher := New(Options{IgnoreRobots: true, RetainURLs: true})
+	walk := func() []string {
+		items, err := collectItems(fetcher, sitemapURL)
+		if err != nil {
+			t.Fatalf("walk failed: %v", err)
+		}
+		var paths []string
+		for _, item := range items {
+			paths = append(paths, item.Loc.Path)
+		}
+		return paths
+	}
+
+	if got := strings.Join(walk(), ","); got != "/one,/two" {
+		t.Fatalf("expected every URL on the first walk, got %s", got)
+	}
+	if got := walk(); len(got) != 0 {
+		t.Fatalf("expected no URLs on an unchanged re-walk, got %v", got)
+	}
+	urlset = `<urlset><url><loc>/one</loc></url><url><loc>/three</loc></url></urlset>`
+	if got := strings.Join(walk(), ","); got != "/three" {
+		t.Fatalf("expected only the new URL, got %s", got)
+	}
+	fetcher.Reset()
+	if got := strings.Join(walk(), ","); got != "/one,/three" {
+		t.Fatalf("expected every URL after Reset, got %s", got)
+	}
+
+	// Without RetainURLs each walk starts over.
+	fetcher = New(Options{IgnoreRobots: true})
+	walk()
+	if got := walk(); len(got) != 2 {
+		t.Fatalf("expected 2 URLs on a re-walk without RetainURLs, got %v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type walkIDKey struct{}
+
+// WithWalkID returns a copy of ctx that makes a walk started with it use id as
+// its walk ID instead of generating one. Use it to tie a walk to the request or
+// job that started it.
+func WithWalkID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, walkIDKey{}, id)
+}
+
+// WalkID returns the walk ID carried by ctx. Every request made by a walk carries
+// the walk's ID in its context, so a custom http.RoundTripper can read it with
+// WalkID(req.Context()).
+func WalkID(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(walkIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// newWalkID returns a random 16-character hex ID.
+func newWalkID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
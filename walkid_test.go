@@ -0,0 +1,72 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestSitemapFetcher_WalkID(t *testing.T) {
+	var mu sync.Mutex
+	var headers []string
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Get("X-Request-ID"))
+		mu.Unlock()
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	handler := &captureHandler{}
+	fetcher := New(Options{
+		Logger:       slog.New(handler),
+		LogLevel:     slog.LevelDebug,
+		WalkIDHeader: "X-Request-ID",
+	})
+	ctx := WithWalkID(context.Background(), "job-42")
+	if err := fetcher.Walk(ctx, sitemapURL, func(Item) error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(headers) == 0 {
+		t.Fatal("expected requests")
+	}
+	for _, header := range headers {
+		if header != "job-42" {
+			t.Fatalf("expected X-Request-ID job-42 on every request, got %q", header)
+		}
+	}
+	record, ok := handler.find("parsed sitemap")
+	if !ok {
+		t.Fatal("expected a parsed sitemap record")
+	}
+	if got := record.attrs["walk_id"].String(); got != "job-42" {
+		t.Fatalf("expected walk_id job-42 in logs, got %q", got)
+	}
+	stats := fetcher.SitemapStats()
+	if len(stats) != 1 || stats[0].WalkID != "job-42" {
+		t.Fatalf("expected stats tagged with the walk ID, got %+v", stats)
+	}
+
+	headers = nil
+	for range 2 {
+		if err := fetcher.Walk(context.Background(), sitemapURL, func(Item) error { return nil }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if headers[0] == "" || headers[0] == "job-42" || headers[0] == headers[len(headers)-1] {
+		t.Fatalf("expected a distinct generated ID per walk, got %v", headers)
+	}
+}
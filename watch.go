@@ -0,0 +1,221 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeKind classifies a Change reported by Watch.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a URL not present in the previous walk.
+	ChangeAdded ChangeKind = iota + 1
+	// ChangeRemoved is a URL present in the previous walk but not the latest one.
+	ChangeRemoved
+	// ChangeModified is a URL whose lastmod, changefreq, or priority changed.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes how a sitemap entry differs between two walks.
+type Change struct {
+	Kind ChangeKind
+	// Item is the current entry, or the last known entry for ChangeRemoved.
+	Item Item
+	// Previous is the earlier entry for ChangeModified.
+	Previous *Item
+}
+
+// Watch walks website every interval and calls fn with entries added, removed,
+// or modified since the previous successful walk. The first walk reports every
+// entry as added. A failed walk is logged and does not produce removals; Watch
+// keeps going until ctx is done or fn returns an error. A non-positive interval
+// defaults to one minute.
+//
+// Removals are reported only after a complete walk. A walk that skipped
+// sitemaps (SkipNon200, SkipFetchErrors, an open circuit) or stopped at
+// StopAtMaxURLs reports additions and modifications, and entries it did not
+// see are kept for the next walk. With RetainURLs, SitemapStates, or a
+// VisitedStore, walks do not re-deliver unchanged entries, so Watch never
+// reports removals.
+func (f *SitemapFetcher) Watch(ctx context.Context, website *url.URL, interval time.Duration, fn func(Change) error) error {
+	if fn == nil {
+		return &ErrNilYield{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	previous := map[string]Item{}
+	for {
+		current := map[string]Item{}
+		// With CallbackConcurrency the callback runs on several goroutines.
+		var mu sync.Mutex
+		err := f.Walk(ctx, website, func(item Item) error {
+			detached := detachItem(item)
+			mu.Lock()
+			current[item.Key()] = detached
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			f.logger.WarnContext(
+				ctx,
+				"watch walk failed",
+				"url", website.String(),
+				"error", err.Error(),
+			)
+		} else {
+			complete := f.completeSnapshots() && f.SkippedSitemapCount() == 0 && !f.ReachedMaxURLs()
+			for _, change := range diffSnapshots(previous, current) {
+				if change.Kind == ChangeRemoved && !complete {
+					continue
+				}
+				if err := fn(change); err != nil {
+					return err
+				}
+			}
+			if !complete {
+				for key, item := range current {
+					previous[key] = item
+				}
+				current = previous
+			}
+			previous = current
+		}
+
+		if err := sleepWithContext(ctx, interval); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// completeSnapshots reports whether a walk that neither skips sitemaps nor
+// stops early delivers every entry of the site, which options that carry state
+// between walks prevent.
+func (f *SitemapFetcher) completeSnapshots() bool {
+	return !f.opts.RetainURLs && f.opts.SitemapStates == nil && f.opts.VisitedStore == nil
+}
+
+// Diff compares two item snapshots, such as the items collected by two walks,
+// and returns the added, removed, and modified entries ordered by Loc. Entries
+// are matched by canonical Loc; if a snapshot lists a Loc more than once, the
+// last occurrence wins.
+func Diff(previous, current []Item) []Change {
+	return diffSnapshots(snapshot(previous), snapshot(current))
+}
+
+// snapshot indexes items by Key, detaching them from any reused storage.
+func snapshot(items []Item) map[string]Item {
+	snap := make(map[string]Item, len(items))
+	for _, item := range items {
+		snap[item.Key()] = detachItem(item)
+	}
+	return snap
+}
+
+// Key identifies the item across sitemaps and walks: its Loc with the scheme
+// and host lowercased and without the fragment. It is "" when Loc is nil.
+// Watch, Diff, DuplicateReporter, and sitemapwriter.Merge match items by Key.
+func (i Item) Key() string {
+	if i.Loc == nil {
+		return ""
+	}
+	key := *i.Loc
+	key.Scheme = strings.ToLower(key.Scheme)
+	key.Host = strings.ToLower(key.Host)
+	key.Fragment = ""
+	key.RawFragment = ""
+	return key.String()
+}
+
+// Equal reports whether i and other are the same entry with the same metadata:
+// equal Keys, the same LastMod instant, ChangeFreq, and Priority. Where the
+// entry was found (Sitemap, Depth, Position) is not compared, so a false result
+// for equal Keys means the entry changed.
+func (i Item) Equal(other Item) bool {
+	return i.Key() == other.Key() &&
+		sameTime(i.LastMod, other.LastMod) &&
+		i.ChangeFreq == other.ChangeFreq &&
+		sameFloat(i.Priority, other.Priority)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func sameFloat(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// detachItem copies the pointer fields of item so it can be retained under ReuseItems.
+func detachItem(item Item) Item {
+	item.Sitemap = cloneURL(item.Sitemap)
+	if item.LastMod != nil {
+		lastMod := *item.LastMod
+		item.LastMod = &lastMod
+	}
+	if item.Priority != nil {
+		priority := *item.Priority
+		item.Priority = &priority
+	}
+	if item.SitemapLastMod != nil {
+		sitemapLastMod := *item.SitemapLastMod
+		item.SitemapLastMod = &sitemapLastMod
+	}
+	return item
+}
+
+// diffSnapshots compares two key → item maps, reporting changes in key order.
+func diffSnapshots(previous, current map[string]Item) []Change {
+	var changes []Change
+	for key, item := range current {
+		old, ok := previous[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeAdded, Item: item})
+		case !old.Equal(item):
+			changes = append(changes, Change{Kind: ChangeModified, Item: item, Previous: &old})
+		}
+	}
+	for key, item := range previous {
+		if _, ok := current[key]; !ok {
+			changes = append(changes, Change{Kind: ChangeRemoved, Item: item})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Item.Key() < changes[j].Item.Key()
+	})
+	return changes
+}
@@ -0,0 +1,232 @@
+package gositemapfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSitemapFetcher_Watch(t *testing.T) {
+	rounds := []string{
+		`<urlset><url><loc>/a</loc><lastmod>2024-01-01</lastmod></url><url><loc>/b</loc></url></urlset>`,
+		`<urlset><url><loc>/a</loc><lastmod>2024-02-01</lastmod></url><url><loc>/c</loc></url></urlset>`,
+	}
+	var round int32
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		i := min(int(atomic.AddInt32(&round, 1))-1, len(rounds)-1)
+		_, _ = w.Write([]byte(rounds[i]))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var changes []string
+	err = New(Options{}).Watch(ctx, sitemapURL, 10*time.Millisecond, func(change Change) error {
+		changes = append(changes, change.Kind.String()+" "+change.Item.Loc.Path)
+		if len(changes) == 5 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	want := []string{"added /a", "added /b", "modified /a", "removed /b", "added /c"}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, changes)
+		}
+	}
+}
+
+func TestSitemapFetcher_WatchCallbackConcurrency(t *testing.T) {
+	var body strings.Builder
+	body.WriteString("<urlset>")
+	for i := range 200 {
+		fmt.Fprintf(&body, "<url><loc>/page-%d</loc></url>", i)
+	}
+	body.WriteString("</urlset>")
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var added int
+	err = New(Options{CallbackConcurrency: 4}).Watch(ctx, sitemapURL, 10*time.Millisecond, func(change Change) error {
+		if change.Kind != ChangeAdded {
+			t.Errorf("expected only additions, got %v %s", change.Kind, change.Item.Loc.Path)
+		}
+		added++
+		if added == 200 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if added != 200 {
+		t.Fatalf("expected 200 additions, got %d", added)
+	}
+}
+
+func TestSitemapFetcher_WatchIncompleteWalk(t *testing.T) {
+	var round atomic.Int32
+	server := newTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			round.Add(1)
+			_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>/a.xml</loc></sitemap><sitemap><loc>/b.xml</loc></sitemap></sitemapindex>`))
+		case "/a.xml":
+			if round.Load() == 1 {
+				_, _ = w.Write([]byte(`<urlset><url><loc>/a</loc></url></urlset>`))
+			} else {
+				_, _ = w.Write([]byte(`<urlset><url><loc>/a2</loc></url></urlset>`))
+			}
+		case "/b.xml":
+			switch round.Load() {
+			case 1:
+				_, _ = w.Write([]byte(`<urlset><url><loc>/b1</loc></url><url><loc>/b2</loc></url></urlset>`))
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				_, _ = w.Write([]byte(`<urlset><url><loc>/b1</loc></url></urlset>`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sitemapURL, err := url.Parse(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Fatalf("failed to parse sitemap URL: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var changes []string
+	err = New(Options{SkipNon200: true}).Watch(ctx, sitemapURL, 10*time.Millisecond, func(change Change) error {
+		changes = append(changes, change.Kind.String()+" "+change.Item.Loc.Path)
+		if round.Load() >= 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	// Round 2 skips /b.xml: /a is removed only once a complete walk (round 3)
+	// confirms it, and /b1 and /b2 survive the outage.
+	want := "added /a,added /b1,added /b2,added /a2,removed /a,removed /b2"
+	if got := strings.Join(changes, ","); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	mustParse := func(raw string) *url.URL {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", raw, err)
+		}
+		return u
+	}
+	oldMod := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newMod := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	previous := []Item{
+		{Loc: mustParse("https://example.com/a"), LastMod: &oldMod},
+		{Loc: mustParse("https://example.com/b")},
+		{Loc: mustParse("https://example.com/same"), ChangeFreq: "daily"},
+	}
+	current := []Item{
+		{Loc: mustParse("https://example.com/a"), LastMod: &newMod},
+		{Loc: mustParse("https://example.com/c")},
+		{Loc: mustParse("https://example.com/same"), ChangeFreq: "daily"},
+	}
+
+	changes := Diff(previous, current)
+	want := []string{"modified /a", "removed /b", "added /c"}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, change := range changes {
+		if got := change.Kind.String() + " " + change.Item.Loc.Path; got != want[i] {
+			t.Fatalf("change %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+	if changes[0].Previous == nil || !changes[0].Previous.LastMod.Equal(oldMod) {
+		t.Fatalf("expected previous lastmod %v, got %+v", oldMod, changes[0].Previous)
+	}
+}
+
+func TestItem_KeyEqual(t *testing.T) {
+	mustParse := func(raw string) *url.URL {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", raw, err)
+		}
+		return u
+	}
+	utc := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	berlin := utc.In(time.FixedZone("CET", 60*60))
+	priority, other := 0.5, 0.8
+
+	a := Item{Loc: mustParse("https://example.com/a#top"), LastMod: &utc, ChangeFreq: ChangeFreqDaily, Priority: &priority, Depth: 1}
+	b := Item{Loc: mustParse("HTTPS://Example.COM/a"), LastMod: &berlin, ChangeFreq: ChangeFreqDaily, Priority: &priority, Sitemap: mustParse("https://example.com/s.xml")}
+	if a.Key() != "https://example.com/a" || a.Key() != b.Key() {
+		t.Fatalf("expected fragment-free matching keys, got %q and %q", a.Key(), b.Key())
+	}
+	if !a.Equal(b) {
+		t.Fatal("expected the same instant and metadata to be equal regardless of location and sitemap")
+	}
+	changed := b
+	changed.Priority = &other
+	if a.Equal(changed) {
+		t.Fatal("expected a priority change to be detected")
+	}
+	changed = b
+	changed.LastMod = nil
+	if a.Equal(changed) {
+		t.Fatal("expected a removed lastmod to be detected")
+	}
+	moved := a
+	moved.Loc = mustParse("https://example.com/b")
+	if a.Equal(moved) {
+		t.Fatal("expected different locs not to be equal")
+	}
+	if (Item{}).Key() != "" {
+		t.Fatal("expected an empty key for a nil Loc")
+	}
+}